@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 
+	"github.com/mirako-ai/mirako-cli/internal/errors"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/root"
 )
 
@@ -15,6 +16,6 @@ var (
 
 func main() {
 	if err := root.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(errors.ExitCode(err))
 	}
 }
@@ -0,0 +1,266 @@
+package agentstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func violationMessages(result *Result) []string {
+	var messages []string
+	for _, v := range result.Violations {
+		messages = append(messages, v.String())
+	}
+	return messages
+}
+
+func TestParseDataStream(t *testing.T) {
+	stream := strings.Join([]string{
+		`f:{"messageId":"msg-1"}`,
+		`0:"Hello"`,
+		`0:", world"`,
+		`b:{"toolCallId":"call-1","toolName":"weather"}`,
+		`c:{"toolCallId":"call-1","argsTextDelta":"{\"city\":"}`,
+		`c:{"toolCallId":"call-1","argsTextDelta":"\"Paris\"}"}`,
+		`9:{"toolCallId":"call-1","toolName":"weather","args":{"city":"Paris"}}`,
+		`a:{"toolCallId":"call-1","result":{"temperature":21}}`,
+		`e:{"finishReason":"tool-calls","isContinued":false}`,
+		`d:{"finishReason":"stop","usage":{"promptTokens":1,"completionTokens":2}}`,
+	}, "\n")
+
+	var kinds []EventKind
+	result, err := Parse(strings.NewReader(stream), FormatDataStream, func(e Event) { kinds = append(kinds, e.Kind) })
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	if len(result.Violations) != 0 {
+		t.Fatalf("unexpected violations %v", violationMessages(result))
+	}
+	if want := []EventKind{EventText, EventText, EventToolCall, EventToolResult, EventFinish}; !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("events = %v, want %v", kinds, want)
+	}
+	if result.Text != "Hello, world" || result.FinishReason != "stop" || len(result.ToolCalls) != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if call := result.ToolCalls[0]; call.Name != "weather" || string(call.Output) != `{"temperature":21}` {
+		t.Fatalf("unexpected tool call %+v", call)
+	}
+}
+
+func TestParseDataStreamViolations(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []string
+	}{
+		{"plain text", "Hello\n", []string{`line 1: "Hello" is not a data stream part; parts look like 0:"text"`, "stream ended without a finish message (d:)"}},
+		{"invalid JSON", "0:Hello\nd:{\"finishReason\":\"stop\"}\n", []string{"line 1: text part (0:) is not valid JSON"}},
+		{"text not a string", "0:{\"text\":\"hi\"}\nd:{\"finishReason\":\"stop\"}\n", []string{"line 1: text part (0:) must be a JSON string"}},
+		{"result without call", "a:{\"toolCallId\":\"x\",\"result\":1}\nd:{\"finishReason\":\"stop\"}\n", []string{"line 1: result for tool call x, which was not sent"}},
+		{"delta after call", "9:{\"toolCallId\":\"x\",\"toolName\":\"t\",\"args\":{}}\nc:{\"toolCallId\":\"x\",\"argsTextDelta\":\"{}\"}\nd:{\"finishReason\":\"tool-calls\"}\n", []string{"line 2: input delta for tool call x after its input was complete"}},
+		{"unfinished tool call", "b:{\"toolCallId\":\"x\",\"toolName\":\"t\"}\nd:{\"finishReason\":\"stop\"}\n", []string{"input of tool call x was never completed"}},
+		{"part after finish", "d:{\"finishReason\":\"stop\"}\n0:\"more\"\n", []string{"line 2: text part (0:) sent after the finish message"}},
+		{"bad finish reason", "d:{\"finishReason\":\"done\"}\n", []string{`line 1: unknown finish reason "done"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(strings.NewReader(tt.stream), FormatDataStream, nil)
+			if err != nil {
+				t.Fatalf("Parse() returned error: %v", err)
+			}
+			if got := violationMessages(result); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("violations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUIMessageStream(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"type":"start","messageId":"msg-1"}`,
+		``,
+		`: keep-alive`,
+		`data: {"type":"start-step"}`,
+		``,
+		`data: {"type":"text-start","id":"t1"}`,
+		``,
+		`data: {"type":"text-delta","id":"t1","delta":"Hi"}`,
+		``,
+		`data: {"type":"text-end","id":"t1"}`,
+		``,
+		`data: {"type":"tool-input-available","toolCallId":"call-1","toolName":"weather","input":{"city":"Paris"}}`,
+		``,
+		`data: {"type":"tool-output-error","toolCallId":"call-1","errorText":"offline"}`,
+		``,
+		`data: {"type":"data-weather","data":{}}`,
+		``,
+		`data: {"type":"finish-step"}`,
+		``,
+		`data: {"type":"finish"}`,
+		``,
+		`data: [DONE]`,
+		``,
+	}, "\n")
+
+	result, err := Parse(strings.NewReader(stream), FormatUIMessageStream, nil)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	if len(result.Violations) != 0 {
+		t.Fatalf("unexpected violations %v", violationMessages(result))
+	}
+	if result.Text != "Hi" || len(result.ToolCalls) != 1 || result.ToolCalls[0].Error != "offline" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	broken := "data: {\"type\":\"text-delta\",\"id\":\"t1\",\"delta\":\"Hi\"}\n\ndata: {\"type\":\"text-start\",\"id\":\"t2\"}\n\ndata: {\"type\":\"bogus\"}\n"
+	result, err = Parse(strings.NewReader(broken), FormatUIMessageStream, nil)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	want := []string{
+		"line 1: text-delta for part t1, which is not started",
+		`line 5: unknown chunk type "bogus"`,
+		"text part t2 was never ended",
+		"stream ended without a finish chunk",
+		"stream ended without data: [DONE]",
+	}
+	if got := violationMessages(result); !reflect.DeepEqual(got, want) {
+		t.Fatalf("violations = %q, want %q", got, want)
+	}
+}
+
+func TestClientSend(t *testing.T) {
+	var request struct {
+		ID       string            `json:"id"`
+		Messages []json.RawMessage `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("0:\"Hi\"\nd:{\"finishReason\":\"stop\"}\n"))
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL, BearerToken: "token"}
+	messages := []Message{{ID: "m1", Role: "user", Content: "Hello"}}
+	result, err := client.Send(context.Background(), "chat-1", messages, nil)
+	if err != nil {
+		t.Fatalf("Send() returned error: %v", err)
+	}
+	if request.ID != "chat-1" || len(request.Messages) != 1 || string(request.Messages[0]) != `{"id":"m1","role":"user","content":"Hello","parts":[{"type":"text","text":"Hello"}]}` {
+		t.Fatalf("unexpected request %+v", request)
+	}
+	if result.Format != FormatDataStream || result.Text != "Hi" || result.Status != http.StatusOK {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if got := violationMessages(result); !reflect.DeepEqual(got, []string{"response has no x-vercel-ai-data-stream: v1 header"}) {
+		t.Fatalf("violations = %q", got)
+	}
+
+	client.BearerToken = "wrong"
+	_, err = client.Send(context.Background(), "chat-1", messages, nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Status != http.StatusUnauthorized || statusErr.Body != "unauthorized" {
+		t.Fatalf("Send() error = %v, want HTTP 401", err)
+	}
+}
+
+// newConformanceServer fakes a custom agent endpoint. A conforming one
+// checks the bearer token, streams replies in parts and runs its tools; the
+// other accepts any token and sends each reply whole without headers.
+func newConformanceServer(t *testing.T, conforming bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conforming && r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		prompt := body.Messages[len(body.Messages)-1].Content
+
+		var parts []string
+		switch {
+		case !conforming:
+			parts = []string{`0:"Hello"`}
+		case prompt == DefaultToolPrompt:
+			parts = []string{
+				`9:{"toolCallId":"call-1","toolName":"search","args":{"q":"x"}}`,
+				`a:{"toolCallId":"call-1","result":["a"]}`,
+				`0:"I can search."`,
+			}
+		case prompt == longPrompt:
+			for i := 1; i <= 100; i++ {
+				parts = append(parts, `0:"`+strings.Repeat("x", i%7)+`\n"`)
+			}
+		default:
+			parts = []string{`0:"Hello"`, `0:"!"`}
+		}
+		if conforming {
+			w.Header().Set("x-vercel-ai-data-stream", "v1")
+			parts = append(parts, `d:{"finishReason":"stop"}`)
+		}
+		for _, part := range parts {
+			_, _ = w.Write([]byte(part + "\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunSuite(t *testing.T) {
+	server := newConformanceServer(t, true)
+	var reported []string
+	results := RunSuite(context.Background(), &Client{URL: server.URL, BearerToken: "token"}, SuiteOptions{}, func(r CheckResult) {
+		reported = append(reported, r.Name)
+	})
+	if want := []string{"simple reply", "tool call", "long stream", "client disconnect", "auth rejection"}; !reflect.DeepEqual(reported, want) {
+		t.Fatalf("reported checks = %v, want %v", reported, want)
+	}
+	for _, result := range results {
+		if result.Status != CheckPassed {
+			t.Errorf("check %s = %s %v, want passed", result.Name, result.Status, result.Failures)
+		}
+	}
+
+	server = newConformanceServer(t, false)
+	results = RunSuite(context.Background(), &Client{URL: server.URL, BearerToken: "token"}, SuiteOptions{}, nil)
+	statuses := map[string]CheckStatus{}
+	for _, result := range results {
+		statuses[result.Name] = result.Status
+	}
+	want := map[string]CheckStatus{
+		"simple reply":      CheckFailed,
+		"tool call":         CheckFailed,
+		"long stream":       CheckFailed,
+		"client disconnect": CheckFailed,
+		"auth rejection":    CheckFailed,
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("statuses = %v, want %v", statuses, want)
+	}
+	if failures := results[4].Failures; !reflect.DeepEqual(failures, []string{"endpoint accepted an invalid bearer token", "endpoint accepted no bearer token"}) {
+		t.Fatalf("auth rejection failures = %q", failures)
+	}
+
+	// Without a bearer token, the auth check is skipped
+	results = RunSuite(context.Background(), &Client{URL: server.URL}, SuiteOptions{}, nil)
+	if results[4].Status != CheckSkipped {
+		t.Fatalf("auth rejection = %s, want skipped", results[4].Status)
+	}
+}
@@ -0,0 +1,149 @@
+package agentstream
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Protocol headers that tell the platform which stream a response carries
+const (
+	dataStreamHeader      = "x-vercel-ai-data-stream"
+	uiMessageStreamHeader = "x-vercel-ai-ui-message-stream"
+)
+
+// Message is a chat message sent to the agent
+type Message struct {
+	ID      string
+	Role    string
+	Content string
+}
+
+type textPart struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// MarshalJSON writes the message with both the content of AI SDK 4 and the
+// parts of AI SDK 5, so that either version of useChat's backend accepts it
+func (m Message) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID      string     `json:"id"`
+		Role    string     `json:"role"`
+		Content string     `json:"content"`
+		Parts   []textPart `json:"parts"`
+	}{m.ID, m.Role, m.Content, []textPart{{Type: "text", Text: m.Content}}})
+}
+
+// Client sends chat turns to a custom agent endpoint
+type Client struct {
+	URL         string
+	BearerToken string
+	// HTTPClient is used for requests; http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// StatusError is returned when the endpoint answers with a non-2xx status
+type StatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("agent endpoint returned HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("agent endpoint returned HTTP %d: %s", e.Status, e.Body)
+}
+
+// Send posts the conversation in chat chatID and parses the streamed reply,
+// calling onEvent for each event as it arrives
+func (c *Client) Send(ctx context.Context, chatID string, messages []Message, onEvent func(Event)) (*Result, error) {
+	body, err := json.Marshal(struct {
+		ID       string    `json:"id"`
+		Messages []Message `json:"messages"`
+	}{chatID, messages})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid agent URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach agent endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &StatusError{Status: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+
+	format := DetectFormat(resp.Header)
+	var firstEvent time.Duration
+	result, err := Parse(resp.Body, format, func(event Event) {
+		if firstEvent == 0 {
+			firstEvent = time.Since(start)
+		}
+		if onEvent != nil {
+			onEvent(event)
+		}
+	})
+	result.Status = resp.StatusCode
+	result.FirstEvent = firstEvent
+	result.Duration = time.Since(start)
+	if err != nil {
+		return result, err
+	}
+	result.Violations = append(headerViolations(resp.Header, format), result.Violations...)
+	return result, nil
+}
+
+// DetectFormat returns the stream format of a response from its headers.
+// Server-sent events are the UI message stream; anything else is read as a
+// data stream.
+func DetectFormat(header http.Header) Format {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType == "text/event-stream" || header.Get(uiMessageStreamHeader) != "" {
+		return FormatUIMessageStream
+	}
+	return FormatDataStream
+}
+
+func headerViolations(header http.Header, format Format) []Violation {
+	name := dataStreamHeader
+	if format == FormatUIMessageStream {
+		name = uiMessageStreamHeader
+	}
+	if header.Get(name) == "v1" {
+		return nil
+	}
+	return []Violation{{Message: fmt.Sprintf("response has no %s: v1 header", name)}}
+}
+
+// NewID returns a random ID for chats and messages
+func NewID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
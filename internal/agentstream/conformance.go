@@ -0,0 +1,209 @@
+package agentstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckStatus is the outcome of a conformance check
+type CheckStatus string
+
+const (
+	CheckPassed  CheckStatus = "passed"
+	CheckFailed  CheckStatus = "failed"
+	CheckSkipped CheckStatus = "skipped"
+)
+
+// CheckResult is the outcome of one conformance check
+type CheckResult struct {
+	Name     string      `json:"name"`
+	Status   CheckStatus `json:"status"`
+	Failures []string    `json:"failures,omitempty"`
+	// Reason says why the check was skipped
+	Reason   string        `json:"reason,omitempty"`
+	Duration time.Duration `json:"-"`
+}
+
+// SuiteOptions configure the conformance suite
+type SuiteOptions struct {
+	// MaxFirstEvent is the longest a reply may take to start streaming
+	MaxFirstEvent time.Duration
+	// Timeout bounds each conversation; 0 means no limit
+	Timeout time.Duration
+	// ToolPrompt is a message that makes the agent call a tool
+	ToolPrompt string
+}
+
+// Default prompts of the conformance suite
+const (
+	DefaultToolPrompt = "Use one of your tools to answer: what can you look up for me?"
+	greetingPrompt    = "Hello! Please reply with a short greeting."
+	longPrompt        = "Count from 1 to 100, writing each number on its own line."
+)
+
+// minStreamedTextEvents is the number of text events below which a long
+// reply is taken to be sent whole rather than streamed
+const minStreamedTextEvents = 2
+
+type check struct {
+	name string
+	run  func(ctx context.Context, c *Client, options SuiteOptions) CheckResult
+}
+
+// checks are the conformance checks, in the order they run
+var checks = []check{
+	{"simple reply", checkSimpleReply},
+	{"tool call", checkToolCall},
+	{"long stream", checkLongStream},
+	{"client disconnect", checkClientDisconnect},
+	{"auth rejection", checkAuthRejection},
+}
+
+// RunSuite runs the conformance checks against the endpoint of c, calling
+// onResult, which may be nil, as each check finishes
+func RunSuite(ctx context.Context, c *Client, options SuiteOptions, onResult func(CheckResult)) []CheckResult {
+	if options.ToolPrompt == "" {
+		options.ToolPrompt = DefaultToolPrompt
+	}
+	results := make([]CheckResult, 0, len(checks))
+	for _, check := range checks {
+		start := time.Now()
+		result := check.run(ctx, c, options)
+		result.Name = check.name
+		result.Duration = time.Since(start)
+		switch {
+		case result.Status == CheckSkipped:
+		case len(result.Failures) > 0:
+			result.Status = CheckFailed
+		default:
+			result.Status = CheckPassed
+		}
+		results = append(results, result)
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+	return results
+}
+
+// converse sends prompt as the first turn of a new chat
+func converse(ctx context.Context, c *Client, options SuiteOptions, prompt string, onEvent func(Event)) (*Result, error) {
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	messages := []Message{{ID: NewID(), Role: "user", Content: prompt}}
+	return c.Send(ctx, NewID(), messages, onEvent)
+}
+
+// replyFailures checks the framing, headers and timing of a reply
+func replyFailures(result *Result, err error, options SuiteOptions) []string {
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var failures []string
+	for _, violation := range result.Violations {
+		failures = append(failures, violation.String())
+	}
+	switch {
+	case result.FirstEvent == 0:
+		failures = append(failures, "reply had no events")
+	case options.MaxFirstEvent > 0 && result.FirstEvent > options.MaxFirstEvent:
+		failures = append(failures, fmt.Sprintf("first event arrived after %s, more than %s", result.FirstEvent.Round(time.Millisecond), options.MaxFirstEvent))
+	}
+	return failures
+}
+
+func checkSimpleReply(ctx context.Context, c *Client, options SuiteOptions) CheckResult {
+	result, err := converse(ctx, c, options, greetingPrompt, nil)
+	failures := replyFailures(result, err, options)
+	if err == nil && strings.TrimSpace(result.Text) == "" {
+		failures = append(failures, "reply has no text")
+	}
+	return CheckResult{Failures: failures}
+}
+
+func checkToolCall(ctx context.Context, c *Client, options SuiteOptions) CheckResult {
+	result, err := converse(ctx, c, options, options.ToolPrompt, nil)
+	failures := replyFailures(result, err, options)
+	if err != nil {
+		return CheckResult{Failures: failures}
+	}
+	if len(result.ToolCalls) == 0 && len(failures) == 0 {
+		return CheckResult{Status: CheckSkipped, Reason: "the agent made no tool call; use --tool-prompt with a message that makes it call one"}
+	}
+	for _, call := range result.ToolCalls {
+		if !isJSONObject(call.Input) {
+			failures = append(failures, fmt.Sprintf("input of tool call %s (%s) is not a JSON object", call.ID, call.Name))
+		}
+		// The platform does not run tools, so the endpoint must send results
+		if call.Output == nil && call.Error == "" {
+			failures = append(failures, fmt.Sprintf("tool call %s (%s) has no result", call.ID, call.Name))
+		}
+	}
+	return CheckResult{Failures: failures}
+}
+
+func checkLongStream(ctx context.Context, c *Client, options SuiteOptions) CheckResult {
+	textEvents := 0
+	result, err := converse(ctx, c, options, longPrompt, func(event Event) {
+		if event.Kind == EventText {
+			textEvents++
+		}
+	})
+	failures := replyFailures(result, err, options)
+	if err == nil && textEvents < minStreamedTextEvents {
+		failures = append(failures, fmt.Sprintf("reply arrived in %d text parts; it should be streamed in parts as it is generated", textEvents))
+	}
+	return CheckResult{Failures: failures}
+}
+
+// checkClientDisconnect drops a reply after its first event, as the platform
+// does when a user interrupts the avatar, then checks the endpoint still answers
+func checkClientDisconnect(ctx context.Context, c *Client, options SuiteOptions) CheckResult {
+	dropCtx, drop := context.WithCancel(ctx)
+	defer drop()
+	_, err := converse(dropCtx, c, options, longPrompt, func(Event) { drop() })
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return CheckResult{Failures: []string{err.Error()}}
+	}
+
+	result, err := converse(ctx, c, options, greetingPrompt, nil)
+	var failures []string
+	for _, failure := range replyFailures(result, err, options) {
+		failures = append(failures, "after a disconnect: "+failure)
+	}
+	return CheckResult{Failures: failures}
+}
+
+func checkAuthRejection(ctx context.Context, c *Client, options SuiteOptions) CheckResult {
+	if c.BearerToken == "" {
+		return CheckResult{Status: CheckSkipped, Reason: "no bearer token is configured"}
+	}
+
+	var failures []string
+	for _, attempt := range []struct{ name, token string }{
+		{"an invalid bearer token", "invalid-" + NewID()},
+		{"no bearer token", ""},
+	} {
+		unauthorized := *c
+		unauthorized.BearerToken = attempt.token
+		_, err := converse(ctx, &unauthorized, options, greetingPrompt, nil)
+		var statusErr *StatusError
+		switch {
+		case err == nil:
+			failures = append(failures, fmt.Sprintf("endpoint accepted %s", attempt.name))
+		case !errors.As(err, &statusErr):
+			failures = append(failures, fmt.Sprintf("request with %s failed: %v", attempt.name, err))
+		case statusErr.Status != http.StatusUnauthorized && statusErr.Status != http.StatusForbidden:
+			failures = append(failures, fmt.Sprintf("request with %s got HTTP %d, want 401 or 403", attempt.name, statusErr.Status))
+		}
+	}
+	return CheckResult{Failures: failures}
+}
@@ -0,0 +1,159 @@
+package agentstream
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// dataStreamPart holds the fields of the object payloads of data stream parts
+type dataStreamPart struct {
+	ToolCallID    *string         `json:"toolCallId"`
+	ToolName      *string         `json:"toolName"`
+	Args          json.RawMessage `json:"args"`
+	ArgsTextDelta *string         `json:"argsTextDelta"`
+	Result        json.RawMessage `json:"result"`
+	FinishReason  *string         `json:"finishReason"`
+	MessageID     *string         `json:"messageId"`
+}
+
+// dataStreamPartNames names the part types for violation messages
+var dataStreamPartNames = map[string]string{
+	"0": "text",
+	"2": "data",
+	"3": "error",
+	"8": "message annotations",
+	"9": "tool call",
+	"a": "tool result",
+	"b": "tool call streaming start",
+	"c": "tool call delta",
+	"d": "finish message",
+	"e": "finish step",
+	"f": "start step",
+	"g": "reasoning",
+	"h": "source",
+	"i": "redacted reasoning",
+	"j": "reasoning signature",
+	"k": "file",
+}
+
+func (p *parser) dataStreamLine(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	prefix, payload, ok := strings.Cut(line, ":")
+	name, known := dataStreamPartNames[prefix]
+	if !ok || !known {
+		p.violate("%q is not a data stream part; parts look like 0:\"text\"", truncate(line))
+		return
+	}
+	raw := json.RawMessage(payload)
+	if !json.Valid(raw) {
+		p.violate("%s part (%s:) is not valid JSON", name, prefix)
+		return
+	}
+	if p.finished {
+		p.violate("%s part (%s:) sent after the finish message", name, prefix)
+		return
+	}
+
+	switch prefix {
+	case "0", "g", "3":
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			p.violate("%s part (%s:) must be a JSON string", name, prefix)
+			return
+		}
+		kind := map[string]EventKind{"0": EventText, "g": EventReasoning, "3": EventError}[prefix]
+		p.emit(Event{Kind: kind, Text: text})
+	case "2", "8":
+		var values []json.RawMessage
+		if err := json.Unmarshal(raw, &values); err != nil {
+			p.violate("%s part (%s:) must be a JSON array", name, prefix)
+		}
+	case "i", "j", "h", "k":
+		// Accepted, but not shown
+	default:
+		var part dataStreamPart
+		if err := json.Unmarshal(raw, &part); err != nil {
+			p.violate("%s part (%s:) must be a JSON object", name, prefix)
+			return
+		}
+		p.dataStreamObject(prefix, name, part)
+	}
+}
+
+func (p *parser) dataStreamObject(prefix, name string, part dataStreamPart) {
+	id := ""
+	if part.ToolCallID != nil {
+		id = *part.ToolCallID
+	}
+	requireID := func() bool {
+		if id == "" {
+			p.violate("%s part (%s:) has no toolCallId", name, prefix)
+			return false
+		}
+		return true
+	}
+	requireName := func() bool {
+		if part.ToolName == nil || *part.ToolName == "" {
+			p.violate("%s part (%s:) has no toolName", name, prefix)
+			return false
+		}
+		return true
+	}
+
+	switch prefix {
+	case "b":
+		if requireID() && requireName() {
+			p.startToolCall(id, *part.ToolName)
+		}
+	case "c":
+		if !requireID() {
+			return
+		}
+		if part.ArgsTextDelta == nil {
+			p.violate("%s part (%s:) has no argsTextDelta", name, prefix)
+			return
+		}
+		p.toolInputDelta(id)
+	case "9":
+		if !requireID() || !requireName() {
+			return
+		}
+		if !isJSONObject(part.Args) {
+			p.violate("tool call %s has no args object", id)
+			return
+		}
+		p.toolCall(id, *part.ToolName, part.Args)
+	case "a":
+		if !requireID() {
+			return
+		}
+		if part.Result == nil {
+			p.violate("tool result for %s has no result", id)
+			return
+		}
+		p.toolResult(id, part.Result, "")
+	case "f":
+		if part.MessageID == nil {
+			p.violate("start step part (f:) has no messageId")
+		}
+	case "e":
+		if part.FinishReason == nil {
+			p.violate("finish step part (e:) has no finishReason")
+		} else if !finishReasons[*part.FinishReason] {
+			p.violate("unknown finish reason %q", *part.FinishReason)
+		}
+	case "d":
+		reason := ""
+		if part.FinishReason != nil {
+			reason = *part.FinishReason
+		}
+		p.finish(reason, true)
+	}
+}
+
+func isJSONObject(raw json.RawMessage) bool {
+	var value map[string]json.RawMessage
+	return raw != nil && json.Unmarshal(raw, &value) == nil && value != nil
+}
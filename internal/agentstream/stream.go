@@ -0,0 +1,293 @@
+// Package agentstream plays the platform side of the Vercel AI SDK streaming
+// protocols that custom agents implement: it sends chat turns and parses the
+// streamed response, reporting where it breaks the protocol
+package agentstream
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format is the streaming protocol of a response
+type Format string
+
+const (
+	// FormatDataStream is the line based data stream of AI SDK 4, where each
+	// line is a part such as 0:"text"
+	FormatDataStream Format = "data-stream"
+	// FormatUIMessageStream is the server-sent events stream of AI SDK 5, where
+	// each event is a JSON chunk such as {"type":"text-delta"}
+	FormatUIMessageStream Format = "ui-message-stream"
+)
+
+// EventKind is the kind of a streamed event
+type EventKind string
+
+const (
+	EventText       EventKind = "text"
+	EventReasoning  EventKind = "reasoning"
+	EventToolCall   EventKind = "tool-call"
+	EventToolResult EventKind = "tool-result"
+	EventToolError  EventKind = "tool-error"
+	EventError      EventKind = "error"
+	EventFinish     EventKind = "finish"
+)
+
+// Event is a part of a response as it arrives
+type Event struct {
+	Kind EventKind
+	// Text is the text or reasoning delta, the error message, or the finish reason
+	Text       string
+	ToolCallID string
+	ToolName   string
+	// Value is the tool input or output
+	Value json.RawMessage
+	// Line is the line of the response the event was read from
+	Line int
+}
+
+// ToolCall is a tool call made by the agent, with its result if one was sent
+type ToolCall struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name"`
+	Input  json.RawMessage `json:"input,omitempty"`
+	Output json.RawMessage `json:"output,omitempty"`
+	Error  string          `json:"error,omitempty"`
+
+	streamed  bool
+	available bool
+}
+
+// Violation is a place where a response breaks the protocol
+type Violation struct {
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	if v.Line == 0 {
+		return v.Message
+	}
+	return fmt.Sprintf("line %d: %s", v.Line, v.Message)
+}
+
+// Result is a parsed response
+type Result struct {
+	Status       int         `json:"status,omitempty"`
+	Format       Format      `json:"format"`
+	Text         string      `json:"text"`
+	Reasoning    string      `json:"reasoning,omitempty"`
+	ToolCalls    []*ToolCall `json:"tool_calls,omitempty"`
+	Errors       []string    `json:"errors,omitempty"`
+	FinishReason string      `json:"finish_reason,omitempty"`
+	Violations   []Violation `json:"violations,omitempty"`
+
+	// FirstEvent is the time from sending the request to the first event
+	FirstEvent time.Duration `json:"-"`
+	// Duration is the time from sending the request to the end of the response
+	Duration time.Duration `json:"-"`
+}
+
+// finishReasons are the finish reasons the AI SDK defines
+var finishReasons = map[string]bool{
+	"stop":           true,
+	"length":         true,
+	"content-filter": true,
+	"tool-calls":     true,
+	"error":          true,
+	"other":          true,
+	"unknown":        true,
+}
+
+// maxLineSize bounds a single line of a response
+const maxLineSize = 16 << 20
+
+// Parse reads a response in the given format, calling onEvent, which may be
+// nil, for each event as it arrives. Violations are collected in the result;
+// the error is only set when the response could not be read, in which case
+// the result holds what was read before.
+func Parse(r io.Reader, format Format, onEvent func(Event)) (*Result, error) {
+	p := &parser{
+		result:  &Result{Format: format},
+		onEvent: onEvent,
+		tools:   map[string]*ToolCall{},
+		open:    map[string]bool{},
+	}
+	handle := p.dataStreamLine
+	if format == FormatUIMessageStream {
+		handle = p.eventStreamLine
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		p.line++
+		handle(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return p.result, fmt.Errorf("failed to read response: %w", err)
+	}
+	p.end()
+	return p.result, nil
+}
+
+type parser struct {
+	result  *Result
+	onEvent func(Event)
+	line    int
+
+	tools    map[string]*ToolCall
+	open     map[string]bool
+	inStep   bool
+	finished bool
+
+	// Server-sent events state
+	data     []string
+	dataLine int
+	done     bool
+}
+
+func (p *parser) violate(format string, args ...any) {
+	p.violateAt(p.line, format, args...)
+}
+
+func (p *parser) violateAt(line int, format string, args ...any) {
+	p.result.Violations = append(p.result.Violations, Violation{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) emit(event Event) {
+	if event.Line == 0 {
+		event.Line = p.line
+	}
+	switch event.Kind {
+	case EventText:
+		p.result.Text += event.Text
+	case EventReasoning:
+		p.result.Reasoning += event.Text
+	case EventError:
+		p.result.Errors = append(p.result.Errors, event.Text)
+	case EventFinish:
+		p.result.FinishReason = event.Text
+	}
+	if p.onEvent != nil {
+		p.onEvent(event)
+	}
+}
+
+// startToolCall records a tool call whose input is streamed in deltas
+func (p *parser) startToolCall(id, name string) {
+	if _, exists := p.tools[id]; exists {
+		p.violate("tool call %s was already started", id)
+		return
+	}
+	call := &ToolCall{ID: id, Name: name, streamed: true}
+	p.tools[id] = call
+	p.result.ToolCalls = append(p.result.ToolCalls, call)
+}
+
+// toolInputDelta checks a delta of streamed tool call input
+func (p *parser) toolInputDelta(id string) {
+	call, ok := p.tools[id]
+	switch {
+	case !ok:
+		p.violate("input delta for tool call %s, which was not started", id)
+	case call.available:
+		p.violate("input delta for tool call %s after its input was complete", id)
+	}
+}
+
+// toolCall records the complete input of a tool call
+func (p *parser) toolCall(id, name string, input json.RawMessage) {
+	call, ok := p.tools[id]
+	switch {
+	case !ok:
+		call = &ToolCall{ID: id, Name: name}
+		p.tools[id] = call
+		p.result.ToolCalls = append(p.result.ToolCalls, call)
+	case call.available:
+		p.violate("tool call %s was already sent", id)
+		return
+	case call.Name != name:
+		p.violate("tool call %s was started as %s but completed as %s", id, call.Name, name)
+	}
+	call.Input = input
+	call.available = true
+	p.emit(Event{Kind: EventToolCall, ToolCallID: id, ToolName: call.Name, Value: input})
+}
+
+// toolResult records the output or error of a tool call
+func (p *parser) toolResult(id string, output json.RawMessage, errorText string) {
+	call, ok := p.tools[id]
+	if !ok || !call.available {
+		p.violate("result for tool call %s, which was not sent", id)
+		return
+	}
+	if call.Output != nil || call.Error != "" {
+		p.violate("tool call %s already has a result", id)
+		return
+	}
+	if errorText != "" {
+		call.Error = errorText
+		p.emit(Event{Kind: EventToolError, ToolCallID: id, ToolName: call.Name, Text: errorText})
+		return
+	}
+	call.Output = output
+	p.emit(Event{Kind: EventToolResult, ToolCallID: id, ToolName: call.Name, Value: output})
+}
+
+func (p *parser) finish(reason string, required bool) {
+	switch {
+	case reason == "" && required:
+		p.violate("finish message has no finishReason")
+	case reason != "" && !finishReasons[reason]:
+		p.violate("unknown finish reason %q", reason)
+	}
+	p.finished = true
+	p.emit(Event{Kind: EventFinish, Text: reason})
+}
+
+func (p *parser) end() {
+	if p.result.Format == FormatUIMessageStream {
+		p.dispatch()
+	}
+	for _, call := range p.result.ToolCalls {
+		if call.streamed && !call.available {
+			p.violateAt(0, "input of tool call %s was never completed", call.ID)
+		}
+	}
+	if p.result.Format == FormatDataStream {
+		if !p.finished {
+			p.violateAt(0, "stream ended without a finish message (d:)")
+		}
+		return
+	}
+	open := make([]string, 0, len(p.open))
+	for key := range p.open {
+		open = append(open, key)
+	}
+	sort.Strings(open)
+	for _, key := range open {
+		kind, id, _ := strings.Cut(key, ":")
+		p.violateAt(0, "%s part %s was never ended", kind, id)
+	}
+	if !p.finished {
+		p.violateAt(0, "stream ended without a finish chunk")
+	}
+	if !p.done {
+		p.violateAt(0, "stream ended without data: [DONE]")
+	}
+}
+
+// truncate shortens s for use in a violation message
+func truncate(s string) string {
+	const max = 60
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "…"
+}
@@ -0,0 +1,201 @@
+package agentstream
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// uiChunk holds the fields of UI message stream chunks
+type uiChunk struct {
+	Type           string          `json:"type"`
+	ID             *string         `json:"id"`
+	Delta          *string         `json:"delta"`
+	ToolCallID     *string         `json:"toolCallId"`
+	ToolName       *string         `json:"toolName"`
+	InputTextDelta *string         `json:"inputTextDelta"`
+	Input          json.RawMessage `json:"input"`
+	Output         json.RawMessage `json:"output"`
+	ErrorText      *string         `json:"errorText"`
+	FinishReason   *string         `json:"finishReason"`
+}
+
+// uiChunkTypes are the chunk types that are accepted but not shown
+var uiChunkTypes = map[string]bool{
+	"start":            true,
+	"abort":            true,
+	"message-metadata": true,
+	"source-url":       true,
+	"source-document":  true,
+	"file":             true,
+}
+
+// eventStreamLine reads a line of server-sent events. Data lines are
+// collected until a blank line ends the event.
+func (p *parser) eventStreamLine(line string) {
+	switch {
+	case line == "":
+		p.dispatch()
+	case strings.HasPrefix(line, ":"):
+		// Comment, often used as a keep-alive
+	case strings.HasPrefix(line, "data:"):
+		if p.data == nil {
+			p.dataLine = p.line
+		}
+		p.data = append(p.data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+	case strings.HasPrefix(line, "event:"), strings.HasPrefix(line, "id:"), strings.HasPrefix(line, "retry:"):
+		// Other fields carry nothing for this protocol
+	default:
+		p.violate("%q is not a server-sent event field; chunks look like data: {\"type\":\"text-delta\"}", truncate(line))
+	}
+}
+
+// dispatch handles the event collected from data lines
+func (p *parser) dispatch() {
+	if p.data == nil {
+		return
+	}
+	data := strings.Join(p.data, "\n")
+	line := p.line
+	p.line = p.dataLine
+	p.data = nil
+	defer func() { p.line = line }()
+
+	if p.done {
+		p.violate("event sent after data: [DONE]")
+		return
+	}
+	if data == "[DONE]" {
+		if !p.finished {
+			p.violate("data: [DONE] sent before a finish chunk")
+		}
+		p.done = true
+		return
+	}
+
+	var chunk uiChunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		p.violate("chunk is not a JSON object: %s", truncate(data))
+		return
+	}
+	if chunk.Type == "" {
+		p.violate("chunk has no type")
+		return
+	}
+	if p.finished {
+		p.violate("%s chunk sent after the finish chunk", chunk.Type)
+		return
+	}
+	p.uiChunk(chunk)
+}
+
+func (p *parser) uiChunk(chunk uiChunk) {
+	str := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return *value
+	}
+	require := func(field string, value *string) bool {
+		if value == nil || *value == "" {
+			p.violate("%s chunk has no %s", chunk.Type, field)
+			return false
+		}
+		return true
+	}
+	id, toolCallID := str(chunk.ID), str(chunk.ToolCallID)
+
+	switch chunk.Type {
+	case "text-start", "reasoning-start":
+		if !require("id", chunk.ID) {
+			return
+		}
+		key := strings.TrimSuffix(chunk.Type, "-start") + ":" + id
+		if p.open[key] {
+			p.violate("%s part %s was already started", strings.TrimSuffix(chunk.Type, "-start"), id)
+		}
+		p.open[key] = true
+	case "text-delta", "reasoning-delta":
+		if !require("id", chunk.ID) {
+			return
+		}
+		kind := strings.TrimSuffix(chunk.Type, "-delta")
+		if !p.open[kind+":"+id] {
+			p.violate("%s for part %s, which is not started", chunk.Type, id)
+		}
+		if chunk.Delta == nil {
+			p.violate("%s chunk has no delta", chunk.Type)
+			return
+		}
+		p.emit(Event{Kind: EventKind(kind), Text: *chunk.Delta})
+	case "text-end", "reasoning-end":
+		if !require("id", chunk.ID) {
+			return
+		}
+		key := strings.TrimSuffix(chunk.Type, "-end") + ":" + id
+		if !p.open[key] {
+			p.violate("%s for part %s, which is not started", chunk.Type, id)
+		}
+		delete(p.open, key)
+	case "tool-input-start":
+		if require("toolCallId", chunk.ToolCallID) && require("toolName", chunk.ToolName) {
+			p.startToolCall(toolCallID, *chunk.ToolName)
+		}
+	case "tool-input-delta":
+		if !require("toolCallId", chunk.ToolCallID) {
+			return
+		}
+		if chunk.InputTextDelta == nil {
+			p.violate("tool-input-delta chunk has no inputTextDelta")
+			return
+		}
+		p.toolInputDelta(toolCallID)
+	case "tool-input-available", "tool-input-error":
+		if !require("toolCallId", chunk.ToolCallID) || !require("toolName", chunk.ToolName) {
+			return
+		}
+		if chunk.Input == nil {
+			p.violate("%s chunk for tool call %s has no input", chunk.Type, toolCallID)
+			return
+		}
+		p.toolCall(toolCallID, *chunk.ToolName, chunk.Input)
+		if chunk.Type == "tool-input-error" && require("errorText", chunk.ErrorText) {
+			p.toolResult(toolCallID, nil, *chunk.ErrorText)
+		}
+	case "tool-output-available":
+		if !require("toolCallId", chunk.ToolCallID) {
+			return
+		}
+		if chunk.Output == nil {
+			p.violate("tool-output-available chunk for tool call %s has no output", toolCallID)
+			return
+		}
+		p.toolResult(toolCallID, chunk.Output, "")
+	case "tool-output-error":
+		if require("toolCallId", chunk.ToolCallID) && require("errorText", chunk.ErrorText) {
+			p.toolResult(toolCallID, nil, *chunk.ErrorText)
+		}
+	case "error":
+		if require("errorText", chunk.ErrorText) {
+			p.emit(Event{Kind: EventError, Text: *chunk.ErrorText})
+		}
+	case "start-step":
+		if p.inStep {
+			p.violate("start-step chunk sent before the previous step finished")
+		}
+		p.inStep = true
+	case "finish-step":
+		if !p.inStep {
+			p.violate("finish-step chunk sent without a start-step")
+		}
+		p.inStep = false
+	case "finish":
+		if p.inStep {
+			p.violate("finish chunk sent before the step finished")
+		}
+		p.finish(str(chunk.FinishReason), false)
+	default:
+		if !uiChunkTypes[chunk.Type] && !strings.HasPrefix(chunk.Type, "data-") {
+			p.violate("unknown chunk type %q", chunk.Type)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package audio reads the properties of WAV and MP3 files in pure Go
+package audio
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Formats reported in Info.Format
+const (
+	FormatWAV = "wav"
+	FormatMP3 = "mp3"
+)
+
+var (
+	// ClipThreshold is the absolute sample value, relative to full scale,
+	// from which a sample counts as clipped
+	ClipThreshold = 0.999
+	// SilenceThreshold is the RMS level in dBFS below which a window counts as silent
+	SilenceThreshold = -40.0
+	// silenceWindow is the length of the windows the silence ratio is measured over
+	silenceWindow = 20 * time.Millisecond
+)
+
+// Info describes an audio file
+type Info struct {
+	Format        string
+	SampleRate    int
+	Channels      int
+	BitsPerSample int // 0 for compressed formats
+	Duration      time.Duration
+	// Levels is nil when the samples were not decoded, which is the case for MP3
+	Levels *Levels
+}
+
+// Levels are measured from the decoded samples
+type Levels struct {
+	// Peak is the largest absolute sample value relative to full scale
+	Peak float64
+	// ClippingRatio is the fraction of samples at or above ClipThreshold
+	ClippingRatio float64
+	// SilenceRatio is the fraction of the duration below SilenceThreshold
+	SilenceRatio float64
+}
+
+// Probe reads the audio file at path. The format is chosen by extension.
+func Probe(path string) (*Info, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var format string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".wav":
+		format = FormatWAV
+	case ".mp3":
+		format = FormatMP3
+	default:
+		return nil, fmt.Errorf("unsupported audio format %q (only .wav and .mp3 are supported)", ext)
+	}
+	info, err := ProbeReader(file, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	return info, nil
+}
+
+// ProbeReader reads audio in the given format, FormatWAV or FormatMP3, from r
+func ProbeReader(r io.Reader, format string) (*Info, error) {
+	switch format {
+	case FormatWAV:
+		return readWAV(r)
+	case FormatMP3:
+		return readMP3(r)
+	default:
+		return nil, fmt.Errorf("unsupported audio format %q", format)
+	}
+}
+
+// levelMeter accumulates Levels from interleaved samples
+type levelMeter struct {
+	windowSize int
+	silenceRMS float64
+
+	samples int64
+	clipped int64
+	peak    float64
+
+	windowSquares float64
+	windowCount   int
+	windows       int64
+	silent        int64
+}
+
+func newLevelMeter(sampleRate, channels int) *levelMeter {
+	windowSize := int(int64(sampleRate) * int64(channels) * int64(silenceWindow) / int64(time.Second))
+	if windowSize < 1 {
+		windowSize = 1
+	}
+	return &levelMeter{windowSize: windowSize, silenceRMS: math.Pow(10, SilenceThreshold/20)}
+}
+
+// add records one sample in the range [-1, 1]
+func (m *levelMeter) add(sample float64) {
+	abs := math.Abs(sample)
+	m.samples++
+	if abs > m.peak {
+		m.peak = abs
+	}
+	if abs >= ClipThreshold {
+		m.clipped++
+	}
+
+	m.windowSquares += sample * sample
+	m.windowCount++
+	if m.windowCount == m.windowSize {
+		m.closeWindow()
+	}
+}
+
+func (m *levelMeter) closeWindow() {
+	if m.windowCount == 0 {
+		return
+	}
+	if math.Sqrt(m.windowSquares/float64(m.windowCount)) < m.silenceRMS {
+		m.silent++
+	}
+	m.windows++
+	m.windowSquares, m.windowCount = 0, 0
+}
+
+func (m *levelMeter) levels() *Levels {
+	m.closeWindow()
+	levels := &Levels{Peak: m.peak}
+	if m.samples > 0 {
+		levels.ClippingRatio = float64(m.clipped) / float64(m.samples)
+	}
+	if m.windows > 0 {
+		levels.SilenceRatio = float64(m.silent) / float64(m.windows)
+	}
+	return levels
+}
@@ -0,0 +1,132 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// pcm16WAV encodes mono 16-bit samples as a WAV file with an extra chunk
+// before the data, as written by many editors
+func pcm16WAV(sampleRate int, samples []int16) []byte {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, samples)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(4+24+10+8+data.Len()))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	for _, v := range []any{uint32(16), uint16(1), uint16(1), uint32(sampleRate), uint32(sampleRate * 2), uint16(2), uint16(16)} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("LIST")
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	buf.Write([]byte{0, 0}) // odd chunk with padding byte
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(data.Len()))
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProbeWAVLevels(t *testing.T) {
+	const rate = 16000
+	samples := make([]int16, 2*rate)
+	// One second of a half-scale tone followed by one second of silence,
+	// with a few clipped samples
+	for i := 0; i < rate; i++ {
+		samples[i] = int16(16384 * math.Sin(2*math.Pi*440*float64(i)/rate))
+	}
+	samples[10], samples[20] = math.MaxInt16, math.MinInt16
+
+	info, err := Probe(writeTestFile(t, "tone.wav", pcm16WAV(rate, samples)))
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if info.Format != FormatWAV || info.SampleRate != rate || info.Channels != 1 || info.BitsPerSample != 16 {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if info.Duration != 2*time.Second {
+		t.Fatalf("expected a duration of 2s, got %v", info.Duration)
+	}
+	if info.Levels == nil {
+		t.Fatal("expected levels for a WAV file")
+	}
+	if info.Levels.Peak < 0.999 {
+		t.Errorf("expected a full-scale peak, got %f", info.Levels.Peak)
+	}
+	if got := info.Levels.ClippingRatio; got != 2.0/float64(len(samples)) {
+		t.Errorf("expected 2 clipped samples, got ratio %f", got)
+	}
+	if got := info.Levels.SilenceRatio; math.Abs(got-0.5) > 0.01 {
+		t.Errorf("expected half of the file to be silent, got %f", got)
+	}
+}
+
+func TestProbeRejectsInvalidWAV(t *testing.T) {
+	if _, err := Probe(writeTestFile(t, "bad.wav", []byte("not audio at all"))); err == nil {
+		t.Fatal("expected an error for a file that is not a WAV")
+	}
+}
+
+// mp3Frames builds a stream of silent MPEG-1 layer III frames at 128 kbit/s,
+// 44.1 kHz and joint stereo
+func mp3Frames(count int) []byte {
+	header := []byte{0xFF, 0xFB, 0x90, 0x40}
+	frame := make([]byte, 417) // 144 * 128000 / 44100
+	copy(frame, header)
+	return bytes.Repeat(frame, count)
+}
+
+func TestProbeMP3Duration(t *testing.T) {
+	var data bytes.Buffer
+	// An ID3v2 tag and some junk must be skipped
+	data.Write([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 20})
+	data.Write(make([]byte, 20))
+	data.Write([]byte{0xFF, 0x00, 0x12})
+	data.Write(mp3Frames(100))
+	data.WriteString("TAG")
+	data.Write(make([]byte, 125))
+
+	info, err := Probe(writeTestFile(t, "clip.mp3", data.Bytes()))
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if info.Format != FormatMP3 || info.SampleRate != 44100 || info.Channels != 2 || info.Levels != nil {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	want := time.Duration(100*1152) * time.Second / 44100
+	if info.Duration != want {
+		t.Fatalf("expected a duration of %v, got %v", want, info.Duration)
+	}
+}
+
+func TestProbeMP3XingHeader(t *testing.T) {
+	data := mp3Frames(3)
+	// Xing header in the first frame, after 32 bytes of side information
+	copy(data[36:], "Xing")
+	binary.BigEndian.PutUint32(data[40:], 1)
+	binary.BigEndian.PutUint32(data[44:], 5000)
+
+	info, err := Probe(writeTestFile(t, "vbr.mp3", data))
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	want := time.Duration(5000*1152) * time.Second / 44100
+	if info.Duration != want {
+		t.Fatalf("expected the duration from the Xing header %v, got %v", want, info.Duration)
+	}
+}
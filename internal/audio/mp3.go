@@ -0,0 +1,182 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// mp3Bitrates holds the bitrates in kbit/s by MPEG version (1, 2/2.5),
+	// layer (1, 2, 3) and bitrate index
+	mp3Bitrates = [2][3][15]int{
+		{
+			{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+			{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		},
+		{
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		},
+	}
+
+	// mp3SampleRates is indexed by the version bits of the header (2.5, -, 2, 1)
+	mp3SampleRates = [4][3]int{
+		{11025, 12000, 8000},
+		{},
+		{22050, 24000, 16000},
+		{44100, 48000, 32000},
+	}
+)
+
+// mp3Frame is a parsed MPEG audio frame header
+type mp3Frame struct {
+	mpeg1      bool
+	layer      int
+	sampleRate int
+	channels   int
+	samples    int // samples per channel in the frame
+	length     int // frame length in bytes, including the header
+}
+
+// parseMP3Frame parses the 4-byte frame header at the start of b
+func parseMP3Frame(b []byte) (mp3Frame, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	version := int(b[1]>>3) & 3
+	layerBits := int(b[1]>>1) & 3
+	bitrateIndex := int(b[2] >> 4)
+	rateIndex := int(b[2]>>2) & 3
+	padding := int(b[2]>>1) & 1
+	if version == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		// Reserved values, or free-format bitrate which needs a full decoder
+		return mp3Frame{}, false
+	}
+
+	f := mp3Frame{
+		mpeg1:      version == 3,
+		layer:      4 - layerBits,
+		sampleRate: mp3SampleRates[version][rateIndex],
+		channels:   2,
+	}
+	if b[3]>>6 == 3 {
+		f.channels = 1
+	}
+	table := 1
+	if f.mpeg1 {
+		table = 0
+	}
+	bitrate := mp3Bitrates[table][f.layer-1][bitrateIndex] * 1000
+
+	switch {
+	case f.layer == 1:
+		f.samples = 384
+		f.length = (12*bitrate/f.sampleRate + padding) * 4
+	case f.layer == 3 && !f.mpeg1:
+		f.samples = 576
+		f.length = 72*bitrate/f.sampleRate + padding
+	default:
+		f.samples = 1152
+		f.length = 144*bitrate/f.sampleRate + padding
+	}
+	return f, true
+}
+
+// xingFrames returns the frame count from a Xing/Info header in frame data,
+// which VBR encoders write into the first frame instead of audio
+func (f mp3Frame) xingFrames(data []byte) (int, bool, bool) {
+	sideInfo := 32
+	switch {
+	case f.mpeg1 && f.channels == 1, !f.mpeg1 && f.channels == 2:
+		sideInfo = 17
+	case !f.mpeg1:
+		sideInfo = 9
+	}
+	offset := 4 + sideInfo
+	if f.layer != 3 || len(data) < offset+12 {
+		return 0, false, false
+	}
+	tag := string(data[offset : offset+4])
+	if tag != "Xing" && tag != "Info" {
+		return 0, false, false
+	}
+	if binary.BigEndian.Uint32(data[offset+4:offset+8])&1 == 0 {
+		return 0, false, true
+	}
+	return int(binary.BigEndian.Uint32(data[offset+8 : offset+12])), true, true
+}
+
+// readMP3 reads the frame headers of an MPEG audio stream to work out its
+// duration. The samples are not decoded, so no levels are reported.
+func readMP3(r io.Reader) (*Info, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := skipID3v2(data)
+	var first *mp3Frame
+	var samples int64
+	for pos+4 <= len(data) {
+		frame, ok := parseMP3Frame(data[pos:])
+		if ok && first == nil {
+			// Guard against a false sync in leading garbage by requiring the
+			// next frame to follow where this one says it ends
+			next := pos + frame.length
+			if next+4 <= len(data) {
+				if _, nextOK := parseMP3Frame(data[next:]); !nextOK {
+					ok = false
+				}
+			}
+		}
+		if !ok {
+			if first != nil && len(data)-pos == 128 && string(data[pos:pos+3]) == "TAG" {
+				break // ID3v1 tag
+			}
+			pos++
+			continue
+		}
+
+		if first == nil {
+			first = &frame
+			end := min(pos+frame.length, len(data))
+			if count, hasCount, isXing := frame.xingFrames(data[pos:end]); isXing {
+				if hasCount {
+					samples = int64(count) * int64(frame.samples)
+					break
+				}
+				pos += frame.length
+				continue
+			}
+		}
+		samples += int64(frame.samples)
+		pos += frame.length
+	}
+
+	if first == nil {
+		return nil, errors.New("no MPEG audio frames found")
+	}
+	return &Info{
+		Format:     FormatMP3,
+		SampleRate: first.sampleRate,
+		Channels:   first.channels,
+		Duration:   time.Duration(samples) * time.Second / time.Duration(first.sampleRate),
+	}, nil
+}
+
+// skipID3v2 returns the offset of the data following an ID3v2 tag
+func skipID3v2(data []byte) int {
+	if len(data) < 10 || string(data[0:3]) != "ID3" {
+		return 0
+	}
+	size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+	size += 10
+	if data[5]&0x10 != 0 {
+		size += 10 // footer
+	}
+	return min(size, len(data))
+}
@@ -0,0 +1,281 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PCM is decoded audio as interleaved signed 16-bit samples
+type PCM struct {
+	SampleRate int
+	Channels   int
+	Samples    []int16
+}
+
+// NewPCM returns empty audio in the given format
+func NewPCM(sampleRate, channels int) *PCM {
+	return &PCM{SampleRate: sampleRate, Channels: channels}
+}
+
+// Frames returns the number of samples per channel
+func (p *PCM) Frames() int {
+	return len(p.Samples) / p.Channels
+}
+
+// Duration returns the length of the audio
+func (p *PCM) Duration() time.Duration {
+	return p.frameTime(p.Frames())
+}
+
+func (p *PCM) frameTime(frames int) time.Duration {
+	return time.Duration(frames) * time.Second / time.Duration(p.SampleRate)
+}
+
+func (p *PCM) frameAt(d time.Duration) int {
+	frame := int(int64(d) * int64(p.SampleRate) / int64(time.Second))
+	return max(0, min(frame, p.Frames()))
+}
+
+// Append adds other, converted to the format of p, to the end of p
+func (p *PCM) Append(other *PCM) {
+	p.Samples = append(p.Samples, other.Convert(p.SampleRate, p.Channels).Samples...)
+}
+
+// AppendSilence adds d of silence to the end of p
+func (p *PCM) AppendSilence(d time.Duration) {
+	frames := int(int64(d) * int64(p.SampleRate) / int64(time.Second))
+	if frames > 0 {
+		p.Samples = append(p.Samples, make([]int16, frames*p.Channels)...)
+	}
+}
+
+// Slice returns the audio between start and end, sharing the samples of p
+func (p *PCM) Slice(start, end time.Duration) *PCM {
+	from, to := p.frameAt(start), p.frameAt(end)
+	if to < from {
+		to = from
+	}
+	return &PCM{SampleRate: p.SampleRate, Channels: p.Channels, Samples: p.Samples[from*p.Channels : to*p.Channels]}
+}
+
+// Convert returns p resampled to sampleRate and mixed to channels. Upsampling
+// interpolates linearly; downsampling low-pass filters first, so that
+// frequencies above the new Nyquist frequency don't alias into the ones kept.
+// p itself is returned when the format already matches.
+func (p *PCM) Convert(sampleRate, channels int) *PCM {
+	if p.SampleRate == sampleRate && p.Channels == channels {
+		return p
+	}
+
+	// Mix to mono first when the channel count changes
+	mono := p.Samples
+	srcChannels := p.Channels
+	if p.Channels != channels {
+		mono = make([]int16, p.Frames())
+		for i := range mono {
+			var sum int
+			for c := 0; c < p.Channels; c++ {
+				sum += int(p.Samples[i*p.Channels+c])
+			}
+			mono[i] = int16(sum / p.Channels)
+		}
+		srcChannels = 1
+	}
+
+	srcFrames := len(mono) / srcChannels
+	dstFrames := srcFrames
+	if p.SampleRate != sampleRate {
+		dstFrames = int(int64(srcFrames) * int64(sampleRate) / int64(p.SampleRate))
+	}
+	// The filter passes up to just below the new Nyquist frequency, as a
+	// fraction of the old one
+	cutoff := 0.0
+	if sampleRate < p.SampleRate {
+		cutoff = 0.95 * float64(sampleRate) / float64(p.SampleRate)
+	}
+
+	out := &PCM{SampleRate: sampleRate, Channels: channels, Samples: make([]int16, dstFrames*channels)}
+	for i := 0; i < dstFrames; i++ {
+		pos := float64(i) * float64(p.SampleRate) / float64(sampleRate)
+		j := int(pos)
+		frac := pos - float64(j)
+		for c := 0; c < channels; c++ {
+			src := c
+			if srcChannels == 1 {
+				src = 0
+			}
+			var value float64
+			if cutoff > 0 {
+				value = lowPass(mono, srcChannels, src, pos, cutoff)
+			} else {
+				a := float64(mono[min(j, srcFrames-1)*srcChannels+src])
+				b := float64(mono[min(j+1, srcFrames-1)*srcChannels+src])
+				value = a + (b-a)*frac
+			}
+			out.Samples[i*channels+c] = int16(max(math.MinInt16, min(math.MaxInt16, math.Round(value))))
+		}
+	}
+	return out
+}
+
+const (
+	// lowPassZeroCrossings is how many zero crossings of the filter are used
+	// on each side of a sample; more give a sharper cutoff
+	lowPassZeroCrossings = 8
+	// lowPassKernelSteps is the resolution of lowPassKernel per zero crossing
+	lowPassKernelSteps = 512
+)
+
+// lowPassKernel holds one side of a Blackman-windowed sinc, which lowPass
+// interpolates instead of computing it for every tap
+var lowPassKernel = func() []float64 {
+	kernel := make([]float64, lowPassZeroCrossings*lowPassKernelSteps+2)
+	for i := range kernel {
+		u := float64(i) / lowPassKernelSteps
+		if u > lowPassZeroCrossings {
+			break
+		}
+		x := math.Pi * u / lowPassZeroCrossings
+		kernel[i] = 0.42 + 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+		if u != 0 {
+			kernel[i] *= math.Sin(math.Pi*u) / (math.Pi * u)
+		}
+	}
+	return kernel
+}()
+
+// lowPass returns channel c of samples at frame position pos, filtered with a
+// windowed sinc to below cutoff, a fraction of the Nyquist frequency
+func lowPass(samples []int16, channels, c int, pos, cutoff float64) float64 {
+	frames := len(samples) / channels
+	halfWidth := lowPassZeroCrossings / cutoff
+	first := max(0, int(math.Ceil(pos-halfWidth)))
+	last := min(frames-1, int(math.Floor(pos+halfWidth)))
+
+	// Weights are normalized so that the gain stays 1, also near the edges
+	var sum, weights float64
+	for k := first; k <= last; k++ {
+		step := math.Abs(pos-float64(k)) * cutoff * lowPassKernelSteps
+		i := int(step)
+		frac := step - float64(i)
+		weight := lowPassKernel[i] + (lowPassKernel[i+1]-lowPassKernel[i])*frac
+		sum += weight * float64(samples[k*channels+c])
+		weights += weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
+
+// DecodeWAV reads a WAV stream into 16-bit PCM, converting other sample formats
+func DecodeWAV(r io.Reader) (*PCM, error) {
+	br := bufio.NewReader(r)
+	format, size, err := readWAVHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	var data io.Reader = br
+	if size >= 0 {
+		data = io.LimitReader(br, size)
+	}
+
+	raw, err := io.ReadAll(data)
+	if err != nil {
+		return nil, err
+	}
+	raw = raw[:len(raw)-len(raw)%format.blockAlign]
+
+	pcm := &PCM{SampleRate: format.sampleRate, Channels: format.channels}
+	sampleBytes := format.bitsPerSample / 8
+	pcm.Samples = make([]int16, len(raw)/sampleBytes)
+	for i := range pcm.Samples {
+		b := raw[i*sampleBytes : (i+1)*sampleBytes]
+		if format.tag == wavFormatPCM && sampleBytes == 2 {
+			pcm.Samples[i] = int16(binary.LittleEndian.Uint16(b))
+			continue
+		}
+		v := math.Max(-1, math.Min(1, decodeSample(b, format.tag)))
+		pcm.Samples[i] = int16(math.Round(v * math.MaxInt16))
+	}
+	return pcm, nil
+}
+
+// DecodeWAVBytes decodes an in-memory WAV file
+func DecodeWAVBytes(data []byte) (*PCM, error) {
+	return DecodeWAV(bytes.NewReader(data))
+}
+
+// ReadWAVFile decodes the WAV file at path
+func ReadWAVFile(path string) (*PCM, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pcm, err := DecodeWAV(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	return pcm, nil
+}
+
+// EncodeWAV writes p as a 16-bit PCM WAV file
+func (p *PCM) EncodeWAV(w io.Writer) error {
+	dataSize := len(p.Samples) * 2
+	if int64(dataSize)+36 > math.MaxUint32 {
+		return errors.New("audio is too long for a WAV file")
+	}
+
+	bw := bufio.NewWriter(w)
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+dataSize))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], wavFormatPCM)
+	binary.LittleEndian.PutUint16(header[22:], uint16(p.Channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(p.SampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(p.SampleRate*p.Channels*2))
+	binary.LittleEndian.PutUint16(header[32:], uint16(p.Channels*2))
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(dataSize))
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+
+	var sample [2]byte
+	for _, s := range p.Samples {
+		binary.LittleEndian.PutUint16(sample[:], uint16(s))
+		if _, err := bw.Write(sample[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteWAVFile saves p as a WAV file at path, creating its directory
+func (p *PCM) WriteWAVFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := p.EncodeWAV(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
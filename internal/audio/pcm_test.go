@@ -0,0 +1,94 @@
+package audio
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPCMRoundTrip(t *testing.T) {
+	pcm := &PCM{SampleRate: 8000, Channels: 2, Samples: []int16{1, -1, 300, -300, 32767, -32768}}
+
+	var buf bytes.Buffer
+	if err := pcm.EncodeWAV(&buf); err != nil {
+		t.Fatalf("EncodeWAV failed: %v", err)
+	}
+	if buf.Len() != 44+12 {
+		t.Fatalf("expected a 56 byte file, got %d", buf.Len())
+	}
+
+	decoded, err := DecodeWAVBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("DecodeWAV failed: %v", err)
+	}
+	if decoded.SampleRate != 8000 || decoded.Channels != 2 || len(decoded.Samples) != 6 || decoded.Samples[4] != 32767 || decoded.Samples[5] != -32768 {
+		t.Fatalf("unexpected decoded audio: %+v", decoded)
+	}
+
+	// The header sizes must agree with what Probe reads back
+	path := filepath.Join(t.TempDir(), "out.wav")
+	if err := decoded.WriteWAVFile(path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := Probe(path)
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	if info.Duration != 375*time.Microsecond {
+		t.Fatalf("expected 3 frames at 8 kHz, got %v", info.Duration)
+	}
+}
+
+func TestPCMAppendConvertsFormat(t *testing.T) {
+	pcm := NewPCM(16000, 1)
+	pcm.Append(&PCM{SampleRate: 16000, Channels: 1, Samples: []int16{100, 200}})
+	pcm.AppendSilence(time.Millisecond)
+	// Stereo at twice the rate is mixed down and halved in length
+	pcm.Append(&PCM{SampleRate: 32000, Channels: 2, Samples: []int16{1000, 3000, 1000, 3000, 1000, 3000, 1000, 3000}})
+
+	want := []int16{100, 200, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2000, 2000}
+	if len(pcm.Samples) != len(want) {
+		t.Fatalf("expected %d samples, got %d: %v", len(want), len(pcm.Samples), pcm.Samples)
+	}
+	for i := range want {
+		if pcm.Samples[i] != want[i] {
+			t.Fatalf("sample %d: expected %d, got %d", i, want[i], pcm.Samples[i])
+		}
+	}
+	if got := pcm.Slice(time.Millisecond/8, 10*time.Second); got.Frames() != 18 {
+		t.Fatalf("expected the slice to hold 18 frames, got %d", got.Frames())
+	}
+}
+
+// tone returns seconds of a mono sine wave at frequency
+func tone(sampleRate int, frequency, seconds float64) *PCM {
+	pcm := NewPCM(sampleRate, 1)
+	pcm.Samples = make([]int16, int(seconds*float64(sampleRate)))
+	for i := range pcm.Samples {
+		pcm.Samples[i] = int16(10000 * math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate)))
+	}
+	return pcm
+}
+
+// rms returns the root mean square of the samples, skipping the edges
+func rms(pcm *PCM) float64 {
+	edge := len(pcm.Samples) / 10
+	var sum float64
+	for _, sample := range pcm.Samples[edge : len(pcm.Samples)-edge] {
+		sum += float64(sample) * float64(sample)
+	}
+	return math.Sqrt(sum / float64(len(pcm.Samples)-2*edge))
+}
+
+func TestPCMConvertFiltersBeforeDownsampling(t *testing.T) {
+	// 1 kHz is kept at 16 kHz, 12 kHz is above its Nyquist frequency and
+	// would alias to 4 kHz without filtering
+	if got := rms(tone(48000, 1000, 0.5).Convert(16000, 1)); math.Abs(got-10000/math.Sqrt2) > 100 {
+		t.Errorf("expected a 1 kHz tone to keep its level, got an RMS of %.0f", got)
+	}
+	if got := rms(tone(48000, 12000, 0.5).Convert(16000, 1)); got > 100 {
+		t.Errorf("expected a 12 kHz tone to be filtered out, got an RMS of %.0f", got)
+	}
+}
@@ -0,0 +1,180 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// WAV format tags
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatFloat      = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// wavFormat is the content of the "fmt " chunk
+type wavFormat struct {
+	tag           uint16
+	channels      int
+	sampleRate    int
+	blockAlign    int
+	bitsPerSample int
+}
+
+// readWAV parses a RIFF/WAVE stream and measures the levels of its samples
+func readWAV(r io.Reader) (*Info, error) {
+	br := bufio.NewReader(r)
+	format, size, err := readWAVHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	return readWAVData(br, format, size)
+}
+
+// readWAVHeader reads the chunks of a RIFF/WAVE stream up to the start of
+// the samples. It returns the format and the size of the data chunk, or -1
+// when the data extends to the end of the stream.
+func readWAVHeader(r io.Reader) (*wavFormat, int64, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, 0, errors.New("not a WAV file")
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, 0, errors.New("not a WAV file")
+	}
+
+	var format *wavFormat
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if format == nil {
+				return nil, 0, errors.New("WAV file has no fmt chunk")
+			}
+			return nil, 0, errors.New("WAV file has no data chunk")
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			f, err := readWAVFormat(r, size)
+			if err != nil {
+				return nil, 0, err
+			}
+			format = f
+		case "data":
+			if format == nil {
+				return nil, 0, errors.New("WAV data chunk precedes the fmt chunk")
+			}
+			// Streamed WAVs may leave the size unset; read to the end instead
+			if size == 0 || size == math.MaxUint32 {
+				size = -1
+			}
+			return format, size, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return nil, 0, fmt.Errorf("truncated %q chunk", id)
+			}
+		}
+	}
+}
+
+func readWAVFormat(r io.Reader, size int64) (*wavFormat, error) {
+	if size < 16 {
+		return nil, errors.New("invalid WAV fmt chunk")
+	}
+	data := make([]byte, size+size%2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, errors.New("truncated WAV fmt chunk")
+	}
+
+	f := &wavFormat{
+		tag:           binary.LittleEndian.Uint16(data[0:2]),
+		channels:      int(binary.LittleEndian.Uint16(data[2:4])),
+		sampleRate:    int(binary.LittleEndian.Uint32(data[4:8])),
+		blockAlign:    int(binary.LittleEndian.Uint16(data[12:14])),
+		bitsPerSample: int(binary.LittleEndian.Uint16(data[14:16])),
+	}
+	if f.tag == wavFormatExtensible && size >= 26 {
+		// The sub-format GUID starts with the actual format tag
+		f.tag = binary.LittleEndian.Uint16(data[24:26])
+	}
+
+	switch {
+	case f.channels == 0 || f.sampleRate == 0:
+		return nil, errors.New("invalid WAV fmt chunk")
+	case f.tag == wavFormatPCM && (f.bitsPerSample == 8 || f.bitsPerSample == 16 || f.bitsPerSample == 24 || f.bitsPerSample == 32):
+	case f.tag == wavFormatFloat && (f.bitsPerSample == 32 || f.bitsPerSample == 64):
+	default:
+		return nil, fmt.Errorf("unsupported WAV encoding (format %#04x, %d bits)", f.tag, f.bitsPerSample)
+	}
+	if f.blockAlign != f.channels*f.bitsPerSample/8 {
+		return nil, errors.New("invalid WAV block alignment")
+	}
+	return f, nil
+}
+
+// readWAVData decodes size bytes of samples, or everything up to EOF when
+// size is -1. A data chunk cut short by the end of the file is measured as far
+// as it goes.
+func readWAVData(r io.Reader, format *wavFormat, size int64) (*Info, error) {
+	if size >= 0 {
+		r = io.LimitReader(r, size)
+	}
+
+	meter := newLevelMeter(format.sampleRate, format.channels)
+	sampleBytes := format.bitsPerSample / 8
+	buf := make([]byte, format.blockAlign*4096)
+	var frames int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		n -= n % format.blockAlign
+		for i := 0; i < n; i += sampleBytes {
+			meter.add(decodeSample(buf[i:i+sampleBytes], format.tag))
+		}
+		frames += int64(n / format.blockAlign)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Info{
+		Format:        FormatWAV,
+		SampleRate:    format.sampleRate,
+		Channels:      format.channels,
+		BitsPerSample: format.bitsPerSample,
+		Duration:      time.Duration(frames) * time.Second / time.Duration(format.sampleRate),
+		Levels:        meter.levels(),
+	}, nil
+}
+
+// decodeSample converts a little-endian sample to the range [-1, 1]
+func decodeSample(b []byte, tag uint16) float64 {
+	if tag == wavFormatFloat {
+		if len(b) == 8 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+
+	switch len(b) {
+	case 1:
+		// 8-bit samples are unsigned
+		return (float64(b[0]) - 128) / 128
+	case 2:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case 3:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
@@ -6,11 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-	"time"
 
 	"github.com/mirako-ai/mirako-cli/internal/config"
 	"github.com/mirako-ai/mirako-cli/internal/errors"
@@ -25,7 +21,7 @@ type Client struct {
 
 func New(cfg *config.Config) (*Client, error) {
 	if !cfg.IsAuthenticated() {
-		return nil, fmt.Errorf("API token is required. Run 'mirako auth login' to authenticate")
+		return nil, errors.WithExitCode(fmt.Errorf("API token is required. Run 'mirako auth login' to authenticate"), errors.ExitAuth)
 	}
 
 	sdkClient, err := sdkclient.NewClient(
@@ -64,7 +60,9 @@ func parseJSONResponse(resp *http.Response, target interface{}) error {
 }
 
 func (c *Client) ListAgents(ctx context.Context) (*api.ListAgentsApiResponseBody, error) {
-	resp, err := c.sdkClient.ListAgents(ctx)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.ListAgents(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +80,9 @@ func (c *Client) ListAgents(ctx context.Context) (*api.ListAgentsApiResponseBody
 }
 
 func (c *Client) GetAgent(ctx context.Context, agentID string) (*api.GetAgentApiResponseBody, error) {
-	resp, err := c.sdkClient.GetAgent(ctx, agentID)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetAgent(ctx, agentID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -105,7 +105,9 @@ func (c *Client) CreateAgent(ctx context.Context, body api.CreateAgentJSONReques
 		return nil, err
 	}
 
-	resp, err := c.sdkClient.CreateAgentWithBody(ctx, "application/json", bytes.NewReader(bodyBytes))
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.CreateAgentWithBody(ctx, "application/json", bytes.NewReader(bodyBytes))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -122,8 +124,38 @@ func (c *Client) CreateAgent(ctx context.Context, body api.CreateAgentJSONReques
 	return &result, nil
 }
 
+// UpdateAgent changes the fields of an agent that are set in body. Fields left
+// nil are not sent and keep their current value.
+func (c *Client) UpdateAgent(ctx context.Context, agentID string, body api.UpdateAgentJSONRequestBody) (*api.UpdateAgentApiResponseBody, error) {
+	bodyBytes, err := marshalJSONOmitNullFields(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Setting the same fields again has no further effect, so updates can be retried
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.UpdateAgentWithBody(ctx, agentID, "application/json", bytes.NewReader(bodyBytes))
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := handleHTTPResponse(resp, "update agent"); err != nil {
+		return nil, err
+	}
+
+	var result api.UpdateAgentApiResponseBody
+	if err := parseJSONResponse(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 func (c *Client) CreateAgentRoute(ctx context.Context, agentID string, body api.CreateAgentRouteJSONRequestBody) (*api.CreateAgentRouteApiResponseBody, error) {
-	resp, err := c.sdkClient.CreateAgentRoute(ctx, agentID, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.CreateAgentRoute(ctx, agentID, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +173,9 @@ func (c *Client) CreateAgentRoute(ctx context.Context, agentID string, body api.
 }
 
 func (c *Client) ListOwnerAgentRoutes(ctx context.Context) (*api.ListAgentRoutesApiResponseBody, error) {
-	resp, err := c.sdkClient.ListOwnerAgentRoutes(ctx)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.ListOwnerAgentRoutes(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -159,7 +193,9 @@ func (c *Client) ListOwnerAgentRoutes(ctx context.Context) (*api.ListAgentRoutes
 }
 
 func (c *Client) GetAgentRoute(ctx context.Context, routeID string) (*api.GetAgentRouteApiResponseBody, error) {
-	resp, err := c.sdkClient.GetAgentRoute(ctx, routeID)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetAgentRoute(ctx, routeID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +213,9 @@ func (c *Client) GetAgentRoute(ctx context.Context, routeID string) (*api.GetAge
 }
 
 func (c *Client) RevokeAgentRoute(ctx context.Context, routeID string) (*api.RevokeAgentRouteApiResponseBody, error) {
-	resp, err := c.sdkClient.RevokeAgentRoute(ctx, routeID)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.RevokeAgentRoute(ctx, routeID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -226,7 +264,9 @@ func removeNullJSONFields(value interface{}) {
 }
 
 func (c *Client) DeleteAgent(ctx context.Context, agentID string) error {
-	resp, err := c.sdkClient.DeleteAgent(ctx, agentID)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.DeleteAgent(ctx, agentID)
+	})
 	if err != nil {
 		return err
 	}
@@ -236,7 +276,9 @@ func (c *Client) DeleteAgent(ctx context.Context, agentID string) error {
 }
 
 func (c *Client) ListAvatars(ctx context.Context) (*api.GetUserAvatarListApiResponseBody, error) {
-	resp, err := c.sdkClient.GetUserAvatarList(ctx)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetUserAvatarList(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -253,8 +295,17 @@ func (c *Client) ListAvatars(ctx context.Context) (*api.GetUserAvatarListApiResp
 	return &result, nil
 }
 
+// VerifyToken checks that the API accepts the configured token. The API has
+// no identity endpoint, so this makes the cheapest authenticated call.
+func (c *Client) VerifyToken(ctx context.Context) error {
+	_, err := c.ListAvatars(ctx)
+	return err
+}
+
 func (c *Client) GetAvatar(ctx context.Context, id string) (*api.GetAvatarApiResponseBody, error) {
-	resp, err := c.sdkClient.GetAvatarById(ctx, id)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetAvatarById(ctx, id)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -276,7 +327,9 @@ func (c *Client) GenerateAvatar(ctx context.Context, prompt string, seed *int64)
 		Prompt: prompt,
 		Seed:   seed,
 	}
-	resp, err := c.sdkClient.GenerateAvatarAsync(ctx, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.GenerateAvatarAsync(ctx, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -294,7 +347,9 @@ func (c *Client) GenerateAvatar(ctx context.Context, prompt string, seed *int64)
 }
 
 func (c *Client) GetAvatarStatus(ctx context.Context, taskID string) (*api.GenerateAvatarStatusApiResponseBody, error) {
-	resp, err := c.sdkClient.GetAvatarGenerationStatus(ctx, taskID)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetAvatarGenerationStatus(ctx, taskID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -312,7 +367,9 @@ func (c *Client) GetAvatarStatus(ctx context.Context, taskID string) (*api.Gener
 }
 
 func (c *Client) DeleteAvatar(ctx context.Context, avatarID string) error {
-	resp, err := c.sdkClient.DeleteAvatar(ctx, avatarID)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.DeleteAvatar(ctx, avatarID)
+	})
 	if err != nil {
 		return err
 	}
@@ -326,7 +383,9 @@ func (c *Client) BuildAvatar(ctx context.Context, name, image string) (*api.Asyn
 		Name:  name,
 		Image: image,
 	}
-	resp, err := c.sdkClient.BuildAvatarAsync(ctx, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.BuildAvatarAsync(ctx, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -344,7 +403,9 @@ func (c *Client) BuildAvatar(ctx context.Context, name, image string) (*api.Asyn
 }
 
 func (c *Client) ListSessions(ctx context.Context) (*api.ListSessionsApiResponseBody, error) {
-	resp, err := c.sdkClient.ListInteractiveSessions(ctx)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.ListInteractiveSessions(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -362,7 +423,9 @@ func (c *Client) ListSessions(ctx context.Context) (*api.ListSessionsApiResponse
 }
 
 func (c *Client) StartSession(ctx context.Context, body api.StartInteractiveSessionJSONRequestBody) (*api.StartSessionApiResponseBody, error) {
-	resp, err := c.sdkClient.StartInteractiveSession(ctx, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.StartInteractiveSession(ctx, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -383,7 +446,9 @@ func (c *Client) StopSessions(ctx context.Context, sessionIDs []string) (*api.St
 	body := api.StopInteractiveSessionsJSONRequestBody{
 		SessionIds: &sessionIDs,
 	}
-	resp, err := c.sdkClient.StopInteractiveSessions(ctx, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.StopInteractiveSessions(ctx, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -401,7 +466,9 @@ func (c *Client) StopSessions(ctx context.Context, sessionIDs []string) (*api.St
 }
 
 func (c *Client) GetSessionProfile(ctx context.Context, sessionID string) (*api.GetSessionProfileApiResponseBody, error) {
-	resp, err := c.sdkClient.GetSessionProfile(ctx, sessionID)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetSessionProfile(ctx, sessionID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -425,7 +492,9 @@ func (c *Client) GenerateImage(ctx context.Context, prompt string, aspectRatio a
 		Seed:        seed,
 		Images:      images,
 	}
-	resp, err := c.sdkClient.GenerateImageAsync(ctx, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.GenerateImageAsync(ctx, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -449,7 +518,9 @@ func (c *Client) GenerateImageSync(ctx context.Context, prompt string, aspectRat
 		Seed:        seed,
 		Images:      images,
 	}
-	resp, err := c.sdkClient.GenerateImage(ctx, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.GenerateImage(ctx, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -467,7 +538,9 @@ func (c *Client) GenerateImageSync(ctx context.Context, prompt string, aspectRat
 }
 
 func (c *Client) GetImageStatus(ctx context.Context, taskID string) (*api.GenerateImageStatusApiResponseBody, error) {
-	resp, err := c.sdkClient.GetImageGenerationStatus(ctx, taskID)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetImageGenerationStatus(ctx, taskID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -488,7 +561,9 @@ func (c *Client) SpeechToText(ctx context.Context, audio string) (*api.STTApiRes
 	body := api.ConvertSpeechToTextJSONRequestBody{
 		Audio: audio,
 	}
-	resp, err := c.sdkClient.ConvertSpeechToText(ctx, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.ConvertSpeechToText(ctx, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -513,7 +588,9 @@ func (c *Client) TextToSpeech(ctx context.Context, text, voiceProfileID, returnT
 		ChineseLanguage: chineseLanguage,
 		Opts:            opts,
 	}
-	resp, err := c.sdkClient.ConvertTextToSpeech(ctx, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.ConvertTextToSpeech(ctx, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -535,7 +612,9 @@ func (c *Client) GenerateTalkingAvatar(ctx context.Context, audio, image string)
 		Audio: audio,
 		Image: image,
 	}
-	resp, err := c.sdkClient.GenerateTalkingAvatarAsync(ctx, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.GenerateTalkingAvatarAsync(ctx, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -559,7 +638,9 @@ func (c *Client) GenerateAvatarMotion(ctx context.Context, audio, image, positiv
 		PositivePrompt: positivePrompt,
 		NegativePrompt: negativePrompt,
 	}
-	resp, err := c.sdkClient.GenerateAvatarMotionAsync(ctx, body)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.GenerateAvatarMotionAsync(ctx, body)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -577,7 +658,9 @@ func (c *Client) GenerateAvatarMotion(ctx context.Context, audio, image, positiv
 }
 
 func (c *Client) GetAvatarMotionStatus(ctx context.Context, taskID string) (*api.GenerateAvatarMotionStatusApiResponseBody, error) {
-	resp, err := c.sdkClient.GetAvatarMotionGenerationStatus(ctx, taskID)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetAvatarMotionGenerationStatus(ctx, taskID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -595,7 +678,9 @@ func (c *Client) GetAvatarMotionStatus(ctx context.Context, taskID string) (*api
 }
 
 func (c *Client) GetTalkingAvatarStatus(ctx context.Context, taskID string) (*api.GenerateTalkingAvatarStatusApiResponseBody, error) {
-	resp, err := c.sdkClient.GetTalkingAvatarGenerationStatus(ctx, taskID)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetTalkingAvatarGenerationStatus(ctx, taskID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -613,7 +698,9 @@ func (c *Client) GetTalkingAvatarStatus(ctx context.Context, taskID string) (*ap
 }
 
 func (c *Client) ListPremadeProfiles(ctx context.Context) (*api.GetPremadeProfilesApiResponseBody, error) {
-	resp, err := c.sdkClient.GetPremadeVoiceProfiles(ctx)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetPremadeVoiceProfiles(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -631,7 +718,9 @@ func (c *Client) ListPremadeProfiles(ctx context.Context) (*api.GetPremadeProfil
 }
 
 func (c *Client) ListVoiceProfiles(ctx context.Context) (*api.GetVoiceProfilesApiResponseBody, error) {
-	resp, err := c.sdkClient.GetUserVoiceProfiles(ctx)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetUserVoiceProfiles(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -649,7 +738,9 @@ func (c *Client) ListVoiceProfiles(ctx context.Context) (*api.GetVoiceProfilesAp
 }
 
 func (c *Client) GetVoiceProfile(ctx context.Context, profileID string) (*api.GetVoiceProfileApiResponseBody, error) {
-	resp, err := c.sdkClient.GetVoiceProfile(ctx, profileID)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetVoiceProfile(ctx, profileID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -667,7 +758,9 @@ func (c *Client) GetVoiceProfile(ctx context.Context, profileID string) (*api.Ge
 }
 
 func (c *Client) DeleteVoiceProfile(ctx context.Context, profileID string) error {
-	resp, err := c.sdkClient.DeleteVoiceProfile(ctx, profileID)
+	resp, err := c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		return c.sdkClient.DeleteVoiceProfile(ctx, profileID)
+	})
 	if err != nil {
 		return err
 	}
@@ -676,119 +769,10 @@ func (c *Client) DeleteVoiceProfile(ctx context.Context, profileID string) error
 	return handleHTTPResponse(resp, "delete voice profile")
 }
 
-func (c *Client) CloneVoice(ctx context.Context, name string, audioDir string, annotationFile string, cleanData bool, description string) (*api.AsyncFinetuningApiResponseBody, error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	if err := writer.WriteField("name", name); err != nil {
-		return nil, fmt.Errorf("failed to write name field: %w", err)
-	}
-
-	cleanDataStr := "false"
-	if cleanData {
-		cleanDataStr = "true"
-	}
-	if err := writer.WriteField("clean_data", cleanDataStr); err != nil {
-		return nil, fmt.Errorf("failed to write clean_data field: %w", err)
-	}
-
-	if description != "" {
-		if err := writer.WriteField("description", description); err != nil {
-			return nil, fmt.Errorf("failed to write description field: %w", err)
-		}
-	}
-
-	annotationFileHandle, err := os.Open(annotationFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open annotation file: %w", err)
-	}
-	defer annotationFileHandle.Close()
-
-	annotationWriter, err := writer.CreatePart(map[string][]string{
-		"Content-Disposition": {fmt.Sprintf(`form-data; name="annotation_list"; filename="%s"`, filepath.Base(annotationFile))},
-		"Content-Type":        {"text/plain"},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create annotation form file: %w", err)
-	}
-
-	if _, err := io.Copy(annotationWriter, annotationFileHandle); err != nil {
-		return nil, fmt.Errorf("failed to write annotation data: %w", err)
-	}
-
-	audioFiles, err := ScanAudioFiles(audioDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan audio files: %w", err)
-	}
-
-	if len(audioFiles) == 0 {
-		return nil, fmt.Errorf("no audio files (.wav or .mp3) found in directory: %s", audioDir)
-	}
-
-	fmt.Printf("Uploading %d audio files for voice cloning...\n", len(audioFiles))
-
-	for _, audioFile := range audioFiles {
-		file, err := os.Open(audioFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open audio file %s: %w", audioFile, err)
-		}
-
-		audioWriter, err := writer.CreatePart(map[string][]string{
-			"Content-Disposition": {fmt.Sprintf(`form-data; name="audio_samples"; filename="%s"`, filepath.Base(audioFile))},
-			"Content-Type":        {"audio/wav"},
-		})
-		if err != nil {
-			file.Close()
-			return nil, fmt.Errorf("failed to create audio form file: %w", err)
-		}
-
-		if _, err := io.Copy(audioWriter, file); err != nil {
-			file.Close()
-			return nil, fmt.Errorf("failed to write audio data: %w", err)
-		}
-
-		file.Close()
-	}
-
-	writer.Close()
-
-	req, err := http.NewRequestWithContext(ctx, "POST",
-		fmt.Sprintf("%s/v1/voice/clone", c.config.APIURL), body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+c.config.APIToken)
-
-	httpClient := &http.Client{
-		Timeout: 1 * time.Hour,
-	}
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var apiResp api.AsyncFinetuningApiResponseBody
-	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w (response: %s)", err, string(bodyBytes))
-	}
-
-	return &apiResp, nil
-}
-
 func (c *Client) GetVoiceCloneStatus(ctx context.Context, taskID string) (*api.FinetuningStatusApiResponseBody, error) {
-	resp, err := c.sdkClient.GetVoiceCloningStatus(ctx, taskID)
+	resp, err := c.send(ctx, retryIdempotent, func() (*http.Response, error) {
+		return c.sdkClient.GetVoiceCloningStatus(ctx, taskID)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/config"
+)
+
+// testClientOption adjusts the configuration of a test client
+type testClientOption func(*config.Config)
+
+func withMaxRetries(maxRetries int) testClientOption {
+	return func(cfg *config.Config) { cfg.MaxRetries = maxRetries }
+}
+
+func withUploadTimeout(timeout time.Duration) testClientOption {
+	return func(cfg *config.Config) { cfg.UploadTimeout = timeout }
+}
+
+// newTestServer starts a server running handler for the duration of the test.
+// Retries wait a millisecond instead of seconds meanwhile.
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	originalDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = originalDelay })
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestClient returns a client, created with New, for a test server running
+// handler
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...testClientOption) *Client {
+	t.Helper()
+
+	server := newTestServer(t, handler)
+	cfg := &config.Config{APIToken: "test-token", APIURL: server.URL}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c
+}
@@ -0,0 +1,375 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// downloadStallTimeout aborts an attempt when no data arrives for this long
+	downloadStallTimeout = 60 * time.Second
+
+	// downloadHTTPClient has no overall timeout, so large files are limited
+	// only by the context, but gives up on servers that never respond
+	downloadHTTPClient = func() *http.Client {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = time.Minute
+		return &http.Client{Transport: transport}
+	}()
+)
+
+// DownloadOptions configure DownloadFile
+type DownloadOptions struct {
+	// MaxRetries is how often a failed or interrupted download is resumed
+	MaxRetries int
+	// Progress is called as data arrives with the bytes written so far and
+	// the total size, or -1 when the server does not report it
+	Progress func(written, total int64)
+	// HTTPClient replaces the default download client
+	HTTPClient *http.Client
+}
+
+// DownloadResult describes a completed download
+type DownloadResult struct {
+	Path   string
+	Size   int64
+	SHA256 string
+	// Verified is set when the server reported a SHA-256 checksum and the
+	// downloaded file matches it
+	Verified bool
+}
+
+// retryableError marks a download failure that is worth resuming
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// partInfo is kept next to a ".part" file to tell whether it can be resumed:
+// it records where the data came from and the validator the server gave for it
+type partInfo struct {
+	// Source is the URL without its query, which for presigned URLs changes
+	// with every signature while the object stays the same
+	Source       string `json:"source"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// SHA256 is the checksum of the whole file reported by the server, in hex
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// validator returns the value for If-Range: a strong ETag, or else the
+// Last-Modified date. Weak ETags can't be used for ranges.
+func (p *partInfo) validator() string {
+	if p.ETag != "" && !strings.HasPrefix(p.ETag, "W/") {
+		return p.ETag
+	}
+	return p.LastModified
+}
+
+func downloadSource(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	return parsed.String()
+}
+
+func partInfoPath(partPath string) string {
+	return partPath + ".json"
+}
+
+// loadPartInfo returns the info of a ".part" file that can be resumed from
+// source. A ".part" file from another source, or one the server gave no
+// validator for, is removed so the download starts over.
+func loadPartInfo(partPath, source string) *partInfo {
+	var info partInfo
+	data, err := os.ReadFile(partInfoPath(partPath))
+	if err == nil && json.Unmarshal(data, &info) == nil && info.Source == source && info.validator() != "" {
+		return &info
+	}
+	os.Remove(partPath)
+	os.Remove(partInfoPath(partPath))
+	return nil
+}
+
+func savePartInfo(partPath string, info *partInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(partInfoPath(partPath), data, 0644)
+}
+
+// DownloadFile saves url to path. Data is written to path + ".part" and
+// renamed when complete, so an interrupted download never leaves a truncated
+// file at path. A ".part" file left by an earlier attempt or run is resumed
+// with a Range request guarded by If-Range, and only when it came from the
+// same file on the server; otherwise the download starts over.
+func DownloadFile(ctx context.Context, url, path string, opts DownloadOptions) (*DownloadResult, error) {
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = downloadHTTPClient
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	partPath := path + ".part"
+	source := downloadSource(url)
+	info := loadPartInfo(partPath, source)
+
+	for attempt := 0; ; attempt++ {
+		var err error
+		info, err = downloadAttempt(ctx, httpClient, url, partPath, source, info, opts.Progress)
+		if err == nil {
+			break
+		}
+
+		var retryable *retryableError
+		if attempt >= opts.MaxRetries || ctx.Err() != nil || !stderrors.As(err, &retryable) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+
+		timer := time.NewTimer(backoffDelay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	size, checksum, err := fileSHA256(partPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download: %w", err)
+	}
+	verified := false
+	if info != nil && info.SHA256 != "" {
+		if info.SHA256 != checksum {
+			os.Remove(partPath)
+			os.Remove(partInfoPath(partPath))
+			return nil, fmt.Errorf("downloaded file does not match the checksum reported by the server (SHA-256 %s, expected %s)", checksum, info.SHA256)
+		}
+		verified = true
+	}
+
+	if err := os.Rename(partPath, path); err != nil {
+		return nil, fmt.Errorf("failed to save download: %w", err)
+	}
+	os.Remove(partInfoPath(partPath))
+	return &DownloadResult{Path: path, Size: size, SHA256: checksum, Verified: verified}, nil
+}
+
+// downloadAttempt fetches the remaining bytes of url into partPath. info
+// describes the data already in partPath, or is nil when there is none; the
+// returned info describes the data after the attempt.
+func downloadAttempt(ctx context.Context, httpClient *http.Client, url, partPath, source string, info *partInfo, progress func(written, total int64)) (*partInfo, error) {
+	var offset int64
+	if info != nil && info.validator() != "" {
+		if stat, err := os.Stat(partPath); err == nil {
+			offset = stat.Size()
+		}
+	}
+
+	// Cancel the attempt when the transfer stalls
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stall := time.AfterFunc(downloadStallTimeout, cancel)
+	defer stall.Stop()
+
+	req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return info, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", info.validator())
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if shouldRetryError(err, retryIdempotent) || (ctx.Err() == nil && attemptCtx.Err() != nil) {
+			return info, &retryableError{err: err}
+		}
+		return info, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	total := int64(-1)
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		etag := resp.Header.Get("ETag")
+		if !ok || start != offset || (etag != "" && info.ETag != "" && etag != info.ETag) {
+			os.Remove(partPath)
+			return nil, &retryableError{err: fmt.Errorf("server returned an unexpected range %q", resp.Header.Get("Content-Range"))}
+		}
+		flags |= os.O_APPEND
+		total = size
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The part file may already hold the whole file
+		if _, size, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && size == offset {
+			if progress != nil {
+				progress(offset, size)
+			}
+			return info, nil
+		}
+		os.Remove(partPath)
+		return nil, &retryableError{err: fmt.Errorf("partial download no longer matches the file on the server")}
+	case resp.StatusCode == http.StatusOK:
+		// Nothing was downloaded yet, the file changed on the server, or the
+		// server ignored the Range header: start over
+		offset = 0
+		flags |= os.O_TRUNC
+		total = resp.ContentLength
+		info = &partInfo{
+			Source:       source,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			SHA256:       serverSHA256(resp.Header),
+		}
+		if err := savePartInfo(partPath, info); err != nil {
+			return nil, fmt.Errorf("failed to create output file: %w", err)
+		}
+	default:
+		err := fmt.Errorf("HTTP %d", resp.StatusCode)
+		if shouldRetryResponse(resp, retryIdempotent) {
+			return info, &retryableError{err: err}
+		}
+		return info, err
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return info, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	writer := &progressWriter{written: offset, total: total, progress: progress, onWrite: func() { stall.Reset(downloadStallTimeout) }}
+	n, copyErr := io.Copy(io.MultiWriter(file, writer), resp.Body)
+	closeErr := file.Close()
+
+	if copyErr != nil {
+		if ctx.Err() != nil {
+			return info, ctx.Err()
+		}
+		return info, &retryableError{err: copyErr}
+	}
+	if closeErr != nil {
+		return info, fmt.Errorf("failed to write output file: %w", closeErr)
+	}
+	if total >= 0 && offset+n != total {
+		return info, &retryableError{err: fmt.Errorf("download incomplete: received %d of %d bytes", offset+n, total)}
+	}
+	return info, nil
+}
+
+// serverSHA256 returns the SHA-256 of the whole file from response headers,
+// in hex, or "" when the server reports none. S3 sends it as
+// x-amz-checksum-sha256 and other servers as an RFC 9530 Repr-Digest.
+func serverSHA256(header http.Header) string {
+	encoded := header.Get("x-amz-checksum-sha256")
+	if encoded == "" {
+		for _, member := range strings.Split(header.Get("Repr-Digest"), ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(member), "=")
+			if ok && strings.EqualFold(name, "sha-256") {
+				encoded = strings.Trim(value, ":")
+			}
+		}
+	}
+	sum, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sum) != sha256.Size {
+		// Missing, or a checksum of parts rather than of the whole file
+		return ""
+	}
+	return hex.EncodeToString(sum)
+}
+
+// progressWriter counts written bytes and reports them to the progress callback
+type progressWriter struct {
+	written  int64
+	total    int64
+	progress func(written, total int64)
+	onWrite  func()
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if w.onWrite != nil {
+		w.onWrite()
+	}
+	if w.progress != nil {
+		w.progress(w.written, w.total)
+	}
+	return len(p), nil
+}
+
+// parseContentRange parses "bytes start-end/size" and "bytes */size". The
+// size is -1 when the server reports it as "*".
+func parseContentRange(value string) (start, size int64, ok bool) {
+	rangeSpec, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	span, sizeText, found := strings.Cut(rangeSpec, "/")
+	if !found {
+		return 0, 0, false
+	}
+
+	size = -1
+	if sizeText != "*" {
+		parsed, err := strconv.ParseInt(sizeText, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		size = parsed
+	}
+	if span == "*" {
+		return 0, size, true
+	}
+
+	startText, _, found := strings.Cut(span, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startText, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+func fileSHA256(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var downloadContent = bytes.Repeat([]byte("mirako video data "), 4096)
+
+const downloadETag = `"v1"`
+
+func serveDownloadContent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("ETag", downloadETag)
+	http.ServeContent(w, r, "video.mp4", time.Time{}, bytes.NewReader(downloadContent))
+}
+
+func checkDownloadedFile(t *testing.T, result *DownloadResult, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read download: %v", err)
+	}
+	if !bytes.Equal(data, downloadContent) {
+		t.Fatalf("downloaded %d bytes that do not match the %d served bytes", len(data), len(downloadContent))
+	}
+	sum := sha256.Sum256(downloadContent)
+	if result.Path != path || result.Size != int64(len(downloadContent)) || result.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Fatalf("expected the .part file to be renamed, stat error: %v", err)
+	}
+}
+
+func TestDownloadFileReportsProgress(t *testing.T) {
+	url := newTestServer(t, serveDownloadContent).URL + "/video.mp4"
+	path := filepath.Join(t.TempDir(), "out", "video.mp4")
+
+	var lastWritten, lastTotal int64
+	result, err := DownloadFile(context.Background(), url, path, DownloadOptions{
+		Progress: func(written, total int64) { lastWritten, lastTotal = written, total },
+	})
+	if err != nil {
+		t.Fatalf("DownloadFile returned error: %v", err)
+	}
+	checkDownloadedFile(t, result, path)
+	if lastWritten != int64(len(downloadContent)) || lastTotal != int64(len(downloadContent)) {
+		t.Fatalf("last progress = %d/%d, want %d", lastWritten, lastTotal, len(downloadContent))
+	}
+}
+
+func TestDownloadFileResumesInterruptedTransfer(t *testing.T) {
+	var requests int32
+	var rangeHeader atomic.Value
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// Announce the full size, send half of it and drop the connection
+			w.Header().Set("ETag", downloadETag)
+			w.Header().Set("Content-Length", strconv.Itoa(len(downloadContent)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(downloadContent[:len(downloadContent)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		rangeHeader.Store(r.Header.Get("Range"))
+		serveDownloadContent(w, r)
+	}).URL + "/video.mp4"
+	path := filepath.Join(t.TempDir(), "video.mp4")
+
+	result, err := DownloadFile(context.Background(), url, path, DownloadOptions{MaxRetries: 2})
+	if err != nil {
+		t.Fatalf("DownloadFile returned error: %v", err)
+	}
+	checkDownloadedFile(t, result, path)
+	if got := rangeHeader.Load(); got != "bytes="+strconv.Itoa(len(downloadContent)/2)+"-" {
+		t.Fatalf("resumed request Range = %v", got)
+	}
+}
+
+func writePartFile(t *testing.T, path string, data []byte, info *partInfo) {
+	t.Helper()
+	if err := os.WriteFile(path+".part", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if info != nil {
+		if err := savePartInfo(path+".part", info); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDownloadFileResumesPartFileFromEarlierRun(t *testing.T) {
+	var rangeHeader, ifRange atomic.Value
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader.Store(r.Header.Get("Range"))
+		ifRange.Store(r.Header.Get("If-Range"))
+		serveDownloadContent(w, r)
+	}).URL + "/video.mp4"
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	// A presigned URL for the same object has a different query
+	writePartFile(t, path, downloadContent[:1000], &partInfo{Source: downloadSource(url), ETag: downloadETag})
+
+	result, err := DownloadFile(context.Background(), url+"?X-Amz-Signature=new", path, DownloadOptions{})
+	if err != nil {
+		t.Fatalf("DownloadFile returned error: %v", err)
+	}
+	checkDownloadedFile(t, result, path)
+	if got := rangeHeader.Load(); got != "bytes=1000-" {
+		t.Fatalf("Range = %v, want bytes=1000-", got)
+	}
+	if got := ifRange.Load(); got != downloadETag {
+		t.Fatalf("If-Range = %v, want %s", got, downloadETag)
+	}
+	if _, err := os.Stat(path + ".part.json"); !os.IsNotExist(err) {
+		t.Fatalf("expected the .part.json file to be removed, stat error: %v", err)
+	}
+}
+
+func TestDownloadFileRestartsStalePartFile(t *testing.T) {
+	stale := bytes.Repeat([]byte("x"), 1000)
+	tests := []struct {
+		name string
+		info *partInfo
+	}{
+		{name: "no metadata"},
+		{name: "other file", info: &partInfo{Source: "http://example.com/other.mp4", ETag: downloadETag}},
+		// The server ignores the Range when If-Range doesn't match
+		{name: "changed on server", info: &partInfo{ETag: `"v0"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := newTestServer(t, serveDownloadContent).URL + "/video.mp4"
+			path := filepath.Join(t.TempDir(), "video.mp4")
+			if tt.info != nil && tt.info.Source == "" {
+				tt.info.Source = downloadSource(url)
+			}
+			writePartFile(t, path, stale, tt.info)
+
+			result, err := DownloadFile(context.Background(), url, path, DownloadOptions{})
+			if err != nil {
+				t.Fatalf("DownloadFile returned error: %v", err)
+			}
+			checkDownloadedFile(t, result, path)
+		})
+	}
+}
+
+func TestDownloadFileVerifiesServerChecksum(t *testing.T) {
+	sum := sha256.Sum256(downloadContent)
+	for _, tt := range []struct {
+		name, header, value string
+	}{
+		{name: "S3", header: "x-amz-checksum-sha256", value: base64.StdEncoding.EncodeToString(sum[:])},
+		{name: "Repr-Digest", header: "Repr-Digest", value: "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(tt.header, tt.value)
+				serveDownloadContent(w, r)
+			}).URL + "/video.mp4"
+			path := filepath.Join(t.TempDir(), "video.mp4")
+
+			result, err := DownloadFile(context.Background(), url, path, DownloadOptions{})
+			if err != nil {
+				t.Fatalf("DownloadFile returned error: %v", err)
+			}
+			checkDownloadedFile(t, result, path)
+			if !result.Verified {
+				t.Fatal("expected the download to be verified")
+			}
+		})
+	}
+
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		other := sha256.Sum256([]byte("other"))
+		w.Header().Set("x-amz-checksum-sha256", base64.StdEncoding.EncodeToString(other[:]))
+		serveDownloadContent(w, r)
+	}).URL + "/video.mp4"
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	_, err := DownloadFile(context.Background(), url, path, DownloadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "does not match the checksum reported by the server") {
+		t.Fatalf("DownloadFile error = %v, want a checksum mismatch", err)
+	}
+	for _, leftover := range []string{path, path + ".part", path + ".part.json"} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed, stat error: %v", leftover, err)
+		}
+	}
+}
+
+func TestDownloadFileDoesNotRetryClientErrors(t *testing.T) {
+	var requests int32
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "expired", http.StatusForbidden)
+	}).URL + "/video.mp4"
+	path := filepath.Join(t.TempDir(), "video.mp4")
+
+	if _, err := DownloadFile(context.Background(), url, path, DownloadOptions{MaxRetries: 3}); err == nil {
+		t.Fatal("expected an error for HTTP 403")
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no output file, stat error: %v", err)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value       string
+		start, size int64
+		ok          bool
+	}{
+		{value: "bytes 100-199/1000", start: 100, size: 1000, ok: true},
+		{value: "bytes 0-99/*", start: 0, size: -1, ok: true},
+		{value: "bytes */1000", start: 0, size: 1000, ok: true},
+		{value: "items 0-1/2"},
+		{value: "bytes 10/20"},
+	}
+	for _, tt := range tests {
+		start, size, ok := parseContentRange(tt.value)
+		if start != tt.start || size != tt.size || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", tt.value, start, size, ok)
+		}
+	}
+}
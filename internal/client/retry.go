@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+)
+
+// retryPolicy describes when a request may safely be sent again
+type retryPolicy int
+
+const (
+	// retryIdempotent is used for list/get/status calls that can be repeated freely
+	retryIdempotent retryPolicy = iota
+	// retryNotProcessed is used for calls with side effects. They are only
+	// repeated when the server clearly did not process the original request.
+	retryNotProcessed
+)
+
+var (
+	// retryBaseDelay is the initial backoff delay, doubled on every attempt
+	retryBaseDelay = 500 * time.Millisecond
+	// retryMaxDelay caps the computed backoff delay
+	retryMaxDelay = 10 * time.Second
+	// retryMaxRetryAfter is the longest Retry-After the client is willing to honor
+	retryMaxRetryAfter = 60 * time.Second
+)
+
+// send performs a request, retrying transient failures according to policy.
+// The send function must build a fresh request on every call.
+func (c *Client) send(ctx context.Context, policy retryPolicy, send func() (*http.Response, error)) (*http.Response, error) {
+	maxRetries := 0
+	if c.config != nil && c.config.MaxRetries > 0 {
+		maxRetries = c.config.MaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := send()
+		if attempt >= maxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if !shouldRetryError(err, policy) {
+				return nil, err
+			}
+			delay = backoffDelay(attempt)
+		case shouldRetryResponse(resp, policy):
+			var ok bool
+			delay, ok = retryAfterDelay(resp)
+			if !ok {
+				delay = backoffDelay(attempt)
+			}
+			if delay > retryMaxRetryAfter {
+				return resp, nil
+			}
+			// Drain the body so the underlying connection can be reused
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// shouldRetryError reports whether a transport error is worth retrying
+func shouldRetryError(err error, policy retryPolicy) bool {
+	if stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if policy == retryIdempotent {
+		return true
+	}
+
+	// A failed dial means the request never reached the server
+	var opErr *net.OpError
+	return stderrors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// shouldRetryResponse reports whether an HTTP response is a transient failure
+func shouldRetryResponse(resp *http.Response, policy retryPolicy) bool {
+	if resp == nil {
+		return false
+	}
+
+	apiErr := errors.NewAPIError(resp.StatusCode, "", "")
+	if apiErr.IsRateLimitError() {
+		// Rate limited requests are rejected before any work is done
+		return true
+	}
+	if !apiErr.IsServerUnavailable() {
+		return false
+	}
+	if policy == retryIdempotent {
+		return true
+	}
+
+	// A 503 with Retry-After is an explicit request to try again later
+	return resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != ""
+}
+
+// retryAfterDelay parses the Retry-After header in either seconds or HTTP-date form
+func retryAfterDelay(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// backoffDelay returns a jittered exponential delay for the given attempt
+func backoffDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	// Equal jitter: wait between half and the full delay
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+)
+
+func TestSendRetriesIdempotentCalls(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"task_id":"task-1","status":"IN_PROGRESS"}}`)
+	}, withMaxRetries(3))
+
+	resp, err := c.GetImageStatus(context.Background(), "task-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Data == nil || resp.Data.TaskId != "task-1" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestSendStopsAfterMaxRetries(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, withMaxRetries(2))
+
+	_, err := c.ListAvatars(context.Background())
+	apiErr, ok := errors.IsAPIError(err)
+	if !ok || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 APIError, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestSendDoesNotRetryProcessedPosts(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}, withMaxRetries(3))
+
+	_, err := c.GenerateAvatar(context.Background(), "a prompt", nil)
+	if err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestSendRetriesRateLimitedPosts(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"task_id":"task-1","status":"IN_QUEUE"}}`)
+	}, withMaxRetries(3))
+
+	resp, err := c.GenerateAvatar(context.Background(), "a prompt", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Data == nil || resp.Data.TaskId != "task-1" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestSendHonorsLongRetryAfterByGivingUp(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}, withMaxRetries(3))
+
+	_, err := c.ListAvatars(context.Background())
+	apiErr, ok := errors.IsAPIError(err)
+	if !ok || !apiErr.IsRateLimitError() {
+		t.Fatalf("expected rate limit APIError, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestRetryAfterDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{name: "missing", header: "", ok: false},
+		{name: "seconds", header: "5", want: 5 * time.Second, ok: true},
+		{name: "negative", header: "-1", ok: false},
+		{name: "past date", header: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, ok: true},
+		{name: "garbage", header: "soon", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+			got, ok := retryAfterDelay(resp)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("retryAfterDelay(%q) = %s, %t; want %s, %t", tt.header, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBackoffDelayIsCapped(t *testing.T) {
+	for attempt := 0; attempt < 40; attempt++ {
+		delay := backoffDelay(attempt)
+		if delay <= 0 || delay > retryMaxDelay {
+			t.Fatalf("attempt %d: delay %s out of range", attempt, delay)
+		}
+	}
+}
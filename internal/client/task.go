@@ -0,0 +1,294 @@
+package client
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-go/api"
+)
+
+// TaskKind identifies the kind of asynchronous task being tracked
+type TaskKind string
+
+const (
+	TaskKindAvatarGenerate TaskKind = "avatar_generate"
+	TaskKindAvatarBuild    TaskKind = "avatar_build"
+	TaskKindImageGenerate  TaskKind = "image_generate"
+	TaskKindTalkingAvatar  TaskKind = "talking_avatar"
+	TaskKindAvatarMotion   TaskKind = "avatar_motion"
+	TaskKindVoiceClone     TaskKind = "voice_clone"
+)
+
+const (
+	defaultWaitInterval    = 2 * time.Second
+	defaultWaitMaxInterval = 30 * time.Second
+	defaultWaitMultiplier  = 1.5
+)
+
+// ErrTaskWaitTimeout is returned when a task does not finish before the wait deadline
+var ErrTaskWaitTimeout = stderrors.New("timed out waiting for task")
+
+// Description returns a human readable name for the task kind
+func (k TaskKind) Description() string {
+	switch k {
+	case TaskKindAvatarGenerate:
+		return "avatar generation"
+	case TaskKindAvatarBuild:
+		return "avatar build"
+	case TaskKindImageGenerate:
+		return "image generation"
+	case TaskKindTalkingAvatar:
+		return "talking avatar video generation"
+	case TaskKindAvatarMotion:
+		return "avatar motion video generation"
+	case TaskKindVoiceClone:
+		return "voice cloning"
+	default:
+		return string(k)
+	}
+}
+
+// TaskResult is the normalized state of an asynchronous task
+type TaskResult struct {
+	Kind           TaskKind
+	TaskID         string
+	Status         string
+	Image          *string
+	FileURL        *string
+	OutputDuration *float64
+	ProfileID      *string
+	Error          *string
+}
+
+// IsCompleted returns true if the task finished successfully
+func (r *TaskResult) IsCompleted() bool {
+	if r.Kind == TaskKindAvatarBuild {
+		return r.Status == string(api.READY)
+	}
+	return r.Status == "COMPLETED"
+}
+
+// IsFailed returns true if the task reached a terminal unsuccessful status
+func (r *TaskResult) IsFailed() bool {
+	if r.Kind == TaskKindAvatarBuild {
+		return r.Status == string(api.ERROR)
+	}
+	switch r.Status {
+	case "FAILED", "CANCELED", "TIMED_OUT":
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTerminal returns true if the task will not change status anymore
+func (r *TaskResult) IsTerminal() bool {
+	return r.IsCompleted() || r.IsFailed()
+}
+
+// Err returns a *TaskFailedError if the task has failed, nil otherwise
+func (r *TaskResult) Err() error {
+	if !r.IsFailed() {
+		return nil
+	}
+	failure := &TaskFailedError{Kind: r.Kind, TaskID: r.TaskID, Status: r.Status}
+	if r.Error != nil {
+		failure.Message = *r.Error
+	}
+	return failure
+}
+
+// TaskFailedError is returned when a task ends in FAILED, CANCELED, TIMED_OUT or ERROR
+type TaskFailedError struct {
+	Kind    TaskKind
+	TaskID  string
+	Status  string
+	Message string
+}
+
+func (e *TaskFailedError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s failed: %s", e.Kind.Description(), e.Message)
+	}
+	return fmt.Sprintf("%s failed with status: %s", e.Kind.Description(), e.Status)
+}
+
+// ExitCode reports the CLI exit code for a failed task
+func (e *TaskFailedError) ExitCode() int {
+	return errors.ExitTaskFailed
+}
+
+// WaitOptions configures how WaitForTask polls for task status
+type WaitOptions struct {
+	// Interval is the delay before the first status check. Defaults to 2s.
+	Interval time.Duration
+	// MaxInterval caps the delay between status checks. Defaults to 30s.
+	MaxInterval time.Duration
+	// Multiplier grows the delay after every check. Values below 1 disable backoff.
+	Multiplier float64
+	// Timeout is the overall deadline for the task. Zero means no deadline.
+	Timeout time.Duration
+	// OnStatus is called whenever the observed task status changes
+	OnStatus func(status string)
+}
+
+func (o WaitOptions) withDefaults() WaitOptions {
+	if o.Interval <= 0 {
+		o.Interval = defaultWaitInterval
+	}
+	if o.MaxInterval <= 0 {
+		o.MaxInterval = defaultWaitMaxInterval
+	}
+	if o.MaxInterval < o.Interval {
+		o.MaxInterval = o.Interval
+	}
+	if o.Multiplier == 0 {
+		o.Multiplier = defaultWaitMultiplier
+	}
+	if o.Multiplier < 1 {
+		o.Multiplier = 1
+	}
+	return o
+}
+
+// nextInterval returns the delay to use after the given one
+func (o WaitOptions) nextInterval(current time.Duration) time.Duration {
+	next := time.Duration(float64(current) * o.Multiplier)
+	if next > o.MaxInterval {
+		return o.MaxInterval
+	}
+	return next
+}
+
+// GetTaskStatus fetches the current status of a task of any kind
+func (c *Client) GetTaskStatus(ctx context.Context, kind TaskKind, taskID string) (*TaskResult, error) {
+	result := &TaskResult{Kind: kind, TaskID: taskID}
+
+	switch kind {
+	case TaskKindAvatarGenerate:
+		resp, err := c.GetAvatarStatus(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Data == nil {
+			return nil, fmt.Errorf("unexpected response from server")
+		}
+		result.Status = string(resp.Data.Status)
+		result.Image = resp.Data.Image
+	case TaskKindAvatarBuild:
+		resp, err := c.GetAvatar(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			return nil, fmt.Errorf("unexpected response from server")
+		}
+		result.Status = string(resp.Data.Status)
+	case TaskKindImageGenerate:
+		resp, err := c.GetImageStatus(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Data == nil {
+			return nil, fmt.Errorf("unexpected response from server")
+		}
+		result.Status = string(resp.Data.Status)
+		result.Image = resp.Data.Image
+	case TaskKindTalkingAvatar:
+		resp, err := c.GetTalkingAvatarStatus(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Data == nil {
+			return nil, fmt.Errorf("unexpected response from server")
+		}
+		result.Status = string(resp.Data.Status)
+		result.FileURL = resp.Data.FileUrl
+		result.OutputDuration = resp.Data.OutputDuration
+		result.Error = resp.Data.Error
+	case TaskKindAvatarMotion:
+		resp, err := c.GetAvatarMotionStatus(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Data == nil {
+			return nil, fmt.Errorf("unexpected response from server")
+		}
+		result.Status = string(resp.Data.Status)
+		result.FileURL = resp.Data.FileUrl
+		result.OutputDuration = resp.Data.OutputDuration
+		result.Error = resp.Data.Error
+	case TaskKindVoiceClone:
+		resp, err := c.GetVoiceCloneStatus(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.Data == nil {
+			return nil, fmt.Errorf("unexpected response from server")
+		}
+		result.Status = string(resp.Data.Status)
+		result.ProfileID = resp.Data.ProfileId
+		result.Error = resp.Data.Error
+	default:
+		return nil, fmt.Errorf("unsupported task kind: %s", kind)
+	}
+
+	return result, nil
+}
+
+// WaitForTask polls a task until it reaches a terminal status, the context is
+// cancelled or the wait timeout expires. A task that ends unsuccessfully is
+// reported as a *TaskFailedError.
+func (c *Client) WaitForTask(ctx context.Context, kind TaskKind, taskID string, opts WaitOptions) (*TaskResult, error) {
+	opts = opts.withDefaults()
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	interval := opts.Interval
+	lastStatus := ""
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if opts.Timeout > 0 && stderrors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w: %s task %s did not finish within %s", ErrTaskWaitTimeout, kind.Description(), taskID, opts.Timeout)
+			}
+			return nil, fmt.Errorf("operation cancelled: %w", ctx.Err())
+		case <-timer.C:
+		}
+
+		result, err := c.GetTaskStatus(ctx, kind, taskID)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			return nil, err
+		}
+
+		if result.Status != lastStatus {
+			lastStatus = result.Status
+			if opts.OnStatus != nil {
+				opts.OnStatus(result.Status)
+			}
+		}
+
+		if result.IsCompleted() {
+			return result, nil
+		}
+		if err := result.Err(); err != nil {
+			return result, err
+		}
+
+		interval = opts.nextInterval(interval)
+		timer.Reset(interval)
+	}
+}
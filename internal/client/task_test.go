@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitForTaskCompletes(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/image/async_generate/task-1/status" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		status := "IN_PROGRESS"
+		if atomic.AddInt32(&calls, 1) >= 3 {
+			status = "COMPLETED"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":{"task_id":"task-1","status":%q,"image":"aGVsbG8="}}`, status)
+	})
+
+	var statuses []string
+	result, err := c.WaitForTask(context.Background(), TaskKindImageGenerate, "task-1", WaitOptions{
+		Interval: time.Millisecond,
+		OnStatus: func(status string) { statuses = append(statuses, status) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsCompleted() {
+		t.Fatalf("expected completed result, got %s", result.Status)
+	}
+	if result.Image == nil || *result.Image != "aGVsbG8=" {
+		t.Fatalf("expected image in result, got %v", result.Image)
+	}
+	if len(statuses) != 2 || statuses[0] != "IN_PROGRESS" || statuses[1] != "COMPLETED" {
+		t.Fatalf("expected status changes [IN_PROGRESS COMPLETED], got %v", statuses)
+	}
+}
+
+func TestWaitForTaskFailedStatus(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"task_id":"task-1","status":"FAILED","error":"bad audio"}}`)
+	})
+
+	_, err := c.WaitForTask(context.Background(), TaskKindTalkingAvatar, "task-1", WaitOptions{Interval: time.Millisecond})
+	var failure *TaskFailedError
+	if !stderrors.As(err, &failure) {
+		t.Fatalf("expected TaskFailedError, got %v", err)
+	}
+	if failure.Status != "FAILED" || failure.Message != "bad audio" {
+		t.Fatalf("unexpected failure details: %+v", failure)
+	}
+	if err.Error() != "talking avatar video generation failed: bad audio" {
+		t.Fatalf("unexpected error message: %q", err.Error())
+	}
+}
+
+func TestWaitForTaskTimeout(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"task_id":"task-1","status":"IN_QUEUE"}}`)
+	})
+
+	_, err := c.WaitForTask(context.Background(), TaskKindAvatarMotion, "task-1", WaitOptions{
+		Interval: time.Millisecond,
+		Timeout:  50 * time.Millisecond,
+	})
+	if !stderrors.Is(err, ErrTaskWaitTimeout) {
+		t.Fatalf("expected ErrTaskWaitTimeout, got %v", err)
+	}
+}
+
+func TestWaitOptionsBackoff(t *testing.T) {
+	opts := WaitOptions{Interval: time.Second, MaxInterval: 3 * time.Second, Multiplier: 2}.withDefaults()
+
+	interval := opts.Interval
+	expected := []time.Duration{2 * time.Second, 3 * time.Second, 3 * time.Second}
+	for i, want := range expected {
+		interval = opts.nextInterval(interval)
+		if interval != want {
+			t.Fatalf("step %d: expected %s, got %s", i, want, interval)
+		}
+	}
+}
+
+func TestTaskResultErr(t *testing.T) {
+	message := "out of memory"
+	failed := &TaskResult{Kind: TaskKindImageGenerate, TaskID: "task-1", Status: "FAILED", Error: &message}
+	var failure *TaskFailedError
+	if !stderrors.As(failed.Err(), &failure) || failure.TaskID != "task-1" || failure.Message != message {
+		t.Fatalf("expected TaskFailedError for failed task, got %v", failed.Err())
+	}
+
+	running := &TaskResult{Kind: TaskKindImageGenerate, TaskID: "task-1", Status: "IN_PROGRESS"}
+	if err := running.Err(); err != nil {
+		t.Fatalf("expected nil error for running task, got %v", err)
+	}
+}
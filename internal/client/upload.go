@@ -0,0 +1,276 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mirako-ai/mirako-go/api"
+)
+
+// audioContentTypes maps the supported sample extensions to their MIME types
+var audioContentTypes = map[string]string{
+	".wav": "audio/wav",
+	".mp3": "audio/mpeg",
+}
+
+// CloneVoiceOptions configure CloneVoice
+type CloneVoiceOptions struct {
+	// Progress is called as the request body is sent with the bytes sent so
+	// far and the total request size
+	Progress func(sent, total int64)
+}
+
+// formField is a plain text field of a multipart form
+type formField struct {
+	name, value string
+}
+
+// formFile is a file part of a multipart form, streamed from disk
+type formFile struct {
+	field       string
+	path        string
+	contentType string
+	size        int64
+}
+
+// multipartUpload streams a multipart form without holding the files in memory
+type multipartUpload struct {
+	boundary string
+	fields   []formField
+	files    []formFile
+}
+
+func newMultipartUpload() (*multipartUpload, error) {
+	var buf [30]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate multipart boundary: %w", err)
+	}
+	return &multipartUpload{boundary: hex.EncodeToString(buf[:])}, nil
+}
+
+func (u *multipartUpload) addField(name, value string) {
+	u.fields = append(u.fields, formField{name: name, value: value})
+}
+
+func (u *multipartUpload) addFile(field, path, contentType string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if contentType == "" {
+		contentType, err = detectContentType(path)
+		if err != nil {
+			return err
+		}
+	}
+	u.files = append(u.files, formFile{field: field, path: path, contentType: contentType, size: info.Size()})
+	return nil
+}
+
+func (u *multipartUpload) contentType() string {
+	return "multipart/form-data; boundary=" + u.boundary
+}
+
+// contentLength computes the exact size of the encoded form by writing
+// everything but the file contents
+func (u *multipartUpload) contentLength() (int64, error) {
+	counter := &countingWriter{}
+	err := u.write(counter, func(w io.Writer, file formFile) error {
+		counter.n += file.size
+		return nil
+	})
+	return counter.n, err
+}
+
+// reader returns the encoded form, produced on demand by a goroutine. Files
+// that change size after addFile fail the upload rather than corrupt it.
+func (u *multipartUpload) reader() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(u.write(pw, copyFormFile))
+	}()
+	return pr
+}
+
+func (u *multipartUpload) write(w io.Writer, copyFile func(io.Writer, formFile) error) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(u.boundary); err != nil {
+		return err
+	}
+
+	for _, field := range u.fields {
+		if err := writer.WriteField(field.name, field.value); err != nil {
+			return fmt.Errorf("failed to write %s field: %w", field.name, err)
+		}
+	}
+
+	for _, file := range u.files {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(file.field), escapeQuotes(filepath.Base(file.path))))
+		header.Set("Content-Type", file.contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return fmt.Errorf("failed to create form file: %w", err)
+		}
+		if err := copyFile(part, file); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+func copyFormFile(w io.Writer, file formFile) error {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.path, err)
+	}
+	defer f.Close()
+
+	n, err := io.Copy(w, io.LimitReader(f, file.size))
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", file.path, err)
+	}
+	if n != file.size {
+		return fmt.Errorf("failed to upload %s: file changed during upload", file.path)
+	}
+	return nil
+}
+
+// sendMultipart posts the form to url, retrying with a fresh body when the request
+// never reached the server
+func (c *Client) sendMultipart(ctx context.Context, url string, upload *multipartUpload, progress func(sent, total int64)) (*http.Response, error) {
+	total, err := upload.contentLength()
+	if err != nil {
+		return nil, err
+	}
+
+	// Uploads bypass the SDK client, whose timeout is too short for large sample sets
+	httpClient := &http.Client{Timeout: c.config.UploadTimeout}
+	return c.send(ctx, retryNotProcessed, func() (*http.Response, error) {
+		body := upload.reader()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &progressReader{reader: body, total: total, progress: progress})
+		if err != nil {
+			body.Close()
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.ContentLength = total
+		req.Header.Set("Content-Type", upload.contentType())
+		req.Header.Set("Authorization", "Bearer "+c.config.APIToken)
+
+		resp, err := httpClient.Do(req)
+		// Stops the writing goroutine when the request failed early
+		body.Close()
+		return resp, err
+	})
+}
+
+// CloneVoice uploads the audio samples in audioDir and the annotation file
+// and starts a voice cloning task. The request body is streamed from disk.
+func (c *Client) CloneVoice(ctx context.Context, name string, audioDir string, annotationFile string, cleanData bool, description string, opts CloneVoiceOptions) (*api.AsyncFinetuningApiResponseBody, error) {
+	audioFiles, err := ScanAudioFiles(audioDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audio files: %w", err)
+	}
+	if len(audioFiles) == 0 {
+		return nil, fmt.Errorf("no audio files (.wav or .mp3) found in directory: %s", audioDir)
+	}
+
+	upload, err := newMultipartUpload()
+	if err != nil {
+		return nil, err
+	}
+	upload.addField("name", name)
+	upload.addField("clean_data", fmt.Sprintf("%t", cleanData))
+	if description != "" {
+		upload.addField("description", description)
+	}
+	if err := upload.addFile("annotation_list", annotationFile, "text/plain"); err != nil {
+		return nil, err
+	}
+	for _, audioFile := range audioFiles {
+		if err := upload.addFile("audio_samples", audioFile, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.sendMultipart(ctx, fmt.Sprintf("%s/v1/voice/clone", c.config.APIURL), upload, opts.Progress)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := handleHTTPResponse(resp, "clone voice"); err != nil {
+		return nil, err
+	}
+
+	var result api.AsyncFinetuningApiResponseBody
+	if err := parseJSONResponse(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// detectContentType returns the MIME type of an audio sample by extension,
+// falling back to sniffing the start of the file
+func detectContentType(path string) (string, error) {
+	if contentType, ok := audioContentTypes[strings.ToLower(filepath.Ext(path))]; ok {
+		return contentType, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// progressReader reports the bytes read through it to the progress callback
+type progressReader struct {
+	reader   io.Reader
+	total    int64
+	sent     int64
+	progress func(sent, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 && r.progress != nil {
+		r.sent += int64(n)
+		r.progress(r.sent, r.total)
+	}
+	return n, err
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mirako-ai/mirako-cli/internal/config"
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+)
+
+type uploadedPart struct {
+	field       string
+	filename    string
+	contentType string
+	data        []byte
+}
+
+func writeCloneSamples(t *testing.T) (audioDir, annotations string) {
+	t.Helper()
+	dir := t.TempDir()
+	audioDir = filepath.Join(dir, "samples")
+	if err := os.Mkdir(audioDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"a.wav": bytes.Repeat([]byte("wav"), 10000),
+		"b.mp3": bytes.Repeat([]byte("mp3"), 10000),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(audioDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	annotations = filepath.Join(dir, "annotation.list")
+	if err := os.WriteFile(annotations, []byte("a.wav|hello\nb.mp3|world\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return audioDir, annotations
+}
+
+func TestCloneVoiceStreamsMultipartBody(t *testing.T) {
+	audioDir, annotations := writeCloneSamples(t)
+
+	var contentLength int64
+	fields := map[string]string{}
+	var parts []uploadedPart
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/voice/clone" || r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("unexpected request %s with authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		contentLength = r.ContentLength
+
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("failed to read multipart body: %v", err)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("failed to read part: %v", err)
+				return
+			}
+			data, _ := io.ReadAll(part)
+			if part.FileName() == "" {
+				fields[part.FormName()] = string(data)
+				continue
+			}
+			parts = append(parts, uploadedPart{part.FormName(), part.FileName(), part.Header.Get("Content-Type"), data})
+		}
+		w.Write([]byte(`{"data":{"task_id":"task-1","status":"IN_QUEUE"}}`))
+	}, withUploadTimeout(config.DefaultUploadTimeout))
+
+	var lastSent, lastTotal int64
+	resp, err := c.CloneVoice(context.Background(), "My Voice", audioDir, annotations, true, "desc", CloneVoiceOptions{
+		Progress: func(sent, total int64) { lastSent, lastTotal = sent, total },
+	})
+	if err != nil {
+		t.Fatalf("CloneVoice failed: %v", err)
+	}
+	if resp.Data == nil || resp.Data.TaskId != "task-1" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	if fields["name"] != "My Voice" || fields["clean_data"] != "true" || fields["description"] != "desc" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+	want := []struct{ field, filename, contentType string }{
+		{"annotation_list", "annotation.list", "text/plain"},
+		{"audio_samples", "a.wav", "audio/wav"},
+		{"audio_samples", "b.mp3", "audio/mpeg"},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("expected %d file parts, got %d", len(want), len(parts))
+	}
+	for i, w := range want {
+		if parts[i].field != w.field || parts[i].filename != w.filename || parts[i].contentType != w.contentType {
+			t.Errorf("part %d: got %s/%s/%s, want %s/%s/%s", i, parts[i].field, parts[i].filename, parts[i].contentType, w.field, w.filename, w.contentType)
+		}
+	}
+	if len(parts[1].data) != 30000 || len(parts[2].data) != 30000 {
+		t.Fatalf("audio parts were not uploaded completely")
+	}
+
+	if contentLength <= 0 || lastTotal != contentLength || lastSent != contentLength {
+		t.Fatalf("expected progress to reach the content length %d, got %d/%d", contentLength, lastSent, lastTotal)
+	}
+}
+
+func TestCloneVoiceReturnsAPIError(t *testing.T) {
+	audioDir, annotations := writeCloneSamples(t)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"detail":"not enough samples"}`))
+	}, withUploadTimeout(config.DefaultUploadTimeout))
+
+	_, err := c.CloneVoice(context.Background(), "My Voice", audioDir, annotations, false, "", CloneVoiceOptions{})
+	apiErr, ok := errors.IsAPIError(err)
+	if !ok {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", apiErr.StatusCode)
+	}
+}
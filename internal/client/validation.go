@@ -10,6 +10,9 @@ import (
 	"strings"
 )
 
+// MinVoiceCloneSamples is the number of audio samples voice clone requires
+const MinVoiceCloneSamples = 6
+
 // ScanAudioFiles scans a directory for .wav and .mp3 files
 func ScanAudioFiles(dir string) ([]string, error) {
 	var audioFiles []string
@@ -85,8 +88,16 @@ func (c *Client) ValidateVoiceCloneInput(audioDir, annotationFile string) error
 	return nil
 }
 
-// parseAnnotationFile parses the annotation file and returns a list of referenced audio files
-func parseAnnotationFile(annotationFile string) ([]string, error) {
+// Annotation is an entry of an annotation file, "filename|transcription"
+type Annotation struct {
+	Line int
+	File string
+	Text string
+}
+
+// ParseAnnotationFile parses the annotation file and returns its entries.
+// Empty transcriptions are accepted here; voice clone rejects them.
+func ParseAnnotationFile(annotationFile string) ([]Annotation, error) {
 	file, err := os.Open(annotationFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open annotation file: %w", err)
@@ -100,9 +111,9 @@ func parseAnnotationFile(annotationFile string) ([]string, error) {
 	}
 
 	// Split content by lines
-	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	lines := strings.Split(string(content), "\n")
 
-	var audioFiles []string
+	var annotations []Annotation
 	for lineNum, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" {
@@ -126,12 +137,32 @@ func parseAnnotationFile(annotationFile string) ([]string, error) {
 			return nil, fmt.Errorf("invalid audio file extension on line %d: %s (only .wav and .mp3 are supported)", lineNum+1, filename)
 		}
 
-		audioFiles = append(audioFiles, filename)
+		// Some tools write extra columns such as speaker and language; the
+		// transcription is always the last one
+		annotations = append(annotations, Annotation{
+			Line: lineNum + 1,
+			File: filename,
+			Text: strings.TrimSpace(parts[len(parts)-1]),
+		})
 	}
 
-	if len(audioFiles) == 0 {
+	if len(annotations) == 0 {
 		return nil, fmt.Errorf("no valid audio file entries found in annotation file")
 	}
 
+	return annotations, nil
+}
+
+// parseAnnotationFile parses the annotation file and returns a list of referenced audio files
+func parseAnnotationFile(annotationFile string) ([]string, error) {
+	annotations, err := ParseAnnotationFile(annotationFile)
+	if err != nil {
+		return nil, err
+	}
+
+	audioFiles := make([]string, len(annotations))
+	for i, annotation := range annotations {
+		audioFiles[i] = annotation.File
+	}
 	return audioFiles, nil
 }
@@ -109,6 +109,22 @@ func TestParseAnnotationFile(t *testing.T) {
 	}
 }
 
+func TestParseAnnotationFileUsesLastColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotation.list")
+	if err := os.WriteFile(path, []byte("\na.wav|speaker|en|Hello world\n\nb.mp3| Bye \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	annotations, err := ParseAnnotationFile(path)
+	if err != nil {
+		t.Fatalf("ParseAnnotationFile failed: %v", err)
+	}
+	want := []Annotation{{Line: 2, File: "a.wav", Text: "Hello world"}, {Line: 4, File: "b.mp3", Text: "Bye"}}
+	if len(annotations) != len(want) || annotations[0] != want[0] || annotations[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, annotations)
+	}
+}
+
 func TestValidateVoiceCloneInput(t *testing.T) {
 	// Create temporary directory for tests
 	tmpDir, err := os.MkdirTemp("", "voice_clone_test_*")
@@ -1,10 +1,16 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/mirako-ai/mirako-cli/internal/credentials"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 )
@@ -19,12 +25,37 @@ type InteractiveProfile struct {
 	IdleTimeout    int64  `mapstructure:"idle_timeout" yaml:"idle_timeout"`
 }
 
+// Context is a named set of credentials and defaults, e.g. for a staging
+// deployment. Empty defaults are inherited from the top level of the config file.
+type Context struct {
+	APIURL          string `mapstructure:"api_url" yaml:"api_url"`
+	APIToken        string `mapstructure:"api_token" yaml:"api_token"`
+	DefaultVoice    string `mapstructure:"default_voice" yaml:"default_voice,omitempty"`
+	DefaultSavePath string `mapstructure:"default_save_path" yaml:"default_save_path,omitempty"`
+}
+
 type Config struct {
 	APIToken            string                        `mapstructure:"api_token" yaml:"api_token"`
 	APIURL              string                        `mapstructure:"api_url" yaml:"api_url"`
 	DefaultVoice        string                        `mapstructure:"default_voice" yaml:"default_voice"`
 	DefaultSavePath     string                        `mapstructure:"default_save_path" yaml:"default_save_path"`
+	MaxRetries          int                           `mapstructure:"max_retries" yaml:"max_retries"`
+	UploadTimeout       time.Duration                 `mapstructure:"upload_timeout" yaml:"upload_timeout"`
 	InteractiveProfiles map[string]InteractiveProfile `mapstructure:"interactive_profiles" yaml:"interactive_profiles"`
+	CurrentContext      string                        `mapstructure:"current_context" yaml:"current_context"`
+	Contexts            map[string]Context            `mapstructure:"contexts" yaml:"contexts"`
+	CredentialStore     string                        `mapstructure:"credential_store" yaml:"credential_store"`
+	CredentialHelper    string                        `mapstructure:"credential_helper" yaml:"credential_helper"`
+
+	// activeContext is the context selected with UseContext, empty for the default context
+	activeContext string
+	// base holds the top-level settings, which UseContext overlays with a context
+	base Context
+	// loadedToken is the token as loaded, so Save only writes changed tokens to the credential store
+	loadedToken string
+	tokenSource string
+	store       credentials.Store
+	storeKey    string // backend and helper the cached store was created for
 }
 
 var (
@@ -32,8 +63,22 @@ var (
 	DefaultConfigFileName   string = "config.yml"
 	DefaultLLMModel         string = "gemini-2.0-flash"
 	DefaultInteractiveModel string = "metis-2.5"
+	DefaultMaxRetries       int    = 3
+	DefaultUploadTimeout           = time.Hour
+	DefaultAPIURL           string = "https://mirako.co"
+	DefaultContextName      string = "default" // the settings at the top level of the config file
+)
+
+// Sources of the API token reported by TokenSource. Tokens read from a
+// credential store are reported with the name of the store.
+const (
+	TokenSourceFlag   = "flag"
+	TokenSourceEnv    = "env"
+	TokenSourceConfig = "config"
 )
 
+var contextNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 func DefaultUserConfigDirPath() string {
 	home, err := homedir.Dir()
 	if err != nil {
@@ -45,9 +90,11 @@ func DefaultUserConfigDirPath() string {
 func Load() (*Config, error) {
 
 	cfg := &Config{
-		APIURL:              "https://mirako.co",
+		APIURL:              DefaultAPIURL,
 		DefaultVoice:        "",
 		DefaultSavePath:     ".",
+		MaxRetries:          DefaultMaxRetries,
+		UploadTimeout:       DefaultUploadTimeout,
 		InteractiveProfiles: map[string]InteractiveProfile{},
 	}
 
@@ -67,6 +114,8 @@ func Load() (*Config, error) {
 
 	// Set defaults
 	viper.SetDefault("api_url", cfg.APIURL)
+	viper.SetDefault("max_retries", cfg.MaxRetries)
+	viper.SetDefault("upload_timeout", cfg.UploadTimeout)
 	if cfg.DefaultVoice != "" {
 		viper.SetDefault("default_voice", cfg.DefaultVoice)
 	}
@@ -81,7 +130,7 @@ func Load() (*Config, error) {
 			IdleTimeout: 15,
 		}
 
-		if err := os.MkdirAll(ConfigPath, 0755); err != nil {
+		if err := os.MkdirAll(ConfigPath, 0700); err != nil {
 			return nil, fmt.Errorf("failed to create config directory: %w", err)
 		}
 		// write default config file to ConfigPath
@@ -98,22 +147,80 @@ func Load() (*Config, error) {
 	if err := viper.Unmarshal(cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	cfg.base = cfg.topLevel()
+	cfg.loadedToken = cfg.APIToken
+	cfg.tokenSource = tokenSourceOf(cfg.APIToken)
 
 	return cfg, nil
 }
 
 func (c *Config) Save() error {
-	viper.Set("api_token", c.APIToken)
-	viper.Set("api_url", c.APIURL)
-	if c.DefaultVoice != "" {
-		viper.Set("default_voice", c.DefaultVoice)
+	store, err := c.TokenStore()
+	if err != nil {
+		return err
+	}
+
+	// With a credential store, a changed token is written to the store and
+	// removed from the config file. Unchanged tokens are left where they are.
+	tokenChanged := c.APIToken != c.loadedToken
+	if store != nil && tokenChanged {
+		if err := saveToken(store, c.ContextName(), c.APIToken); err != nil {
+			return err
+		}
+	}
+
+	// Changes made while a context is active belong to that context; the
+	// top level keeps the settings of the default context.
+	top := c.topLevel()
+	if c.activeContext != "" {
+		ctx := c.contextFromActive()
+		if store != nil {
+			ctx.APIToken = c.Contexts[c.activeContext].APIToken
+			if tokenChanged {
+				ctx.APIToken = ""
+			}
+		}
+		c.Contexts[c.activeContext] = ctx
+		top = c.base
+	} else if store != nil {
+		top.APIToken = c.base.APIToken
+		if tokenChanged {
+			top.APIToken = ""
+		}
+		c.base.APIToken = top.APIToken
+	}
+
+	viper.Set("api_token", top.APIToken)
+	viper.Set("api_url", top.APIURL)
+	if top.DefaultVoice != "" {
+		viper.Set("default_voice", top.DefaultVoice)
 	}
-	viper.Set("default_save_path", c.DefaultSavePath)
+	viper.Set("default_save_path", top.DefaultSavePath)
 	viper.Set("interactive_profiles", c.InteractiveProfiles)
+	if c.CurrentContext != "" || viper.IsSet("current_context") {
+		viper.Set("current_context", c.CurrentContext)
+	}
+	if c.Contexts != nil {
+		viper.Set("contexts", c.Contexts)
+	}
+	if c.CredentialStore != "" || viper.IsSet("credential_store") {
+		viper.Set("credential_store", c.CredentialStore)
+	}
+	if c.CredentialHelper != "" || viper.IsSet("credential_helper") {
+		viper.Set("credential_helper", c.CredentialHelper)
+	}
 
-	if err := viper.WriteConfigAs(filepath.Join(ConfigPath, DefaultConfigFileName)); err != nil {
+	// The config file may hold API tokens, so keep it private to the user.
+	// The mode only applies on creation, hence the explicit chmod.
+	path := filepath.Join(ConfigPath, DefaultConfigFileName)
+	viper.SetConfigPermissions(0600)
+	if err := viper.WriteConfigAs(path); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to restrict config file permissions: %w", err)
+	}
+	c.loadedToken = c.APIToken
 
 	return nil
 }
@@ -121,3 +228,326 @@ func (c *Config) Save() error {
 func (c *Config) IsAuthenticated() bool {
 	return c.APIToken != ""
 }
+
+// ContextName returns the name of the context in use
+func (c *Config) ContextName() string {
+	if c.activeContext == "" {
+		return DefaultContextName
+	}
+	return c.activeContext
+}
+
+// HasContext reports whether name refers to the default context or a context in the config file
+func (c *Config) HasContext(name string) bool {
+	name = strings.ToLower(name)
+	if name == DefaultContextName {
+		return true
+	}
+	_, ok := c.Contexts[name]
+	return ok
+}
+
+// ContextNames returns the default context followed by the configured contexts in order
+func (c *Config) ContextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultContextName}, names...)
+}
+
+// ContextSettings returns the stored settings of a context, without environment overrides
+func (c *Config) ContextSettings(name string) (Context, bool) {
+	name = strings.ToLower(name)
+	if name == DefaultContextName {
+		if c.activeContext == "" {
+			return c.topLevel(), true
+		}
+		return c.base, true
+	}
+	if name == c.activeContext {
+		return c.contextFromActive(), true
+	}
+	ctx, ok := c.Contexts[name]
+	if ok && ctx.APIURL == "" {
+		ctx.APIURL = DefaultAPIURL
+	}
+	return ctx, ok
+}
+
+// UseContext applies the settings of the named context. An empty name selects
+// the context from MIRAKO_CONTEXT, then current_context, then the default context.
+// MIRAKO_CONTEXT set to an empty value selects the default context.
+// The MIRAKO_API_TOKEN and MIRAKO_API_URL environment variables still take precedence.
+func (c *Config) UseContext(name string) error {
+	if name == "" {
+		envName, ok := os.LookupEnv("MIRAKO_CONTEXT")
+		if !ok {
+			envName = c.CurrentContext
+		}
+		name = envName
+	}
+	name = strings.ToLower(name)
+
+	if c.activeContext != "" {
+		c.activeContext = ""
+		c.applyContext(c.base)
+	}
+	c.loadedToken = c.APIToken
+	c.tokenSource = tokenSourceOf(c.APIToken)
+	if name == "" || name == DefaultContextName {
+		return nil
+	}
+
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return fmt.Errorf("context %q not found. Run 'mirako auth list' to see available contexts", name)
+	}
+
+	c.activeContext = name
+	c.APIToken = ctx.APIToken
+	c.APIURL = ctx.APIURL
+	if c.APIURL == "" {
+		c.APIURL = DefaultAPIURL
+	}
+	if ctx.DefaultVoice != "" {
+		c.DefaultVoice = ctx.DefaultVoice
+	}
+	if ctx.DefaultSavePath != "" {
+		c.DefaultSavePath = ctx.DefaultSavePath
+	}
+
+	// Like viper, ignore environment variables that are set but empty
+	if token := os.Getenv("MIRAKO_API_TOKEN"); token != "" {
+		c.APIToken = token
+	}
+	if apiURL := os.Getenv("MIRAKO_API_URL"); apiURL != "" {
+		c.APIURL = apiURL
+	}
+	c.loadedToken = c.APIToken
+	c.tokenSource = tokenSourceOf(c.APIToken)
+	return nil
+}
+
+// TokenSource describes where the API token came from: TokenSourceFlag,
+// TokenSourceEnv, TokenSourceConfig or the name of a credential store.
+// It is empty when there is no token.
+func (c *Config) TokenSource() string {
+	return c.tokenSource
+}
+
+// OverrideAPIToken replaces the token with one given on the command line
+func (c *Config) OverrideAPIToken(token string) {
+	c.APIToken = token
+	c.tokenSource = TokenSourceFlag
+}
+
+func tokenSourceOf(token string) string {
+	switch {
+	case os.Getenv("MIRAKO_API_TOKEN") != "":
+		return TokenSourceEnv
+	case token != "":
+		return TokenSourceConfig
+	default:
+		return ""
+	}
+}
+
+// AddContext creates an empty context and switches to it. Save persists it.
+func (c *Config) AddContext(name string) error {
+	name = strings.ToLower(name)
+	if name == DefaultContextName {
+		return c.UseContext(name)
+	}
+	if !contextNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid context name %q: use letters, digits, '-' and '_'", name)
+	}
+	if _, ok := c.Contexts[name]; ok {
+		return fmt.Errorf("context %q already exists", name)
+	}
+
+	if c.Contexts == nil {
+		c.Contexts = map[string]Context{}
+	}
+	c.Contexts[name] = Context{APIURL: DefaultAPIURL}
+	return c.UseContext(name)
+}
+
+// TokenStore returns the credential store selected with credential_store,
+// or nil when tokens are kept in the config file
+func (c *Config) TokenStore() (credentials.Store, error) {
+	key := c.CredentialStore + "\x00" + c.CredentialHelper
+	if c.store != nil && c.storeKey == key {
+		return c.store, nil
+	}
+
+	store, err := credentials.New(c.CredentialStore, credentials.Options{
+		Dir:    ConfigPath,
+		Helper: c.CredentialHelper,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.store, c.storeKey = store, key
+	return store, nil
+}
+
+// LoadToken reads the token of the selected context from the credential
+// store, unless the config file or the environment already provides one
+func (c *Config) LoadToken() error {
+	if c.APIToken != "" {
+		return nil
+	}
+	store, err := c.TokenStore()
+	if err != nil || store == nil {
+		return err
+	}
+
+	token, err := store.Get(c.ContextName())
+	if errors.Is(err, credentials.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read API token from the %s credential store: %w", store.Name(), err)
+	}
+	c.APIToken = token
+	c.loadedToken = token
+	c.tokenSource = store.Name()
+	return nil
+}
+
+// HasToken reports whether a token is stored for the named context, in the
+// config file or the credential store
+func (c *Config) HasToken(name string) bool {
+	settings, ok := c.ContextSettings(name)
+	if !ok {
+		return false
+	}
+	if settings.APIToken != "" {
+		return true
+	}
+	store, err := c.TokenStore()
+	if err != nil || store == nil {
+		return false
+	}
+	_, err = store.Get(strings.ToLower(name))
+	return err == nil
+}
+
+// MigrateTokens moves the tokens of all contexts to the given credential
+// store and saves the config. Migrating to the plaintext store moves tokens
+// back into the config file. Returns the number of tokens moved.
+func (c *Config) MigrateTokens(backend, helper string) (int, error) {
+	if c.activeContext != "" {
+		return 0, fmt.Errorf("tokens must be migrated without an active context")
+	}
+	if _, ok := os.LookupEnv("MIRAKO_API_TOKEN"); ok {
+		return 0, fmt.Errorf("unset MIRAKO_API_TOKEN before migrating tokens, so it is not mistaken for a stored token")
+	}
+
+	from, err := c.TokenStore()
+	if err != nil {
+		return 0, err
+	}
+	to, err := credentials.New(backend, credentials.Options{Dir: ConfigPath, Helper: helper})
+	if err != nil {
+		return 0, err
+	}
+
+	// Collect every token before changing anything
+	tokens := map[string]string{}
+	for _, name := range c.ContextNames() {
+		settings, _ := c.ContextSettings(name)
+		token := settings.APIToken
+		if token == "" && from != nil {
+			token, err = from.Get(name)
+			if errors.Is(err, credentials.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return 0, fmt.Errorf("failed to read API token of context %q: %w", name, err)
+			}
+		}
+		if token != "" {
+			tokens[name] = token
+		}
+	}
+
+	for name, token := range tokens {
+		yamlToken := token
+		if to != nil {
+			if err := to.Set(name, token); err != nil {
+				return 0, fmt.Errorf("failed to store API token of context %q: %w", name, err)
+			}
+			yamlToken = ""
+		}
+		if name == DefaultContextName {
+			c.APIToken, c.base.APIToken = yamlToken, yamlToken
+		} else {
+			ctx := c.Contexts[name]
+			ctx.APIToken = yamlToken
+			c.Contexts[name] = ctx
+		}
+	}
+	c.loadedToken = c.APIToken
+
+	fromBackend := c.CredentialStore
+	c.CredentialStore, c.CredentialHelper = backend, helper
+	c.store, c.storeKey = to, backend+"\x00"+helper
+	if err := c.Save(); err != nil {
+		return 0, err
+	}
+
+	// Only clean up the old store once the config points at the new one
+	if from != nil && fromBackend != backend {
+		for name := range tokens {
+			_ = from.Delete(name)
+		}
+	}
+	return len(tokens), nil
+}
+
+func saveToken(store credentials.Store, name, token string) error {
+	var err error
+	if token == "" {
+		err = store.Delete(name)
+	} else {
+		err = store.Set(name, token)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save API token to the %s credential store: %w", store.Name(), err)
+	}
+	return nil
+}
+
+func (c *Config) topLevel() Context {
+	return Context{
+		APIURL:          c.APIURL,
+		APIToken:        c.APIToken,
+		DefaultVoice:    c.DefaultVoice,
+		DefaultSavePath: c.DefaultSavePath,
+	}
+}
+
+func (c *Config) applyContext(ctx Context) {
+	c.APIURL = ctx.APIURL
+	c.APIToken = ctx.APIToken
+	c.DefaultVoice = ctx.DefaultVoice
+	c.DefaultSavePath = ctx.DefaultSavePath
+}
+
+// contextFromActive returns the active context with the current settings.
+// Defaults still equal to the inherited top-level values stay unset.
+func (c *Config) contextFromActive() Context {
+	ctx := c.Contexts[c.activeContext]
+	ctx.APIToken = c.APIToken
+	ctx.APIURL = c.APIURL
+	if ctx.DefaultVoice != "" || c.DefaultVoice != c.base.DefaultVoice {
+		ctx.DefaultVoice = c.DefaultVoice
+	}
+	if ctx.DefaultSavePath != "" || c.DefaultSavePath != c.base.DefaultSavePath {
+		ctx.DefaultSavePath = c.DefaultSavePath
+	}
+	return ctx
+}
@@ -267,6 +267,36 @@ func TestSaveConfig(t *testing.T) {
 	}
 }
 
+func TestLoadConfigMaxRetries(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mirako-config-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	os.Setenv("MIRAKO_CONFIG_PATH", tempDir)
+	tempConfigFile := filepath.Join(tempDir, "config.yml")
+
+	tests := []struct {
+		name          string
+		configContent string
+		expected      int
+	}{
+		{"default", "api_token: test-token\n", DefaultMaxRetries},
+		{"override", "api_token: test-token\nmax_retries: 5\n", 5},
+		{"disabled", "api_token: test-token\nmax_retries: 0\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			require.NoError(t, os.WriteFile(tempConfigFile, []byte(tt.configContent), 0644))
+
+			cfg, err := Load()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg.MaxRetries)
+		})
+	}
+}
+
 func TestIsAuthenticated(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -284,3 +314,235 @@ func TestIsAuthenticated(t *testing.T) {
 		})
 	}
 }
+
+func TestContexts(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mirako-config-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	os.Setenv("MIRAKO_CONFIG_PATH", tempDir)
+	tempConfigFile := filepath.Join(tempDir, "config.yml")
+
+	content := `api_token: prod-token
+default_voice: prod-voice
+current_context: staging
+contexts:
+  staging:
+    api_url: https://staging.mirako.co
+    api_token: staging-token
+  Local:
+    api_url: http://localhost:8080
+    default_voice: local-voice
+`
+	load := func() *Config {
+		viper.Reset()
+		cfg, err := Load()
+		require.NoError(t, err)
+		return cfg
+	}
+
+	t.Run("current context", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(tempConfigFile, []byte(content), 0644))
+		cfg := load()
+		// Load keeps the top-level settings until a context is selected
+		assert.Equal(t, "prod-token", cfg.APIToken)
+
+		require.NoError(t, cfg.UseContext(""))
+		assert.Equal(t, "staging", cfg.ContextName())
+		assert.Equal(t, "staging-token", cfg.APIToken)
+		assert.Equal(t, "https://staging.mirako.co", cfg.APIURL)
+		assert.Equal(t, "prod-voice", cfg.DefaultVoice, "defaults are inherited from the top level")
+		assert.Equal(t, []string{"default", "local", "staging"}, cfg.ContextNames())
+	})
+
+	t.Run("explicit context", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(tempConfigFile, []byte(content), 0644))
+		cfg := load()
+
+		require.NoError(t, cfg.UseContext("LOCAL"))
+		assert.Equal(t, "local", cfg.ContextName())
+		assert.Equal(t, "", cfg.APIToken, "tokens are never inherited")
+		assert.Equal(t, "local-voice", cfg.DefaultVoice)
+
+		require.NoError(t, cfg.UseContext("default"))
+		assert.Equal(t, "default", cfg.ContextName())
+		assert.Equal(t, "prod-token", cfg.APIToken)
+		assert.Equal(t, DefaultAPIURL, cfg.APIURL)
+
+		err := cfg.UseContext("missing")
+		assert.ErrorContains(t, err, `context "missing" not found`)
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(tempConfigFile, []byte(content), 0644))
+		t.Setenv("MIRAKO_CONTEXT", "local")
+		t.Setenv("MIRAKO_API_TOKEN", "env-token")
+		cfg := load()
+
+		require.NoError(t, cfg.UseContext(""))
+		assert.Equal(t, "local", cfg.ContextName())
+		assert.Equal(t, "env-token", cfg.APIToken)
+	})
+
+	t.Run("empty environment", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(tempConfigFile, []byte(content), 0644))
+		t.Setenv("MIRAKO_CONTEXT", "")
+		cfg := load()
+		require.Equal(t, "staging", cfg.CurrentContext)
+
+		require.NoError(t, cfg.UseContext(""))
+		assert.Equal(t, "default", cfg.ContextName())
+		assert.Equal(t, "prod-token", cfg.APIToken)
+	})
+
+	t.Run("save to context", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(tempConfigFile, []byte(content), 0644))
+		cfg := load()
+		require.NoError(t, cfg.UseContext("staging"))
+		cfg.APIToken = "new-staging-token"
+		require.NoError(t, cfg.Save())
+
+		cfg = load()
+		assert.Equal(t, "prod-token", cfg.APIToken)
+		assert.Equal(t, "staging", cfg.CurrentContext)
+		staging, ok := cfg.ContextSettings("staging")
+		require.True(t, ok)
+		assert.Equal(t, "new-staging-token", staging.APIToken)
+		assert.Equal(t, "", staging.DefaultVoice, "inherited defaults are not copied into the context")
+	})
+
+	t.Run("add context", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(tempConfigFile, []byte(content), 0644))
+		cfg := load()
+		assert.ErrorContains(t, cfg.AddContext("staging"), "already exists")
+		assert.ErrorContains(t, cfg.AddContext("dev.eu"), "invalid context name")
+
+		require.NoError(t, cfg.AddContext("dev"))
+		cfg.APIToken = "dev-token"
+		require.NoError(t, cfg.Save())
+
+		cfg = load()
+		require.NoError(t, cfg.UseContext("dev"))
+		assert.Equal(t, "dev-token", cfg.APIToken)
+		assert.Equal(t, DefaultAPIURL, cfg.APIURL)
+		assert.Equal(t, "staging", cfg.CurrentContext)
+	})
+}
+
+func TestCredentialStore(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mirako-config-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	os.Setenv("MIRAKO_CONFIG_PATH", tempDir)
+	t.Setenv("MIRAKO_CREDENTIALS_PASSPHRASE", "test-passphrase")
+	tempConfigFile := filepath.Join(tempDir, "config.yml")
+
+	content := `api_token: prod-token
+contexts:
+  staging:
+    api_url: https://staging.mirako.co
+    api_token: staging-token
+`
+	require.NoError(t, os.WriteFile(tempConfigFile, []byte(content), 0644))
+
+	load := func(context string) *Config {
+		viper.Reset()
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.NoError(t, cfg.UseContext(context))
+		require.NoError(t, cfg.LoadToken())
+		return cfg
+	}
+
+	cfg := load("default")
+	moved, err := cfg.MigrateTokens("file", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+
+	data, err := os.ReadFile(tempConfigFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "prod-token")
+	assert.NotContains(t, string(data), "staging-token")
+	assert.Contains(t, string(data), "credential_store: file")
+
+	info, err := os.Stat(tempConfigFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Tokens are read back from the store
+	cfg = load("staging")
+	assert.Equal(t, "staging-token", cfg.APIToken)
+	assert.True(t, cfg.HasToken("default"))
+
+	// A changed token goes to the store, not the config file
+	cfg.APIToken = "new-staging-token"
+	require.NoError(t, cfg.Save())
+	data, err = os.ReadFile(tempConfigFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "new-staging-token")
+	assert.Equal(t, "new-staging-token", load("staging").APIToken)
+	assert.Equal(t, "prod-token", load("default").APIToken)
+
+	// Logging out removes the token from the store
+	cfg = load("staging")
+	cfg.APIToken = ""
+	require.NoError(t, cfg.Save())
+	assert.Equal(t, "", load("staging").APIToken)
+	assert.False(t, load("default").HasToken("staging"))
+
+	// Migrating back to plaintext restores the token in the config file
+	cfg = load("default")
+	moved, err = cfg.MigrateTokens("plaintext", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved)
+	data, err = os.ReadFile(tempConfigFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "api_token: prod-token")
+}
+
+func TestTokenSource(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mirako-config-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	os.Setenv("MIRAKO_CONFIG_PATH", tempDir)
+	content := `api_token: prod-token
+contexts:
+  staging:
+    api_token: staging-token
+  empty:
+    api_url: https://empty.mirako.co
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "config.yml"), []byte(content), 0644))
+
+	tests := []struct {
+		name    string
+		context string
+		env     string
+		flag    string
+		want    string
+	}{
+		{name: "config file", context: "default", want: TokenSourceConfig},
+		{name: "context", context: "staging", want: TokenSourceConfig},
+		{name: "no token", context: "empty", want: ""},
+		{name: "environment", context: "staging", env: "env-token", want: TokenSourceEnv},
+		{name: "flag", context: "default", env: "env-token", flag: "flag-token", want: TokenSourceFlag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			if tt.env != "" {
+				t.Setenv("MIRAKO_API_TOKEN", tt.env)
+			}
+			cfg, err := Load()
+			require.NoError(t, err)
+			require.NoError(t, cfg.UseContext(tt.context))
+			if tt.flag != "" {
+				cfg.OverrideAPIToken(tt.flag)
+			}
+			assert.Equal(t, tt.want, cfg.TokenSource())
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+var (
+	DefaultFileName string = "credentials.enc"
+	// PassphraseEnv names the environment variable read by DefaultPassphrase
+	PassphraseEnv string = "MIRAKO_CREDENTIALS_PASSPHRASE"
+)
+
+const (
+	fileVersion       = 1
+	fileKDF           = "pbkdf2-sha256"
+	defaultIterations = 600000
+	keyLength         = 32
+	saltLength        = 16
+)
+
+// PassphraseFunc returns the passphrase of the encrypted credentials file.
+// confirm is true when the file is about to be created, so the passphrase
+// should be entered twice.
+type PassphraseFunc func(confirm bool) (string, error)
+
+// FilePath returns the location of the encrypted credentials file in dir
+func FilePath(dir string) string {
+	return filepath.Join(dir, DefaultFileName)
+}
+
+// FileStore keeps tokens in a file encrypted with AES-256-GCM, using a key
+// derived from a passphrase with PBKDF2-SHA256
+type FileStore struct {
+	path       string
+	passphrase PassphraseFunc
+	iterations int
+
+	mu     sync.Mutex
+	secret string // passphrase cached after the first successful use
+}
+
+// encryptedFile is the on-disk format of the credentials file
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewFileStore creates a store backed by the encrypted file at path
+func NewFileStore(path string, passphrase PassphraseFunc) *FileStore {
+	return &FileStore{path: path, passphrase: passphrase, iterations: defaultIterations}
+}
+
+// Name identifies the backend in messages
+func (s *FileStore) Name() string {
+	return BackendFile
+}
+
+// Get returns the token stored under key
+func (s *FileStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	token, ok := tokens[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return token, nil
+}
+
+// Set stores token under key
+func (s *FileStore) Set(key, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+	tokens[key] = token
+	return s.save(tokens)
+}
+
+// Delete removes the token stored under key
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[key]; !ok {
+		return nil
+	}
+	delete(tokens, key)
+	return s.save(tokens)
+}
+
+// load decrypts the credentials file. A missing file holds no tokens and
+// does not ask for a passphrase.
+func (s *FileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %s: %w", s.path, err)
+	}
+	if file.Version != fileVersion || file.KDF != fileKDF {
+		return nil, fmt.Errorf("unsupported credentials file %s (version %d, kdf %q)", s.path, file.Version, file.KDF)
+	}
+
+	passphrase, err := s.getPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		s.secret = ""
+		return nil, fmt.Errorf("failed to decrypt credentials file: incorrect passphrase or corrupted file")
+	}
+
+	tokens := map[string]string{}
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %s: %w", s.path, err)
+	}
+	return tokens, nil
+}
+
+// save encrypts tokens with a fresh salt and nonce and replaces the file
+func (s *FileStore) save(tokens map[string]string) error {
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	_, statErr := os.Stat(s.path)
+	passphrase, err := s.getPassphrase(os.IsNotExist(statErr))
+	if err != nil {
+		return err
+	}
+
+	file := encryptedFile{
+		Version:    fileVersion,
+		KDF:        fileKDF,
+		Iterations: s.iterations,
+		Salt:       make([]byte, saltLength),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := newGCM(passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials file: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+
+	// CreateTemp creates the file with mode 0600, and the rename keeps a
+	// crash from leaving a truncated file behind
+	tmp, err := os.CreateTemp(dir, DefaultFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) getPassphrase(confirm bool) (string, error) {
+	if s.secret != "" {
+		return s.secret, nil
+	}
+	passphrase, err := s.passphrase(confirm)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("the credentials passphrase must not be empty")
+	}
+	s.secret = passphrase
+	return passphrase, nil
+}
+
+func newGCM(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations <= 0 || len(salt) == 0 {
+		return nil, errors.New("invalid key derivation parameters in credentials file")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// DefaultPassphrase reads the passphrase from MIRAKO_CREDENTIALS_PASSPHRASE,
+// or prompts for it when stdin is a terminal
+func DefaultPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("the credentials file is encrypted: set %s or run in a terminal", PassphraseEnv)
+	}
+
+	passphrase, err := readPassword(fd, "Credentials passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		again, err := readPassword(fd, "Confirm passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+func readPassword(fd int, prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,108 @@
+package credentials
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestFileStore(path, passphrase string) *FileStore {
+	store := NewFileStore(path, func(bool) (string, error) { return passphrase, nil })
+	store.iterations = 1000 // keep the tests fast
+	return store
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+	store := newTestFileStore(path, "correct horse")
+
+	if _, err := store.Get("default"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() on a missing file error = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Set("default", "prod-token"); err != nil {
+		t.Fatalf("Set() returned error: %v", err)
+	}
+	if err := store.Set("staging", "staging-token"); err != nil {
+		t.Fatalf("Set() returned error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("credentials file not written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("credentials file mode = %o, want 600", perm)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "prod-token") {
+		t.Fatalf("credentials file contains the plaintext token")
+	}
+
+	// A new store must decrypt the file with the same passphrase
+	reopened := newTestFileStore(path, "correct horse")
+	if token, err := reopened.Get("staging"); err != nil || token != "staging-token" {
+		t.Fatalf("Get(staging) = %q, %v", token, err)
+	}
+
+	if err := reopened.Delete("staging"); err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+	if _, err := reopened.Get("staging"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+	if token, err := reopened.Get("default"); err != nil || token != "prod-token" {
+		t.Fatalf("Get(default) = %q, %v", token, err)
+	}
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+	if err := newTestFileStore(path, "right").Set("default", "token"); err != nil {
+		t.Fatalf("Set() returned error: %v", err)
+	}
+
+	_, err := newTestFileStore(path, "wrong").Get("default")
+	if err == nil || !strings.Contains(err.Error(), "incorrect passphrase") {
+		t.Fatalf("Get() with wrong passphrase error = %v", err)
+	}
+}
+
+func TestFileStoreConfirmsNewPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+	var confirms []bool
+	store := NewFileStore(path, func(confirm bool) (string, error) {
+		confirms = append(confirms, confirm)
+		return "secret", nil
+	})
+	store.iterations = 1000
+
+	if err := store.Set("default", "token"); err != nil {
+		t.Fatalf("Set() returned error: %v", err)
+	}
+	if err := store.Set("staging", "token"); err != nil {
+		t.Fatalf("Set() returned error: %v", err)
+	}
+	// Asked once, with confirmation, and cached afterwards
+	if len(confirms) != 1 || !confirms[0] {
+		t.Fatalf("passphrase requests = %v, want [true]", confirms)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if store, err := New(BackendPlaintext, Options{}); err != nil || store != nil {
+		t.Fatalf("New(plaintext) = %v, %v, want nil store", store, err)
+	}
+	if _, err := New(BackendHelper, Options{}); err == nil || !strings.Contains(err.Error(), "credential_helper") {
+		t.Fatalf("New(helper) without a command error = %v", err)
+	}
+	if _, err := New("vault", Options{}); err == nil || !strings.Contains(err.Error(), "unknown credential store") {
+		t.Fatalf("New(vault) error = %v", err)
+	}
+	store, err := New(BackendFile, Options{Dir: "/tmp/mirako"})
+	if err != nil || store.(*FileStore).path != filepath.Join("/tmp/mirako", DefaultFileName) {
+		t.Fatalf("New(file) = %v, %v", store, err)
+	}
+}
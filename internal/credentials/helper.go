@@ -0,0 +1,91 @@
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// HelperStore delegates to an external command, in the style of git
+// credential helpers. The command is run with one of the actions "get",
+// "store" or "erase" appended, and receives key=value lines on stdin:
+//
+//	context=<auth context name>
+//	token=<API token>          (store only)
+//
+// For "get" it prints "token=<API token>" on stdout, or nothing when no
+// token is stored. Its stderr is passed through so it can prompt the user.
+type HelperStore struct {
+	command string
+}
+
+// NewHelperStore creates a store that runs the given shell command
+func NewHelperStore(command string) *HelperStore {
+	return &HelperStore{command: command}
+}
+
+// Name identifies the backend in messages
+func (s *HelperStore) Name() string {
+	return BackendHelper
+}
+
+// Get returns the token stored under key
+func (s *HelperStore) Get(key string) (string, error) {
+	output, err := s.run("get", map[string]string{"context": key})
+	if err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok && name == "token" && value != "" {
+			return value, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Set stores token under key
+func (s *HelperStore) Set(key, token string) error {
+	if strings.ContainsAny(token, "\r\n") {
+		return fmt.Errorf("API token must not contain line breaks")
+	}
+	_, err := s.run("store", map[string]string{"context": key, "token": token})
+	return err
+}
+
+// Delete removes the token stored under key
+func (s *HelperStore) Delete(key string) error {
+	_, err := s.run("erase", map[string]string{"context": key})
+	return err
+}
+
+func (s *HelperStore) run(action string, attributes map[string]string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", s.command+" "+action)
+	} else {
+		// Like git, run the helper through the shell with the action as its argument
+		cmd = exec.Command("sh", "-c", s.command+` "$@"`, s.command, action)
+	}
+
+	var stdin bytes.Buffer
+	for _, name := range []string{"context", "token"} {
+		if value, ok := attributes[name]; ok {
+			fmt.Fprintf(&stdin, "%s=%s\n", name, value)
+		}
+	}
+	cmd.Stdin = &stdin
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %q failed to %s: %w", s.command, action, err)
+	}
+	return output, nil
+}
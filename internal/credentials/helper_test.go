@@ -0,0 +1,75 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeTestHelper creates a credential helper script that keeps one file per context in dir
+func writeTestHelper(t *testing.T, dir string) string {
+	t.Helper()
+	script := fmt.Sprintf(`#!/bin/sh
+dir=%q
+read line
+context=${line#context=}
+case "$1" in
+get)
+	[ -f "$dir/$context" ] && printf 'token=%%s\n' "$(cat "$dir/$context")"
+	;;
+store)
+	read line
+	printf '%%s' "${line#token=}" > "$dir/$context"
+	;;
+erase)
+	rm -f "$dir/$context"
+	;;
+esac
+exit 0
+`, dir)
+	path := filepath.Join(dir, "helper.sh")
+	if err := os.WriteFile(path, []byte(script), 0700); err != nil {
+		t.Fatalf("failed to write helper: %v", err)
+	}
+	return path
+}
+
+func TestHelperStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test helper is a shell script")
+	}
+	dir := t.TempDir()
+	store := NewHelperStore(writeTestHelper(t, dir))
+
+	if _, err := store.Get("staging"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() before Set() error = %v, want ErrNotFound", err)
+	}
+	if err := store.Set("staging", "staging-token"); err != nil {
+		t.Fatalf("Set() returned error: %v", err)
+	}
+	if token, err := store.Get("staging"); err != nil || token != "staging-token" {
+		t.Fatalf("Get() = %q, %v", token, err)
+	}
+	if err := store.Delete("staging"); err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+	if _, err := store.Get("staging"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+	if err := store.Set("staging", "bad\ntoken"); err == nil {
+		t.Fatalf("Set() accepted a token with a line break")
+	}
+}
+
+func TestHelperStoreFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test helper is a shell command")
+	}
+	store := NewHelperStore("exit 3;")
+	if _, err := store.Get("default"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() with a failing helper error = %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// DefaultKeyringService is the service name tokens are stored under in the OS keyring
+const DefaultKeyringService = "mirako-cli"
+
+// KeyringStore keeps tokens in the OS keyring: the Secret Service on Linux
+// (through secret-tool from libsecret) and the login keychain on macOS
+// (through the security tool).
+type KeyringStore struct {
+	service string
+}
+
+// NewKeyringStore creates a keyring store using the given service name
+func NewKeyringStore(service string) *KeyringStore {
+	return &KeyringStore{service: service}
+}
+
+// Name identifies the backend in messages
+func (s *KeyringStore) Name() string {
+	return BackendKeyring
+}
+
+// Get returns the token stored under key
+func (s *KeyringStore) Get(key string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", s.service, "account", key)
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", s.service, "-a", key, "-w")
+	default:
+		return "", errKeyringUnsupported()
+	}
+
+	output, err := s.run(cmd, nil)
+	if err != nil {
+		if isKeyringNotFound(err) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	token := strings.TrimRight(output, "\r\n")
+	if token == "" {
+		return "", ErrNotFound
+	}
+	return token, nil
+}
+
+// Set stores token under key
+func (s *KeyringStore) Set(key, token string) error {
+	var cmd *exec.Cmd
+	var stdin []byte
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		// secret-tool reads the secret from stdin, keeping it out of the process list
+		cmd = exec.Command("secret-tool", "store", "--label", "Mirako CLI ("+key+")", "service", s.service, "account", key)
+		stdin = []byte(token)
+	case "darwin":
+		// security only takes the password as an argument or from a terminal
+		// prompt, so the command is sent to its interactive mode on stdin to
+		// keep the token out of the process list
+		if strings.ContainsAny(token, "\r\n") {
+			return fmt.Errorf("token must not contain line breaks")
+		}
+		cmd = exec.Command("security", "-i")
+		stdin = []byte(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", securityQuote(s.service), securityQuote(key), securityQuote(token)))
+	default:
+		return errKeyringUnsupported()
+	}
+
+	if _, err := s.run(cmd, stdin); err != nil {
+		return err
+	}
+	if runtime.GOOS == "darwin" {
+		// security -i exits with status 0 even when a command fails
+		if stored, err := s.Get(key); err != nil || stored != token {
+			return fmt.Errorf("failed to store the token in the keychain")
+		}
+	}
+	return nil
+}
+
+// Delete removes the token stored under key
+func (s *KeyringStore) Delete(key string) error {
+	if _, err := s.Get(key); errors.Is(err, ErrNotFound) {
+		return nil
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "clear", "service", s.service, "account", key)
+	case "darwin":
+		cmd = exec.Command("security", "delete-generic-password", "-s", s.service, "-a", key)
+	default:
+		return errKeyringUnsupported()
+	}
+
+	_, err := s.run(cmd, nil)
+	return err
+}
+
+// securityQuote quotes an argument for a command line of security -i
+func securityQuote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+func (s *KeyringStore) run(cmd *exec.Cmd, stdin []byte) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("keyring tool %q not found: install it or use the file or helper credential store", cmd.Path)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return "", fmt.Errorf("%s: %w", strings.TrimSpace(stderr.String()), err)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// isKeyringNotFound reports whether a lookup failed because nothing is stored:
+// secret-tool exits with status 1 and no message, security with status 44
+func isKeyringNotFound(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	if runtime.GOOS == "darwin" {
+		return exitErr.ExitCode() == 44
+	}
+	// run only returns the bare ExitError when nothing was printed to stderr
+	return exitErr.ExitCode() == 1 && err == error(exitErr)
+}
+
+func errKeyringUnsupported() error {
+	return fmt.Errorf("the keyring credential store is not supported on %s; use the file or helper credential store", runtime.GOOS)
+}
@@ -0,0 +1,17 @@
+package credentials
+
+import "testing"
+
+func TestSecurityQuote(t *testing.T) {
+	tests := map[string]string{
+		"mk_abc123":    `"mk_abc123"`,
+		`with "quote"`: `"with \"quote\""`,
+		`back\slash`:   `"back\\slash"`,
+		"two words":    `"two words"`,
+	}
+	for arg, want := range tests {
+		if got := securityQuote(arg); got != want {
+			t.Errorf("securityQuote(%q) = %s, want %s", arg, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Credential store backends selectable with credential_store in the config file
+const (
+	BackendPlaintext = "plaintext"
+	BackendKeyring   = "keyring"
+	BackendFile      = "file"
+	BackendHelper    = "helper"
+)
+
+// SupportedBackends describes the accepted credential_store values
+const SupportedBackends = "plaintext, keyring, file, helper"
+
+// ErrNotFound is returned when no token is stored under a key
+var ErrNotFound = errors.New("credential not found")
+
+// Store keeps API tokens outside the config file. Keys are auth context names.
+type Store interface {
+	// Get returns the token stored under key, or ErrNotFound
+	Get(key string) (string, error)
+	// Set stores token under key, replacing any previous token
+	Set(key, token string) error
+	// Delete removes the token stored under key. Missing keys are not an error.
+	Delete(key string) error
+	// Name identifies the backend in messages
+	Name() string
+}
+
+// Options configure the store returned by New
+type Options struct {
+	// Dir is the directory of the encrypted credentials file
+	Dir string
+	// Helper is the credential helper command
+	Helper string
+	// Passphrase supplies the passphrase of the encrypted credentials file.
+	// DefaultPassphrase is used when nil.
+	Passphrase PassphraseFunc
+}
+
+// New returns the store of the given backend. The plaintext backend keeps
+// tokens in the config file itself, so New returns a nil Store for it.
+func New(backend string, opts Options) (Store, error) {
+	switch backend {
+	case "", BackendPlaintext:
+		return nil, nil
+	case BackendKeyring:
+		return NewKeyringStore(DefaultKeyringService), nil
+	case BackendFile:
+		passphrase := opts.Passphrase
+		if passphrase == nil {
+			passphrase = DefaultPassphrase
+		}
+		return NewFileStore(FilePath(opts.Dir), passphrase), nil
+	case BackendHelper:
+		if opts.Helper == "" {
+			return nil, fmt.Errorf("credential_store is %q but credential_helper is not set", BackendHelper)
+		}
+		return NewHelperStore(opts.Helper), nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q. Supported stores: %s", backend, SupportedBackends)
+	}
+}
@@ -0,0 +1,227 @@
+package dataset
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mirako-ai/mirako-cli/internal/audio"
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/internal/parallel"
+	"github.com/mirako-ai/mirako-go/api"
+)
+
+// Thresholds below which a transcription is flagged for review. The API
+// does not report a confidence for every model, so implausible speech rates
+// are flagged as well.
+var (
+	MinConfidence = 0.6
+	// Words per second for space-separated languages
+	MinWordsPerSecond = 0.5
+	MaxWordsPerSecond = 5.0
+	// Characters per second for Chinese and other scripts without spaces
+	MinCharsPerSecond = 1.0
+	MaxCharsPerSecond = 9.0
+)
+
+// Transcriber converts base64 encoded audio to text. It is implemented by
+// *client.Client.
+type Transcriber interface {
+	SpeechToText(ctx context.Context, audio string) (*api.STTApiResponseBody, error)
+}
+
+// AnnotateOptions configure Annotate
+type AnnotateOptions struct {
+	// Concurrency is the number of files transcribed at once
+	Concurrency int
+	// OnResult is called once for every transcribed file; calls are serialized
+	OnResult func(Transcription)
+}
+
+// Transcription is the draft annotation of an audio sample
+type Transcription struct {
+	File       string   `json:"file"`
+	Text       string   `json:"text"`
+	Duration   float64  `json:"duration,omitempty"` // seconds
+	Confidence *float64 `json:"confidence,omitempty"`
+	// Review lists the reasons to check the transcription by hand
+	Review []string `json:"review,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// Annotate transcribes every .wav and .mp3 file in audioDir. The results are
+// in the order of client.ScanAudioFiles. Files that fail to transcribe are
+// returned with Error set rather than failing the whole run.
+func Annotate(ctx context.Context, transcriber Transcriber, audioDir string, opts AnnotateOptions) ([]Transcription, error) {
+	files, err := client.ScanAudioFiles(audioDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audio directory: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no audio files (.wav or .mp3) found in directory: %s", audioDir)
+	}
+
+	results := make([]Transcription, len(files))
+	var mu sync.Mutex
+
+	parallel.ForEach(ctx, len(files), opts.Concurrency, func(ctx context.Context, i int) error {
+		result := transcribe(ctx, transcriber, files[i])
+
+		mu.Lock()
+		defer mu.Unlock()
+		results[i] = result
+		if opts.OnResult != nil {
+			opts.OnResult(result)
+		}
+		return nil
+	})
+	return results, nil
+}
+
+func transcribe(ctx context.Context, transcriber Transcriber, path string) Transcription {
+	result := Transcription{File: filepath.Base(path)}
+	fail := func(err error) Transcription {
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			result.Error = apiErr.GetUserFriendlyMessage()
+		} else {
+			result.Error = err.Error()
+		}
+		result.Review = append(result.Review, "transcription failed")
+		return result
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fail(fmt.Errorf("failed to read audio file: %w", err))
+	}
+	resp, err := transcriber.SpeechToText(ctx, base64.StdEncoding.EncodeToString(data))
+	if err != nil {
+		return fail(err)
+	}
+	if resp == nil || resp.Data == nil {
+		return fail(fmt.Errorf("unexpected response from server"))
+	}
+
+	result.Text = cleanTranscription(resp.Data.Text)
+	if resp.Data.InputDuration != nil {
+		result.Duration = *resp.Data.InputDuration
+	} else if info, err := audio.Probe(path); err == nil {
+		result.Duration = info.Duration.Seconds()
+	}
+	if resp.Data.Transcription != nil {
+		result.Confidence = segmentConfidence(*resp.Data.Transcription)
+	}
+	result.Review = reviewReasons(result)
+	return result
+}
+
+// cleanTranscription puts the text on a single line without the "|"
+// separator of the annotation format
+func cleanTranscription(text string) string {
+	text = strings.ReplaceAll(text, "|", " ")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// segmentConfidence averages the "confidence" of the transcription
+// segments, weighted by their length when start and end are given
+func segmentConfidence(segments []interface{}) *float64 {
+	var sum, weights float64
+	for _, item := range segments {
+		segment, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		confidence, ok := segment["confidence"].(float64)
+		if !ok {
+			continue
+		}
+		weight := 1.0
+		start, hasStart := segment["start"].(float64)
+		end, hasEnd := segment["end"].(float64)
+		if hasStart && hasEnd && end > start {
+			weight = end - start
+		}
+		sum += confidence * weight
+		weights += weight
+	}
+	if weights == 0 {
+		return nil
+	}
+	average := sum / weights
+	return &average
+}
+
+func reviewReasons(t Transcription) []string {
+	if t.Text == "" {
+		return []string{"empty transcription"}
+	}
+
+	var reasons []string
+	if t.Confidence != nil && *t.Confidence < MinConfidence {
+		reasons = append(reasons, fmt.Sprintf("low confidence %.2f", *t.Confidence))
+	}
+	if t.Duration > 0 {
+		words, chars := countSpeechUnits(t.Text)
+		if chars > words {
+			rate := float64(chars) / t.Duration
+			if rate < MinCharsPerSecond || rate > MaxCharsPerSecond {
+				reasons = append(reasons, fmt.Sprintf("unusual speech rate of %.1f characters per second", rate))
+			}
+		} else {
+			rate := float64(words) / t.Duration
+			if rate < MinWordsPerSecond || rate > MaxWordsPerSecond {
+				reasons = append(reasons, fmt.Sprintf("unusual speech rate of %.1f words per second", rate))
+			}
+		}
+	}
+	return reasons
+}
+
+// countSpeechUnits counts space-separated words and the characters of
+// scripts written without spaces, such as Chinese
+func countSpeechUnits(text string) (words, chars int) {
+	for _, field := range strings.Fields(text) {
+		hasWord := false
+		for _, r := range field {
+			switch {
+			case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+				chars++
+			case unicode.IsLetter(r) || unicode.IsDigit(r):
+				hasWord = true
+			}
+		}
+		if hasWord {
+			words++
+		}
+	}
+	return words, chars
+}
+
+// WriteAnnotations writes transcriptions in the "filename|transcription"
+// format read by client.ParseAnnotationFile for voice clone. Files without a
+// transcription are listed with an empty one, to be filled in by hand.
+func WriteAnnotations(path string, transcriptions []Transcription) error {
+	var b strings.Builder
+	for _, t := range transcriptions {
+		fmt.Fprintf(&b, "%s|%s\n", t.File, t.Text)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write annotation file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package dataset
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-go/api"
+)
+
+// fakeTranscriber answers with the transcription registered for the audio
+// content and records the peak number of concurrent calls
+type fakeTranscriber struct {
+	texts    map[string]*api.STTOutput
+	active   atomic.Int32
+	maxSeen  atomic.Int32
+	failures map[string]bool
+}
+
+func (f *fakeTranscriber) SpeechToText(ctx context.Context, audio string) (*api.STTApiResponseBody, error) {
+	n := f.active.Add(1)
+	defer f.active.Add(-1)
+	for {
+		seen := f.maxSeen.Load()
+		if n <= seen || f.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	data, _ := base64.StdEncoding.DecodeString(audio)
+	if f.failures[string(data)] {
+		return nil, fmt.Errorf("connection reset")
+	}
+	return &api.STTApiResponseBody{Data: f.texts[string(data)]}, nil
+}
+
+func stt(text string, duration float64, segments ...interface{}) *api.STTOutput {
+	out := &api.STTOutput{Text: text, InputDuration: &duration}
+	if segments != nil {
+		out.Transcription = &segments
+	}
+	return out
+}
+
+func TestAnnotateWritesDraftAndFlagsReview(t *testing.T) {
+	dir := t.TempDir()
+	contents := map[string]string{
+		"a.wav": "audio-a", "b.wav": "audio-b", "c.mp3": "audio-c",
+		"d.wav": "audio-d", "e.wav": "audio-e", "f.wav": "audio-f",
+	}
+	for name, content := range contents {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transcriber := &fakeTranscriber{
+		texts: map[string]*api.STTOutput{
+			"audio-a": stt("Hello | there,\n how are you?", 2),
+			"audio-b": stt("", 3),
+			"audio-c": stt("今天天氣很好", 2),
+			"audio-d": stt("Maybe", 10),
+			"audio-e": stt("Quiet words here", 2, map[string]interface{}{"start": 0.0, "end": 1.0, "confidence": 0.9}, map[string]interface{}{"start": 1.0, "end": 4.0, "confidence": 0.3}),
+		},
+		failures: map[string]bool{"audio-f": true},
+	}
+
+	var calls int
+	results, err := Annotate(context.Background(), transcriber, dir, AnnotateOptions{
+		Concurrency: 2,
+		OnResult:    func(Transcription) { calls++ },
+	})
+	if err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	if calls != 6 || len(results) != 6 {
+		t.Fatalf("expected 6 results and callbacks, got %d and %d", len(results), calls)
+	}
+	if got := transcriber.maxSeen.Load(); got > 2 {
+		t.Fatalf("expected at most 2 concurrent requests, saw %d", got)
+	}
+
+	wantReview := map[string]string{
+		"a.wav": "",
+		"b.wav": "empty transcription",
+		"c.mp3": "",
+		"d.wav": "unusual speech rate of 0.1 words per second",
+		"e.wav": "low confidence 0.45",
+		"f.wav": "transcription failed",
+	}
+	for _, r := range results {
+		got := ""
+		if len(r.Review) > 0 {
+			got = r.Review[0]
+		}
+		if got != wantReview[r.File] {
+			t.Errorf("%s: expected review %q, got %v", r.File, wantReview[r.File], r.Review)
+		}
+	}
+	if results[0].Text != "Hello there, how are you?" {
+		t.Errorf("expected the text on one line without separators, got %q", results[0].Text)
+	}
+	if results[5].Error == "" {
+		t.Errorf("expected an error for the failed file")
+	}
+
+	path := filepath.Join(dir, DefaultAnnotationFileName)
+	if err := WriteAnnotations(path, results); err != nil {
+		t.Fatalf("WriteAnnotations failed: %v", err)
+	}
+	annotations, err := client.ParseAnnotationFile(path)
+	if err != nil {
+		t.Fatalf("the draft annotation file does not parse: %v", err)
+	}
+	if len(annotations) != 6 || annotations[0].Text != "Hello there, how are you?" || annotations[2].Text != "今天天氣很好" || annotations[5].Text != "" {
+		t.Fatalf("unexpected annotations: %+v", annotations)
+	}
+}
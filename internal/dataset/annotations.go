@@ -0,0 +1,6 @@
+// Package dataset inspects voice clone datasets: a directory of audio samples
+// and the annotation file listing their transcriptions
+package dataset
+
+// DefaultAnnotationFileName is the conventional name of the annotation file
+var DefaultAnnotationFileName = "annotation.list"
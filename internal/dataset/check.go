@@ -0,0 +1,231 @@
+package dataset
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/audio"
+	"github.com/mirako-ai/mirako-cli/internal/client"
+)
+
+// Recommended properties of voice clone samples. Samples outside these
+// ranges are accepted by the API but tend to give worse voices.
+var (
+	MinClipDuration  = 3 * time.Second
+	MaxClipDuration  = 15 * time.Second
+	MinTotalDuration = time.Minute
+	MaxTotalDuration = 30 * time.Minute
+	MinSampleRate    = 16000
+	MaxClippingRatio = 0.001
+	MaxSilenceRatio  = 0.3
+)
+
+// Severity of an Issue
+type Severity string
+
+const (
+	// SeverityError marks a problem that makes voice clone fail or the sample unusable
+	SeverityError Severity = "error"
+	// SeverityWarning marks a sample outside the recommended ranges
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found in a dataset
+type Issue struct {
+	Severity Severity `json:"severity"`
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Sample describes an audio sample of the dataset. Levels are only measured
+// for WAV files.
+type Sample struct {
+	File          string   `json:"file"`
+	Format        string   `json:"format,omitempty"`
+	Duration      float64  `json:"duration"` // seconds
+	SampleRate    int      `json:"sample_rate,omitempty"`
+	Channels      int      `json:"channels,omitempty"`
+	Peak          *float64 `json:"peak,omitempty"`
+	ClippingRatio *float64 `json:"clipping_ratio,omitempty"`
+	SilenceRatio  *float64 `json:"silence_ratio,omitempty"`
+	Transcription string   `json:"transcription,omitempty"`
+}
+
+// Report is the result of Check
+type Report struct {
+	Samples       []Sample `json:"samples"`
+	TotalDuration float64  `json:"total_duration"` // seconds
+	Errors        int      `json:"errors"`
+	Warnings      int      `json:"warnings"`
+	Issues        []Issue  `json:"issues"`
+}
+
+func (r *Report) add(severity Severity, file string, line int, format string, args ...any) {
+	r.Issues = append(r.Issues, Issue{Severity: severity, File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+	if severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// Check inspects every .wav and .mp3 file in audioDir and, unless
+// annotationFile is empty, the transcriptions listed for them
+func Check(audioDir, annotationFile string) (*Report, error) {
+	files, err := client.ScanAudioFiles(audioDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audio directory: %w", err)
+	}
+
+	// Samples are referenced by pointer below, so the slice must not grow
+	report := &Report{Samples: make([]Sample, 0, len(files)), Issues: []Issue{}}
+	if len(files) < client.MinVoiceCloneSamples {
+		report.add(SeverityError, "", 0, "found %d audio files, at least %d are required", len(files), client.MinVoiceCloneSamples)
+	}
+
+	samples := map[string]*Sample{}
+	var total time.Duration
+	unmeasured := 0
+	for _, path := range files {
+		name := filepath.Base(path)
+		report.Samples = append(report.Samples, Sample{File: name})
+		sample := &report.Samples[len(report.Samples)-1]
+		if _, ok := samples[name]; ok {
+			report.add(SeverityError, name, 0, "more than one file is named %s, annotations refer to files by name", name)
+		}
+		samples[name] = sample
+
+		info, err := audio.Probe(path)
+		if err != nil {
+			report.add(SeverityError, name, 0, "%v", err)
+			continue
+		}
+		total += info.Duration
+		if info.Levels == nil {
+			unmeasured++
+		}
+		checkSample(report, sample, info)
+	}
+	report.TotalDuration = total.Seconds()
+
+	if len(files) > 0 {
+		switch {
+		case total < MinTotalDuration:
+			report.add(SeverityWarning, "", 0, "total duration %s is shorter than the recommended %s", formatDuration(total), formatDuration(MinTotalDuration))
+		case total > MaxTotalDuration:
+			report.add(SeverityWarning, "", 0, "total duration %s is longer than the recommended %s", formatDuration(total), formatDuration(MaxTotalDuration))
+		}
+	}
+	// Levels are only measured from decoded WAV samples
+	if unmeasured > 0 {
+		report.add(SeverityWarning, "", 0, "clipping and silence are not checked for MP3 files (%d found), convert them to WAV to check them", unmeasured)
+	}
+
+	if annotationFile != "" {
+		annotations, err := client.ParseAnnotationFile(annotationFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotation file: %w", err)
+		}
+		checkAnnotations(report, samples, annotations)
+	}
+
+	// Report dataset-wide issues first, then by file
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+func checkSample(report *Report, sample *Sample, info *audio.Info) {
+	sample.Format = info.Format
+	sample.Duration = info.Duration.Seconds()
+	sample.SampleRate = info.SampleRate
+	sample.Channels = info.Channels
+
+	name := sample.File
+	switch {
+	case info.Duration == 0:
+		report.add(SeverityError, name, 0, "contains no audio")
+	case info.Duration < MinClipDuration:
+		report.add(SeverityWarning, name, 0, "duration %s is shorter than the recommended %s", formatDuration(info.Duration), formatDuration(MinClipDuration))
+	case info.Duration > MaxClipDuration:
+		report.add(SeverityWarning, name, 0, "duration %s is longer than the recommended %s", formatDuration(info.Duration), formatDuration(MaxClipDuration))
+	}
+	if info.SampleRate < MinSampleRate {
+		report.add(SeverityWarning, name, 0, "sample rate %d Hz is below the recommended %d Hz", info.SampleRate, MinSampleRate)
+	}
+	if info.Channels > 2 {
+		report.add(SeverityWarning, name, 0, "has %d channels, use mono or stereo", info.Channels)
+	}
+
+	if info.Levels == nil {
+		return
+	}
+	levels := *info.Levels
+	sample.Peak = &levels.Peak
+	sample.ClippingRatio = &levels.ClippingRatio
+	sample.SilenceRatio = &levels.SilenceRatio
+	if levels.ClippingRatio > MaxClippingRatio {
+		report.add(SeverityWarning, name, 0, "%.2f%% of the samples are clipped", levels.ClippingRatio*100)
+	}
+	if levels.SilenceRatio > MaxSilenceRatio {
+		report.add(SeverityWarning, name, 0, "%.0f%% of the clip is silent, trim leading and trailing silence", levels.SilenceRatio*100)
+	}
+}
+
+func checkAnnotations(report *Report, samples map[string]*Sample, annotations []client.Annotation) {
+	annotated := map[string]bool{}
+	texts := map[string][]client.Annotation{}
+	for _, a := range annotations {
+		if annotated[a.File] {
+			report.add(SeverityError, a.File, a.Line, "is listed more than once in the annotation file")
+			continue
+		}
+		annotated[a.File] = true
+
+		sample, ok := samples[a.File]
+		if !ok {
+			report.add(SeverityError, a.File, a.Line, "is listed in the annotation file but not found in the audio directory")
+			continue
+		}
+		sample.Transcription = a.Text
+
+		if a.Text == "" {
+			report.add(SeverityError, a.File, a.Line, "has an empty transcription")
+			continue
+		}
+		key := strings.ToLower(strings.Join(strings.Fields(a.Text), " "))
+		texts[key] = append(texts[key], a)
+	}
+
+	for _, sample := range report.Samples {
+		if !annotated[sample.File] {
+			report.add(SeverityError, sample.File, 0, "is not listed in the annotation file")
+		}
+	}
+
+	for _, duplicates := range texts {
+		if len(duplicates) < 2 {
+			continue
+		}
+		for _, a := range duplicates[1:] {
+			report.add(SeverityWarning, a.File, a.Line, "has the same transcription as %s", duplicates[0].File)
+		}
+	}
+}
+
+// formatDuration formats d with one decimal for seconds, e.g. "2.5s" or "1m30s"
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
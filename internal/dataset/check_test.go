@@ -0,0 +1,143 @@
+package dataset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// toneWAV encodes seconds of a mono 16 kHz tone as a 16-bit WAV file
+func toneWAV(seconds float64) []byte {
+	const rate = 16000
+	samples := make([]int16, int(seconds*rate))
+	for i := range samples {
+		samples[i] = int16(8000 * math.Sin(2*math.Pi*220*float64(i)/rate))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+2*len(samples)))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []any{uint32(16), uint16(1), uint16(1), uint32(rate), uint32(rate * 2), uint16(2), uint16(16)} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(2*len(samples)))
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+func writeDataset(t *testing.T, clips map[string]float64, annotations string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	audioDir := filepath.Join(dir, "samples")
+	if err := os.Mkdir(audioDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, seconds := range clips {
+		if err := os.WriteFile(filepath.Join(audioDir, name), toneWAV(seconds), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	annotationFile := filepath.Join(dir, DefaultAnnotationFileName)
+	if err := os.WriteFile(annotationFile, []byte(annotations), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return audioDir, annotationFile
+}
+
+func issueMessages(report *Report, file string) []string {
+	var messages []string
+	for _, issue := range report.Issues {
+		if issue.File == file {
+			messages = append(messages, string(issue.Severity)+": "+issue.Message)
+		}
+	}
+	return messages
+}
+
+func TestCheckReportsSampleAndAnnotationIssues(t *testing.T) {
+	audioDir, annotations := writeDataset(t, map[string]float64{
+		"01.wav": 5, "02.wav": 5, "03.wav": 5, "04.wav": 5, "05.wav": 1, "06.wav": 20, "07.wav": 5,
+	}, strings.Join([]string{
+		"01.wav|Hello there.",
+		"02.wav|hello   there.",
+		"03.wav|",
+		"04.wav|Good morning.",
+		"05.wav|Short one.",
+		"06.wav|A rather long sentence.",
+		"08.wav|Missing file.",
+	}, "\n"))
+
+	report, err := Check(audioDir, annotations)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(report.Samples) != 7 || report.TotalDuration != 46 {
+		t.Fatalf("expected 7 samples totalling 46s, got %d totalling %v", len(report.Samples), report.TotalDuration)
+	}
+
+	expect := map[string]string{
+		"02.wav": "warning: has the same transcription as 01.wav",
+		"03.wav": "error: has an empty transcription",
+		"05.wav": "warning: duration 1.0s is shorter than the recommended 3.0s",
+		"06.wav": "warning: duration 20.0s is longer than the recommended 15.0s",
+		"07.wav": "error: is not listed in the annotation file",
+		"08.wav": "error: is listed in the annotation file but not found in the audio directory",
+		"":       "warning: total duration 46.0s is shorter than the recommended 1m0s",
+	}
+	for file, want := range expect {
+		got := issueMessages(report, file)
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("%q: expected [%s], got %v", file, want, got)
+		}
+	}
+	if report.Errors != 3 || report.Warnings != 4 {
+		t.Errorf("expected 3 errors and 4 warnings, got %d and %d", report.Errors, report.Warnings)
+	}
+
+	for _, sample := range report.Samples {
+		if sample.File == "01.wav" && (sample.Transcription != "Hello there." || sample.SilenceRatio == nil || *sample.SilenceRatio != 0) {
+			t.Errorf("unexpected sample: %+v", sample)
+		}
+	}
+}
+
+func TestCheckRequiresMinimumSamples(t *testing.T) {
+	audioDir, _ := writeDataset(t, map[string]float64{"a.wav": 5}, "")
+
+	report, err := Check(audioDir, "")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if got := issueMessages(report, ""); len(got) == 0 || got[0] != "error: found 1 audio files, at least 6 are required" {
+		t.Fatalf("unexpected dataset issues: %v", got)
+	}
+}
+
+func TestCheckReportsUnmeasuredMP3Levels(t *testing.T) {
+	audioDir, _ := writeDataset(t, map[string]float64{"a.wav": 5}, "")
+	// Silent MPEG-1 layer III frames at 128 kbit/s and 44.1 kHz, 5s in total
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x40})
+	if err := os.WriteFile(filepath.Join(audioDir, "b.mp3"), bytes.Repeat(frame, 192), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Check(audioDir, "")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	want := "warning: clipping and silence are not checked for MP3 files (1 found), convert them to WAV to check them"
+	if got := issueMessages(report, ""); !slices.Contains(got, want) {
+		t.Fatalf("expected %q, got %v", want, got)
+	}
+	if report.Samples[1].File != "b.mp3" || report.Samples[1].ClippingRatio != nil {
+		t.Fatalf("unexpected MP3 sample: %+v", report.Samples[1])
+	}
+}
@@ -2,6 +2,7 @@ package errors
 
 import (
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -44,6 +45,13 @@ func (e *APIError) IsRateLimitError() bool {
 	return e.StatusCode == http.StatusTooManyRequests
 }
 
+// IsServerUnavailable returns true if the error indicates a temporary gateway or server outage
+func (e *APIError) IsServerUnavailable() bool {
+	return e.StatusCode == http.StatusBadGateway ||
+		e.StatusCode == http.StatusServiceUnavailable ||
+		e.StatusCode == http.StatusGatewayTimeout
+}
+
 // IsNotFound returns true if the error indicates a resource was not found
 func (e *APIError) IsNotFound() bool {
 	return e.StatusCode == http.StatusNotFound
@@ -69,6 +77,20 @@ func (e *APIError) GetUserFriendlyMessage() string {
 	return fmt.Sprintf("❌ API request failed with status %d", e.StatusCode)
 }
 
+// UserError returns an error whose message is GetUserFriendlyMessage while
+// still unwrapping to e, so the status code survives for exit codes and
+// structured error output
+func (e *APIError) UserError() error {
+	return &userError{apiErr: e}
+}
+
+type userError struct {
+	apiErr *APIError
+}
+
+func (e *userError) Error() string { return e.apiErr.GetUserFriendlyMessage() }
+func (e *userError) Unwrap() error { return e.apiErr }
+
 // HandleHTTPError processes an HTTP response and returns an appropriate error
 func HandleHTTPError(resp *http.Response, context string) error {
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
@@ -112,8 +134,9 @@ func NewAPIError(statusCode int, message, context string) *APIError {
 	}
 }
 
-// IsAPIError checks if an error is an APIError
+// IsAPIError checks if an error is, or wraps, an APIError
 func IsAPIError(err error) (*APIError, bool) {
-	apiErr, ok := err.(*APIError)
+	var apiErr *APIError
+	ok := stderrors.As(err, &apiErr)
 	return apiErr, ok
 }
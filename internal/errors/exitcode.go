@@ -0,0 +1,121 @@
+package errors
+
+import (
+	stderrors "errors"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/mirako-ai/mirako-go/api"
+)
+
+// Exit codes of the CLI. They are documented in the README and must stay
+// stable, since scripts branch on them.
+const (
+	ExitOK         = 0
+	ExitError      = 1 // any error not covered below
+	ExitValidation = 2 // invalid flags or arguments, or a request rejected as invalid by the API
+	ExitAuth       = 3 // missing or rejected API token
+	ExitCredits    = 4 // insufficient credits
+	ExitRateLimit  = 5 // rate limit exceeded
+	ExitNotFound   = 6 // resource not found
+	ExitNetwork    = 7 // API unreachable or temporarily unavailable
+	ExitTaskFailed = 8 // an asynchronous task finished unsuccessfully
+)
+
+// ExitCoder is implemented by errors that carry their own exit code
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// codedError attaches an exit code to an error without changing its message
+type codedError struct {
+	err  error
+	code int
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+func (e *codedError) ExitCode() int { return e.code }
+
+// WithExitCode returns err annotated with the given exit code
+func WithExitCode(err error, code int) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{err: err, code: code}
+}
+
+// NewValidationError marks err as a usage or input validation error
+func NewValidationError(err error) error {
+	return WithExitCode(err, ExitValidation)
+}
+
+// ExitCode returns the process exit code for err
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var coder ExitCoder
+	if stderrors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+
+	if apiErr, ok := IsAPIError(err); ok {
+		return apiErr.ExitCode()
+	}
+
+	var netErr net.Error
+	var urlErr *url.Error
+	if stderrors.As(err, &netErr) || stderrors.As(err, &urlErr) {
+		return ExitNetwork
+	}
+
+	return ExitError
+}
+
+// ExitCode maps the HTTP status of the error to an exit code
+func (e *APIError) ExitCode() int {
+	switch {
+	case e.IsAuthenticationError():
+		return ExitAuth
+	case e.IsInsufficientCredits():
+		return ExitCredits
+	case e.IsRateLimitError():
+		return ExitRateLimit
+	case e.IsNotFound():
+		return ExitNotFound
+	case e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity:
+		return ExitValidation
+	case e.StatusCode == 0 || e.IsServerUnavailable():
+		return ExitNetwork
+	default:
+		return ExitError
+	}
+}
+
+// ErrorOutput is the machine-readable form of an error, printed to stderr
+// when a structured output format is selected
+type ErrorOutput struct {
+	Error      string          `json:"error"`
+	ExitCode   int             `json:"exit_code"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Context    string          `json:"context,omitempty"`
+	Detail     *api.ErrorModel `json:"detail,omitempty"`
+}
+
+// NewErrorOutput describes err, including the API response details when err
+// wraps an APIError
+func NewErrorOutput(err error) ErrorOutput {
+	output := ErrorOutput{
+		Error:    err.Error(),
+		ExitCode: ExitCode(err),
+	}
+	if apiErr, ok := IsAPIError(err); ok {
+		output.StatusCode = apiErr.StatusCode
+		output.Context = apiErr.Context
+		output.Detail = apiErr.ErrorModel
+	}
+	return output
+}
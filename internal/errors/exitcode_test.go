@@ -0,0 +1,75 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: ExitOK},
+		{name: "plain error", err: fmt.Errorf("boom"), want: ExitError},
+		{name: "unauthorized", err: &APIError{StatusCode: http.StatusUnauthorized}, want: ExitAuth},
+		{name: "forbidden", err: &APIError{StatusCode: http.StatusForbidden}, want: ExitAuth},
+		{name: "payment required", err: &APIError{StatusCode: http.StatusPaymentRequired}, want: ExitCredits},
+		{name: "rate limited", err: &APIError{StatusCode: http.StatusTooManyRequests}, want: ExitRateLimit},
+		{name: "not found", err: &APIError{StatusCode: http.StatusNotFound}, want: ExitNotFound},
+		{name: "bad request", err: &APIError{StatusCode: http.StatusBadRequest}, want: ExitValidation},
+		{name: "unprocessable", err: &APIError{StatusCode: http.StatusUnprocessableEntity}, want: ExitValidation},
+		{name: "unavailable", err: &APIError{StatusCode: http.StatusServiceUnavailable}, want: ExitNetwork},
+		{name: "server error", err: &APIError{StatusCode: http.StatusInternalServerError}, want: ExitError},
+		{name: "wrapped user error", err: fmt.Errorf("failed: %w", (&APIError{StatusCode: http.StatusPaymentRequired}).UserError()), want: ExitCredits},
+		{name: "validation", err: NewValidationError(fmt.Errorf("bad flag")), want: ExitValidation},
+		{name: "explicit code", err: fmt.Errorf("wrapped: %w", WithExitCode(fmt.Errorf("no token"), ExitAuth)), want: ExitAuth},
+		{name: "dial error", err: &url.Error{Op: "Get", URL: "http://example.invalid", Err: &net.OpError{Op: "dial", Err: fmt.Errorf("refused")}}, want: ExitNetwork},
+		{name: "canceled", err: context.Canceled, want: ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserErrorKeepsAPIError(t *testing.T) {
+	apiErr := &APIError{StatusCode: http.StatusNotFound, Context: "get avatar"}
+	err := apiErr.UserError()
+
+	if err.Error() != apiErr.GetUserFriendlyMessage() {
+		t.Errorf("Error() = %q, want the user friendly message", err.Error())
+	}
+	if got, ok := IsAPIError(err); !ok || got != apiErr {
+		t.Errorf("IsAPIError() did not find the wrapped APIError")
+	}
+}
+
+func TestNewErrorOutput(t *testing.T) {
+	resp := httptest.NewRecorder()
+	resp.WriteHeader(http.StatusPaymentRequired)
+	resp.WriteString(`{"detail": "Not enough credits", "status": 402}`)
+
+	err := fmt.Errorf("wrapped: %w", HandleHTTPError(resp.Result(), "generate avatar"))
+	output := NewErrorOutput(err)
+
+	if output.ExitCode != ExitCredits || output.StatusCode != http.StatusPaymentRequired || output.Context != "generate avatar" {
+		t.Fatalf("unexpected error output: %+v", output)
+	}
+	if output.Detail == nil || output.Detail.Detail == nil || *output.Detail.Detail != "Not enough credits" {
+		t.Fatalf("expected ErrorModel detail, got %+v", output.Detail)
+	}
+	if output.Error != err.Error() {
+		t.Errorf("Error = %q, want %q", output.Error, err.Error())
+	}
+}
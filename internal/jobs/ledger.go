@@ -0,0 +1,251 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/config"
+)
+
+var (
+	DefaultLedgerFileName string = "jobs.json"
+	// MaxJobs is the number of most recent jobs kept in the ledger
+	MaxJobs int = 500
+)
+
+// ErrJobNotFound is returned when no job matches the requested task ID
+var ErrJobNotFound = errors.New("job not found")
+
+// ledgerMu serializes read-modify-write cycles on ledger files within this
+// process; lock serializes them across processes
+var ledgerMu sync.Mutex
+
+// Job is an asynchronous task submitted from the CLI
+type Job struct {
+	Kind        client.TaskKind   `json:"kind"`
+	TaskID      string            `json:"task_id"`
+	Inputs      map[string]string `json:"inputs,omitempty"`
+	OutputPath  string            `json:"output_path,omitempty"`
+	SubmittedAt time.Time         `json:"submitted_at"`
+	Status      string            `json:"status,omitempty"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	// CaptionsAudio is the audio transcribed into captions when a video is saved
+	CaptionsAudio string `json:"captions_audio,omitempty"`
+	// Context and APIURL are the auth context and API the task was submitted
+	// to, where it has to be looked up again
+	Context string `json:"context,omitempty"`
+	APIURL  string `json:"api_url,omitempty"`
+}
+
+// Ledger stores submitted jobs in a JSON file so they can be listed and resumed later
+type Ledger struct {
+	path string
+}
+
+// NewLedger creates a ledger backed by the file at path
+func NewLedger(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// DefaultLedger returns the ledger stored next to the config file
+func DefaultLedger() *Ledger {
+	dir := config.ConfigPath
+	if dir == "" {
+		dir = config.DefaultUserConfigDirPath()
+	}
+	return NewLedger(filepath.Join(dir, DefaultLedgerFileName))
+}
+
+// Path returns the location of the ledger file
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Add records a job, replacing any existing entry with the same task ID
+func (l *Ledger) Add(job Job) error {
+	if job.TaskID == "" {
+		return fmt.Errorf("job task ID is required")
+	}
+	if job.SubmittedAt.IsZero() {
+		job.SubmittedAt = time.Now()
+	}
+	if job.UpdatedAt.IsZero() {
+		job.UpdatedAt = job.SubmittedAt
+	}
+
+	return l.update(func(jobs []Job) ([]Job, error) {
+		for i := range jobs {
+			if jobs[i].TaskID == job.TaskID {
+				jobs[i] = job
+				return jobs, nil
+			}
+		}
+		return append(jobs, job), nil
+	})
+}
+
+// UpdateStatus sets the last known status of a job
+func (l *Ledger) UpdateStatus(taskID, status string) error {
+	return l.update(func(jobs []Job) ([]Job, error) {
+		for i := range jobs {
+			if jobs[i].TaskID == taskID {
+				jobs[i].Status = status
+				jobs[i].UpdatedAt = time.Now()
+				return jobs, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, taskID)
+	})
+}
+
+// List returns all recorded jobs, most recently submitted first
+func (l *Ledger) List() ([]Job, error) {
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+
+	jobs, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].SubmittedAt.After(jobs[j].SubmittedAt)
+	})
+	return jobs, nil
+}
+
+// Get looks up a job by its task ID or by an unambiguous prefix of it
+func (l *Ledger) Get(taskID string) (*Job, error) {
+	jobs, err := l.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Job
+	for _, job := range jobs {
+		if job.TaskID == taskID {
+			return &job, nil
+		}
+		if strings.HasPrefix(job.TaskID, taskID) {
+			matches = append(matches, job)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, taskID)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("task ID prefix %q matches %d jobs, please use a longer prefix", taskID, len(matches))
+	}
+}
+
+// update applies fn to the stored jobs and writes the result back
+func (l *Ledger) update(fn func([]Job) ([]Job, error)) error {
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	jobs, err := l.load()
+	if err != nil {
+		return err
+	}
+	jobs, err = fn(jobs)
+	if err != nil {
+		return err
+	}
+	return l.save(jobs)
+}
+
+// lock takes an advisory lock on a file next to the ledger, so that CLI
+// processes running at the same time, e.g. a batch and a video status, don't
+// overwrite each other's changes. The ledger itself is replaced on every save
+// and can't hold the lock.
+func (l *Ledger) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job ledger directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock job ledger: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock job ledger: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+func (l *Ledger) load() ([]Job, error) {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read job ledger: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var jobs []Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse job ledger %s: %w", l.path, err)
+	}
+	return jobs, nil
+}
+
+func (l *Ledger) save(jobs []Job) error {
+	// Keep only the most recently submitted jobs
+	if len(jobs) > MaxJobs {
+		sort.SliceStable(jobs, func(i, j int) bool {
+			return jobs[i].SubmittedAt.Before(jobs[j].SubmittedAt)
+		})
+		jobs = jobs[len(jobs)-MaxJobs:]
+	}
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode job ledger: %w", err)
+	}
+
+	dir := filepath.Dir(l.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create job ledger directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated ledger
+	tmp, err := os.CreateTemp(dir, DefaultLedgerFileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write job ledger: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write job ledger: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write job ledger: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return fmt.Errorf("failed to write job ledger: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	stderrors "errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLedger(t *testing.T) *Ledger {
+	t.Helper()
+	return NewLedger(filepath.Join(t.TempDir(), DefaultLedgerFileName))
+}
+
+func TestLedgerAddAndList(t *testing.T) {
+	ledger := newTestLedger(t)
+	now := time.Now()
+
+	require.NoError(t, ledger.Add(Job{Kind: client.TaskKindImageGenerate, TaskID: "task-old", SubmittedAt: now.Add(-time.Hour)}))
+	require.NoError(t, ledger.Add(Job{Kind: client.TaskKindTalkingAvatar, TaskID: "task-new", OutputPath: "out.mp4", SubmittedAt: now}))
+
+	jobs, err := ledger.List()
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "task-new", jobs[0].TaskID)
+	assert.Equal(t, "out.mp4", jobs[0].OutputPath)
+	assert.Equal(t, "task-old", jobs[1].TaskID)
+}
+
+func TestLedgerListMissingFile(t *testing.T) {
+	jobs, err := newTestLedger(t).List()
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestLedgerUpdateStatus(t *testing.T) {
+	ledger := newTestLedger(t)
+	require.NoError(t, ledger.Add(Job{Kind: client.TaskKindAvatarBuild, TaskID: "avatar-1", Status: "PENDING"}))
+	require.NoError(t, ledger.UpdateStatus("avatar-1", "READY"))
+
+	job, err := ledger.Get("avatar-1")
+	require.NoError(t, err)
+	assert.Equal(t, "READY", job.Status)
+
+	err = ledger.UpdateStatus("missing", "READY")
+	assert.True(t, stderrors.Is(err, ErrJobNotFound))
+}
+
+func TestLedgerGetByPrefix(t *testing.T) {
+	ledger := newTestLedger(t)
+	require.NoError(t, ledger.Add(Job{TaskID: "abc-123"}))
+	require.NoError(t, ledger.Add(Job{TaskID: "abd-456"}))
+
+	job, err := ledger.Get("abc")
+	require.NoError(t, err)
+	assert.Equal(t, "abc-123", job.TaskID)
+
+	_, err = ledger.Get("ab")
+	assert.Error(t, err)
+
+	_, err = ledger.Get("xyz")
+	assert.True(t, stderrors.Is(err, ErrJobNotFound))
+}
+
+func TestLedgerKeepsMostRecentJobs(t *testing.T) {
+	original := MaxJobs
+	MaxJobs = 2
+	t.Cleanup(func() { MaxJobs = original })
+
+	ledger := newTestLedger(t)
+	now := time.Now()
+	for i, id := range []string{"first", "second", "third"} {
+		require.NoError(t, ledger.Add(Job{TaskID: id, SubmittedAt: now.Add(time.Duration(i) * time.Minute)}))
+	}
+
+	jobs, err := ledger.List()
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "third", jobs[0].TaskID)
+	assert.Equal(t, "second", jobs[1].TaskID)
+}
+
+func TestLedgerUpdateWaitsForLockHeldElsewhere(t *testing.T) {
+	ledger := newTestLedger(t)
+
+	// Another process holding the lock looks the same as another open file
+	f, err := os.OpenFile(ledger.Path()+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, lockFile(f))
+
+	done := make(chan error)
+	go func() { done <- ledger.Add(Job{TaskID: "task-1"}) }()
+	select {
+	case err := <-done:
+		t.Fatalf("Add returned while the ledger was locked: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, unlockFile(f))
+	require.NoError(t, <-done)
+	_, err = ledger.Get("task-1")
+	require.NoError(t, err)
+}
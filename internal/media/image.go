@@ -0,0 +1,184 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"slices"
+
+	// Registered so that GIF inputs can be converted
+	_ "image/gif"
+)
+
+// jpegQuality is used when images are re-encoded as JPEG
+const jpegQuality = 90
+
+// maxDecodePixels limits the size of images decoded for conversion. Decoding
+// takes 4 bytes per pixel, and a small file can claim a huge size.
+const maxDecodePixels = 64 << 20
+
+// maxShrinkSteps limits how often an image that is still too large after
+// encoding is scaled down further
+const maxShrinkSteps = 6
+
+func preflightImage(in *Input, policy Policy, normalize bool, report *Report) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(in.Data))
+	if err != nil {
+		// Formats such as HEIC and WebP can't be decoded by the standard library
+		if typeErr := checkType(in, policy); typeErr != nil {
+			return typeErr
+		}
+		return fmt.Errorf("failed to decode %s: %w", in.Label(), err)
+	}
+
+	supported := slices.Contains(policy.Types, in.ContentType)
+	tooLarge := policy.MaxDimension > 0 && max(config.Width, config.Height) > policy.MaxDimension
+	tooBig := policy.MaxBytes > 0 && len(in.Data) > policy.MaxBytes
+	if supported && !tooLarge && !tooBig {
+		return nil
+	}
+	if !normalize {
+		switch {
+		case !supported:
+			return checkType(in, policy)
+		case tooLarge:
+			return fmt.Errorf("%s is %dx%d, larger than the limit of %d pixels per side", in.Label(), config.Width, config.Height, policy.MaxDimension)
+		default:
+			return fmt.Errorf("%s is %s, larger than the limit of %s", in.Label(), formatSize(len(in.Data)), formatSize(policy.MaxBytes))
+		}
+	}
+
+	if pixels := int64(config.Width) * int64(config.Height); pixels > maxDecodePixels {
+		return fmt.Errorf("%s is %dx%d, too large to convert (limit %d megapixels)", in.Label(), config.Width, config.Height, maxDecodePixels>>20)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(in.Data))
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", in.Label(), err)
+	}
+	img := toRGBA(decoded)
+	// Re-encoding drops the EXIF data, so the rotation it asks for is applied
+	// to the pixels
+	if in.ContentType == "image/jpeg" {
+		if orientation := jpegOrientation(in.Data); orientation > 1 {
+			img = orient(img, orientation)
+			config.Width, config.Height = img.Rect.Dx(), img.Rect.Dy()
+			report.Changes = append(report.Changes, "rotated to its EXIF orientation")
+		}
+	}
+
+	// Keep the format when it is accepted, and otherwise use PNG for images
+	// with transparency and JPEG for the rest
+	contentType := in.ContentType
+	if !supported {
+		contentType = "image/jpeg"
+		if !img.Opaque() && slices.Contains(policy.Types, "image/png") {
+			contentType = "image/png"
+		}
+	}
+
+	width, height := config.Width, config.Height
+	if tooLarge {
+		width, height = fitWithin(width, height, policy.MaxDimension)
+	}
+	var data []byte
+	for step := 0; ; step++ {
+		scaled := img
+		if width != config.Width || height != config.Height {
+			scaled = resize(img, width, height)
+		}
+		if data, err = encodeImage(scaled, contentType); err != nil {
+			return fmt.Errorf("failed to convert %s: %w", in.Label(), err)
+		}
+		if policy.MaxBytes == 0 || len(data) <= policy.MaxBytes {
+			break
+		}
+		// An opaque PNG shrinks most as a JPEG; anything else is scaled down
+		if contentType == "image/png" && img.Opaque() && slices.Contains(policy.Types, "image/jpeg") {
+			contentType = "image/jpeg"
+			continue
+		}
+		if step >= maxShrinkSteps {
+			return fmt.Errorf("%s is %s, larger than the limit of %s even at %dx%d", in.Label(), formatSize(len(in.Data)), formatSize(policy.MaxBytes), width, height)
+		}
+		width, height = max(1, width*3/4), max(1, height*3/4)
+	}
+
+	if width != config.Width || height != config.Height {
+		report.Changes = append(report.Changes, fmt.Sprintf("resized from %dx%d to %dx%d", config.Width, config.Height, width, height))
+	}
+	if contentType != in.ContentType {
+		report.Changes = append(report.Changes, fmt.Sprintf("converted from %s to %s", typeName(in.ContentType), typeName(contentType)))
+	}
+	if len(report.Changes) == 0 {
+		report.Changes = append(report.Changes, "re-encoded as "+typeName(contentType))
+	}
+	in.Data = data
+	in.ContentType = contentType
+	return nil
+}
+
+// fitWithin scales width and height down so that neither exceeds limit,
+// keeping the aspect ratio
+func fitWithin(width, height, limit int) (int, int) {
+	if width >= height {
+		return limit, max(1, height*limit/width)
+	}
+	return max(1, width*limit/height), limit
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// resize scales src down to width x height by averaging the source pixels
+// that fall into each destination pixel. The colors are premultiplied, so
+// transparent pixels do not bleed into their neighbours.
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max(y0+1, (y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max(x0+1, (x+1)*srcWidth/width)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			offset := y*dst.Stride + x*4
+			for c := range sum {
+				dst.Pix[offset+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
+
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,313 @@
+// Package media reads media inputs from files, standard input or http(s)
+// URLs, and writes media outputs to files or standard output
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Stdio is the path that stands for standard input or standard output
+const Stdio = "-"
+
+var (
+	// Stdin and Stdout are used for the Stdio path
+	Stdin  io.Reader = os.Stdin
+	Stdout io.Writer = os.Stdout
+
+	// httpClient fetches URL inputs; the context bounds the whole transfer
+	httpClient = func() *http.Client {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = time.Minute
+		return &http.Client{Transport: transport}
+	}()
+
+	stdinMu   sync.Mutex
+	stdinUsed bool
+)
+
+// contentTypes maps the extensions of supported media to their MIME types
+var contentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".wav":  "audio/wav",
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".ogg":  "audio/ogg",
+	".flac": "audio/flac",
+	".mp4":  "video/mp4",
+}
+
+// IsStdio reports whether path stands for standard input or output
+func IsStdio(path string) bool {
+	return path == Stdio
+}
+
+// IsURL reports whether source is an http or https URL
+func IsURL(source string) bool {
+	lower := strings.ToLower(source)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// Input is a media input read into memory
+type Input struct {
+	// Source is the path, URL or Stdio the input was read from
+	Source string
+	// Name is the file name of the input, empty for standard input
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// maxReadBytes bounds inputs read from standard input or an http(s) URL.
+// Inputs over an upload limit may still be converted to fit it, so this is
+// well above the limits of the policies.
+const maxReadBytes = 1 << 30
+
+// Read reads a media input from a local path, from standard input when
+// source is Stdio, or from an http(s) URL. Standard input can be read only
+// once per process. The content type is sniffed from the data, and only
+// taken from the server or the extension when the data is not recognized.
+func Read(ctx context.Context, source string) (*Input, error) {
+	return ReadWithin(ctx, source, maxReadBytes)
+}
+
+// ReadWithin reads a media input like Read, but stops reading standard input
+// or a URL past maxBytes and fails instead
+func ReadWithin(ctx context.Context, source string, maxBytes int) (*Input, error) {
+	var in *Input
+	var err error
+	switch {
+	case IsStdio(source):
+		in, err = readStdin(maxBytes)
+	case IsURL(source):
+		in, err = fetch(ctx, source, maxBytes)
+	default:
+		var data []byte
+		if data, err = os.ReadFile(source); err == nil {
+			in = &Input{Source: source, Name: filepath.Base(source), Data: data}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sniffed := Sniff(in.Data); sniffed != "" {
+		in.ContentType = sniffed
+	} else if in.ContentType == "" {
+		in.ContentType = contentTypes[strings.ToLower(filepath.Ext(in.Name))]
+	}
+	if in.ContentType == "" {
+		in.ContentType = "application/octet-stream"
+	}
+	return in, nil
+}
+
+func readStdin(maxBytes int) (*Input, error) {
+	stdinMu.Lock()
+	defer stdinMu.Unlock()
+
+	if stdinUsed {
+		return nil, fmt.Errorf("standard input (-) can only be used for one input")
+	}
+	stdinUsed = true
+	if f, ok := Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return nil, fmt.Errorf("no input piped to standard input (-)")
+	}
+	data, err := io.ReadAll(io.LimitReader(Stdin, int64(maxBytes)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read standard input: %w", err)
+	}
+	if len(data) > maxBytes {
+		return nil, fmt.Errorf("standard input (-) is larger than the limit of %s", formatSize(maxBytes))
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("standard input (-) is empty")
+	}
+	return &Input{Source: Stdio, Data: data}, nil
+}
+
+func fetch(ctx context.Context, source string, maxBytes int) (*Input, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", source, err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to download %s: %s", source, resp.Status)
+	}
+	tooLarge := fmt.Errorf("failed to download %s: it is larger than the limit of %s", source, formatSize(maxBytes))
+	if resp.ContentLength > int64(maxBytes) {
+		return nil, tooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	if len(data) > maxBytes {
+		return nil, tooLarge
+	}
+
+	in := &Input{Source: source, Data: data}
+	if u, err := url.Parse(source); err == nil {
+		in.Name = path.Base(u.Path)
+		if in.Name == "/" || in.Name == "." {
+			in.Name = ""
+		}
+	}
+	// Servers often answer with a generic type, which says nothing useful
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType != "application/octet-stream" && mediaType != "binary/octet-stream" {
+		in.ContentType = mediaType
+	}
+	return in, nil
+}
+
+// Sniff returns the MIME type of data from its content, or "" when the
+// content is not recognized
+func Sniff(data []byte) string {
+	switch {
+	case isWAV(data):
+		return "audio/wav"
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return "image/webp"
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return "audio/ogg"
+	case bytes.HasPrefix(data, []byte("ID3")):
+		return "audio/mpeg"
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		// ISO media files are told apart by their major brand
+		switch string(data[8:12]) {
+		case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1":
+			return "image/heic"
+		case "avif", "avis":
+			return "image/avif"
+		case "M4A ", "M4B ":
+			return "audio/mp4"
+		}
+		return "video/mp4"
+	}
+
+	if contentType, _, err := mime.ParseMediaType(http.DetectContentType(data)); err == nil && contentType != "application/octet-stream" && !strings.HasPrefix(contentType, "text/") {
+		return contentType
+	}
+	// MP3 without an ID3 tag starts with a frame sync
+	if len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0 {
+		return "audio/mpeg"
+	}
+	return ""
+}
+
+func isWAV(data []byte) bool {
+	return len(data) >= 12 && bytes.Equal(data[0:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WAVE"))
+}
+
+// IsWAV reports whether the input is WAV audio
+func (in *Input) IsWAV() bool {
+	return in.ContentType == "audio/wav" || isWAV(in.Data)
+}
+
+// Base64 returns the data encoded as standard base64
+func (in *Input) Base64() string {
+	return base64.StdEncoding.EncodeToString(in.Data)
+}
+
+// DataURL returns the data as a base64 data URL
+func (in *Input) DataURL() string {
+	return fmt.Sprintf("data:%s;base64,%s", in.ContentType, in.Base64())
+}
+
+// Label returns a short description of the input for messages
+func (in *Input) Label() string {
+	if IsStdio(in.Source) {
+		return "standard input"
+	}
+	return in.Source
+}
+
+// DecodeBase64 decodes standard base64 data, which may be given as a data URL
+func DecodeBase64(data string) ([]byte, error) {
+	if strings.HasPrefix(data, "data:") {
+		if comma := strings.Index(data, ","); comma != -1 {
+			data = data[comma+1:]
+		}
+	}
+	return base64.StdEncoding.DecodeString(data)
+}
+
+// Create opens path for writing, creating its directory, or returns standard
+// output when path is Stdio. Closing the returned writer leaves standard
+// output open.
+func Create(path string) (io.WriteCloser, error) {
+	if IsStdio(path) {
+		return nopCloser{Stdout}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// WriteFile writes data to path, creating its directory, or to standard
+// output when path is Stdio
+func WriteFile(path string, data []byte) error {
+	w, err := Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return w.Close()
+}
+
+// CopyFile copies the file at src to path with WriteFile semantics
+func CopyFile(path, src string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w, err := Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, file); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return w.Close()
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,139 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var wavHeader = []byte("RIFF\x24\x00\x00\x00WAVEfmt ")
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "face.png")
+	if err := os.WriteFile(path, []byte("not really a png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	in, err := Read(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if in.Name != "face.png" || in.ContentType != "image/png" || string(in.Data) != "not really a png" {
+		t.Errorf("unexpected input %+v", in)
+	}
+	if got := in.DataURL(); got != "data:image/png;base64,bm90IHJlYWxseSBhIHBuZw==" {
+		t.Errorf("unexpected data URL %s", got)
+	}
+}
+
+func TestReadURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/speech":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(wavHeader)
+		case "/face.jpg":
+			w.Header().Set("Content-Type", "image/jpeg; charset=binary")
+			w.Write([]byte("jpeg"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	in, err := Read(context.Background(), server.URL+"/speech")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if in.ContentType != "audio/wav" || !in.IsWAV() {
+		t.Errorf("expected WAV audio detected from the content, got %q", in.ContentType)
+	}
+
+	in, err = Read(context.Background(), server.URL+"/face.jpg")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if in.Name != "face.jpg" || in.ContentType != "image/jpeg" {
+		t.Errorf("unexpected input %+v", in)
+	}
+
+	if _, err := Read(context.Background(), server.URL+"/missing.wav"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 error, got %v", err)
+	}
+}
+
+func TestReadWithinStopsAtLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// Flushing first leaves out the Content-Length
+			w.(http.Flusher).Flush()
+		}
+		w.Write(bytes.Repeat([]byte("x"), 2048))
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/sized", "/chunked"} {
+		_, err := ReadWithin(context.Background(), server.URL+path, 1024)
+		if err == nil || !strings.Contains(err.Error(), "larger than the limit of 1.0 KB") {
+			t.Errorf("%s: expected a size error, got %v", path, err)
+		}
+		if _, err := ReadWithin(context.Background(), server.URL+path, 2048); err != nil {
+			t.Errorf("%s: ReadWithin failed at the limit: %v", path, err)
+		}
+	}
+}
+
+func TestReadStdinOnce(t *testing.T) {
+	defer func() {
+		Stdin = os.Stdin
+		stdinUsed = false
+	}()
+	Stdin = bytes.NewReader(wavHeader)
+	stdinUsed = false
+
+	in, err := Read(context.Background(), Stdio)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !in.IsWAV() || in.Label() != "standard input" {
+		t.Errorf("unexpected input %+v", in)
+	}
+	if _, err := Read(context.Background(), Stdio); err == nil {
+		t.Error("expected standard input to be readable only once")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	defer func() { Stdout = os.Stdout }()
+	var stdout bytes.Buffer
+	Stdout = &stdout
+
+	if err := WriteFile(Stdio, []byte("video")); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "video" {
+		t.Errorf("expected the data on stdout, got %q", stdout.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "out", "image.jpg")
+	if err := WriteFile(path, []byte("image")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "image" {
+		t.Errorf("expected the file to be written, got %q, %v", data, err)
+	}
+}
+
+func TestDecodeBase64(t *testing.T) {
+	for _, data := range []string{"aGk=", "data:image/jpeg;base64,aGk="} {
+		decoded, err := DecodeBase64(data)
+		if err != nil || string(decoded) != "hi" {
+			t.Errorf("%s: got %q, %v", data, decoded, err)
+		}
+	}
+}
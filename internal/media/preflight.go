@@ -0,0 +1,214 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mirako-ai/mirako-cli/internal/audio"
+)
+
+// Policy describes what the API accepts for one kind of input
+type Policy struct {
+	// Types lists the accepted MIME types
+	Types []string
+	// MaxBytes is the largest accepted input; 0 for no limit
+	MaxBytes int
+	// MaxDimension is the longest accepted side of an image in pixels; 0 for
+	// no limit
+	MaxDimension int
+	// SampleRate and Mono are what WAV audio is converted to when it is
+	// normalized. They are not limits: other audio is accepted as it is
+	// when normalization is off.
+	SampleRate int
+	Mono       bool
+}
+
+var (
+	// ImagePolicy applies to avatar faces and image prompts
+	ImagePolicy = Policy{Types: []string{"image/jpeg", "image/png"}, MaxBytes: 10 << 20, MaxDimension: 4096}
+	// VideoAudioPolicy applies to the speech a video is generated from. The
+	// audio ends up in the video, so it is only converted to fit the size.
+	VideoAudioPolicy = Policy{Types: []string{"audio/wav", "audio/mpeg"}, MaxBytes: 50 << 20}
+	// SpeechPolicy applies to audio sent to speech recognition, which works
+	// on 16 kHz mono
+	SpeechPolicy = Policy{Types: []string{"audio/wav", "audio/mpeg"}, SampleRate: 16000, Mono: true}
+)
+
+// typeNames are the names of media types used in messages
+var typeNames = map[string]string{
+	"image/jpeg": "JPEG",
+	"image/png":  "PNG",
+	"image/gif":  "GIF",
+	"image/bmp":  "BMP",
+	"image/webp": "WebP",
+	"image/heic": "HEIC",
+	"image/avif": "AVIF",
+	"audio/wav":  "WAV",
+	"audio/mpeg": "MP3",
+	"audio/flac": "FLAC",
+	"audio/ogg":  "Ogg",
+	"audio/mp4":  "M4A",
+	"video/mp4":  "MP4",
+}
+
+func typeName(contentType string) string {
+	if name, ok := typeNames[contentType]; ok {
+		return name
+	}
+	return contentType
+}
+
+// Report describes what Preflight changed in an input
+type Report struct {
+	Input   string
+	Changes []string
+	// OriginalSize and Size are the sizes in bytes before and after
+	OriginalSize int
+	Size         int
+}
+
+// Changed reports whether the input was converted
+func (r *Report) Changed() bool {
+	return len(r.Changes) > 0
+}
+
+// Preflight checks in against policy before it is uploaded. With normalize,
+// inputs outside the limits are converted to fit and WAV audio is converted
+// to the sample rate and channels of the policy; in is updated in place and
+// the report lists the changes. Without it, inputs outside the limits are an
+// error. Formats that can't be converted in pure Go, such as HEIC or FLAC,
+// are always an error.
+func Preflight(in *Input, policy Policy, normalize bool) (*Report, error) {
+	report := &Report{Input: in.Label(), OriginalSize: len(in.Data), Size: len(in.Data)}
+
+	var err error
+	switch {
+	case strings.HasPrefix(in.ContentType, "image/") && hasImageType(policy):
+		err = preflightImage(in, policy, normalize, report)
+	case in.IsWAV() && slices.Contains(policy.Types, "audio/wav"):
+		err = preflightWAV(in, policy, normalize, report)
+	default:
+		err = checkType(in, policy)
+		if err == nil && policy.MaxBytes > 0 && len(in.Data) > policy.MaxBytes {
+			err = fmt.Errorf("%s is %s, larger than the limit of %s", in.Label(), formatSize(len(in.Data)), formatSize(policy.MaxBytes))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	report.Size = len(in.Data)
+	return report, nil
+}
+
+func hasImageType(policy Policy) bool {
+	return slices.ContainsFunc(policy.Types, func(t string) bool { return strings.HasPrefix(t, "image/") })
+}
+
+// checkType fails unless the type of in is accepted by policy
+func checkType(in *Input, policy Policy) error {
+	if slices.Contains(policy.Types, in.ContentType) {
+		return nil
+	}
+	names := make([]string, len(policy.Types))
+	for i, t := range policy.Types {
+		names[i] = typeName(t)
+	}
+	if in.ContentType == "" || in.ContentType == "application/octet-stream" {
+		return fmt.Errorf("%s is not a recognized media file; convert it to %s", in.Label(), strings.Join(names, " or "))
+	}
+	return fmt.Errorf("%s is %s, which is not supported; convert it to %s", in.Label(), describeType(in.ContentType), strings.Join(names, " or "))
+}
+
+func describeType(contentType string) string {
+	name, ok := typeNames[contentType]
+	switch {
+	case !ok:
+		return "of type " + contentType
+	case strings.ContainsRune("AHMO", rune(name[0])):
+		return fmt.Sprintf("an %s file", name)
+	}
+	return fmt.Sprintf("a %s file", name)
+}
+
+func preflightWAV(in *Input, policy Policy, normalize bool, report *Report) error {
+	tooBig := policy.MaxBytes > 0 && len(in.Data) > policy.MaxBytes
+	if !normalize {
+		if tooBig {
+			return fmt.Errorf("%s is %s, larger than the limit of %s", in.Label(), formatSize(len(in.Data)), formatSize(policy.MaxBytes))
+		}
+		return nil
+	}
+
+	pcm, err := audio.DecodeWAVBytes(in.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", in.Label(), err)
+	}
+	sampleRate, channels := pcm.SampleRate, pcm.Channels
+	if policy.SampleRate > 0 && sampleRate > policy.SampleRate {
+		sampleRate = policy.SampleRate
+	}
+	if policy.Mono {
+		channels = 1
+	}
+	// Audio that is too large is mixed to mono, then resampled to 24 kHz,
+	// which keeps speech intact
+	size := func() int { return 44 + pcm.Frames()*sampleRate/pcm.SampleRate*channels*2 }
+	if tooBig && size() > policy.MaxBytes {
+		channels = 1
+	}
+	if tooBig && size() > policy.MaxBytes && sampleRate > 24000 {
+		sampleRate = 24000
+	}
+	if sampleRate == pcm.SampleRate && channels == pcm.Channels && !tooBig {
+		return nil
+	}
+
+	converted := pcm.Convert(sampleRate, channels)
+	var buf bytes.Buffer
+	if err := converted.EncodeWAV(&buf); err != nil {
+		return fmt.Errorf("failed to convert %s: %w", in.Label(), err)
+	}
+	if policy.MaxBytes > 0 && buf.Len() > policy.MaxBytes {
+		return fmt.Errorf("%s is %s, larger than the limit of %s even as %s mono; shorten the audio", in.Label(), formatSize(len(in.Data)), formatSize(policy.MaxBytes), formatRate(sampleRate))
+	}
+
+	if sampleRate != pcm.SampleRate {
+		report.Changes = append(report.Changes, fmt.Sprintf("resampled from %s to %s", formatRate(pcm.SampleRate), formatRate(sampleRate)))
+	}
+	if channels != pcm.Channels {
+		report.Changes = append(report.Changes, fmt.Sprintf("mixed %s to mono", describeChannels(pcm.Channels)))
+	}
+	if len(report.Changes) == 0 {
+		report.Changes = append(report.Changes, "re-encoded as 16-bit PCM")
+	}
+	in.Data = buf.Bytes()
+	in.ContentType = "audio/wav"
+	return nil
+}
+
+func describeChannels(channels int) string {
+	if channels == 2 {
+		return "stereo"
+	}
+	return fmt.Sprintf("%d channels", channels)
+}
+
+func formatRate(sampleRate int) string {
+	if sampleRate%1000 == 0 {
+		return fmt.Sprintf("%d kHz", sampleRate/1000)
+	}
+	return fmt.Sprintf("%.1f kHz", float64(sampleRate)/1000)
+}
+
+// formatSize formats a byte count for messages, in binary units
+func formatSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
@@ -0,0 +1,207 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/mirako-ai/mirako-cli/internal/audio"
+)
+
+func pngInput(t *testing.T, width, height int, alpha uint8) *Input {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	rng := rand.New(rand.NewSource(1))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(rng.Intn(256)), uint8(x), uint8(y), alpha})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return &Input{Source: "face.png", Name: "face.png", ContentType: Sniff(buf.Bytes()), Data: buf.Bytes()}
+}
+
+func TestPreflightResizesImage(t *testing.T) {
+	in := pngInput(t, 300, 150, 128)
+	report, err := Preflight(in, Policy{Types: ImagePolicy.Types, MaxDimension: 100}, true)
+	if err != nil {
+		t.Fatalf("Preflight failed: %v", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(in.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 100 || config.Height != 50 || in.ContentType != "image/png" {
+		t.Errorf("expected a 100x50 PNG, got %dx%d %s", config.Width, config.Height, in.ContentType)
+	}
+	if !report.Changed() || report.Changes[0] != "resized from 300x150 to 100x50" {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	in = pngInput(t, 300, 150, 128)
+	if _, err := Preflight(in, Policy{Types: ImagePolicy.Types, MaxDimension: 100}, false); err == nil || !strings.Contains(err.Error(), "300x150") {
+		t.Errorf("expected an error without normalization, got %v", err)
+	}
+}
+
+func TestPreflightConvertsOpaquePNG(t *testing.T) {
+	in := pngInput(t, 200, 200, 255)
+	size := len(in.Data)
+	report, err := Preflight(in, Policy{Types: ImagePolicy.Types, MaxBytes: size / 2}, true)
+	if err != nil {
+		t.Fatalf("Preflight failed: %v", err)
+	}
+	if in.ContentType != "image/jpeg" || Sniff(in.Data) != "image/jpeg" || len(in.Data) > size/2 {
+		t.Errorf("expected a JPEG under %d bytes, got %s of %d bytes", size/2, in.ContentType, len(in.Data))
+	}
+	if report.OriginalSize != size || report.Size != len(in.Data) {
+		t.Errorf("unexpected sizes in report %+v", report)
+	}
+}
+
+func TestPreflightNormalizesSpeech(t *testing.T) {
+	pcm := audio.NewPCM(48000, 2)
+	pcm.Samples = make([]int16, 48000*2)
+	var buf bytes.Buffer
+	if err := pcm.EncodeWAV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	in := &Input{Source: "talk.wav", Name: "talk.wav", ContentType: "audio/wav", Data: buf.Bytes()}
+
+	report, err := Preflight(in, SpeechPolicy, true)
+	if err != nil {
+		t.Fatalf("Preflight failed: %v", err)
+	}
+	converted, err := audio.DecodeWAVBytes(in.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted.SampleRate != 16000 || converted.Channels != 1 || converted.Frames() != 16000 {
+		t.Errorf("expected 1s of 16 kHz mono, got %d Hz, %d channels, %d frames", converted.SampleRate, converted.Channels, converted.Frames())
+	}
+	want := []string{"resampled from 48 kHz to 16 kHz", "mixed stereo to mono"}
+	if strings.Join(report.Changes, "|") != strings.Join(want, "|") {
+		t.Errorf("expected changes %q, got %q", want, report.Changes)
+	}
+
+	in.Data = buf.Bytes()
+	report, err = Preflight(in, SpeechPolicy, false)
+	if err != nil || report.Changed() || len(in.Data) != buf.Len() {
+		t.Errorf("expected the audio to be kept without normalization, got %+v, %v", report, err)
+	}
+}
+
+func TestPreflightRejectsUnsupportedTypes(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		policy Policy
+		want   string
+	}{
+		{"speech.flac", "fLaC\x00\x00\x00\x22", SpeechPolicy, "speech.flac is a FLAC file, which is not supported; convert it to WAV or MP3"},
+		{"face.heic", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", ImagePolicy, "face.heic is an HEIC file, which is not supported; convert it to JPEG or PNG"},
+		{"notes.txt", "hello", VideoAudioPolicy, "notes.txt is not a recognized media file; convert it to WAV or MP3"},
+	}
+	for _, tt := range tests {
+		in := &Input{Source: tt.name, Name: tt.name, ContentType: Sniff([]byte(tt.data)), Data: []byte(tt.data)}
+		_, err := Preflight(in, tt.policy, true)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestSniff(t *testing.T) {
+	tests := map[string]string{
+		"RIFF\x24\x00\x00\x00WAVEfmt ":         "audio/wav",
+		"RIFF\x24\x00\x00\x00WEBPVP8 ":         "image/webp",
+		"ID3\x04\x00\x00\x00\x00\x00\x00":      "audio/mpeg",
+		"\xff\xfb\x90\x00\x00\x00\x00\x00":     "audio/mpeg",
+		"\x00\x00\x00\x18ftypM4A \x00\x00\x00": "audio/mp4",
+		"\x00\x00\x00\x18ftypisom\x00\x00\x00": "video/mp4",
+		"\xff\xd8\xff\xe0\x00\x10JFIF\x00":     "image/jpeg",
+		"\x00\x01\x02\x03":                     "",
+	}
+	for data, want := range tests {
+		if got := Sniff([]byte(data)); got != want {
+			t.Errorf("Sniff(%q) = %q, want %q", data, got, want)
+		}
+	}
+}
+
+// withOrientation inserts an EXIF segment with the given orientation after
+// the start of a JPEG
+func withOrientation(jpegData []byte, orientation byte) []byte {
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, 0, 0, 0, 0, 0, 0}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := append([]byte{0xFF, 0xE1, 0, byte(len(segment) + 2)}, segment...)
+	return append(append(append([]byte{}, jpegData[:2]...), app1...), jpegData[2:]...)
+}
+
+func TestPreflightAppliesEXIFOrientation(t *testing.T) {
+	// A 60x20 photo, white on the left, taken with the camera turned
+	img := image.NewRGBA(image.Rect(0, 0, 60, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 60; x++ {
+			if x < 30 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := withOrientation(buf.Bytes(), 6)
+	in := &Input{Source: "photo.jpg", Name: "photo.jpg", ContentType: Sniff(data), Data: data}
+
+	report, err := Preflight(in, Policy{Types: ImagePolicy.Types, MaxDimension: 30}, true)
+	if err != nil {
+		t.Fatalf("Preflight failed: %v", err)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(in.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Turned 90° clockwise, the white half is at the top
+	if bounds := decoded.Bounds(); bounds.Dx() != 10 || bounds.Dy() != 30 {
+		t.Fatalf("expected a 10x30 image, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+	if r, _, _, _ := decoded.At(5, 2).RGBA(); r < 0xC000 {
+		t.Errorf("expected the top to be white, got %v", decoded.At(5, 2))
+	}
+	if r, _, _, _ := decoded.At(5, 27).RGBA(); r > 0x4000 {
+		t.Errorf("expected the bottom to be black, got %v", decoded.At(5, 27))
+	}
+	if len(report.Changes) < 2 || report.Changes[0] != "rotated to its EXIF orientation" || report.Changes[1] != "resized from 20x60 to 10x30" {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestPreflightRejectsHugeImages(t *testing.T) {
+	// Only the header is needed to claim the size
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	ihdr := []byte{'I', 'H', 'D', 'R', 0, 1, 0, 0, 0, 1, 0, 0, 8, 6, 0, 0, 0}
+	buf.Write([]byte{0, 0, 0, 13})
+	buf.Write(ihdr)
+	buf.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(ihdr)))
+	in := &Input{Source: "bomb.png", Name: "bomb.png", ContentType: "image/png", Data: buf.Bytes()}
+
+	_, err := Preflight(in, ImagePolicy, true)
+	if err == nil || !strings.Contains(err.Error(), "65536x65536, too large to convert") {
+		t.Fatalf("expected the image to be rejected before decoding, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+// Package parallel runs work concurrently with a limit on how much runs at once
+package parallel
+
+import (
+	"context"
+	"sync"
+)
+
+// ForEach calls fn for every index in [0, n), each in its own goroutine with
+// at most limit calls running at once, and returns when all calls have
+// returned. The first error cancels the context passed to the other calls and
+// is returned. Once the context is done, the remaining calls start without
+// waiting for a slot, so that each can record why it did not run.
+func ForEach(ctx context.Context, n, limit int, fn func(ctx context.Context, i int) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+			}
+
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,54 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachLimitsConcurrency(t *testing.T) {
+	var running, peak, calls int32
+	err := ForEach(context.Background(), 20, 3, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&calls, 1)
+		now := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEach returned error: %v", err)
+	}
+	if calls != 20 || peak > 3 {
+		t.Fatalf("calls = %d, peak = %d, want 20 calls and at most 3 at once", calls, peak)
+	}
+}
+
+func TestForEachCancelsOnFirstError(t *testing.T) {
+	failure := errors.New("failed")
+	var canceled int32
+	err := ForEach(context.Background(), 10, 1, func(ctx context.Context, i int) error {
+		if ctx.Err() != nil {
+			atomic.AddInt32(&canceled, 1)
+			return ctx.Err()
+		}
+		if i == 0 || i == 5 {
+			return failure
+		}
+		return nil
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("ForEach error = %v, want the first failure", err)
+	}
+	// Every index is still called, with a canceled context after the failure
+	if canceled == 0 {
+		t.Fatal("expected calls after the failure to see a canceled context")
+	}
+}
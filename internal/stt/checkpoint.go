@@ -0,0 +1,133 @@
+package stt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoint keeps the segments of transcribed windows in a file, so that an
+// interrupted transcription resumes without sending those windows again
+type Checkpoint struct {
+	path  string
+	mu    sync.Mutex
+	state checkpointState
+}
+
+type checkpointState struct {
+	// Audio is the SHA-256 of the audio file the windows belong to
+	Audio   string                         `json:"audio"`
+	Windows map[string][]checkpointSegment `json:"windows"`
+}
+
+type checkpointSegment struct {
+	Start int64  `json:"start_ms"`
+	End   int64  `json:"end_ms"`
+	Text  string `json:"text"`
+}
+
+// OpenCheckpoint loads the checkpoint at path. A missing file, or one written
+// for other audio, gives an empty checkpoint.
+func OpenCheckpoint(path, audioHash string) (*Checkpoint, error) {
+	c := &Checkpoint{path: path, state: checkpointState{Audio: audioHash, Windows: map[string][]checkpointSegment{}}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcription progress: %w", err)
+	}
+	var state checkpointState
+	if err := json.Unmarshal(data, &state); err != nil || state.Audio != audioHash || state.Windows == nil {
+		return c, nil
+	}
+	c.state = state
+	return c, nil
+}
+
+// Path returns the location of the checkpoint file
+func (c *Checkpoint) Path() string {
+	return c.path
+}
+
+// Len returns the number of transcribed windows
+func (c *Checkpoint) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.state.Windows)
+}
+
+// Lookup returns the segments of a window transcribed earlier
+func (c *Checkpoint) Lookup(w Window) ([]Segment, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, ok := c.state.Windows[windowKey(w)]
+	if !ok {
+		return nil, false
+	}
+	segments := make([]Segment, len(stored))
+	for i, s := range stored {
+		segments[i] = Segment{Start: time.Duration(s.Start) * time.Millisecond, End: time.Duration(s.End) * time.Millisecond, Text: s.Text}
+	}
+	return segments, true
+}
+
+// Store records the segments of a window and saves the checkpoint
+func (c *Checkpoint) Store(w Window, segments []Segment) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored := make([]checkpointSegment, len(segments))
+	for i, s := range segments {
+		stored[i] = checkpointSegment{Start: s.Start.Milliseconds(), End: s.End.Milliseconds(), Text: s.Text}
+	}
+	c.state.Windows[windowKey(w)] = stored
+	return c.save()
+}
+
+// Remove deletes the checkpoint file
+func (c *Checkpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Checkpoint) save() error {
+	data, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to save transcription progress: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated file
+	tmp, err := os.CreateTemp(dir, filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save transcription progress: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save transcription progress: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save transcription progress: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to save transcription progress: %w", err)
+	}
+	return nil
+}
+
+func windowKey(w Window) string {
+	return fmt.Sprintf("%d-%d", w.Start.Milliseconds(), w.End.Milliseconds())
+}
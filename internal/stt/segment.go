@@ -0,0 +1,116 @@
+// Package stt transcribes audio into timestamped segments, splitting
+// recordings locally when the API does not return timestamps, and writes the
+// segments as subtitles
+package stt
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/text"
+	"github.com/mirako-ai/mirako-go/api"
+)
+
+// Transcriber converts speech to text. It is implemented by *client.Client.
+type Transcriber interface {
+	SpeechToText(ctx context.Context, audio string) (*api.STTApiResponseBody, error)
+}
+
+// Segment is a span of transcribed speech
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// ParseSegments reads the timestamped segments of an STT response. Items are
+// objects with a text and start and end times in seconds, named start/end,
+// start_time/end_time or given as a two-element timestamp. Items without
+// usable times are skipped.
+func ParseSegments(items *[]interface{}) []Segment {
+	if items == nil {
+		return nil
+	}
+
+	var segments []Segment
+	for _, item := range *items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		text, _ := fields["text"].(string)
+		text = strings.Join(strings.Fields(text), " ")
+		if text == "" {
+			continue
+		}
+
+		start, okStart := seconds(fields, "start", "start_time", "begin")
+		end, okEnd := seconds(fields, "end", "end_time")
+		if timestamp, ok := fields["timestamp"].([]interface{}); ok && len(timestamp) == 2 {
+			start, okStart = toDuration(timestamp[0])
+			end, okEnd = toDuration(timestamp[1])
+		}
+		if !okStart || !okEnd || end < start {
+			continue
+		}
+		segments = append(segments, Segment{Start: start, End: end, Text: text})
+	}
+	return segments
+}
+
+func seconds(fields map[string]interface{}, keys ...string) (time.Duration, bool) {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			return toDuration(value)
+		}
+	}
+	return 0, false
+}
+
+func toDuration(value interface{}) (time.Duration, bool) {
+	var s float64
+	switch v := value.(type) {
+	case float64:
+		s = v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, false
+		}
+		s = f
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		s = f
+	default:
+		return 0, false
+	}
+	if s < 0 {
+		return 0, false
+	}
+	return time.Duration(s * float64(time.Second)), true
+}
+
+// Offset returns segments moved later by d
+func Offset(segments []Segment, d time.Duration) []Segment {
+	moved := make([]Segment, len(segments))
+	for i, segment := range segments {
+		moved[i] = Segment{Start: segment.Start + d, End: segment.End + d, Text: segment.Text}
+	}
+	return moved
+}
+
+// JoinText joins the text of segments with spaces, except around Chinese and
+// Japanese text, which is written without spaces
+func JoinText(segments []Segment) string {
+	parts := make([]string, len(segments))
+	for i, segment := range segments {
+		parts[i] = segment.Text
+	}
+	return text.Join(parts...)
+}
@@ -0,0 +1,117 @@
+package stt
+
+import (
+	"math"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/audio"
+)
+
+// frameLength is the resolution of the silence detection
+const frameLength = 20 * time.Millisecond
+
+// SplitOptions configure Split
+type SplitOptions struct {
+	// Window is the longest piece of audio
+	Window time.Duration
+	// MinSilence is the shortest pause a window may end in. With 0 the audio
+	// is cut into fixed windows.
+	MinSilence time.Duration
+	// Threshold is the RMS level in dBFS below which audio counts as silent
+	Threshold float64
+}
+
+// DefaultSplitOptions cut audio into windows of up to 30 seconds at pauses
+// of at least 300ms
+var DefaultSplitOptions = SplitOptions{
+	Window:     30 * time.Second,
+	MinSilence: 300 * time.Millisecond,
+	Threshold:  audio.SilenceThreshold,
+}
+
+// Window is a span of the audio that is transcribed in one request
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Split cuts pcm into windows of at most opts.Window. Each window ends in the
+// middle of the longest pause in its second half, or at the window length
+// when there is no pause. When splitting on silence, windows that are silent
+// throughout are left out.
+func Split(pcm *audio.PCM, opts SplitOptions) []Window {
+	if opts.Window <= 0 {
+		opts.Window = DefaultSplitOptions.Window
+	}
+
+	silent := silentFrames(pcm, opts.Threshold)
+	total := len(silent)
+	maxFrames := max(1, int(opts.Window/frameLength))
+	minSilence := int(opts.MinSilence / frameLength)
+
+	frameTime := func(frame int) time.Duration {
+		return min(time.Duration(frame)*frameLength, pcm.Duration())
+	}
+
+	var windows []Window
+	for start := 0; start < total; {
+		end := min(start+maxFrames, total)
+		if end < total && minSilence > 0 {
+			end = max(start+1, cutAtPause(silent, start+maxFrames/2, end, minSilence, end))
+		}
+
+		if minSilence == 0 || !allSilent(silent[start:end]) {
+			windows = append(windows, Window{Start: frameTime(start), End: frameTime(end)})
+		}
+		start = end
+	}
+	return windows
+}
+
+// cutAtPause returns the middle of the longest run of at least minRun silent
+// frames within [from, to), or fallback when there is none
+func cutAtPause(silent []bool, from, to, minRun, fallback int) int {
+	bestStart, bestLength := -1, 0
+	run := 0
+	for i := from; i <= to; i++ {
+		if i < to && silent[i] {
+			run++
+			continue
+		}
+		if run >= minRun && run >= bestLength {
+			bestStart, bestLength = i-run, run
+		}
+		run = 0
+	}
+	if bestStart < 0 {
+		return fallback
+	}
+	return bestStart + bestLength/2
+}
+
+// silentFrames reports for every frame of pcm whether its RMS level is below
+// threshold dBFS
+func silentFrames(pcm *audio.PCM, threshold float64) []bool {
+	samplesPerFrame := max(1, int(int64(pcm.SampleRate)*int64(frameLength)/int64(time.Second))) * pcm.Channels
+	limit := math.Pow(10, threshold/20) * math.MaxInt16
+
+	frames := make([]bool, 0, len(pcm.Samples)/samplesPerFrame+1)
+	for i := 0; i < len(pcm.Samples); i += samplesPerFrame {
+		frame := pcm.Samples[i:min(i+samplesPerFrame, len(pcm.Samples))]
+		var sum float64
+		for _, s := range frame {
+			sum += float64(s) * float64(s)
+		}
+		frames = append(frames, math.Sqrt(sum/float64(len(frame))) < limit)
+	}
+	return frames
+}
+
+func allSilent(frames []bool) bool {
+	for _, silent := range frames {
+		if !silent {
+			return false
+		}
+	}
+	return true
+}
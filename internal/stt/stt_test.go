@@ -0,0 +1,252 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/audio"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/mirako-ai/mirako-go/api"
+)
+
+// speech returns 8 kHz mono audio alternating between tone and silence
+func speech(parts ...time.Duration) *audio.PCM {
+	pcm := audio.NewPCM(8000, 1)
+	for i, d := range parts {
+		if i%2 == 1 {
+			pcm.AppendSilence(d)
+			continue
+		}
+		frames := int(d * 8000 / time.Second)
+		for f := 0; f < frames; f++ {
+			value := int16(8000)
+			if f%2 == 1 {
+				value = -8000
+			}
+			pcm.Samples = append(pcm.Samples, value)
+		}
+	}
+	return pcm
+}
+
+// fakeTranscriber answers with the duration of the audio it receives, or
+// with fixed segments for the whole file. Audio of failDuration fails.
+type fakeTranscriber struct {
+	mu           sync.Mutex
+	requests     int
+	segments     *[]interface{}
+	failDuration time.Duration
+}
+
+func (f *fakeTranscriber) SpeechToText(ctx context.Context, encoded string) (*api.STTApiResponseBody, error) {
+	f.mu.Lock()
+	f.requests++
+	f.mu.Unlock()
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	pcm, err := audio.DecodeWAVBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	if f.failDuration > 0 && pcm.Duration() == f.failDuration {
+		return nil, errors.New("request failed")
+	}
+	duration := pcm.Duration().Seconds()
+	return &api.STTApiResponseBody{Data: &api.STTOutput{
+		Text:          fmt.Sprintf("words for %.1fs", duration),
+		InputDuration: &duration,
+		Transcription: f.segments,
+	}}, nil
+}
+
+func wavInput(t *testing.T, pcm *audio.PCM) *media.Input {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := pcm.EncodeWAV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return &media.Input{Source: "audio.wav", Name: "audio.wav", ContentType: "audio/wav", Data: buf.Bytes()}
+}
+
+func TestParseSegments(t *testing.T) {
+	items := []interface{}{
+		map[string]interface{}{"start": 0.5, "end": 1.25, "text": " hello\nworld "},
+		map[string]interface{}{"start_time": "2", "end_time": "3", "text": "again"},
+		map[string]interface{}{"timestamp": []interface{}{4.0, 5.0}, "text": "third"},
+		map[string]interface{}{"text": "no times"},
+		"not an object",
+	}
+	segments := ParseSegments(&items)
+	want := []Segment{
+		{Start: 500 * time.Millisecond, End: 1250 * time.Millisecond, Text: "hello world"},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "again"},
+		{Start: 4 * time.Second, End: 5 * time.Second, Text: "third"},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("expected %d segments, got %+v", len(want), segments)
+	}
+	for i := range want {
+		if segments[i] != want[i] {
+			t.Errorf("segment %d: expected %+v, got %+v", i, want[i], segments[i])
+		}
+	}
+}
+
+func TestSplitOnSilence(t *testing.T) {
+	pcm := speech(4*time.Second, time.Second, 3*time.Second, 2*time.Second, 5*time.Second)
+	windows := Split(pcm, SplitOptions{Window: 6 * time.Second, MinSilence: 300 * time.Millisecond, Threshold: -40})
+
+	want := []Window{
+		{Start: 0, End: 4500 * time.Millisecond},
+		{Start: 4500 * time.Millisecond, End: 9 * time.Second},
+		{Start: 9 * time.Second, End: 15 * time.Second},
+	}
+	if len(windows) != len(want) {
+		t.Fatalf("expected %d windows, got %+v", len(want), windows)
+	}
+	for i := range want {
+		if windows[i] != want[i] {
+			t.Errorf("window %d: expected %+v, got %+v", i, want[i], windows[i])
+		}
+	}
+}
+
+func TestSplitFixedWindows(t *testing.T) {
+	pcm := speech(2500 * time.Millisecond)
+	windows := Split(pcm, SplitOptions{Window: time.Second})
+	if len(windows) != 3 || windows[2].End != 2500*time.Millisecond {
+		t.Fatalf("expected three fixed windows, got %+v", windows)
+	}
+}
+
+func TestSplitSkipsSilence(t *testing.T) {
+	pcm := speech(time.Second, 10*time.Second, time.Second)
+	for _, window := range Split(pcm, SplitOptions{Window: 2 * time.Second, MinSilence: 200 * time.Millisecond, Threshold: -40}) {
+		if window.Start > 2*time.Second && window.End < 10*time.Second {
+			t.Errorf("silent window %+v was not skipped", window)
+		}
+	}
+}
+
+func TestTranscribeUsesAPISegments(t *testing.T) {
+	items := []interface{}{map[string]interface{}{"start": 0.0, "end": 1.0, "text": "from the api"}}
+	transcriber := &fakeTranscriber{segments: &items}
+	result, err := Transcribe(context.Background(), transcriber, wavInput(t, speech(2*time.Second)), Options{})
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if result.Split || transcriber.requests != 1 || len(result.Segments) != 1 || result.Segments[0].Text != "from the api" {
+		t.Fatalf("expected the API segments, got %+v after %d requests", result, transcriber.requests)
+	}
+}
+
+func TestTranscribeSplitsWithoutTimestamps(t *testing.T) {
+	transcriber := &fakeTranscriber{}
+	pcm := speech(4*time.Second, time.Second, 3*time.Second)
+	var progress []int
+	result, err := Transcribe(context.Background(), transcriber, wavInput(t, pcm), Options{
+		Split:      SplitOptions{Window: 6 * time.Second, MinSilence: 300 * time.Millisecond, Threshold: -40},
+		Timestamps: true,
+		OnProgress: func(done, total int) { progress = append(progress, done) },
+	})
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if !result.Split || transcriber.requests != 3 || len(progress) != 2 {
+		t.Fatalf("expected the whole file and two windows to be sent, got %d requests and progress %v", transcriber.requests, progress)
+	}
+	if result.Text != "words for 4.5s words for 3.5s" {
+		t.Errorf("unexpected text %q", result.Text)
+	}
+	if result.Segments[1].Start != 4500*time.Millisecond || result.Segments[1].End != 8*time.Second {
+		t.Errorf("expected the second segment to be offset, got %+v", result.Segments[1])
+	}
+}
+
+func TestTranscribeLongAudioResumes(t *testing.T) {
+	pcm := speech(4*time.Second, time.Second, 3*time.Second, 2*time.Second, 5*time.Second)
+	in := wavInput(t, pcm)
+	opts := Options{
+		Split:         SplitOptions{Window: 6 * time.Second, MinSilence: 300 * time.Millisecond, Threshold: -40},
+		SplitOver:     10 * time.Second,
+		Concurrency:   2,
+		CheckpointDir: t.TempDir(),
+	}
+
+	// The last window fails, the others are kept
+	failing := &fakeTranscriber{failDuration: 6 * time.Second}
+	_, err := Transcribe(context.Background(), failing, in, opts)
+	var windowErr *WindowError
+	if !errors.As(err, &windowErr) || windowErr.Failed != 1 || windowErr.Total != 3 || windowErr.Checkpoint == "" {
+		t.Fatalf("expected one failed window, got %v", err)
+	}
+	if failing.requests != 3 {
+		t.Errorf("expected the long audio to be split without a whole-file request, got %d requests", failing.requests)
+	}
+
+	transcriber := &fakeTranscriber{}
+	result, err := Transcribe(context.Background(), transcriber, in, opts)
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if transcriber.requests != 1 || result.Resumed != 2 {
+		t.Errorf("expected only the failed window to be sent again, got %d requests and %d resumed", transcriber.requests, result.Resumed)
+	}
+	if result.Text != "words for 4.5s words for 4.5s words for 6.0s" {
+		t.Errorf("unexpected text %q", result.Text)
+	}
+	if result.Segments[2].Start != 9*time.Second {
+		t.Errorf("expected the segments in order with offsets, got %+v", result.Segments)
+	}
+	if _, err := os.Stat(windowErr.Checkpoint); !os.IsNotExist(err) {
+		t.Errorf("expected the checkpoint to be removed after success, got %v", err)
+	}
+}
+
+func TestWriteSubtitles(t *testing.T) {
+	result := &Result{
+		Text: "short",
+		Segments: []Segment{
+			{Start: 0, End: 1500 * time.Millisecond, Text: "short"},
+			{Start: time.Hour, End: time.Hour + 10*time.Second, Text: strings.Repeat("word ", 30)},
+		},
+	}
+
+	var srt bytes.Buffer
+	if err := Write(&srt, FormatSRT, result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(srt.String(), "1\n00:00:00,000 --> 00:00:01,500\nshort\n\n2\n01:00:00,000 --> ") {
+		t.Errorf("unexpected SRT:\n%s", srt.String())
+	}
+	if !strings.Contains(srt.String(), "\n3\n") || !strings.Contains(srt.String(), "--> 01:00:10,000\n") {
+		t.Errorf("expected the long segment to be split into cues ending at 01:00:10,000:\n%s", srt.String())
+	}
+
+	var vtt bytes.Buffer
+	if err := Write(&vtt, FormatVTT, result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(vtt.String(), "WEBVTT\n\n00:00:00.000 --> 00:00:01.500\nshort\n\n") {
+		t.Errorf("unexpected VTT:\n%s", vtt.String())
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	for path, want := range map[string]string{"a.SRT": FormatSRT, "a.vtt": FormatVTT, "out/a.json": FormatJSON, "a.txt": FormatText, "": FormatText} {
+		if got := FormatForPath(path); got != want {
+			t.Errorf("%q: expected %s, got %s", path, want, got)
+		}
+	}
+}
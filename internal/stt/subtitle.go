@@ -0,0 +1,181 @@
+package stt
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mirako-ai/mirako-cli/internal/media"
+)
+
+// MaxCueRunes is the longest subtitle cue, two lines of 42 characters
+const MaxCueRunes = 84
+
+// Cues splits segments longer than maxRunes characters into subtitle cues at
+// word boundaries, dividing the time of a segment by the length of its parts
+func Cues(segments []Segment, maxRunes int) []Segment {
+	var cues []Segment
+	for _, segment := range segments {
+		parts := splitCue(segment.Text, maxRunes)
+		total := utf8.RuneCountInString(strings.Join(parts, ""))
+		span := segment.End - segment.Start
+		start, done := segment.Start, 0
+		for i, part := range parts {
+			done += utf8.RuneCountInString(part)
+			end := segment.Start + time.Duration(int64(span)*int64(done)/int64(max(total, 1)))
+			if i == len(parts)-1 {
+				end = segment.End
+			}
+			cues = append(cues, Segment{Start: start, End: end, Text: part})
+			start = end
+		}
+	}
+	return cues
+}
+
+func splitCue(text string, maxRunes int) []string {
+	if utf8.RuneCountInString(text) <= maxRunes {
+		return []string{text}
+	}
+
+	// Words longer than a cue, such as Chinese sentences, are cut anywhere
+	var words []Segment
+	for _, word := range strings.Fields(text) {
+		runes := []rune(word)
+		for len(runes) > maxRunes {
+			words = append(words, Segment{Text: string(runes[:maxRunes])})
+			runes = runes[maxRunes:]
+		}
+		words = append(words, Segment{Text: string(runes)})
+	}
+
+	var parts []string
+	var current []Segment
+	for _, word := range words {
+		joined := JoinText(append(current, word))
+		if len(current) > 0 && utf8.RuneCountInString(joined) > maxRunes {
+			parts = append(parts, JoinText(current))
+			current = nil
+		}
+		current = append(current, word)
+	}
+	if len(current) > 0 {
+		parts = append(parts, JoinText(current))
+	}
+	return parts
+}
+
+// WriteSRT writes segments as SubRip subtitles
+func WriteSRT(w io.Writer, segments []Segment) error {
+	bw := bufio.NewWriter(w)
+	for i, segment := range segments {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1, formatTimestamp(segment.Start, ","), formatTimestamp(segment.End, ","), segment.Text)
+	}
+	return bw.Flush()
+}
+
+// WriteVTT writes segments as WebVTT subtitles
+func WriteVTT(w io.Writer, segments []Segment) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "WEBVTT\n\n")
+	for _, segment := range segments {
+		fmt.Fprintf(bw, "%s --> %s\n%s\n\n", formatTimestamp(segment.Start, "."), formatTimestamp(segment.End, "."), segment.Text)
+	}
+	return bw.Flush()
+}
+
+// formatTimestamp formats d as hh:mm:ss followed by sep and milliseconds
+func formatTimestamp(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// Transcript formats accepted by Write
+const (
+	FormatText = "text"
+	FormatSRT  = "srt"
+	FormatVTT  = "vtt"
+	FormatJSON = "json"
+)
+
+// Formats lists the transcript formats
+var Formats = []string{FormatText, FormatSRT, FormatVTT, FormatJSON}
+
+// FormatForPath returns the transcript format matching the extension of path,
+// or FormatText
+func FormatForPath(path string) string {
+	switch ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")); ext {
+	case FormatSRT, FormatVTT, FormatJSON:
+		return ext
+	}
+	return FormatText
+}
+
+// Transcript is the JSON form of a Result
+type Transcript struct {
+	Text     string              `json:"text"`
+	Duration float64             `json:"duration,omitempty"`
+	Segments []TranscriptSegment `json:"segments"`
+}
+
+// TranscriptSegment is a segment with times in seconds
+type TranscriptSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Transcript returns the JSON form of r
+func (r *Result) Transcript() Transcript {
+	transcript := Transcript{Text: r.Text, Duration: roundSeconds(r.Duration), Segments: []TranscriptSegment{}}
+	for _, segment := range r.Segments {
+		transcript.Segments = append(transcript.Segments, TranscriptSegment{
+			Start: roundSeconds(segment.Start),
+			End:   roundSeconds(segment.End),
+			Text:  segment.Text,
+		})
+	}
+	return transcript
+}
+
+// Write writes the transcript in the given format. Subtitles are split into
+// cues of at most MaxCueRunes characters.
+func Write(w io.Writer, format string, r *Result) error {
+	switch format {
+	case FormatSRT:
+		return WriteSRT(w, Cues(r.Segments, MaxCueRunes))
+	case FormatVTT:
+		return WriteVTT(w, Cues(r.Segments, MaxCueRunes))
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r.Transcript())
+	default:
+		_, err := fmt.Fprintln(w, r.Text)
+		return err
+	}
+}
+
+// WriteFile saves the transcript at path, creating its directory, or writes
+// it to stdout when path is media.Stdio
+func WriteFile(path, format string, r *Result) error {
+	w, err := media.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(w, format, r); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return w.Close()
+}
+
+func roundSeconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*1000) / 1000
+}
@@ -0,0 +1,281 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/audio"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/mirako-ai/mirako-cli/internal/parallel"
+)
+
+// DefaultSplitOver is the length from which WAV audio is split locally
+// instead of being sent in a single request
+var DefaultSplitOver = 5 * time.Minute
+
+// Options configure Transcribe
+type Options struct {
+	// Split configures how WAV audio is split into windows
+	Split SplitOptions
+	// SplitOver is the length from which WAV audio is split before it is
+	// sent; 0 uses DefaultSplitOver
+	SplitOver time.Duration
+	// Timestamps asks for segments with times. Without it a transcription of
+	// the whole file is used as is.
+	Timestamps bool
+	// Concurrency is the number of windows transcribed at once
+	Concurrency int
+	// CheckpointDir keeps the progress of split audio so that a failed
+	// transcription can be resumed; empty to not keep progress
+	CheckpointDir string
+	// Restart discards the progress kept for the audio
+	Restart bool
+	// OnProgress is called after each transcribed window; calls are serialized
+	OnProgress func(done, total int)
+}
+
+// Result is the transcription of an audio file
+type Result struct {
+	Text     string
+	Segments []Segment
+	Duration time.Duration
+	// Split is set when the audio was transcribed window by window
+	Split bool
+	// Resumed is the number of windows taken from an earlier run
+	Resumed int
+}
+
+// Transcribe converts the audio input to text with timestamps.
+//
+// WAV audio longer than opts.SplitOver is split at pauses and the windows are
+// transcribed concurrently. Shorter audio is sent whole; when timestamps are
+// wanted but the API returns none, WAV audio is then split as well, and other
+// formats become a single segment.
+func Transcribe(ctx context.Context, t Transcriber, in *media.Input, opts Options) (*Result, error) {
+	data := in.Data
+	isWAV := in.IsWAV()
+	splitOver := opts.SplitOver
+	if splitOver <= 0 {
+		splitOver = DefaultSplitOver
+	}
+
+	var pcm *audio.PCM
+	if isWAV {
+		var err error
+		if pcm, err = audio.DecodeWAVBytes(data); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", in.Label(), err)
+		}
+		if pcm.Duration() > splitOver {
+			return transcribeSplit(ctx, t, pcm, data, opts)
+		}
+	}
+
+	resp, err := t.SpeechToText(ctx, base64.StdEncoding.EncodeToString(data))
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return nil, fmt.Errorf("unexpected response from server")
+	}
+
+	result := &Result{
+		Text:     strings.TrimSpace(resp.Data.Text),
+		Segments: ParseSegments(resp.Data.Transcription),
+	}
+	if resp.Data.InputDuration != nil {
+		result.Duration = time.Duration(*resp.Data.InputDuration * float64(time.Second))
+	}
+	if !opts.Timestamps || len(result.Segments) > 0 || result.Text == "" {
+		return result, nil
+	}
+
+	if !isWAV {
+		if result.Duration == 0 && in.ContentType == "audio/mpeg" {
+			if info, err := audio.ProbeReader(bytes.NewReader(data), audio.FormatMP3); err == nil {
+				result.Duration = info.Duration
+			}
+		}
+		result.Segments = []Segment{{Start: 0, End: result.Duration, Text: strings.Join(strings.Fields(result.Text), " ")}}
+		return result, nil
+	}
+	return transcribeSplit(ctx, t, pcm, data, opts)
+}
+
+// transcribeSplit transcribes pcm window by window, resuming from the
+// checkpoint of an earlier run when there is one
+func transcribeSplit(ctx context.Context, t Transcriber, pcm *audio.PCM, data []byte, opts Options) (*Result, error) {
+	var checkpoint *Checkpoint
+	if opts.CheckpointDir != "" {
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		path := filepath.Join(opts.CheckpointDir, hash[:32]+".json")
+		if opts.Restart {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to discard transcription progress: %w", err)
+			}
+		}
+		var err error
+		if checkpoint, err = OpenCheckpoint(path, hash); err != nil {
+			return nil, err
+		}
+	}
+
+	windows := Split(pcm, opts.Split)
+	resumed := 0
+	if checkpoint != nil {
+		for _, window := range windows {
+			if _, ok := checkpoint.Lookup(window); ok {
+				resumed++
+			}
+		}
+	}
+
+	segments, err := TranscribeWindows(ctx, t, pcm, windows, WindowOptions{
+		Concurrency: opts.Concurrency,
+		Checkpoint:  checkpoint,
+		OnProgress:  opts.OnProgress,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint != nil {
+		checkpoint.Remove()
+	}
+	return &Result{Text: JoinText(segments), Segments: segments, Duration: pcm.Duration(), Split: true, Resumed: resumed}, nil
+}
+
+// WindowOptions configure TranscribeWindows
+type WindowOptions struct {
+	// Concurrency is the number of windows transcribed at once
+	Concurrency int
+	// Checkpoint, if set, provides windows transcribed earlier and records
+	// newly transcribed ones
+	Checkpoint *Checkpoint
+	// OnProgress is called after each window; calls are serialized
+	OnProgress func(done, total int)
+}
+
+// WindowError reports windows that could not be transcribed
+type WindowError struct {
+	Failed     int
+	Total      int
+	Err        error // error of the first failed window
+	Checkpoint string
+}
+
+func (e *WindowError) Error() string {
+	msg := fmt.Sprintf("%d of %d segments failed: %v", e.Failed, e.Total, e.Err)
+	if e.Checkpoint != "" {
+		msg += "; the other segments were saved and are skipped when the command is run again"
+	}
+	return msg
+}
+
+func (e *WindowError) Unwrap() error {
+	return e.Err
+}
+
+// TranscribeWindows transcribes the windows of pcm concurrently and returns
+// the segments in order, with offsets from the start of pcm. A failed window
+// does not stop the others, so that they are recorded in the checkpoint.
+func TranscribeWindows(ctx context.Context, t Transcriber, pcm *audio.PCM, windows []Window, opts WindowOptions) ([]Segment, error) {
+	results := make([][]Segment, len(windows))
+	errs := make([]error, len(windows))
+	var mu sync.Mutex
+	done := 0
+
+	parallel.ForEach(ctx, len(windows), opts.Concurrency, func(ctx context.Context, i int) error {
+		window := windows[i]
+		var segments []Segment
+		var err error
+		cached := false
+		if opts.Checkpoint != nil {
+			segments, cached = opts.Checkpoint.Lookup(window)
+		}
+		if !cached {
+			if err = ctx.Err(); err == nil {
+				segments, err = transcribeWindow(ctx, t, pcm, window)
+			}
+			if err == nil && opts.Checkpoint != nil {
+				err = opts.Checkpoint.Store(window, segments)
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs[i] = fmt.Errorf("segment %d (%s-%s): %w", i+1, formatOffset(window.Start), formatOffset(window.End), err)
+			return nil
+		}
+		results[i] = segments
+		done++
+		if opts.OnProgress != nil {
+			opts.OnProgress(done, len(windows))
+		}
+		return nil
+	})
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var windowErr *WindowError
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if windowErr == nil {
+			windowErr = &WindowError{Total: len(windows), Err: err}
+			if opts.Checkpoint != nil {
+				windowErr.Checkpoint = opts.Checkpoint.Path()
+			}
+		}
+		windowErr.Failed++
+	}
+	if windowErr != nil {
+		return nil, windowErr
+	}
+
+	var segments []Segment
+	for _, windowSegments := range results {
+		segments = append(segments, windowSegments...)
+	}
+	return segments, nil
+}
+
+func transcribeWindow(ctx context.Context, t Transcriber, pcm *audio.PCM, window Window) ([]Segment, error) {
+	var buf bytes.Buffer
+	if err := pcm.Slice(window.Start, window.End).EncodeWAV(&buf); err != nil {
+		return nil, err
+	}
+	resp, err := t.SpeechToText(ctx, base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil {
+		return nil, fmt.Errorf("unexpected response from server")
+	}
+
+	if segments := ParseSegments(resp.Data.Transcription); len(segments) > 0 {
+		return Offset(segments, window.Start), nil
+	}
+	text := strings.Join(strings.Fields(resp.Data.Text), " ")
+	if text == "" {
+		return []Segment{}, nil
+	}
+	return []Segment{{Start: window.Start, End: window.End, Text: text}}, nil
+}
+
+// formatOffset formats d as m:ss
+func formatOffset(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,37 @@
+// Package text joins pieces of text, such as speech chunks or transcribed
+// segments, the way the language they are written in expects
+package text
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// cjkPunctuation are the Chinese and Japanese punctuation marks, which are
+// written without spaces around them
+const cjkPunctuation = "。！？；，、：」』》）…"
+
+// Join joins pieces of text with spaces, except around Chinese and Japanese
+// text, which is written without spaces. Empty pieces are skipped.
+func Join(parts ...string) string {
+	var b strings.Builder
+	last := rune(0)
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(part)
+		if b.Len() > 0 && !IsCJK(last) && !IsCJK(first) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(part)
+		last, _ = utf8.DecodeLastRuneInString(part)
+	}
+	return b.String()
+}
+
+// IsCJK reports whether r is a Chinese or Japanese character or punctuation mark
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || strings.ContainsRune(cjkPunctuation, r)
+}
@@ -0,0 +1,21 @@
+package text
+
+import "testing"
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"Hello", "world."}, "Hello world."},
+		{[]string{"你好。", "世界"}, "你好。世界"},
+		{[]string{"こんにちは", "Mirako"}, "こんにちはMirako"},
+		{[]string{"", "Hello", "", "again"}, "Hello again"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := Join(tt.parts...); got != tt.want {
+			t.Errorf("Join(%q) = %q, want %q", tt.parts, got, tt.want)
+		}
+	}
+}
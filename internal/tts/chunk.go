@@ -0,0 +1,172 @@
+package tts
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mirako-ai/mirako-cli/internal/text"
+)
+
+// MaxChunkRunes is the longest text the API accepts in one request
+const MaxChunkRunes = 10000
+
+// Chunk is the text of one TTS request, or a pause when Text is empty
+type Chunk struct {
+	Text        string
+	Voice       string
+	Chinese     string
+	Temperature *float32
+	Pause       time.Duration
+}
+
+// ChunkOptions configure Split
+type ChunkOptions struct {
+	// MaxRunes is the longest chunk in characters
+	MaxRunes int
+	// ParagraphPause is inserted at blank lines
+	ParagraphPause time.Duration
+}
+
+var paragraphBreak = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+// sentence terminators, and characters such as closing quotes that belong to
+// the sentence they follow
+const (
+	terminators     = ".!?;…。！？；"
+	cjkTerminators  = "。！？；…"
+	sentenceClosers = "\"'”’)]）」』》"
+	clauseBreaks    = ",，、:：—"
+)
+
+// Split packs the text of segments into chunks of whole sentences of at most
+// opts.MaxRunes characters. Segments and paragraphs always start a new chunk,
+// and pauses become chunks of their own.
+func Split(segments []Segment, opts ChunkOptions) []Chunk {
+	maxRunes := opts.MaxRunes
+	if maxRunes <= 0 || maxRunes > MaxChunkRunes {
+		maxRunes = MaxChunkRunes
+	}
+
+	var chunks []Chunk
+	for _, segment := range segments {
+		if segment.Text == "" {
+			if segment.Pause > 0 {
+				chunks = append(chunks, Chunk{Pause: segment.Pause})
+			}
+			continue
+		}
+
+		for i, paragraph := range paragraphBreak.Split(segment.Text, -1) {
+			sentences := SplitSentences(paragraph)
+			if len(sentences) == 0 {
+				continue
+			}
+			if i > 0 && opts.ParagraphPause > 0 && len(chunks) > 0 {
+				chunks = append(chunks, Chunk{Pause: opts.ParagraphPause})
+			}
+			for _, text := range pack(sentences, maxRunes) {
+				chunks = append(chunks, Chunk{
+					Text:        text,
+					Voice:       segment.Voice,
+					Chinese:     segment.Chinese,
+					Temperature: segment.Temperature,
+				})
+			}
+		}
+	}
+	return chunks
+}
+
+// SplitSentences splits text after sentence terminators. Line breaks inside
+// the text are read as spaces.
+func SplitSentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+	flush := func() {
+		if s := strings.Join(strings.Fields(current.String()), " "); s != "" {
+			sentences = append(sentences, s)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		current.WriteRune(r)
+		if !strings.ContainsRune(terminators, r) {
+			continue
+		}
+
+		// Keep runs like "?!" or "..." and closing quotes with the sentence
+		cjk := strings.ContainsRune(cjkTerminators, r)
+		for i+1 < len(runes) && (strings.ContainsRune(terminators, runes[i+1]) || strings.ContainsRune(sentenceClosers, runes[i+1])) {
+			i++
+			cjk = cjk || strings.ContainsRune(cjkTerminators, runes[i])
+			current.WriteRune(runes[i])
+		}
+		// Western terminators only end a sentence before whitespace, so that
+		// numbers such as "3.14" stay intact
+		if cjk || i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+			flush()
+		}
+	}
+	flush()
+	return sentences
+}
+
+// pack joins sentences into chunks of at most maxRunes, splitting sentences
+// that are longer on their own
+func pack(sentences []string, maxRunes int) []string {
+	var chunks []string
+	current := ""
+	for _, sentence := range sentences {
+		for _, part := range splitLong(sentence, maxRunes) {
+			joined := text.Join(current, part)
+			if current != "" && utf8.RuneCountInString(joined) > maxRunes {
+				chunks = append(chunks, current)
+				joined = part
+			}
+			current = joined
+		}
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// splitLong splits s at clause breaks or spaces, or anywhere as a last
+// resort, into parts of at most maxRunes
+func splitLong(s string, maxRunes int) []string {
+	var parts []string
+	runes := []rune(s)
+	for len(runes) > maxRunes {
+		cut := -1
+		for i := maxRunes - 1; i > maxRunes/2; i-- {
+			if strings.ContainsRune(clauseBreaks, runes[i]) {
+				cut = i + 1
+				break
+			}
+		}
+		if cut < 0 {
+			for i := maxRunes; i > maxRunes/2; i-- {
+				if unicode.IsSpace(runes[i]) {
+					cut = i
+					break
+				}
+			}
+		}
+		if cut < 0 {
+			cut = maxRunes
+		}
+		parts = append(parts, strings.TrimSpace(string(runes[:cut])))
+		runes = []rune(strings.TrimSpace(string(runes[cut:])))
+	}
+	if len(runes) > 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
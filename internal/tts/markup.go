@@ -0,0 +1,165 @@
+// Package tts turns long or marked-up text into a single speech track by
+// splitting it into API-sized chunks, synthesizing them in parallel and
+// stitching the audio back together
+package tts
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chinese language variants accepted by the API
+const (
+	ChineseMandarin = "mandarin"
+	ChineseYue      = "yue"
+)
+
+// DefaultBreak is the pause of a <break/> tag without a time
+var DefaultBreak = 500 * time.Millisecond
+
+// Segment is a run of text spoken with the same settings, or a pause
+type Segment struct {
+	Text        string
+	Voice       string   // voice profile ID, empty for the default voice
+	Chinese     string   // ChineseMandarin, ChineseYue or empty for the default
+	Temperature *float32 // nil for the default temperature
+	Pause       time.Duration
+}
+
+var (
+	markupTag       = regexp.MustCompile(`(?i)<\s*(/?)\s*(break|voice|lang)\b([^<>]*?)(/?)\s*>`)
+	markupAttribute = regexp.MustCompile(`([\w:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	markupEntities  = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&amp;", "&")
+)
+
+// ParseMarkup splits text into segments according to a small SSML-like markup:
+//
+//	<break time="800ms"/>                pause, 500ms without a time
+//	<voice id="PROFILE_ID">...</voice>   speak with another voice profile
+//	<voice temperature="0.6">...</voice> change the temperature
+//	<lang chinese="yue">...</lang>       Mandarin ("mandarin") or Cantonese ("yue")
+//
+// xml:lang="zh-CN" and xml:lang="zh-HK" are accepted in place of chinese.
+// Tags may be nested. Other text in angle brackets is read as is; &lt; and
+// &gt; can be used for literal brackets next to tag names.
+func ParseMarkup(text string) ([]Segment, error) {
+	type openTag struct {
+		name  string
+		state Segment
+	}
+	stack := []openTag{{}}
+	var segments []Segment
+
+	addText := func(s string) {
+		if strings.TrimSpace(s) == "" {
+			return
+		}
+		segment := stack[len(stack)-1].state
+		segment.Text = markupEntities.Replace(s)
+		segments = append(segments, segment)
+	}
+
+	pos := 0
+	for _, m := range markupTag.FindAllStringSubmatchIndex(text, -1) {
+		addText(text[pos:m[0]])
+		pos = m[1]
+
+		closing := m[3] > m[2]
+		name := strings.ToLower(text[m[4]:m[5]])
+		attributes := parseAttributes(text[m[6]:m[7]])
+		selfClosing := m[9] > m[8]
+		line := strings.Count(text[:m[0]], "\n") + 1
+
+		switch {
+		case name == "break":
+			if closing {
+				continue // </break> has no meaning, like in SSML
+			}
+			pause := DefaultBreak
+			if value, ok := attributes["time"]; ok {
+				d, err := time.ParseDuration(value)
+				if err != nil || d < 0 {
+					return nil, fmt.Errorf("line %d: invalid break time %q, use e.g. 500ms or 1.5s", line, value)
+				}
+				pause = d
+			}
+			segments = append(segments, Segment{Pause: pause})
+		case closing:
+			if top := stack[len(stack)-1]; len(stack) == 1 || top.name != name {
+				return nil, fmt.Errorf("line %d: unexpected </%s>", line, name)
+			}
+			stack = stack[:len(stack)-1]
+		case selfClosing:
+			return nil, fmt.Errorf("line %d: <%s/> must enclose text, e.g. <%s ...>text</%s>", line, name, name, name)
+		default:
+			state := stack[len(stack)-1].state
+			if err := applyAttributes(&state, name, attributes); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			stack = append(stack, openTag{name: name, state: state})
+		}
+	}
+	addText(text[pos:])
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("<%s> is not closed", stack[len(stack)-1].name)
+	}
+	return segments, nil
+}
+
+func parseAttributes(s string) map[string]string {
+	attributes := map[string]string{}
+	for _, m := range markupAttribute.FindAllStringSubmatch(s, -1) {
+		value := m[2]
+		if value == "" {
+			value = m[3]
+		}
+		attributes[strings.ToLower(m[1])] = strings.TrimSpace(value)
+	}
+	return attributes
+}
+
+func applyAttributes(state *Segment, name string, attributes map[string]string) error {
+	switch name {
+	case "voice":
+		if id, ok := attributes["id"]; ok {
+			if id == "" {
+				return fmt.Errorf("<voice> needs a voice profile id")
+			}
+			state.Voice = id
+		}
+		if value, ok := attributes["temperature"]; ok {
+			t, err := strconv.ParseFloat(value, 32)
+			if err != nil || t < 0 || t > 1 {
+				return fmt.Errorf("invalid temperature %q, use a number between 0.0 and 1.0", value)
+			}
+			temperature := float32(t)
+			state.Temperature = &temperature
+		}
+	case "lang":
+		value, ok := attributes["chinese"]
+		if !ok {
+			value, ok = attributes["xml:lang"]
+		}
+		chinese, valid := parseChinese(value)
+		if !ok || !valid {
+			return fmt.Errorf("invalid <lang> tag, use chinese=\"mandarin\" or chinese=\"yue\"")
+		}
+		state.Chinese = chinese
+	}
+	return nil
+}
+
+// parseChinese maps a variant name or language code to the API variant
+func parseChinese(value string) (string, bool) {
+	switch strings.ToLower(value) {
+	case ChineseMandarin, "zh", "zh-cn", "cmn":
+		return ChineseMandarin, true
+	case ChineseYue, "cantonese", "zh-hk", "yue-hk":
+		return ChineseYue, true
+	}
+	return "", false
+}
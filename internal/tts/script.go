@@ -0,0 +1,253 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/text"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLinePause is the pause between script lines when the script sets none
+var DefaultLinePause = 400 * time.Millisecond
+
+// Speaker is a character of a script and the voice it speaks with
+type Speaker struct {
+	Voice       string   `yaml:"voice" json:"voice"`
+	Chinese     string   `yaml:"chinese" json:"chinese,omitempty"`
+	Temperature *float32 `yaml:"temperature" json:"temperature,omitempty"`
+}
+
+// UnmarshalYAML accepts either a voice as a plain string or a mapping
+func (s *Speaker) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = Speaker{Voice: value.Value}
+		return nil
+	}
+	type plain Speaker
+	return value.Decode((*plain)(s))
+}
+
+// ScriptLine is a line spoken by a speaker, or a pause when it has no text
+type ScriptLine struct {
+	Speaker string `yaml:"speaker"`
+	Text    string `yaml:"text"`
+	// Pause follows the line in place of the script pause; on its own it
+	// replaces the pause between the surrounding lines
+	Pause *time.Duration `yaml:"pause"`
+}
+
+// Script is a dialogue rendered into a single audio track
+type Script struct {
+	Speakers map[string]Speaker `yaml:"speakers"`
+	Pause    *time.Duration     `yaml:"pause"`
+	Lines    []ScriptLine       `yaml:"lines"`
+}
+
+// LoadScript reads and validates a YAML script
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+
+	var script Script
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse script: %w", err)
+	}
+	if err := script.Validate(); err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+// Validate checks that every line names a known speaker and that every
+// speaker has a voice
+func (s *Script) Validate() error {
+	if len(s.Speakers) == 0 {
+		return fmt.Errorf("script has no speakers")
+	}
+	for _, name := range s.SpeakerNames() {
+		speaker := s.Speakers[name]
+		if strings.TrimSpace(speaker.Voice) == "" {
+			return fmt.Errorf("speaker %q has no voice", name)
+		}
+		if speaker.Chinese != "" && speaker.Chinese != ChineseMandarin && speaker.Chinese != ChineseYue {
+			return fmt.Errorf("speaker %q: invalid chinese language variant %q, use mandarin or yue", name, speaker.Chinese)
+		}
+		if t := speaker.Temperature; t != nil && (*t < 0 || *t > 1) {
+			return fmt.Errorf("speaker %q: temperature must be between 0.0 and 1.0", name)
+		}
+	}
+	if s.Pause != nil && *s.Pause < 0 {
+		return fmt.Errorf("pause cannot be negative")
+	}
+
+	spoken := 0
+	for i, line := range s.Lines {
+		if line.Pause != nil && *line.Pause < 0 {
+			return fmt.Errorf("line %d: pause cannot be negative", i+1)
+		}
+		if strings.TrimSpace(line.Text) == "" {
+			if line.Speaker != "" {
+				return fmt.Errorf("line %d: speaker %q has no text", i+1, line.Speaker)
+			}
+			if line.Pause == nil {
+				return fmt.Errorf("line %d: needs a speaker and text, or a pause", i+1)
+			}
+			continue
+		}
+		if line.Speaker == "" {
+			return fmt.Errorf("line %d: no speaker", i+1)
+		}
+		if _, ok := s.Speakers[line.Speaker]; !ok {
+			return fmt.Errorf("line %d: unknown speaker %q", i+1, line.Speaker)
+		}
+		if _, err := ParseMarkup(line.Text); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+		spoken++
+	}
+	if spoken == 0 {
+		return fmt.Errorf("script has no lines")
+	}
+	return nil
+}
+
+// SpeakerNames returns the names of the speakers in sorted order
+func (s *Script) SpeakerNames() []string {
+	names := make([]string, 0, len(s.Speakers))
+	for name := range s.Speakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RenderOptions configure Render
+type RenderOptions struct {
+	// MaxRunes is the longest request in characters
+	MaxRunes int
+	// Gap is the silence between the chunks of a long line
+	Gap time.Duration
+	// Concurrency is the number of requests sent at once
+	Concurrency int
+	// OnProgress is called after each synthesized chunk
+	OnProgress func(done, total int)
+}
+
+// LineTiming locates a script line in the rendered audio
+type LineTiming struct {
+	Line    int // 1-based index into Script.Lines
+	Speaker string
+	Voice   string
+	Text    string
+	Start   time.Duration
+	End     time.Duration
+}
+
+// RenderResult is the rendered audio of a script
+type RenderResult struct {
+	Result
+	Lines []LineTiming
+}
+
+// Render synthesizes all lines of the script into one track. voices maps
+// speaker names to voice profile IDs and overrides the voices in the script.
+func Render(ctx context.Context, s Synthesizer, script *Script, voices map[string]string, opts RenderOptions) (*RenderResult, error) {
+	linePause := DefaultLinePause
+	if script.Pause != nil {
+		linePause = *script.Pause
+	}
+
+	var chunks []Chunk
+	var owners []int // script line of each text chunk
+	lineVoices := make([]string, len(script.Lines))
+	lineTexts := make([]string, len(script.Lines))
+	var pending *time.Duration
+	for i, line := range script.Lines {
+		if strings.TrimSpace(line.Text) == "" {
+			pending = line.Pause
+			continue
+		}
+
+		speaker := script.Speakers[line.Speaker]
+		voice := speaker.Voice
+		if id, ok := voices[line.Speaker]; ok {
+			voice = id
+		}
+
+		segments, err := ParseMarkup(line.Text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		spokenText := ""
+		for k := range segments {
+			if words := strings.Join(strings.Fields(segments[k].Text), " "); words != "" {
+				spokenText = text.Join(spokenText, words)
+			}
+			if segments[k].Voice == "" {
+				segments[k].Voice = voice
+			}
+			if segments[k].Chinese == "" {
+				segments[k].Chinese = speaker.Chinese
+			}
+			if segments[k].Temperature == nil {
+				segments[k].Temperature = speaker.Temperature
+			}
+		}
+		lineVoices[i] = voice
+		lineTexts[i] = spokenText
+
+		if pending != nil {
+			// A zero pause chunk keeps the gap from being inserted
+			chunks = append(chunks, Chunk{Pause: *pending})
+		}
+		for _, chunk := range Split(segments, ChunkOptions{MaxRunes: opts.MaxRunes, ParagraphPause: DefaultBreak}) {
+			chunks = append(chunks, chunk)
+			if chunk.Text != "" {
+				owners = append(owners, i)
+			}
+		}
+
+		pause := linePause
+		if line.Pause != nil {
+			pause = *line.Pause
+		}
+		pending = &pause
+	}
+	if last := script.Lines[len(script.Lines)-1]; strings.TrimSpace(last.Text) == "" && last.Pause != nil {
+		chunks = append(chunks, Chunk{Pause: *last.Pause})
+	}
+
+	result, err := Synthesize(ctx, s, chunks, Options{
+		Gap:         opts.Gap,
+		Concurrency: opts.Concurrency,
+		OnProgress:  opts.OnProgress,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &RenderResult{Result: *result}
+	for k, timing := range result.Timings {
+		i := owners[k]
+		if n := len(rendered.Lines); n > 0 && rendered.Lines[n-1].Line == i+1 {
+			rendered.Lines[n-1].End = timing.End
+			continue
+		}
+		rendered.Lines = append(rendered.Lines, LineTiming{
+			Line:    i + 1,
+			Speaker: script.Lines[i].Speaker,
+			Voice:   lineVoices[i],
+			Text:    lineTexts[i],
+			Start:   timing.Start,
+			End:     timing.End,
+		})
+	}
+	return rendered, nil
+}
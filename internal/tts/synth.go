@@ -0,0 +1,157 @@
+package tts
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/audio"
+	"github.com/mirako-ai/mirako-cli/internal/parallel"
+	"github.com/mirako-ai/mirako-go/api"
+)
+
+// Synthesizer converts text to speech. It is implemented by *client.Client.
+type Synthesizer interface {
+	TextToSpeech(ctx context.Context, text, voiceProfileID, returnType string, chineseLanguage *api.TTSApiRequestBodyChineseLanguage, opts *api.TTSParams) (*api.TTSApiResponseBody, error)
+}
+
+// Options configure Synthesize
+type Options struct {
+	// Voice is the voice profile for chunks without a voice of their own
+	Voice string
+	// Chinese is the variant for chunks without one, empty for the API default
+	Chinese string
+	// Params are sent with every request; a chunk temperature overrides them
+	Params *api.TTSParams
+	// Gap is the silence between two chunks that have no pause between them
+	Gap time.Duration
+	// Concurrency is the number of requests sent at once
+	Concurrency int
+	// OnProgress is called after each synthesized chunk; calls are serialized
+	OnProgress func(done, total int)
+}
+
+// Timing locates a chunk in the synthesized audio
+type Timing struct {
+	Text  string
+	Voice string
+	Start time.Duration
+	End   time.Duration
+}
+
+// Result is the stitched audio of all chunks
+type Result struct {
+	Audio   *audio.PCM
+	Timings []Timing
+}
+
+// Synthesize converts the text chunks in parallel and joins the audio in
+// order. The output uses the sample format of the first chunk; chunks in
+// other formats are converted.
+func Synthesize(ctx context.Context, s Synthesizer, chunks []Chunk, opts Options) (*Result, error) {
+	var pending []int
+	for i, chunk := range chunks {
+		if chunk.Text != "" {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, fmt.Errorf("no text to convert to speech")
+	}
+
+	decoded := make([]*audio.PCM, len(chunks))
+	var mu sync.Mutex
+	done := 0
+
+	err := parallel.ForEach(ctx, len(pending), opts.Concurrency, func(ctx context.Context, k int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		i := pending[k]
+		pcm, err := synthesizeChunk(ctx, s, chunks[i], opts)
+		if err != nil {
+			return fmt.Errorf("chunk %d of %d: %w", k+1, len(pending), err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		decoded[i] = pcm
+		done++
+		if opts.OnProgress != nil {
+			opts.OnProgress(done, len(pending))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	first := decoded[pending[0]]
+	result := &Result{Audio: audio.NewPCM(first.SampleRate, first.Channels)}
+	previousText := false
+	for i, chunk := range chunks {
+		if chunk.Text == "" {
+			result.Audio.AppendSilence(chunk.Pause)
+			previousText = false
+			continue
+		}
+		if previousText {
+			result.Audio.AppendSilence(opts.Gap)
+		}
+		start := result.Audio.Duration()
+		result.Audio.Append(decoded[i])
+		voice := chunk.Voice
+		if voice == "" {
+			voice = opts.Voice
+		}
+		result.Timings = append(result.Timings, Timing{Text: chunk.Text, Voice: voice, Start: start, End: result.Audio.Duration()})
+		previousText = true
+	}
+	return result, nil
+}
+
+func synthesizeChunk(ctx context.Context, s Synthesizer, chunk Chunk, opts Options) (*audio.PCM, error) {
+	voice := chunk.Voice
+	if voice == "" {
+		voice = opts.Voice
+	}
+
+	var chineseLanguage *api.TTSApiRequestBodyChineseLanguage
+	chinese := chunk.Chinese
+	if chinese == "" {
+		chinese = opts.Chinese
+	}
+	if chinese != "" {
+		value := api.TTSApiRequestBodyChineseLanguage(chinese)
+		chineseLanguage = &value
+	}
+
+	params := opts.Params
+	if chunk.Temperature != nil {
+		p := api.TTSParams{}
+		if params != nil {
+			p = *params
+		}
+		p.Temperature = chunk.Temperature
+		params = &p
+	}
+
+	resp, err := s.TextToSpeech(ctx, chunk.Text, voice, "b64_audio_str", chineseLanguage, params)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Data == nil || resp.Data.B64AudioStr == nil {
+		return nil, fmt.Errorf("no audio data received from server")
+	}
+	data, err := base64.StdEncoding.DecodeString(*resp.Data.B64AudioStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio data: %w", err)
+	}
+	pcm, err := audio.DecodeWAVBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio data: %w", err)
+	}
+	return pcm, nil
+}
@@ -0,0 +1,273 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/audio"
+	"github.com/mirako-ai/mirako-go/api"
+)
+
+func TestParseMarkup(t *testing.T) {
+	segments, err := ParseMarkup(`Hello <break time="1s"/> <voice id="v2" temperature="0.5">there <lang chinese="yue">你好</lang></voice> a &lt;b&gt;`)
+	if err != nil {
+		t.Fatalf("ParseMarkup failed: %v", err)
+	}
+	if len(segments) != 5 {
+		t.Fatalf("expected 5 segments, got %d: %+v", len(segments), segments)
+	}
+	if segments[0].Text != "Hello " || segments[0].Voice != "" {
+		t.Errorf("unexpected first segment: %+v", segments[0])
+	}
+	if segments[1].Pause != time.Second {
+		t.Errorf("expected a 1s pause, got %+v", segments[1])
+	}
+	if segments[2].Voice != "v2" || segments[2].Temperature == nil || *segments[2].Temperature != 0.5 {
+		t.Errorf("unexpected voice segment: %+v", segments[2])
+	}
+	if segments[3].Text != "你好" || segments[3].Voice != "v2" || segments[3].Chinese != ChineseYue {
+		t.Errorf("unexpected nested segment: %+v", segments[3])
+	}
+	if segments[4].Text != " a <b>" || segments[4].Voice != "" {
+		t.Errorf("unexpected last segment: %+v", segments[4])
+	}
+}
+
+func TestParseMarkupErrors(t *testing.T) {
+	tests := map[string]string{
+		"unclosed":     `<voice id="v">text`,
+		"mismatched":   "<voice id=\"v\">\n<lang chinese=\"yue\">text</voice></lang>",
+		"bad break":    `<break time="soon"/>`,
+		"bad language": `<lang chinese="klingon">text</lang>`,
+		"temperature":  `<voice temperature="3">text</voice>`,
+		"self closing": `<voice id="v"/>`,
+	}
+	for name, text := range tests {
+		if _, err := ParseMarkup(text); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	_, err := ParseMarkup("<voice id=\"v\">\n<lang chinese=\"yue\">text</voice></lang>")
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected the error to name line 2, got %v", err)
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	got := SplitSentences("Pi is 3.14, roughly.  Really?! \"Yes.\" 你好。再见！ok")
+	want := []string{"Pi is 3.14, roughly.", "Really?!", "\"Yes.\"", "你好。", "再见！", "ok"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	text := "One two. Three four.\n\nFive six seven eight nine ten eleven twelve."
+	chunks := Split([]Segment{{Text: text}}, ChunkOptions{MaxRunes: 20, ParagraphPause: time.Second})
+
+	var got []string
+	for _, chunk := range chunks {
+		if chunk.Text == "" {
+			got = append(got, chunk.Pause.String())
+			continue
+		}
+		if n := len([]rune(chunk.Text)); n > 20 {
+			t.Errorf("chunk %q is %d characters long", chunk.Text, n)
+		}
+		got = append(got, chunk.Text)
+	}
+	want := []string{"One two. Three four.", "1s", "Five six seven eight", "nine ten eleven", "twelve."}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestSplitJoinsChineseWithoutSpaces(t *testing.T) {
+	chunks := Split([]Segment{{Text: "你好。再见。"}}, ChunkOptions{MaxRunes: 100})
+	if len(chunks) != 1 || chunks[0].Text != "你好。再见。" {
+		t.Fatalf("unexpected chunks: %+v", chunks)
+	}
+}
+
+// fakeSynthesizer returns 100ms of audio per request, at 8 kHz for "slow" text
+type fakeSynthesizer struct {
+	mu       sync.Mutex
+	requests []string
+	params   []*api.TTSParams
+	fail     string
+}
+
+func (f *fakeSynthesizer) TextToSpeech(ctx context.Context, text, voiceProfileID, returnType string, chineseLanguage *api.TTSApiRequestBodyChineseLanguage, opts *api.TTSParams) (*api.TTSApiResponseBody, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, voiceProfileID+":"+text)
+	f.params = append(f.params, opts)
+	f.mu.Unlock()
+
+	if text == f.fail {
+		return nil, errors.New("boom")
+	}
+	rate := 16000
+	if strings.Contains(text, "slow") {
+		rate = 8000
+	}
+	pcm := audio.NewPCM(rate, 1)
+	pcm.AppendSilence(100 * time.Millisecond)
+	var buf bytes.Buffer
+	if err := pcm.EncodeWAV(&buf); err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	return &api.TTSApiResponseBody{Data: &api.TTSOutput{B64AudioStr: &encoded}}, nil
+}
+
+func TestSynthesize(t *testing.T) {
+	temperature := float32(0.3)
+	chunks := []Chunk{
+		{Text: "one"},
+		{Text: "slow two", Voice: "other", Temperature: &temperature},
+		{Pause: 500 * time.Millisecond},
+		{Text: "three"},
+	}
+	synth := &fakeSynthesizer{}
+	var progress []int
+	result, err := Synthesize(context.Background(), synth, chunks, Options{
+		Voice:       "default",
+		Gap:         50 * time.Millisecond,
+		Concurrency: 2,
+		OnProgress:  func(done, total int) { progress = append(progress, done) },
+	})
+	if err != nil {
+		t.Fatalf("Synthesize failed: %v", err)
+	}
+
+	if result.Audio.SampleRate != 16000 {
+		t.Errorf("expected the format of the first chunk, got %d Hz", result.Audio.SampleRate)
+	}
+	if d := result.Audio.Duration(); d != 850*time.Millisecond {
+		t.Errorf("expected 3x100ms of speech, a 50ms gap and a 500ms pause, got %v", d)
+	}
+	if len(progress) != 3 || progress[2] != 3 {
+		t.Errorf("unexpected progress calls: %v", progress)
+	}
+
+	want := []Timing{
+		{Text: "one", Voice: "default", Start: 0, End: 100 * time.Millisecond},
+		{Text: "slow two", Voice: "other", Start: 150 * time.Millisecond, End: 250 * time.Millisecond},
+		{Text: "three", Voice: "default", Start: 750 * time.Millisecond, End: 850 * time.Millisecond},
+	}
+	if len(result.Timings) != len(want) {
+		t.Fatalf("expected %d timings, got %+v", len(want), result.Timings)
+	}
+	for i := range want {
+		if result.Timings[i] != want[i] {
+			t.Errorf("timing %d: expected %+v, got %+v", i, want[i], result.Timings[i])
+		}
+	}
+
+	for i, request := range synth.requests {
+		if request == "other:slow two" {
+			if p := synth.params[i]; p == nil || p.Temperature == nil || *p.Temperature != 0.3 {
+				t.Errorf("expected the chunk temperature to be sent, got %+v", p)
+			}
+		}
+	}
+}
+
+func TestSynthesizeFails(t *testing.T) {
+	synth := &fakeSynthesizer{fail: "two"}
+	_, err := Synthesize(context.Background(), synth, []Chunk{{Text: "one"}, {Text: "two"}}, Options{Voice: "v", Concurrency: 1})
+	if err == nil || !strings.Contains(err.Error(), "chunk 2 of 2") {
+		t.Fatalf("expected the failing chunk to be reported, got %v", err)
+	}
+}
+
+func TestLoadScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.yaml")
+	script := `speakers:
+  host: voice-a
+  guest:
+    voice: Premade Name
+    chinese: yue
+pause: 200ms
+lines:
+  - speaker: host
+    text: Hello.
+  - pause: 1s
+  - speaker: guest
+    text: Hi <break time="300ms"/> there.
+`
+	if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadScript(path)
+	if err != nil {
+		t.Fatalf("LoadScript failed: %v", err)
+	}
+	if loaded.Speakers["host"].Voice != "voice-a" || loaded.Speakers["guest"].Chinese != ChineseYue {
+		t.Errorf("unexpected speakers: %+v", loaded.Speakers)
+	}
+	if loaded.Pause == nil || *loaded.Pause != 200*time.Millisecond || loaded.Lines[1].Pause == nil || *loaded.Lines[1].Pause != time.Second {
+		t.Errorf("unexpected pauses: %+v", loaded)
+	}
+
+	invalid := map[string]string{
+		"unknown speaker": "speakers: {a: v}\nlines: [{speaker: b, text: hi}]",
+		"no voice":        "speakers: {a: {chinese: yue}}\nlines: [{speaker: a, text: hi}]",
+		"no text":         "speakers: {a: v}\nlines: [{speaker: a}]",
+		"no lines":        "speakers: {a: v}\nlines: [{pause: 1s}]",
+		"bad markup":      "speakers: {a: v}\nlines: [{speaker: a, text: '<voice>hi'}]",
+	}
+	for name, content := range invalid {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadScript(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	second := 500 * time.Millisecond
+	script := &Script{
+		Speakers: map[string]Speaker{"host": {Voice: "Host Name"}, "guest": {Voice: "guest-id"}},
+		Lines: []ScriptLine{
+			{Speaker: "host", Text: "One. Two."},
+			{Pause: &second},
+			{Speaker: "guest", Text: "Three <break time=\"50ms\"/> four."},
+			{Speaker: "host", Text: "Five."},
+		},
+	}
+	synth := &fakeSynthesizer{}
+	result, err := Render(context.Background(), synth, script, map[string]string{"host": "host-id"}, RenderOptions{MaxRunes: 5, Gap: 10 * time.Millisecond, Concurrency: 3})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	want := []LineTiming{
+		{Line: 1, Speaker: "host", Voice: "host-id", Text: "One. Two.", Start: 0, End: 210 * time.Millisecond},
+		{Line: 3, Speaker: "guest", Voice: "guest-id", Text: "Three four.", Start: 710 * time.Millisecond, End: 960 * time.Millisecond},
+		{Line: 4, Speaker: "host", Voice: "host-id", Text: "Five.", Start: 1360 * time.Millisecond, End: 1460 * time.Millisecond},
+	}
+	if len(result.Lines) != len(want) {
+		t.Fatalf("expected %d lines, got %+v", len(want), result.Lines)
+	}
+	for i := range want {
+		if result.Lines[i] != want[i] {
+			t.Errorf("line %d: expected %+v, got %+v", i, want[i], result.Lines[i])
+		}
+	}
+	for _, request := range synth.requests {
+		if !strings.HasPrefix(request, "host-id:") && !strings.HasPrefix(request, "guest-id:") {
+			t.Errorf("request sent with an unresolved voice: %s", request)
+		}
+	}
+}
@@ -81,12 +81,19 @@ func NewAgentCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "agent",
 		Short: "Manage agents",
-		Long:  `Create, list, view, and delete persistent agent configurations and routes`,
+		Long:  `Create, list, view, update, delete, export, and import persistent agent configurations and routes`,
 	}
 
 	cmd.AddCommand(newListCmd())
 	cmd.AddCommand(newViewCmd())
 	cmd.AddCommand(newCreateCmd())
+	cmd.AddCommand(newUpdateCmd())
+	cmd.AddCommand(newEditCmd())
+	cmd.AddCommand(newApplyCmd())
+	cmd.AddCommand(newExportCmd())
+	cmd.AddCommand(newImportCmd())
+	cmd.AddCommand(newDevCmd())
+	cmd.AddCommand(newVerifyCmd())
 	cmd.AddCommand(newDeleteCmd())
 	cmd.AddCommand(newRoutesCmd())
 
@@ -117,9 +124,12 @@ func runList(cmd *cobra.Command, args []string) error {
 		return formatAPIError(err, "failed to list agents")
 	}
 
-	useJSON, _ := cmd.Flags().GetBool("json")
-	if useJSON {
-		return printSafeJSON(resp)
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return printSafeOutput(format, resp)
 	}
 
 	if resp == nil || resp.Data == nil || len(*resp.Data) == 0 {
@@ -168,9 +178,12 @@ func runView(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unexpected response from server")
 	}
 
-	useJSON, _ := cmd.Flags().GetBool("json")
-	if useJSON {
-		return printSafeJSON(resp)
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return printSafeOutput(format, resp)
 	}
 
 	return printAgentDetails(resp.Data)
@@ -248,6 +261,7 @@ func newCreateCmd() *cobra.Command {
 	cmd.Flags().String("custom-agent-bearer-token", "", "Bearer token sent to the custom agent endpoint")
 	cmd.Flags().String("custom-agent-bearer-token-file", "", "Path to a file containing the custom agent bearer token")
 	cmd.Flags().String("custom-agent-protocol", customAgentProtocolVercelAISDK, "Custom agent streaming protocol")
+	cmd.Flags().Bool("verify", false, "Check the custom agent endpoint with 'agent verify' before creating the agent")
 	cmd.Flags().BoolP("json", "j", false, "Output in JSON format")
 
 	return cmd
@@ -289,6 +303,11 @@ func runCreate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if verify, _ := cmd.Flags().GetBool("verify"); verify {
+		if err := verifyCreateBody(cmd, body); err != nil {
+			return err
+		}
+	}
 
 	if c == nil {
 		c, err = newClient(cmd)
@@ -305,9 +324,12 @@ func runCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unexpected response from server")
 	}
 
-	useJSON, _ := cmd.Flags().GetBool("json")
-	if useJSON {
-		return printSafeJSON(resp)
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return printSafeOutput(format, resp)
 	}
 
 	printAgentCreateSuccess(resp.Data)
@@ -857,17 +879,12 @@ func newClient(cmd *cobra.Command) (*client.Client, error) {
 	return c, nil
 }
 
-func printSafeJSON(value any) error {
+func printSafeOutput(format ui.OutputFormat, value any) error {
 	sanitized, err := sanitizedJSONValue(value)
 	if err != nil {
 		return err
 	}
-	data, err := json.MarshalIndent(sanitized, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to format JSON output: %w", err)
-	}
-	fmt.Println(string(data))
-	return nil
+	return util.PrintOutput(format, sanitized)
 }
 
 func sanitizedJSONValue(value any) (any, error) {
@@ -923,7 +940,7 @@ func defaultIfEmpty(value, defaultValue string) string {
 
 func formatAPIError(err error, fallback string) error {
 	if apiErr, ok := apierrors.IsAPIError(err); ok {
-		return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+		return apiErr.UserError()
 	}
 	return fmt.Errorf("%s: %w", fallback, err)
 }
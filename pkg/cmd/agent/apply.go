@@ -0,0 +1,646 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/config"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-go/api"
+	"github.com/spf13/cobra"
+)
+
+// Kinds of apply actions
+const (
+	applyCreate      = "create"
+	applyUpdate      = "update"
+	applyUnchanged   = "unchanged"
+	applyDelete      = "delete"
+	applyCreateRoute = "create-route"
+	applyRevokeRoute = "revoke-route"
+)
+
+// applyAction is one step of bringing the agents in line with the specs
+type applyAction struct {
+	Action  string   `json:"action"`
+	Agent   string   `json:"agent"`
+	AgentID string   `json:"agent_id,omitempty"`
+	Route   string   `json:"route,omitempty"`
+	RouteID string   `json:"route_id,omitempty"`
+	Changes []string `json:"changes,omitempty"`
+	// URL is the address of a created route
+	URL string `json:"url,omitempty"`
+
+	// diff describes the changes line by line for --dry-run
+	diff   []string
+	spec   *agentSpec
+	update api.UpdateAgentJSONRequestBody
+	route  routeSpec
+	done   bool
+}
+
+// applyResult is the structured output of `agent apply`
+type applyResult struct {
+	DryRun  bool           `json:"dry_run"`
+	Actions []*applyAction `json:"actions"`
+}
+
+func newApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Create or update agents from spec files",
+		Long: `Create or update agents to match YAML or JSON spec files.
+
+Each spec declares an agent with the same fields as the flags of 'agent
+create', plus the routes it should have:
+
+  name: Support bot
+  avatar: avatar-123
+  voice: voice-456
+  instruction-file: prompts/support.md
+  tools-file: tools/support.json
+  routes:
+    - label: website
+    - label: campaign
+      valid-for: 720h
+
+Agents are matched by name, or by id when the spec sets one, which allows
+renaming. Custom agents take their bearer token from
+custom-agent-bearer-token-file or custom-agent-bearer-token-env; it is only
+sent when the agent has no token yet, use 'agent update' to rotate it. Routes
+are matched by label and created when missing.
+
+With --prune, agents that apply created or updated before from the same
+files or directories and that are no longer in any spec are deleted, and
+active routes missing from a spec that has a routes key are revoked. Agents
+made in other ways, or applied from other files, are never deleted. Apply
+keeps track of its agents in applied-agents.json next to the config file.
+
+A file may hold
+several agents as a list, under an "agents" key, or as separate YAML
+documents; a directory applies all .yaml, .yml and .json files in it.`,
+		Example: `  mirako agent apply -f agents/ --dry-run
+  mirako agent apply -f support.yaml
+  mirako agent apply -f agents/ --prune --force`,
+		Args: cobra.NoArgs,
+		RunE: runApply,
+	}
+
+	cmd.Flags().StringArrayP("file", "f", nil, "Spec file or directory of spec files (repeatable)")
+	cmd.Flags().Bool("dry-run", false, "Show the changes without making them")
+	cmd.Flags().Bool("prune", false, "Delete agents applied before from these files and revoke routes that are no longer in the specs")
+	cmd.Flags().Bool("force", false, "Skip the confirmation prompt of --prune")
+	cmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	files, _ := cmd.Flags().GetStringArray("file")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	prune, _ := cmd.Flags().GetBool("prune")
+	force, _ := cmd.Flags().GetBool("force")
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	specs, err := loadAgentSpecs(files)
+	if err != nil {
+		return err
+	}
+
+	sources := map[string]bool{}
+	for _, file := range files {
+		if source, err := filepath.Abs(file); err == nil {
+			sources[source] = true
+		}
+	}
+
+	c, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	applied, err := loadAppliedAgents()
+	if err != nil {
+		return err
+	}
+	actions, err := planApply(cmd, c, specs, prune, applied, sources)
+	if err != nil {
+		return err
+	}
+
+	result := applyResult{DryRun: dryRun, Actions: actions}
+	if dryRun {
+		if !format.IsTable() {
+			return printSafeOutput(format, result)
+		}
+		printApplyPlan(actions, true)
+		return nil
+	}
+
+	if destructive := countActions(actions, applyDelete, applyRevokeRoute); destructive > 0 && !force {
+		if !format.IsTable() || !stdinIsTTY() {
+			return fmt.Errorf("--prune would delete or revoke %d agents and routes. Review them with --dry-run and use --force to confirm", destructive)
+		}
+		printApplyPlan(actions, true)
+		confirmed := false
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Delete %d agents and revoke %d routes that are no longer in the specs? This cannot be undone.", countActions(actions, applyDelete), countActions(actions, applyRevokeRoute)),
+			Default: false,
+		}
+		if err := survey.AskOne(prompt, &confirmed); err != nil {
+			return fmt.Errorf("error getting confirmation: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("Apply cancelled")
+			return nil
+		}
+	}
+
+	// After a failure only the actions that were carried out are shown
+	applyErr := executeApply(cmd, c, actions)
+	if err := saveAppliedAgents(applied, actions); err != nil && applyErr == nil {
+		applyErr = err
+	}
+	var done []*applyAction
+	for _, action := range actions {
+		if action.done {
+			done = append(done, action)
+		}
+	}
+	result.Actions = done
+	if !format.IsTable() {
+		if applyErr != nil {
+			return applyErr
+		}
+		return printSafeOutput(format, result)
+	}
+	printApplyPlan(done, false)
+	return applyErr
+}
+
+// planApply compares the specs with the existing agents and routes and
+// returns the actions that make them match. With prune, agents applied
+// before from one of sources that no spec matches are deleted.
+func planApply(cmd *cobra.Command, c *client.Client, specs []*agentSpec, prune bool, applied map[string]appliedAgent, sources map[string]bool) ([]*applyAction, error) {
+	agentsResp, err := c.ListAgents(cmd.Context())
+	if err != nil {
+		return nil, formatAPIError(err, "failed to list agents")
+	}
+	var agents []api.AgentResponse
+	if agentsResp != nil && agentsResp.Data != nil {
+		agents = *agentsResp.Data
+	}
+
+	var routes []api.AgentRouteResponse
+	if prune || specsManageRoutes(specs) {
+		routesResp, err := c.ListOwnerAgentRoutes(cmd.Context())
+		if err != nil {
+			return nil, formatAgentRouteListAPIError(err, "failed to list agent routes")
+		}
+		if routesResp != nil && routesResp.Data != nil {
+			routes = *routesResp.Data
+		}
+	}
+
+	var actions []*applyAction
+	matched := map[string]bool{}
+	for _, spec := range specs {
+		current, err := matchAgent(spec, agents)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil {
+			actions = append(actions, &applyAction{Action: applyCreate, Agent: spec.Name, spec: spec, diff: describeNewAgent(spec)})
+		} else {
+			matched[current.Id] = true
+			fields := spec.fields()
+			// The token can't be compared, so it is only set where none is configured
+			if current.HasCustomAgentBearerToken && current.RuntimeKind == customAgentRuntimeKind {
+				fields.CustomAgentBearerToken = nil
+			}
+			body, changed, err := diffAgent(*current, fields)
+			if err != nil {
+				return nil, fmt.Errorf("%s: agent %q: %w", spec.source, spec.Name, err)
+			}
+			action := &applyAction{Action: applyUnchanged, Agent: spec.Name, AgentID: current.Id, spec: spec}
+			if len(changed) > 0 {
+				action.Action = applyUpdate
+				action.Changes = changed
+				action.update = body
+				action.diff = describeAgentChanges(*current, body)
+			}
+			actions = append(actions, action)
+		}
+
+		if !spec.managesRoutes {
+			continue
+		}
+		active := map[string]bool{}
+		if current != nil {
+			for _, route := range routes {
+				if route.AgentId != current.Id || route.Status != api.Active {
+					continue
+				}
+				label := optionalString(route.Label)
+				if routeDeclared(spec, label) {
+					active[label] = true
+				} else if prune {
+					actions = append(actions, &applyAction{Action: applyRevokeRoute, Agent: spec.Name, AgentID: current.Id, Route: label, RouteID: route.Id})
+				}
+			}
+		}
+		for _, route := range spec.Routes {
+			if !active[route.Label] {
+				actions = append(actions, &applyAction{Action: applyCreateRoute, Agent: spec.Name, Route: route.Label, spec: spec, route: route})
+			}
+		}
+	}
+
+	if prune {
+		for _, agent := range agents {
+			if record, ok := applied[agent.Id]; ok && sources[record.Source] && !matched[agent.Id] {
+				actions = append(actions, &applyAction{Action: applyDelete, Agent: agent.Name, AgentID: agent.Id})
+			}
+		}
+	}
+	return actions, nil
+}
+
+// appliedAgentsFileName lists the agents apply manages, next to the config file
+const appliedAgentsFileName = "applied-agents.json"
+
+// appliedAgent is an agent apply created or updated, with the spec file or
+// directory it was applied from
+type appliedAgent struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+func appliedAgentsPath() string {
+	dir := config.ConfigPath
+	if dir == "" {
+		dir = config.DefaultUserConfigDirPath()
+	}
+	return filepath.Join(dir, appliedAgentsFileName)
+}
+
+// loadAppliedAgents returns the agents apply manages by ID
+func loadAppliedAgents() (map[string]appliedAgent, error) {
+	applied := map[string]appliedAgent{}
+	data, err := os.ReadFile(appliedAgentsPath())
+	if os.IsNotExist(err) {
+		return applied, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied agents: %w", err)
+	}
+	var list []appliedAgent
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", appliedAgentsPath(), err)
+	}
+	for _, agent := range list {
+		applied[agent.ID] = agent
+	}
+	return applied, nil
+}
+
+// saveAppliedAgents records the agents that the carried out actions created,
+// updated or found up to date, and forgets the deleted ones
+func saveAppliedAgents(applied map[string]appliedAgent, actions []*applyAction) error {
+	for _, action := range actions {
+		switch {
+		case !action.done:
+		case action.Action == applyDelete:
+			delete(applied, action.AgentID)
+		case action.spec != nil && action.AgentID != "":
+			applied[action.AgentID] = appliedAgent{ID: action.AgentID, Name: action.spec.Name, Source: action.spec.root}
+		}
+	}
+
+	list := make([]appliedAgent, 0, len(applied))
+	for _, agent := range applied {
+		list = append(list, agent)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Source != list[j].Source {
+			return list[i].Source < list[j].Source
+		}
+		return list[i].Name < list[j].Name
+	})
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode applied agents: %w", err)
+	}
+	path := appliedAgentsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to save applied agents: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to save applied agents: %w", err)
+	}
+	return nil
+}
+
+// matchAgent finds the existing agent a spec describes, by ID or by name
+func matchAgent(spec *agentSpec, agents []api.AgentResponse) (*api.AgentResponse, error) {
+	var match *api.AgentResponse
+	for i := range agents {
+		agent := &agents[i]
+		if spec.ID != "" {
+			if agent.Id == spec.ID {
+				return agent, nil
+			}
+			continue
+		}
+		if agent.Name != spec.Name {
+			continue
+		}
+		if match != nil {
+			return nil, fmt.Errorf("%s: several agents are named %q (%s, %s). Set id in the spec to choose one", spec.source, spec.Name, match.Id, agent.Id)
+		}
+		match = agent
+	}
+	if spec.ID != "" {
+		return nil, fmt.Errorf("%s: agent %q: no agent with ID %s", spec.source, spec.Name, spec.ID)
+	}
+	return match, nil
+}
+
+func specsManageRoutes(specs []*agentSpec) bool {
+	for _, spec := range specs {
+		if spec.managesRoutes {
+			return true
+		}
+	}
+	return false
+}
+
+func routeDeclared(spec *agentSpec, label string) bool {
+	for _, route := range spec.Routes {
+		if route.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+// executeApply performs the actions in order and stops at the first failure
+func executeApply(cmd *cobra.Command, c *client.Client, actions []*applyAction) error {
+	agentIDs := map[*agentSpec]string{}
+	for _, action := range actions {
+		if action.spec != nil && action.AgentID != "" {
+			agentIDs[action.spec] = action.AgentID
+		}
+	}
+
+	for _, action := range actions {
+		switch action.Action {
+		case applyCreate:
+			resp, err := c.CreateAgent(cmd.Context(), action.spec.createBody())
+			if err != nil {
+				return formatAPIError(err, fmt.Sprintf("failed to create agent %q", action.Agent))
+			}
+			if resp == nil {
+				return fmt.Errorf("unexpected response from server")
+			}
+			action.AgentID = resp.Data.Id
+			agentIDs[action.spec] = resp.Data.Id
+		case applyUpdate:
+			if _, err := c.UpdateAgent(cmd.Context(), action.AgentID, action.update); err != nil {
+				return formatAPIError(err, fmt.Sprintf("failed to update agent %q", action.Agent))
+			}
+		case applyCreateRoute:
+			action.AgentID = agentIDs[action.spec]
+			body := api.CreateAgentRouteJSONRequestBody{Label: &action.route.Label, ValiditySeconds: action.route.validitySeconds}
+			resp, err := c.CreateAgentRoute(cmd.Context(), action.AgentID, body)
+			if err != nil {
+				return formatAPIError(err, fmt.Sprintf("failed to create route %q of agent %q", action.Route, action.Agent))
+			}
+			if resp == nil {
+				return fmt.Errorf("unexpected response from server")
+			}
+			if err := validateCreatedAgentRoute(resp.Data, action.AgentID, body); err != nil {
+				return err
+			}
+			action.RouteID = resp.Data.Id
+			action.URL = optionalString(resp.Data.Url)
+		case applyRevokeRoute:
+			if _, err := c.RevokeAgentRoute(cmd.Context(), action.RouteID); err != nil {
+				return formatAgentRouteAPIError(err, fmt.Sprintf("failed to revoke route %q of agent %q", action.Route, action.Agent), action.RouteID)
+			}
+		case applyDelete:
+			if err := c.DeleteAgent(cmd.Context(), action.AgentID); err != nil {
+				return formatAPIError(err, fmt.Sprintf("failed to delete agent %q", action.Agent))
+			}
+		}
+		action.done = true
+	}
+	return nil
+}
+
+func countActions(actions []*applyAction, kinds ...string) int {
+	count := 0
+	for _, action := range actions {
+		for _, kind := range kinds {
+			if action.Action == kind {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// printApplyPlan prints one line per action, with the field changes when
+// showDiff is set
+func printApplyPlan(actions []*applyAction, showDiff bool) {
+	for _, action := range actions {
+		agent := sanitizeAgentRouteOutput(action.Agent)
+		if action.AgentID != "" {
+			agent = fmt.Sprintf("%s (%s)", agent, sanitizeAgentRouteOutput(action.AgentID))
+		}
+		route := sanitizeAgentRouteOutput(action.Route)
+
+		switch action.Action {
+		case applyCreate:
+			fmt.Printf("+ create agent %s\n", agent)
+		case applyUpdate:
+			fmt.Printf("~ update agent %s: %s\n", agent, strings.Join(action.Changes, ", "))
+		case applyUnchanged:
+			fmt.Printf("= agent %s is up to date\n", agent)
+		case applyCreateRoute:
+			fmt.Printf("+ create route %q of agent %s\n", route, agent)
+			if action.URL != "" {
+				fmt.Printf("    URL: %s\n", sanitizeAgentRouteOutput(action.URL))
+			}
+		case applyRevokeRoute:
+			fmt.Printf("- revoke route %q (%s) of agent %s\n", route, sanitizeAgentRouteOutput(action.RouteID), agent)
+		case applyDelete:
+			fmt.Printf("- delete agent %s\n", agent)
+		}
+		if showDiff {
+			for _, line := range action.diff {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+
+	format := "\nApplied: %d created, %d updated, %d deleted, %d routes created, %d routes revoked\n"
+	if showDiff {
+		format = "\nPlan: %d to create, %d to update, %d to delete, %d routes to create, %d routes to revoke\n"
+	}
+	fmt.Printf(format, countActions(actions, applyCreate), countActions(actions, applyUpdate), countActions(actions, applyDelete),
+		countActions(actions, applyCreateRoute), countActions(actions, applyRevokeRoute))
+}
+
+func describeNewAgent(spec *agentSpec) []string {
+	lines := []string{
+		fmt.Sprintf("runtime kind: %s", spec.RuntimeKind),
+		fmt.Sprintf("avatar: %s", spec.Avatar),
+		fmt.Sprintf("voice profile: %s", spec.Voice),
+	}
+	if spec.RuntimeKind == customAgentRuntimeKind {
+		lines = append(lines, fmt.Sprintf("custom agent URL: %s", spec.CustomAgentURL))
+	} else if spec.Tools != nil {
+		lines = append(lines, fmt.Sprintf("tools: %d", len(*spec.Tools)))
+	}
+	return lines
+}
+
+// describeAgentChanges renders the fields set in body next to their current
+// values. Instruction and tools are shown as a line diff.
+func describeAgentChanges(current api.AgentResponse, body api.UpdateAgentJSONRequestBody) []string {
+	var lines []string
+	scalar := func(label, old string, value *string) {
+		if value != nil {
+			lines = append(lines, fmt.Sprintf("%s: %q → %q", label, old, *value))
+		}
+	}
+	if body.RuntimeKind != nil {
+		lines = append(lines, fmt.Sprintf("runtime kind: %s → %s", current.RuntimeKind, *body.RuntimeKind))
+	}
+	scalar("name", current.Name, body.Name)
+	scalar("description", optionalString(current.Description), body.Description)
+	scalar("avatar", current.AvatarId, body.AvatarId)
+	scalar("voice profile", current.VoiceProfileId, body.VoiceProfileId)
+	scalar("model", current.Model, body.Model)
+	scalar("custom agent URL", optionalString(current.CustomAgentUrl), body.CustomAgentUrl)
+	if body.CustomAgentProtocol != nil {
+		lines = append(lines, fmt.Sprintf("custom agent protocol: %q → %q", optionalString(current.CustomAgentProtocol), *body.CustomAgentProtocol))
+	}
+	if body.CustomAgentBearerToken != nil {
+		lines = append(lines, "custom agent bearer token: set")
+	}
+	if body.Instruction != nil {
+		lines = append(lines, "instruction:")
+		lines = append(lines, indentLines(diffLines(optionalString(current.Instruction), *body.Instruction))...)
+	}
+	if body.Tools != nil {
+		currentTools := []any{}
+		if current.Tools != nil {
+			currentTools = *current.Tools
+		}
+		lines = append(lines, "tools:")
+		lines = append(lines, indentLines(diffLines(formatToolsJSON(currentTools), formatToolsJSON(*body.Tools)))...)
+	}
+	return lines
+}
+
+func formatToolsJSON(tools []any) string {
+	data, err := json.MarshalIndent(tools, "", "  ")
+	if err != nil {
+		return fmt.Sprint(tools)
+	}
+	return string(data)
+}
+
+func indentLines(lines []string) []string {
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return lines
+}
+
+// diffContextLines is the number of unchanged lines shown around a change
+const diffContextLines = 2
+
+// diffLines returns a line diff of old and new, with "-" and "+" marking
+// removed and added lines and unchanged lines far from a change left out
+func diffLines(old, new string) []string {
+	a := strings.Split(strings.TrimRight(old, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(new, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffLine{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffLine{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffLine{'+', b[j]})
+			j++
+		}
+	}
+
+	var out []string
+	skipped := false
+	for k, op := range ops {
+		if op.op == ' ' && !nearChange(ops, k) {
+			if !skipped {
+				out = append(out, "  …")
+				skipped = true
+			}
+			continue
+		}
+		skipped = false
+		out = append(out, fmt.Sprintf("%c %s", op.op, op.text))
+	}
+	return out
+}
+
+// diffLine is a line of a diff, marked ' ', '-' or '+'
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// nearChange reports whether a changed line is within diffContextLines of ops[k]
+func nearChange(ops []diffLine, k int) bool {
+	for n := max(0, k-diffContextLines); n <= min(len(ops)-1, k+diffContextLines); n++ {
+		if ops[n].op != ' ' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+const testActiveRouteJSON = `{
+  "id": "route-old",
+  "agent_id": "agent-1",
+  "label": "old",
+  "path": "/r/old",
+  "status": "active",
+  "route_version": 1,
+  "created_at": "2026-05-25T00:00:00Z",
+  "updated_at": "2026-05-25T00:00:00Z"
+}`
+
+// applyTestServer fakes the agent endpoints used by apply and records every
+// request that changes something
+type applyTestServer struct {
+	mu     sync.Mutex
+	calls  []string
+	bodies map[string]map[string]any
+}
+
+func newApplyTestServer(t *testing.T) *applyTestServer {
+	t.Helper()
+	s := &applyTestServer{bodies: map[string]map[string]any{}}
+	server := newAgentTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		call := r.Method + " " + r.URL.Path
+		if r.Method != http.MethodGet {
+			data, _ := io.ReadAll(r.Body)
+			var body map[string]any
+			_ = json.Unmarshal(data, &body)
+			s.mu.Lock()
+			s.calls = append(s.calls, call)
+			s.bodies[call] = body
+			s.mu.Unlock()
+		}
+
+		switch call {
+		case "GET /v1/agents":
+			writeJSON(w, http.StatusOK, fmt.Sprintf(`{"data":[%s,%s]}`, testAgentJSON, testCustomAgentJSON))
+		case "GET /v1/agent-routes":
+			writeJSON(w, http.StatusOK, fmt.Sprintf(`{"data":[%s]}`, testActiveRouteJSON))
+		case "POST /v1/agents":
+			writeJSON(w, http.StatusOK, fmt.Sprintf(`{"data":%s}`, strings.Replace(testAgentJSON, `"id": "agent-1"`, `"id": "agent-new"`, 1)))
+		case "PATCH /v1/agents/agent-1":
+			writeJSON(w, http.StatusOK, fmt.Sprintf(`{"data":%s}`, testAgentJSON))
+		case "POST /v1/agents/agent-new/routes":
+			writeJSON(w, http.StatusOK, `{"data":{"id":"route-new","agent_id":"agent-new","label":"website","path":"/r/new","url":"https://example.test/r/new","status":"active","route_version":1,"created_at":"2026-05-25T00:00:00Z","updated_at":"2026-05-25T00:00:00Z"}}`)
+		case "POST /v1/agent-routes/route-old/revoke":
+			writeJSON(w, http.StatusOK, `{"data":{"id":"route-old","agent_id":"agent-1","label":"old","path":"/r/old","status":"revoked","route_version":2,"revoked_at":"2026-05-25T00:01:00Z","created_at":"2026-05-25T00:00:00Z","updated_at":"2026-05-25T00:01:00Z"}}`)
+		case "DELETE /v1/agents/custom-agent-1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	})
+	configureAgentTest(t, server.URL)
+	return s
+}
+
+func writeApplySpec(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "one.md"), []byte("Be helpful\nBe brief\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spec := `name: Agent One
+description: Helpful agent
+avatar: avatar-1
+voice: voice-1
+instruction-file: one.md
+tools:
+  - type: function
+    name: search
+routes: []
+---
+agents:
+  - name: New Agent
+    avatar: avatar-1
+    voice: voice-2
+    instruction: Be new
+    routes:
+      - label: website
+`
+	if err := os.WriteFile(filepath.Join(dir, "agents.yaml"), []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// writeAppliedAgents records agents as applied from source by an earlier run
+func writeAppliedAgents(t *testing.T, agents ...appliedAgent) {
+	t.Helper()
+	data, err := json.Marshal(agents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(os.Getenv("MIRAKO_CONFIG_PATH"), appliedAgentsFileName), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAgentSpecs(t *testing.T) {
+	dir := writeApplySpec(t)
+	specs, err := loadAgentSpecs([]string{dir})
+	if err != nil {
+		t.Fatalf("loadAgentSpecs() returned error: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("expected 2 specs, got %d", len(specs))
+	}
+	if specs[0].Instruction != "Be helpful\nBe brief\n" || specs[0].RuntimeKind != managedAgentRuntimeKind || !specs[0].managesRoutes {
+		t.Fatalf("unexpected first spec %+v", specs[0])
+	}
+	if specs[1].Name != "New Agent" || len(specs[1].Routes) != 1 || specs[1].Routes[0].Label != "website" {
+		t.Fatalf("unexpected second spec %+v", specs[1])
+	}
+
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{"unknown field", "name: A\navatar: a\nvoice: v\ninstructions: hi\n", `unknown field "instructions"`},
+		{"missing instruction", "name: A\navatar: a\nvoice: v\n", "instruction is required"},
+		{"custom fields on managed agent", "name: A\navatar: a\nvoice: v\nruntime-kind: managed_agent\ninstruction: hi\ncustom-agent-url: https://example.test\n", "custom agent fields do not apply"},
+		{"duplicate name", "- {name: A, avatar: a, voice: v, instruction: hi}\n- {name: A, avatar: a, voice: v, instruction: hi}\n", `agent "A" is declared in both`},
+		{"duplicate route", "name: A\navatar: a\nvoice: v\ninstruction: hi\nroutes: [{label: web}, {label: web}]\n", `route "web" is declared twice`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "agent.yaml")
+			if err := os.WriteFile(path, []byte(tt.spec), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadAgentSpecs([]string{path}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("loadAgentSpecs() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadAgentSpecsExpandsHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	homedir.DisableCache = true
+	t.Cleanup(func() { homedir.DisableCache = false })
+	if err := os.WriteFile(filepath.Join(home, "tools.json"), []byte(`[{"type":"function","name":"search"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "token"), []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	spec := `name: Agent One
+avatar: avatar-1
+voice: voice-1
+instruction: Be helpful
+tools-file: ~/tools.json
+---
+name: Custom Agent
+avatar: avatar-1
+voice: voice-1
+custom-agent-url: https://example.test/chat
+custom-agent-bearer-token-file: ~/token
+`
+	if err := os.WriteFile(filepath.Join(dir, "agents.yaml"), []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	specs, err := loadAgentSpecs([]string{dir})
+	if err != nil {
+		t.Fatalf("loadAgentSpecs() returned error: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("expected 2 specs, got %d", len(specs))
+	}
+	if specs[0].Tools == nil || len(*specs[0].Tools) != 1 {
+		t.Fatalf("expected tools from ~/tools.json, got %+v", specs[0].Tools)
+	}
+	if specs[1].bearerToken == nil || *specs[1].bearerToken != "secret" {
+		t.Fatalf("expected bearer token from ~/token, got %v", specs[1].bearerToken)
+	}
+}
+
+func TestApplyDryRunShowsDiff(t *testing.T) {
+	forceNonInteractive(t)
+	server := newApplyTestServer(t)
+
+	dir := writeApplySpec(t)
+	writeAppliedAgents(t, appliedAgent{ID: "custom-agent-1", Name: "Custom Agent", Source: dir})
+
+	cmd := newApplyCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"file": dir, "dry-run": "true", "prune": "true"})
+	output, err := captureStdout(t, func() error { return runApply(cmd, nil) })
+	if err != nil {
+		t.Fatalf("runApply() returned error: %v", err)
+	}
+	if len(server.calls) != 0 {
+		t.Fatalf("dry run changed something: %v", server.calls)
+	}
+	assertContainsInOrder(t, output,
+		"~ update agent Agent One (agent-1): instruction",
+		"  Be helpful",
+		"+ Be brief",
+		`- revoke route "old" (route-old) of agent Agent One (agent-1)`,
+		"+ create agent New Agent",
+		`+ create route "website" of agent New Agent`,
+		"- delete agent Custom Agent (custom-agent-1)",
+		"Plan: 1 to create, 1 to update, 1 to delete, 1 routes to create, 1 routes to revoke",
+	)
+}
+
+func TestApplyCreatesUpdatesAndPrunes(t *testing.T) {
+	forceNonInteractive(t)
+	server := newApplyTestServer(t)
+
+	dir := writeApplySpec(t)
+	writeAppliedAgents(t, appliedAgent{ID: "custom-agent-1", Name: "Custom Agent", Source: dir})
+
+	cmd := newApplyCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"file": dir, "prune": "true", "force": "true"})
+	output, err := captureStdout(t, func() error { return runApply(cmd, nil) })
+	if err != nil {
+		t.Fatalf("runApply() returned error: %v", err)
+	}
+
+	want := []string{
+		"PATCH /v1/agents/agent-1",
+		"POST /v1/agent-routes/route-old/revoke",
+		"POST /v1/agents",
+		"POST /v1/agents/agent-new/routes",
+		"DELETE /v1/agents/custom-agent-1",
+	}
+	if !reflect.DeepEqual(server.calls, want) {
+		t.Fatalf("calls = %v, want %v", server.calls, want)
+	}
+	if got := server.bodies["PATCH /v1/agents/agent-1"]; !reflect.DeepEqual(got, map[string]any{"instruction": "Be helpful\nBe brief\n"}) {
+		t.Fatalf("PATCH body = %v, want only the instruction", got)
+	}
+	if got := server.bodies["POST /v1/agents/agent-new/routes"]; got["label"] != "website" {
+		t.Fatalf("route body = %v, want the website label", got)
+	}
+	assertContainsInOrder(t, output, `+ create route "website" of agent New Agent (agent-new)`, "URL: https://example.test/r/new", "Applied: 1 created, 1 updated, 1 deleted")
+}
+
+func TestApplyPruneOnlyDeletesAppliedAgents(t *testing.T) {
+	forceNonInteractive(t)
+	server := newApplyTestServer(t)
+	dir := writeApplySpec(t)
+	// Applied, but from other specs
+	writeAppliedAgents(t, appliedAgent{ID: "custom-agent-1", Name: "Custom Agent", Source: filepath.Join(dir, "other")})
+
+	cmd := newApplyCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"file": dir, "prune": "true", "force": "true"})
+	if _, err := captureStdout(t, func() error { return runApply(cmd, nil) }); err != nil {
+		t.Fatalf("runApply() returned error: %v", err)
+	}
+	if slices.Contains(server.calls, "DELETE /v1/agents/custom-agent-1") {
+		t.Fatalf("pruned an agent applied from other specs: %v", server.calls)
+	}
+
+	applied, err := loadAppliedAgents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]appliedAgent{
+		"agent-1":        {ID: "agent-1", Name: "Agent One", Source: dir},
+		"agent-new":      {ID: "agent-new", Name: "New Agent", Source: dir},
+		"custom-agent-1": {ID: "custom-agent-1", Name: "Custom Agent", Source: filepath.Join(dir, "other")},
+	}
+	if !reflect.DeepEqual(applied, want) {
+		t.Fatalf("applied agents = %v, want %v", applied, want)
+	}
+}
+
+func TestApplyPruneRequiresForce(t *testing.T) {
+	forceNonInteractive(t)
+	server := newApplyTestServer(t)
+	dir := writeApplySpec(t)
+	writeAppliedAgents(t, appliedAgent{ID: "custom-agent-1", Name: "Custom Agent", Source: dir})
+
+	cmd := newApplyCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"file": dir, "prune": "true"})
+	err := runApply(cmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "use --force to confirm") {
+		t.Fatalf("runApply() error = %v, want a request for --force", err)
+	}
+	if len(server.calls) != 0 {
+		t.Fatalf("expected no changes, got %v", server.calls)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng"
+	got := diffLines(old, "a\nb\nc\nd\nE\nf\ng")
+	want := []string{"  …", "  c", "  d", "- e", "+ E", "  f", "  g"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffLines() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,413 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	promptui "github.com/mirako-ai/mirako-cli/pkg/ui/prompt"
+	"github.com/mirako-ai/mirako-go/api"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// agentBundleVersion is the version of the bundle format written by export
+const agentBundleVersion = 1
+
+// agentBundle is a portable copy of an agent. Secrets are removed from the
+// agent and routes keep only their label and validity, as their URLs are
+// bearer capabilities.
+type agentBundle struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Source     string         `json:"source,omitempty"`
+	Agent      map[string]any `json:"agent"`
+	Routes     []bundleRoute  `json:"routes,omitempty"`
+}
+
+type bundleRoute struct {
+	Label           string `json:"label,omitempty"`
+	ValiditySeconds *int64 `json:"validity_seconds,omitempty"`
+}
+
+// idMapping maps avatar and voice profile IDs of the exporting account to
+// those of the importing one
+type idMapping struct {
+	Avatars map[string]string `yaml:"avatars"`
+	Voices  map[string]string `yaml:"voices"`
+}
+
+// importOutput is the structured output of `agent import`
+type importOutput struct {
+	Data   api.AgentResponse        `json:"data"`
+	Routes []api.AgentRouteResponse `json:"routes,omitempty"`
+}
+
+func newExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [agent-id]",
+		Short: "Export an agent to a bundle file",
+		Long: `Export an agent and the labels of its active routes to a JSON bundle that
+'agent import' can recreate in another account or context.
+
+The custom agent bearer token is not exported, and neither are route URLs.`,
+		Example: `  mirako agent export agent-123 -o support.agent.json
+  mirako agent export agent-123 > support.agent.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: runExport,
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Bundle file to write (prints to stdout if not provided or -)")
+
+	return cmd
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	agentID := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+
+	c, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	resp, err := c.GetAgent(cmd.Context(), agentID)
+	if err != nil {
+		return formatAPIError(err, "failed to get agent")
+	}
+	if resp == nil {
+		return fmt.Errorf("unexpected response from server")
+	}
+	routesResp, err := c.ListOwnerAgentRoutes(cmd.Context())
+	if err != nil {
+		return formatAgentRouteListAPIError(err, "failed to list agent routes")
+	}
+
+	sanitized, err := sanitizedJSONValue(resp.Data)
+	if err != nil {
+		return err
+	}
+	agent, ok := sanitized.(map[string]any)
+	if !ok {
+		return fmt.Errorf("unexpected response from server")
+	}
+
+	bundle := agentBundle{Version: agentBundleVersion, ExportedAt: time.Now().UTC(), Agent: agent}
+	if cfg, err := util.GetConfig(cmd); err == nil {
+		bundle.Source = cfg.APIURL
+	}
+	if routesResp != nil && routesResp.Data != nil {
+		for _, route := range *routesResp.Data {
+			if route.AgentId != agentID || route.Status != api.Active {
+				continue
+			}
+			exported := bundleRoute{Label: optionalString(route.Label)}
+			if route.ExpiresAt != nil {
+				seconds := int64(route.ExpiresAt.Sub(route.CreatedAt).Round(time.Second) / time.Second)
+				exported.ValiditySeconds = &seconds
+			}
+			bundle.Routes = append(bundle.Routes, exported)
+		}
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	if err := media.WriteFile(defaultIfEmpty(outputPath, media.Stdio), append(data, '\n')); err != nil {
+		return err
+	}
+	if outputPath != "" && !media.IsStdio(outputPath) {
+		fmt.Printf("Agent %s exported to %s\n", agentID, outputPath)
+	}
+	return nil
+}
+
+func newImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import [bundle-file]",
+		Short: "Create an agent from an exported bundle",
+		Long: `Create an agent in the current account or context from a bundle written by
+'agent export', along with its routes.
+
+Avatar and voice profile IDs are kept when they exist in this account. Others
+are replaced through --avatar and --voice, through a --map file, or by picking
+one interactively. A map file lists the replacements by original ID:
+
+  avatars:
+    avatar-staging-1: avatar-prod-1
+  voices:
+    voice-staging-1: voice-prod-1
+
+A custom agent that had a bearer token needs a new one, from
+--custom-agent-bearer-token-file or a prompt.`,
+		Example: `  mirako agent import support.agent.json --context production --map ids.yaml
+  mirako agent import support.agent.json --avatar avatar-456 --name "Support (copy)"`,
+		Args: cobra.ExactArgs(1),
+		RunE: runImportCommand,
+	}
+
+	cmd.Flags().StringP("name", "n", "", "Name for the imported agent (default: the exported name)")
+	cmd.Flags().StringP("avatar", "a", "", "Avatar ID to use instead of the exported one")
+	cmd.Flags().StringP("voice", "v", "", "Voice profile ID to use instead of the exported one")
+	cmd.Flags().String("map", "", "YAML or JSON file mapping exported avatar and voice IDs to IDs in this account")
+	cmd.Flags().String("custom-agent-bearer-token", "", "Bearer token sent to the custom agent endpoint")
+	cmd.Flags().String("custom-agent-bearer-token-file", "", "Path to a file containing the custom agent bearer token")
+	cmd.Flags().Bool("no-routes", false, "Do not recreate the exported routes")
+	cmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runImportCommand(cmd *cobra.Command, args []string) error {
+	err := runImport(cmd, args)
+	if isPromptCancelled(err) {
+		cmd.SilenceErrors = true
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Cancelled")
+	}
+	return err
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	bundle, err := readAgentBundle(args[0])
+	if err != nil {
+		return err
+	}
+	mapping, err := readIDMapping(stringFlag(cmd, "map"))
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	body, err := buildImportAgentBody(cmd, bundle, mapping, defaultAgentPrompter, stdinIsTTY(), apiAgentSelectionProvider{client: c})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.CreateAgent(cmd.Context(), body)
+	if err != nil {
+		return formatAPIError(err, "failed to create agent")
+	}
+	if resp == nil {
+		return fmt.Errorf("unexpected response from server")
+	}
+
+	output := importOutput{Data: resp.Data}
+	noRoutes, _ := cmd.Flags().GetBool("no-routes")
+	if !noRoutes {
+		output.Routes, err = importRoutes(cmd, c, resp.Data.Id, bundle.Routes)
+	}
+
+	format, formatErr := util.GetOutputFormat(cmd)
+	if formatErr != nil {
+		return formatErr
+	}
+	if !format.IsTable() {
+		if err != nil {
+			return err
+		}
+		return printSafeOutput(format, output)
+	}
+
+	printAgentCreateSuccess(resp.Data)
+	for _, route := range output.Routes {
+		fmt.Printf("Route %q created: %s\n", sanitizeAgentRouteOutput(optionalString(route.Label)), sanitizeAgentRouteOutput(optionalString(route.Url)))
+	}
+	return err
+}
+
+// importRoutes creates the exported routes for agentID and returns those that
+// were created before any failure
+func importRoutes(cmd *cobra.Command, c *client.Client, agentID string, routes []bundleRoute) ([]api.AgentRouteResponse, error) {
+	var created []api.AgentRouteResponse
+	for _, route := range routes {
+		body := api.CreateAgentRouteJSONRequestBody{ValiditySeconds: route.ValiditySeconds}
+		if route.Label != "" {
+			label := route.Label
+			body.Label = &label
+		}
+		resp, err := c.CreateAgentRoute(cmd.Context(), agentID, body)
+		if err != nil {
+			return created, formatAPIError(err, fmt.Sprintf("agent %s was created, but creating route %q failed", agentID, route.Label))
+		}
+		if resp == nil {
+			return created, fmt.Errorf("unexpected response from server")
+		}
+		if err := validateCreatedAgentRoute(resp.Data, agentID, body); err != nil {
+			return created, err
+		}
+		created = append(created, resp.Data)
+	}
+	return created, nil
+}
+
+func readAgentBundle(path string) (*agentBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	var bundle agentBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle %s: %w", path, err)
+	}
+	if bundle.Version != agentBundleVersion || bundle.Agent == nil {
+		return nil, fmt.Errorf("%s is not an agent bundle written by 'mirako agent export'", path)
+	}
+	return &bundle, nil
+}
+
+func readIDMapping(path string) (*idMapping, error) {
+	mapping := &idMapping{}
+	if strings.TrimSpace(path) == "" {
+		return mapping, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ID map: %w", err)
+	}
+	if err := yaml.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse ID map %s: %w", path, err)
+	}
+	return mapping, nil
+}
+
+// buildImportAgentBody turns the exported agent into a create request for
+// this account, replacing avatar and voice IDs and asking for stripped secrets
+func buildImportAgentBody(cmd *cobra.Command, bundle *agentBundle, mapping *idMapping, prompter agentPrompter, stdinTTY bool, selectionProvider agentSelectionProvider) (api.CreateAgentJSONRequestBody, error) {
+	if prompter == nil {
+		prompter = defaultAgentPrompter
+	}
+
+	// The agent was sanitized into a generic map on export
+	data, err := json.Marshal(bundle.Agent)
+	if err != nil {
+		return api.CreateAgentJSONRequestBody{}, fmt.Errorf("failed to read exported agent: %w", err)
+	}
+	var agent api.AgentResponse
+	if err := json.Unmarshal(data, &agent); err != nil {
+		return api.CreateAgentJSONRequestBody{}, fmt.Errorf("failed to read exported agent: %w", err)
+	}
+
+	runtimeKind, err := parseRuntimeKind(agent.RuntimeKind)
+	if err != nil {
+		return api.CreateAgentJSONRequestBody{}, err
+	}
+	name := strings.TrimSpace(defaultIfEmpty(stringFlag(cmd, "name"), agent.Name))
+	if name == "" {
+		return api.CreateAgentJSONRequestBody{}, fmt.Errorf("name is required. Use --name flag")
+	}
+
+	avatarID, err := resolveImportedID(cmd, "avatar", agent.AvatarId, stringFlag(cmd, "avatar"), mapping.Avatars, selectionProvider.AvatarOptions, prompter, stdinTTY)
+	if err != nil {
+		return api.CreateAgentJSONRequestBody{}, err
+	}
+	voiceID, err := resolveImportedID(cmd, "voice profile", agent.VoiceProfileId, stringFlag(cmd, "voice"), mapping.Voices, selectionProvider.VoiceProfileOptions, prompter, stdinTTY)
+	if err != nil {
+		return api.CreateAgentJSONRequestBody{}, err
+	}
+
+	body := api.CreateAgentJSONRequestBody{
+		Name:           name,
+		AvatarId:       avatarID,
+		VoiceProfileId: voiceID,
+		RuntimeKind:    &runtimeKind,
+	}
+	if description := strings.TrimSpace(optionalString(agent.Description)); description != "" {
+		body.Description = &description
+	}
+	if agent.Model != "" {
+		body.Model = &agent.Model
+	}
+
+	switch runtimeKind {
+	case api.CreateAgentInputRuntimeKindManagedAgent:
+		if strings.TrimSpace(optionalString(agent.Instruction)) == "" {
+			return api.CreateAgentJSONRequestBody{}, fmt.Errorf("the exported agent has no instruction")
+		}
+		tools := []any{}
+		if agent.Tools != nil {
+			tools = *agent.Tools
+		}
+		body.Instruction = agent.Instruction
+		body.Tools = &tools
+	case api.CreateAgentInputRuntimeKindCustomAgent:
+		customAgentURL := optionalString(agent.CustomAgentUrl)
+		if err := validateCustomAgentURL(customAgentURL); err != nil {
+			return api.CreateAgentJSONRequestBody{}, err
+		}
+		protocol, err := parseCustomAgentProtocol(defaultIfEmpty(optionalString(agent.CustomAgentProtocol), customAgentProtocolVercelAISDK))
+		if err != nil {
+			return api.CreateAgentJSONRequestBody{}, err
+		}
+		body.CustomAgentUrl = &customAgentURL
+		body.CustomAgentProtocol = &protocol
+
+		bearerToken, err := resolveCustomAgentBearerToken(cmd, nil, false)
+		if err != nil {
+			return api.CreateAgentJSONRequestBody{}, err
+		}
+		if bearerToken == "" && agent.HasCustomAgentBearerToken {
+			if !stdinTTY {
+				return api.CreateAgentJSONRequestBody{}, fmt.Errorf("the exported agent used a bearer token, which is not exported. Use --custom-agent-bearer-token-file flag")
+			}
+			answer, err := prompter.Password(fmt.Sprintf("Custom agent bearer token for %s", customAgentURL))
+			if err != nil {
+				return api.CreateAgentJSONRequestBody{}, fmt.Errorf("error getting custom agent bearer token: %w", err)
+			}
+			bearerToken = strings.TrimSpace(answer)
+			if bearerToken == "" {
+				return api.CreateAgentJSONRequestBody{}, fmt.Errorf("custom agent bearer token is required")
+			}
+		}
+		if bearerToken != "" {
+			body.CustomAgentBearerToken = &bearerToken
+		}
+	}
+	return body, nil
+}
+
+// resolveImportedID picks the ID that replaces an exported avatar or voice
+// profile ID: the flag, the map file, the same ID when it exists in this
+// account, or an interactive choice
+func resolveImportedID(cmd *cobra.Command, kind, exportedID, override string, mapping map[string]string, listOptions func(context.Context) ([]promptui.SelectOption, error), prompter agentPrompter, stdinTTY bool) (string, error) {
+	if value := strings.TrimSpace(override); value != "" {
+		return value, nil
+	}
+	if value := strings.TrimSpace(mapping[exportedID]); value != "" {
+		return value, nil
+	}
+
+	options, err := listOptions(cmd.Context())
+	if err != nil {
+		return "", formatAPIError(err, fmt.Sprintf("failed to list %ss", kind))
+	}
+	if slices.ContainsFunc(options, func(option promptui.SelectOption) bool { return option.Result() == exportedID }) {
+		return exportedID, nil
+	}
+
+	flag := strings.Fields(kind)[0]
+	if !stdinTTY {
+		return "", fmt.Errorf("%s %s does not exist in this account. Map it with --map or --%s", kind, exportedID, flag)
+	}
+	if len(options) == 0 {
+		return "", fmt.Errorf("%s %s does not exist in this account and there are none to choose from", kind, exportedID)
+	}
+	choice, err := prompter.SearchSelect(fmt.Sprintf("Choose %s to replace %s", kind, exportedID), options, "")
+	if err != nil {
+		return "", fmt.Errorf("error choosing %s: %w", kind, err)
+	}
+	choice = strings.TrimSpace(choice)
+	if choice == "" {
+		return "", fmt.Errorf("%s ID is required. Use --%s flag", kind, flag)
+	}
+	return choice, nil
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	promptui "github.com/mirako-ai/mirako-cli/pkg/ui/prompt"
+)
+
+func writeTestBundle(t *testing.T, agentJSON string) string {
+	t.Helper()
+	var agent map[string]any
+	if err := json.Unmarshal([]byte(agentJSON), &agent); err != nil {
+		t.Fatal(err)
+	}
+	removeSecretFields(agent)
+	validity := int64(86400)
+	data, err := json.Marshal(agentBundle{Version: agentBundleVersion, Agent: agent, Routes: []bundleRoute{{Label: "website", ValiditySeconds: &validity}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "agent.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExportWritesBundleWithoutSecrets(t *testing.T) {
+	server := newAgentTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/agents/custom-agent-1":
+			writeJSON(w, http.StatusOK, fmt.Sprintf(`{"data":%s}`, testCustomAgentWithSecretJSON))
+		case "/v1/agent-routes":
+			writeJSON(w, http.StatusOK, `{"data":[
+  {"id":"route-1","agent_id":"custom-agent-1","label":"website","path":"/r/secret-path","url":"https://example.test/r/secret-path","status":"active","route_version":1,"created_at":"2026-05-25T00:00:00Z","expires_at":"2026-05-26T00:00:00Z","updated_at":"2026-05-25T00:00:00Z"},
+  {"id":"route-2","agent_id":"custom-agent-1","label":"old","path":"/r/old","status":"revoked","route_version":2,"created_at":"2026-05-25T00:00:00Z","updated_at":"2026-05-25T00:00:00Z"},
+  {"id":"route-3","agent_id":"agent-1","label":"other","path":"/r/other","status":"active","route_version":1,"created_at":"2026-05-25T00:00:00Z","updated_at":"2026-05-25T00:00:00Z"}
+]}`)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	})
+	configureAgentTest(t, server.URL)
+
+	path := filepath.Join(t.TempDir(), "bundle", "agent.json")
+	cmd := newExportCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"output": path})
+	output, err := captureStdout(t, func() error { return runExport(cmd, []string{"custom-agent-1"}) })
+	if err != nil {
+		t.Fatalf("runExport() returned error: %v", err)
+	}
+	if !strings.Contains(output, "Agent custom-agent-1 exported to "+path) {
+		t.Fatalf("unexpected output %q", output)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoSecret(t, string(data))
+	if strings.Contains(string(data), "secret-path") {
+		t.Fatalf("bundle leaked a route URL: %s", data)
+	}
+	bundle, err := readAgentBundle(path)
+	if err != nil {
+		t.Fatalf("readAgentBundle() returned error: %v", err)
+	}
+	if bundle.Agent["custom_agent_url"] != "https://agent.example.test/api/chat" || bundle.Agent["has_custom_agent_bearer_token"] != true {
+		t.Fatalf("unexpected exported agent %v", bundle.Agent)
+	}
+	if len(bundle.Routes) != 1 || bundle.Routes[0].Label != "website" || bundle.Routes[0].ValiditySeconds == nil || *bundle.Routes[0].ValiditySeconds != 86400 {
+		t.Fatalf("unexpected exported routes %+v", bundle.Routes)
+	}
+}
+
+func TestImportCreatesAgentAndRoutes(t *testing.T) {
+	forceNonInteractive(t)
+	var calls []string
+	bodies := map[string]map[string]any{}
+	server := newAgentTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		call := r.Method + " " + r.URL.Path
+		calls = append(calls, call)
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(data, &body)
+		bodies[call] = body
+
+		switch call {
+		case "POST /v1/agents":
+			writeJSON(w, http.StatusOK, fmt.Sprintf(`{"data":%s}`, strings.Replace(testCustomAgentJSON, `"id": "custom-agent-1"`, `"id": "agent-new"`, 1)))
+		case "POST /v1/agents/agent-new/routes":
+			writeJSON(w, http.StatusOK, `{"data":{"id":"route-new","agent_id":"agent-new","label":"website","path":"/r/new","url":"https://example.test/r/new","status":"active","route_version":1,"created_at":"2026-05-25T00:00:00Z","expires_at":"2026-05-26T00:00:00Z","updated_at":"2026-05-25T00:00:00Z"}}`)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	})
+	configureAgentTest(t, server.URL)
+
+	dir := t.TempDir()
+	mapPath := filepath.Join(dir, "ids.yaml")
+	if err := os.WriteFile(mapPath, []byte("avatars:\n  avatar-1: avatar-prod\nvoices:\n  voice-1: voice-prod\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tokenPath := filepath.Join(dir, "token.txt")
+	if err := os.WriteFile(tokenPath, []byte("new-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newImportCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"map": mapPath, "custom-agent-bearer-token-file": tokenPath})
+	output, err := captureStdout(t, func() error { return runImport(cmd, []string{writeTestBundle(t, testCustomAgentWithSecretJSON)}) })
+	if err != nil {
+		t.Fatalf("runImport() returned error: %v", err)
+	}
+
+	if want := []string{"POST /v1/agents", "POST /v1/agents/agent-new/routes"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	created := bodies["POST /v1/agents"]
+	if created["avatar_id"] != "avatar-prod" || created["voice_profile_id"] != "voice-prod" || created["custom_agent_bearer_token"] != "new-token" || created["name"] != "Custom Agent" {
+		t.Fatalf("unexpected create body %v", created)
+	}
+	if route := bodies["POST /v1/agents/agent-new/routes"]; route["label"] != "website" || route["validity_seconds"] != float64(86400) {
+		t.Fatalf("unexpected route body %v", route)
+	}
+	assertContainsInOrder(t, output, "agent-new", `Route "website" created: https://example.test/r/new`)
+}
+
+func TestBuildImportAgentBodyResolvesMissingIDs(t *testing.T) {
+	bundle, err := readAgentBundle(writeTestBundle(t, testCustomAgentWithSecretJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &fakeAgentSelectionProvider{
+		avatarOptions: []promptui.SelectOption{{Label: "Prod", Value: "avatar-prod"}},
+		voiceOptions:  []promptui.SelectOption{{Label: "Voice One", Value: "voice-1"}},
+	}
+
+	prompter := &fakeAgentPrompter{passwords: map[string]string{"Custom agent bearer token for https://agent.example.test/api/chat": "typed-token"}}
+	body, err := buildImportAgentBody(newImportCmd(), bundle, &idMapping{}, prompter, true, provider)
+	if err != nil {
+		t.Fatalf("buildImportAgentBody() returned error: %v", err)
+	}
+	if body.AvatarId != "avatar-prod" || body.VoiceProfileId != "voice-1" || optionalString(body.CustomAgentBearerToken) != "typed-token" {
+		t.Fatalf("unexpected body %+v", body)
+	}
+	if !containsCall(prompter.calls, "search-select:Choose avatar to replace avatar-1") || containsCallPrefix(prompter.calls, "search-select:Choose voice profile") {
+		t.Fatalf("unexpected prompts %v", prompter.calls)
+	}
+
+	// Without a terminal, missing IDs and stripped secrets must come from flags
+	_, err = buildImportAgentBody(newImportCmd(), bundle, &idMapping{}, &fakeAgentPrompter{}, false, provider)
+	if err == nil || !strings.Contains(err.Error(), "avatar avatar-1 does not exist in this account. Map it with --map or --avatar") {
+		t.Fatalf("buildImportAgentBody() error = %v, want a request to map the avatar", err)
+	}
+	cmd := newImportCmd()
+	setFlags(t, cmd, map[string]string{"avatar": "avatar-prod"})
+	_, err = buildImportAgentBody(cmd, bundle, &idMapping{}, &fakeAgentPrompter{}, false, provider)
+	if err == nil || !strings.Contains(err.Error(), "bearer token, which is not exported") {
+		t.Fatalf("buildImportAgentBody() error = %v, want a request for the bearer token", err)
+	}
+}
@@ -0,0 +1,324 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/agentstream"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// devInput is where `agent dev` reads turns from when no --message is given
+var devInput io.Reader = os.Stdin
+
+// maxToolValueLength bounds the tool inputs and outputs shown by `agent dev`
+const maxToolValueLength = 200
+
+func newDevCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Chat with a custom agent endpoint from the terminal",
+		Long: `Act as the platform side of the custom agent protocol, so an endpoint can be
+developed and tested without an avatar session.
+
+Each line typed, or read from standard input, is sent as a user turn along
+with the conversation so far. The reply is shown as it streams: text,
+reasoning, tool calls and their results. Protocol violations are listed after
+each turn. Both the AI SDK 4 data stream and the AI SDK 5 UI message stream
+are understood.
+
+Type /reset to start a new conversation. The command fails when a turn could
+not be sent or broke the protocol, so --message can be used in CI.`,
+		Example: `  mirako agent dev --url http://localhost:3000/api/chat
+  mirako agent dev --url http://localhost:3000/api/chat --custom-agent-bearer-token-file token.txt \
+    -m "Hi" -m "What's the weather in Paris?"`,
+		Args: cobra.NoArgs,
+		RunE: runDev,
+	}
+
+	cmd.Flags().String("url", "", "Custom agent endpoint URL")
+	cmd.Flags().String("custom-agent-bearer-token", "", "Bearer token sent to the custom agent endpoint")
+	cmd.Flags().String("custom-agent-bearer-token-file", "", "Path to a file containing the custom agent bearer token")
+	cmd.Flags().StringArrayP("message", "m", nil, "Send this turn instead of reading from standard input (repeatable)")
+	cmd.Flags().Duration("timeout", 2*time.Minute, "Maximum time to wait for each reply (0 waits indefinitely)")
+	cmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	_ = cmd.MarkFlagRequired("url")
+
+	return cmd
+}
+
+// devTurn is a turn of `agent dev` in structured output
+type devTurn struct {
+	Message string `json:"message"`
+	*agentstream.Result
+	Error        string `json:"error,omitempty"`
+	FirstEventMs int64  `json:"first_event_ms,omitempty"`
+	DurationMs   int64  `json:"duration_ms,omitempty"`
+}
+
+// devSession keeps the conversation with the endpoint
+type devSession struct {
+	client   *agentstream.Client
+	timeout  time.Duration
+	chatID   string
+	messages []agentstream.Message
+	// out receives the streamed reply; nil for structured output
+	out io.Writer
+}
+
+func runDev(cmd *cobra.Command, args []string) error {
+	endpoint := strings.TrimSpace(stringFlag(cmd, "url"))
+	if err := validateCustomAgentURL(endpoint); err != nil {
+		return err
+	}
+	bearerToken, err := resolveCustomAgentBearerToken(cmd, nil, false)
+	if err != nil {
+		return err
+	}
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	messages, _ := cmd.Flags().GetStringArray("message")
+
+	session := &devSession{
+		client:  &agentstream.Client{URL: endpoint, BearerToken: bearerToken},
+		timeout: timeout,
+		chatID:  agentstream.NewID(),
+	}
+	if format.IsTable() {
+		session.out = os.Stdout
+	}
+
+	interactive := len(messages) == 0 && stdinIsTTY() && format.IsTable()
+	if interactive {
+		fmt.Printf("Chatting with %s. /reset starts a new conversation, Ctrl-D exits.\n", endpoint)
+	}
+
+	var turns []devTurn
+	next := devTurnSource(messages, interactive)
+	for {
+		message, ok := next()
+		if !ok {
+			break
+		}
+		if message == "/reset" {
+			session.reset()
+			if session.out != nil {
+				fmt.Fprintln(session.out, "Started a new conversation")
+			}
+			continue
+		}
+		turns = append(turns, session.send(cmd.Context(), message))
+	}
+
+	if !format.IsTable() {
+		if err := util.PrintOutput(format, turns); err != nil {
+			return err
+		}
+	}
+	return devTurnsError(turns)
+}
+
+// devTurnSource returns the turns to send: the --message values, or lines
+// read from devInput with a prompt when interactive
+func devTurnSource(messages []string, interactive bool) func() (string, bool) {
+	if len(messages) > 0 {
+		return func() (string, bool) {
+			if len(messages) == 0 {
+				return "", false
+			}
+			message := messages[0]
+			messages = messages[1:]
+			return message, true
+		}
+	}
+
+	scanner := bufio.NewScanner(devInput)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	return func() (string, bool) {
+		for {
+			if interactive {
+				ui.IDColor.Print("> ")
+			}
+			if !scanner.Scan() {
+				if interactive {
+					fmt.Println()
+				}
+				return "", false
+			}
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				return line, true
+			}
+		}
+	}
+}
+
+func (s *devSession) reset() {
+	s.chatID = agentstream.NewID()
+	s.messages = nil
+}
+
+// send sends message as the next user turn and shows the reply as it arrives
+func (s *devSession) send(ctx context.Context, message string) devTurn {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	s.messages = append(s.messages, agentstream.Message{ID: agentstream.NewID(), Role: "user", Content: message})
+	var onEvent func(agentstream.Event)
+	var renderer *devRenderer
+	if s.out != nil {
+		renderer = &devRenderer{out: s.out}
+		onEvent = renderer.render
+	}
+
+	result, err := s.client.Send(ctx, s.chatID, s.messages, onEvent)
+	turn := devTurn{Message: message, Result: result}
+	if err != nil {
+		turn.Error = err.Error()
+		// Leave the failed turn out of the conversation so it can be retried
+		s.messages = s.messages[:len(s.messages)-1]
+	} else {
+		s.messages = append(s.messages, agentstream.Message{ID: agentstream.NewID(), Role: "assistant", Content: result.Text})
+	}
+	if result != nil {
+		turn.FirstEventMs = result.FirstEvent.Milliseconds()
+		turn.DurationMs = result.Duration.Milliseconds()
+	}
+
+	if renderer != nil {
+		renderer.summary(turn)
+	}
+	return turn
+}
+
+// devTurnsError reports failed turns and protocol violations as the error of
+// the command
+func devTurnsError(turns []devTurn) error {
+	failed, violations := 0, 0
+	for _, turn := range turns {
+		if turn.Error != "" {
+			failed++
+		}
+		if turn.Result != nil {
+			violations += len(turn.Violations)
+		}
+	}
+
+	var problems []string
+	if failed > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d turns failed", failed, len(turns)))
+	}
+	if violations > 0 {
+		problems = append(problems, fmt.Sprintf("%d protocol violations", violations))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(problems, ", "))
+}
+
+// devRenderer writes the events of a reply as they arrive
+type devRenderer struct {
+	out io.Writer
+	// midLine is set when the last output did not end a line
+	midLine bool
+	last    agentstream.EventKind
+}
+
+func (r *devRenderer) newline() {
+	if r.midLine {
+		fmt.Fprintln(r.out)
+		r.midLine = false
+	}
+}
+
+// endText records whether text left the cursor in the middle of a line
+func (r *devRenderer) endText(text string) {
+	if text != "" {
+		r.midLine = !strings.HasSuffix(text, "\n")
+	}
+}
+
+func (r *devRenderer) render(event agentstream.Event) {
+	if event.Kind != r.last {
+		r.newline()
+	}
+	r.last = event.Kind
+
+	switch event.Kind {
+	case agentstream.EventText:
+		fmt.Fprint(r.out, event.Text)
+		r.endText(event.Text)
+	case agentstream.EventReasoning:
+		ui.HeaderColor.Fprint(r.out, event.Text)
+		r.endText(event.Text)
+	case agentstream.EventToolCall:
+		ui.IDColor.Fprintf(r.out, "→ %s", event.ToolName)
+		fmt.Fprintf(r.out, " %s\n", compactJSON(event.Value))
+	case agentstream.EventToolResult:
+		ui.IDColor.Fprintf(r.out, "← %s", event.ToolName)
+		fmt.Fprintf(r.out, " %s\n", compactJSON(event.Value))
+	case agentstream.EventToolError:
+		ui.IDColor.Fprintf(r.out, "← %s", event.ToolName)
+		ui.StatusError.Fprintf(r.out, " failed: %s\n", event.Text)
+	case agentstream.EventError:
+		ui.StatusError.Fprintf(r.out, "error: %s\n", event.Text)
+	}
+}
+
+// summary ends the reply with its finish reason, timing and violations
+func (r *devRenderer) summary(turn devTurn) {
+	r.newline()
+	if turn.Error != "" {
+		ui.StatusError.Fprintf(r.out, "✗ %s\n", turn.Error)
+	}
+	result := turn.Result
+	if result == nil {
+		return
+	}
+
+	details := []string{string(result.Format)}
+	if result.FinishReason != "" {
+		details = append(details, "finish: "+result.FinishReason)
+	}
+	if result.FirstEvent > 0 {
+		details = append(details, "first event "+result.FirstEvent.Round(time.Millisecond).String())
+	}
+	details = append(details, result.Duration.Round(time.Millisecond).String())
+	ui.HeaderColor.Fprintf(r.out, "[%s]\n", strings.Join(details, " · "))
+
+	if len(result.Violations) > 0 {
+		ui.StatusError.Fprintf(r.out, "✗ %d protocol violations\n", len(result.Violations))
+		for _, violation := range result.Violations {
+			fmt.Fprintf(r.out, "  - %s\n", violation)
+		}
+	}
+	fmt.Fprintln(r.out)
+}
+
+// compactJSON formats a tool input or output on one line
+func compactJSON(value json.RawMessage) string {
+	var compacted bytes.Buffer
+	text := string(value)
+	if err := json.Compact(&compacted, value); err == nil {
+		text = compacted.String()
+	}
+	if runes := []rune(text); len(runes) > maxToolValueLength {
+		return string(runes[:maxToolValueLength]) + "…"
+	}
+	return text
+}
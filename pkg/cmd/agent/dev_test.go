@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newDevTestServer fakes a custom agent endpoint that answers every turn with
+// reply and records how many messages each request carried
+func newDevTestServer(t *testing.T, reply string) (string, *[]int) {
+	t.Helper()
+	var lengths []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer dev-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		lengths = append(lengths, len(body.Messages))
+		w.Header().Set("x-vercel-ai-data-stream", "v1")
+		_, _ = w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+	forceNonInteractive(t)
+	return server.URL, &lengths
+}
+
+func TestDevRendersTurns(t *testing.T) {
+	url, lengths := newDevTestServer(t, strings.Join([]string{
+		`0:"Let me check."`,
+		`9:{"toolCallId":"call-1","toolName":"weather","args":{"city":"Paris"}}`,
+		`a:{"toolCallId":"call-1","result":{"temperature":21}}`,
+		`0:"It is 21 degrees."`,
+		`d:{"finishReason":"stop"}`,
+	}, "\n"))
+
+	cmd := newDevCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"url": url, "custom-agent-bearer-token": "dev-token"})
+	if err := cmd.Flags().Set("message", "Hi"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Flags().Set("message", "Weather in Paris?"); err != nil {
+		t.Fatal(err)
+	}
+	output, err := captureStdout(t, func() error { return runDev(cmd, nil) })
+	if err != nil {
+		t.Fatalf("runDev() returned error: %v", err)
+	}
+
+	// The second turn carries the first turn and its reply
+	if len(*lengths) != 2 || (*lengths)[0] != 1 || (*lengths)[1] != 3 {
+		t.Fatalf("message counts = %v, want [1 3]", *lengths)
+	}
+	assertContainsInOrder(t, output,
+		"Let me check.\n",
+		`→ weather {"city":"Paris"}`,
+		`← weather {"temperature":21}`,
+		"It is 21 degrees.\n",
+		"[data-stream · finish: stop",
+	)
+	if strings.Contains(output, "protocol violations") {
+		t.Fatalf("unexpected violations in %q", output)
+	}
+}
+
+func TestDevReportsViolations(t *testing.T) {
+	url, _ := newDevTestServer(t, "Hello there\n")
+
+	oldInput := devInput
+	t.Cleanup(func() { devInput = oldInput })
+	devInput = strings.NewReader("Hi\n\n/reset\nHi again\n")
+
+	cmd := newDevCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"url": url, "custom-agent-bearer-token": "dev-token", "json": "true"})
+	output, err := captureStdout(t, func() error { return runDev(cmd, nil) })
+	if err == nil || err.Error() != "4 protocol violations" {
+		t.Fatalf("runDev() error = %v, want 4 protocol violations", err)
+	}
+
+	var turns []struct {
+		Message    string `json:"message"`
+		Format     string `json:"format"`
+		Violations []struct {
+			Line    int    `json:"line"`
+			Message string `json:"message"`
+		} `json:"violations"`
+	}
+	if err := json.Unmarshal([]byte(output), &turns); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", output, err)
+	}
+	if len(turns) != 2 || turns[1].Message != "Hi again" || turns[0].Format != "data-stream" || len(turns[0].Violations) != 2 {
+		t.Fatalf("unexpected turns %+v", turns)
+	}
+	if v := turns[0].Violations[0]; v.Line != 1 || !strings.Contains(v.Message, "is not a data stream part") {
+		t.Fatalf("unexpected violation %+v", v)
+	}
+}
+
+func TestDevReportsFailedTurns(t *testing.T) {
+	url, _ := newDevTestServer(t, "")
+
+	cmd := newDevCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"url": url, "custom-agent-bearer-token": "wrong", "message": "Hi"})
+	output, err := captureStdout(t, func() error { return runDev(cmd, nil) })
+	if err == nil || err.Error() != "1 of 1 turns failed" {
+		t.Fatalf("runDev() error = %v, want a failed turn", err)
+	}
+	if !strings.Contains(output, "agent endpoint returned HTTP 401: unauthorized") {
+		t.Fatalf("unexpected output %q", output)
+	}
+}
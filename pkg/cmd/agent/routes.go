@@ -12,6 +12,7 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	apierrors "github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
 	"github.com/mirako-ai/mirako-cli/pkg/ui"
 	"github.com/mirako-ai/mirako-go/api"
 	"github.com/spf13/cobra"
@@ -66,9 +67,12 @@ func runRoutesList(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
-	useJSON, _ := cmd.Flags().GetBool("json")
-	if useJSON {
-		return printSafeJSON(resp)
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return printSafeOutput(format, resp)
 	}
 	if resp.Data == nil || len(*resp.Data) == 0 {
 		fmt.Println("No agent routes found")
@@ -125,9 +129,12 @@ func runRoutesView(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unexpected response from server: agent route has the wrong route ID")
 	}
 
-	useJSON, _ := cmd.Flags().GetBool("json")
-	if useJSON {
-		return printSafeJSON(resp)
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return printSafeOutput(format, resp)
 	}
 	printAgentRouteDetails(resp.Data)
 	return nil
@@ -153,9 +160,15 @@ func runRoutesRevoke(cmd *cobra.Command, args []string) error {
 	}
 
 	force, _ := cmd.Flags().GetBool("force")
-	useJSON, _ := cmd.Flags().GetBool("json")
-	if useJSON && !force {
-		return fmt.Errorf("--json requires --force for route revocation")
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() && !force {
+		if useJSON, _ := cmd.Flags().GetBool("json"); useJSON {
+			return fmt.Errorf("--json requires --force for route revocation")
+		}
+		return fmt.Errorf("--%s requires --force for route revocation", util.OutputFormatFlag)
 	}
 	if !force {
 		confirmed := false
@@ -193,8 +206,8 @@ func runRoutesRevoke(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unexpected response from server: revoked route has inconsistent lifecycle state")
 	}
 
-	if useJSON {
-		return printSafeJSON(resp)
+	if !format.IsTable() {
+		return printSafeOutput(format, resp)
 	}
 	fmt.Println("Agent route revoked successfully.")
 	fmt.Println()
@@ -245,9 +258,12 @@ func runRoutesCreate(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	useJSON, _ := cmd.Flags().GetBool("json")
-	if useJSON {
-		return printSafeJSON(resp)
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return printSafeOutput(format, resp)
 	}
 
 	printAgentRouteCreateSuccess(resp.Data)
@@ -411,7 +427,7 @@ func sanitizeAgentRouteOutput(value string) string {
 func formatAgentRouteListAPIError(err error, fallback string) error {
 	if apiErr, ok := apierrors.IsAPIError(err); ok {
 		safeError := apierrors.NewAPIError(apiErr.StatusCode, "", apiErr.Context)
-		return errors.New(safeError.GetUserFriendlyMessage())
+		return safeError.UserError()
 	}
 	return errors.New(fallback)
 }
@@ -423,5 +439,9 @@ func formatAgentRouteAPIError(err error, fallback, routeID string) error {
 	if len(routeID) >= 8 {
 		formatted = strings.ReplaceAll(formatted, routeID, "REDACTED")
 	}
+	// Keep the status code for exit codes, but drop the response detail that may contain the route ID
+	if apiErr, ok := apierrors.IsAPIError(err); ok {
+		return apierrors.NewAPIError(apiErr.StatusCode, formatted, apiErr.Context)
+	}
 	return errors.New(formatted)
 }
@@ -0,0 +1,412 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mirako-ai/mirako-cli/internal/config"
+	"github.com/mirako-ai/mirako-go/api"
+	"github.com/mitchellh/go-homedir"
+	"gopkg.in/yaml.v3"
+)
+
+// agentSpec is the declared state of one agent in a spec file. Field names
+// follow the flags of `agent create`. JSON files are parsed as YAML.
+type agentSpec struct {
+	// ID pins the spec to an existing agent, so that it can be renamed.
+	// Without it agents are matched by name.
+	ID                         string      `yaml:"id"`
+	Name                       string      `yaml:"name"`
+	Description                string      `yaml:"description"`
+	Avatar                     string      `yaml:"avatar"`
+	Voice                      string      `yaml:"voice"`
+	Model                      string      `yaml:"model"`
+	RuntimeKind                string      `yaml:"runtime-kind"`
+	Instruction                string      `yaml:"instruction"`
+	InstructionFile            string      `yaml:"instruction-file"`
+	Tools                      *[]any      `yaml:"tools"`
+	ToolsFile                  string      `yaml:"tools-file"`
+	CustomAgentURL             string      `yaml:"custom-agent-url"`
+	CustomAgentProtocol        string      `yaml:"custom-agent-protocol"`
+	CustomAgentBearerTokenFile string      `yaml:"custom-agent-bearer-token-file"`
+	CustomAgentBearerTokenEnv  string      `yaml:"custom-agent-bearer-token-env"`
+	Routes                     []routeSpec `yaml:"routes"`
+
+	// source is the file the spec was loaded from
+	source string
+	// root is the absolute path of the file or directory given to apply that
+	// holds source
+	root string
+	// managesRoutes is set when the spec has a routes key, even an empty one
+	managesRoutes bool
+	// bearerToken is read from the token file or environment variable
+	bearerToken *string
+}
+
+// routeSpec is a route an agent should have, identified by its label
+type routeSpec struct {
+	Label    string `yaml:"label"`
+	ValidFor string `yaml:"valid-for"`
+
+	validitySeconds *int64
+}
+
+// agentSpecExtensions are the files read from a spec directory
+var agentSpecExtensions = []string{".yaml", ".yml", ".json"}
+
+// loadAgentSpecs reads the agents declared in the given files and
+// directories, reading referenced files relative to the spec that names them
+func loadAgentSpecs(paths []string) ([]*agentSpec, error) {
+	var files []string
+	roots := map[string]string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read agent spec: %w", err)
+		}
+		root, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read agent spec: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			roots[path] = root
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read agent spec directory: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && slices.Contains(agentSpecExtensions, strings.ToLower(filepath.Ext(entry.Name()))) {
+				file := filepath.Join(path, entry.Name())
+				files = append(files, file)
+				roots[file] = root
+			}
+		}
+	}
+
+	var specs []*agentSpec
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read agent spec: %w", err)
+		}
+		parsed, err := parseAgentSpecs(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, spec := range parsed {
+			spec.source = file
+			spec.root = roots[file]
+			if err := spec.resolve(filepath.Dir(file)); err != nil {
+				return nil, fmt.Errorf("%s: agent %q: %w", file, spec.Name, err)
+			}
+		}
+		specs = append(specs, parsed...)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no agents found in %s", strings.Join(paths, ", "))
+	}
+
+	names := map[string]string{}
+	ids := map[string]string{}
+	for _, spec := range specs {
+		if previous, ok := names[spec.Name]; ok {
+			return nil, fmt.Errorf("agent %q is declared in both %s and %s", spec.Name, previous, spec.source)
+		}
+		names[spec.Name] = spec.source
+		if spec.ID == "" {
+			continue
+		}
+		if previous, ok := ids[spec.ID]; ok {
+			return nil, fmt.Errorf("agent ID %s is declared in both %s and %s", spec.ID, previous, spec.source)
+		}
+		ids[spec.ID] = spec.source
+	}
+	return specs, nil
+}
+
+// parseAgentSpecs parses the YAML documents in data. Each document is a
+// single agent, a list of agents, or a mapping with an "agents" list.
+func parseAgentSpecs(data []byte) ([]*agentSpec, error) {
+	var specs []*agentSpec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse agent spec: %w", err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		node := doc.Content[0]
+		if node.Kind == yaml.MappingNode {
+			if agents := mappingValue(node, "agents"); agents != nil {
+				node = agents
+			}
+		}
+		var items []*yaml.Node
+		switch node.Kind {
+		case yaml.SequenceNode:
+			items = node.Content
+		case yaml.MappingNode:
+			items = []*yaml.Node{node}
+		default:
+			return nil, fmt.Errorf("line %d: expected an agent or a list of agents", node.Line)
+		}
+
+		for _, item := range items {
+			spec, err := parseAgentSpec(item)
+			if err != nil {
+				return nil, err
+			}
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
+
+func parseAgentSpec(node *yaml.Node) (*agentSpec, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected an agent mapping", node.Line)
+	}
+	if err := checkSpecKeys(node, reflect.TypeOf(agentSpec{})); err != nil {
+		return nil, err
+	}
+	var spec agentSpec
+	if err := node.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	if routes := mappingValue(node, "routes"); routes != nil {
+		spec.managesRoutes = true
+		for _, route := range routes.Content {
+			if err := checkSpecKeys(route, reflect.TypeOf(routeSpec{})); err != nil {
+				return nil, err
+			}
+		}
+	}
+	spec.Name = strings.TrimSpace(spec.Name)
+	if spec.Name == "" {
+		return nil, fmt.Errorf("line %d: agent name is required", node.Line)
+	}
+	return &spec, nil
+}
+
+// checkSpecKeys rejects keys that have no field in typ, so that typos are not
+// silently ignored
+func checkSpecKeys(node *yaml.Node, typ reflect.Type) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var known []string
+	for i := 0; i < typ.NumField(); i++ {
+		if tag := typ.Field(i).Tag.Get("yaml"); tag != "" {
+			known = append(known, tag)
+		}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !slices.Contains(known, key.Value) {
+			return fmt.Errorf("line %d: unknown field %q", key.Line, key.Value)
+		}
+	}
+	return nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// resolve validates the spec and reads the files it refers to. Relative paths
+// are relative to baseDir and ~ is the home directory.
+func (s *agentSpec) resolve(baseDir string) error {
+	resolvePath := func(path string) string {
+		path = strings.TrimSpace(path)
+		if expanded, err := homedir.Expand(path); err == nil {
+			path = expanded
+		}
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(baseDir, path)
+	}
+
+	if strings.TrimSpace(s.Avatar) == "" {
+		return fmt.Errorf("avatar is required")
+	}
+	if strings.TrimSpace(s.Voice) == "" {
+		return fmt.Errorf("voice is required")
+	}
+
+	if s.RuntimeKind == "" {
+		s.RuntimeKind = managedAgentRuntimeKind
+		if s.CustomAgentURL != "" {
+			s.RuntimeKind = customAgentRuntimeKind
+		}
+	}
+	runtimeKind, err := parseRuntimeKind(s.RuntimeKind)
+	if err != nil {
+		return err
+	}
+	s.RuntimeKind = string(runtimeKind)
+
+	switch s.RuntimeKind {
+	case managedAgentRuntimeKind:
+		if s.CustomAgentURL != "" || s.CustomAgentProtocol != "" || s.CustomAgentBearerTokenFile != "" || s.CustomAgentBearerTokenEnv != "" {
+			return fmt.Errorf("custom agent fields do not apply to managed agents")
+		}
+		if s.Instruction != "" && s.InstructionFile != "" {
+			return fmt.Errorf("use either instruction or instruction-file, not both")
+		}
+		if s.InstructionFile != "" {
+			if s.Instruction, err = readInstructionFile(resolvePath(s.InstructionFile)); err != nil {
+				return err
+			}
+		}
+		if strings.TrimSpace(s.Instruction) == "" {
+			return fmt.Errorf("instruction is required. Use instruction or instruction-file")
+		}
+
+		if s.Tools != nil && s.ToolsFile != "" {
+			return fmt.Errorf("use either tools or tools-file, not both")
+		}
+		if s.ToolsFile != "" {
+			data, err := os.ReadFile(resolvePath(s.ToolsFile))
+			if err != nil {
+				return fmt.Errorf("failed to read tools file: %w", err)
+			}
+			var tools []any
+			if err := json.Unmarshal(data, &tools); err != nil {
+				return fmt.Errorf("tools must be a valid JSON array: %w", err)
+			}
+			s.Tools = &tools
+		}
+		if s.Tools == nil || *s.Tools == nil {
+			s.Tools = &[]any{}
+		}
+	case customAgentRuntimeKind:
+		if s.Instruction != "" || s.InstructionFile != "" || s.Tools != nil || s.ToolsFile != "" {
+			return fmt.Errorf("instruction and tools do not apply to custom agents")
+		}
+		if s.CustomAgentURL == "" {
+			return fmt.Errorf("custom-agent-url is required")
+		}
+		if err := validateCustomAgentURL(s.CustomAgentURL); err != nil {
+			return err
+		}
+		if s.CustomAgentProtocol == "" {
+			s.CustomAgentProtocol = customAgentProtocolVercelAISDK
+		}
+		if _, err := parseCustomAgentProtocol(s.CustomAgentProtocol); err != nil {
+			return err
+		}
+
+		switch {
+		case s.CustomAgentBearerTokenFile != "" && s.CustomAgentBearerTokenEnv != "":
+			return fmt.Errorf("use either custom-agent-bearer-token-file or custom-agent-bearer-token-env, not both")
+		case s.CustomAgentBearerTokenFile != "":
+			data, err := os.ReadFile(resolvePath(s.CustomAgentBearerTokenFile))
+			if err != nil {
+				return fmt.Errorf("failed to read custom agent bearer token file: %w", err)
+			}
+			token := strings.TrimSpace(string(data))
+			s.bearerToken = &token
+		case s.CustomAgentBearerTokenEnv != "":
+			token := strings.TrimSpace(os.Getenv(s.CustomAgentBearerTokenEnv))
+			if token == "" {
+				return fmt.Errorf("environment variable %s is not set", s.CustomAgentBearerTokenEnv)
+			}
+			s.bearerToken = &token
+		}
+	}
+
+	labels := map[string]bool{}
+	for i := range s.Routes {
+		route := &s.Routes[i]
+		route.Label = strings.TrimSpace(route.Label)
+		if route.Label == "" {
+			return fmt.Errorf("every route needs a label")
+		}
+		if utf8.RuneCountInString(route.Label) > maxAgentRouteLabelLength {
+			return fmt.Errorf("route label must be at most %d characters", maxAgentRouteLabelLength)
+		}
+		if labels[route.Label] {
+			return fmt.Errorf("route %q is declared twice", route.Label)
+		}
+		labels[route.Label] = true
+		if route.validitySeconds, err = parseValidFor(route.ValidFor); err != nil {
+			return fmt.Errorf("route %q: %w", route.Label, err)
+		}
+	}
+	return nil
+}
+
+// fields returns the declared state for comparison with an existing agent
+func (s *agentSpec) fields() agentFields {
+	fields := agentFields{
+		Name:           &s.Name,
+		Description:    &s.Description,
+		AvatarID:       &s.Avatar,
+		VoiceProfileID: &s.Voice,
+		RuntimeKind:    &s.RuntimeKind,
+	}
+	if s.Model != "" {
+		fields.Model = &s.Model
+	}
+	if s.RuntimeKind == managedAgentRuntimeKind {
+		fields.Instruction = &s.Instruction
+		fields.Tools = s.Tools
+	} else {
+		fields.CustomAgentURL = &s.CustomAgentURL
+		fields.CustomAgentProtocol = &s.CustomAgentProtocol
+		fields.CustomAgentBearerToken = s.bearerToken
+	}
+	return fields
+}
+
+// createBody returns the request that creates the agent
+func (s *agentSpec) createBody() api.CreateAgentJSONRequestBody {
+	runtimeKind := api.CreateAgentInputRuntimeKind(s.RuntimeKind)
+	model := defaultIfEmpty(s.Model, config.DefaultInteractiveModel)
+	body := api.CreateAgentJSONRequestBody{
+		Name:           s.Name,
+		AvatarId:       strings.TrimSpace(s.Avatar),
+		VoiceProfileId: strings.TrimSpace(s.Voice),
+		Model:          &model,
+		RuntimeKind:    &runtimeKind,
+	}
+	if description := strings.TrimSpace(s.Description); description != "" {
+		body.Description = &description
+	}
+	if s.RuntimeKind == managedAgentRuntimeKind {
+		body.Instruction = &s.Instruction
+		body.Tools = s.Tools
+	} else {
+		protocol := api.CreateAgentInputCustomAgentProtocol(s.CustomAgentProtocol)
+		body.CustomAgentUrl = &s.CustomAgentURL
+		body.CustomAgentProtocol = &protocol
+		if s.bearerToken != nil && *s.bearerToken != "" {
+			body.CustomAgentBearerToken = s.bearerToken
+		}
+	}
+	return body
+}
@@ -0,0 +1,480 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-go/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	managedAgentFlags = []string{"instruction", "instruction-file", "tools", "tools-file"}
+	customAgentFlags  = []string{"custom-agent-url", "custom-agent-bearer-token", "custom-agent-bearer-token-file", "custom-agent-protocol"}
+)
+
+// runEditor opens path in the user's editor and waits for it to exit
+var runEditor = func(path string) error {
+	editor := strings.Fields(editorCommand())
+	editorCmd := exec.Command(editor[0], append(editor[1:], path)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor[0], err)
+	}
+	return nil
+}
+
+func editorCommand() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+			return value
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+func newUpdateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update [agent-id]",
+		Short: "Update an agent",
+		Long: `Update a persistent agent configuration in place, keeping its ID and routes.
+
+Only the fields given as flags are changed, and only those that differ from the
+current configuration are sent. Instruction and tools apply to managed agents,
+the custom agent flags to custom agents; change --runtime-kind to switch
+between them. An empty --custom-agent-bearer-token-file removes the token.`,
+		Example: `  mirako agent update agent-123 --instruction-file prompt.md
+  mirako agent update agent-123 --custom-agent-bearer-token-file token.txt
+  mirako agent update agent-123 --name "Support bot" --voice voice-456`,
+		Args: cobra.ExactArgs(1),
+		RunE: runUpdate,
+	}
+
+	cmd.Flags().StringP("name", "n", "", "Name for the agent")
+	cmd.Flags().StringP("description", "d", "", "Description for the agent (empty to remove it)")
+	cmd.Flags().StringP("avatar", "a", "", "Avatar ID to use")
+	cmd.Flags().StringP("voice", "v", "", "Voice profile ID to use")
+	cmd.Flags().StringP("model", "m", "", "Interactive model to use")
+	cmd.Flags().String("runtime-kind", "", "Runtime kind for the agent (managed_agent or custom_agent)")
+	cmd.Flags().StringP("instruction", "i", "", "Instruction prompt text for managed agents")
+	cmd.Flags().String("instruction-file", "", "Path to a .txt, .md, or .markdown file containing the managed-agent instruction prompt")
+	cmd.Flags().String("tools", "", "Tools to use for the managed agent (JSON array string)")
+	cmd.Flags().String("tools-file", "", "Path to a JSON file containing a managed-agent tools array")
+	cmd.Flags().String("custom-agent-url", "", "Custom agent endpoint URL")
+	cmd.Flags().String("custom-agent-bearer-token", "", "Bearer token sent to the custom agent endpoint")
+	cmd.Flags().String("custom-agent-bearer-token-file", "", "Path to a file containing the custom agent bearer token")
+	cmd.Flags().String("custom-agent-protocol", "", "Custom agent streaming protocol")
+	cmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runUpdate(cmd *cobra.Command, args []string) error {
+	agentID := args[0]
+	if !updateHasFlags(cmd) {
+		return fmt.Errorf("nothing to update. Use flags such as --name or --instruction-file, or 'mirako agent edit %s' to edit the instruction", agentID)
+	}
+
+	c, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	current, err := c.GetAgent(cmd.Context(), agentID)
+	if err != nil {
+		return formatAPIError(err, "failed to get agent")
+	}
+	if current == nil {
+		return fmt.Errorf("unexpected response from server")
+	}
+
+	body, changed, err := buildUpdateAgentBody(cmd, current.Data)
+	if err != nil {
+		return err
+	}
+	return sendAgentUpdate(cmd, c, current.Data, body, changed)
+}
+
+// sendAgentUpdate sends body unless nothing changed and prints the result
+func sendAgentUpdate(cmd *cobra.Command, c *client.Client, current api.AgentResponse, body api.UpdateAgentJSONRequestBody, changed []string) error {
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	agent := current
+	if len(changed) > 0 {
+		resp, err := c.UpdateAgent(cmd.Context(), current.Id, body)
+		if err != nil {
+			return formatAPIError(err, "failed to update agent")
+		}
+		if resp == nil {
+			return fmt.Errorf("unexpected response from server")
+		}
+		agent = resp.Data
+	}
+
+	if !format.IsTable() {
+		return printSafeOutput(format, api.UpdateAgentApiResponseBody{Data: agent})
+	}
+	if len(changed) == 0 {
+		fmt.Printf("No changes to agent %s\n", current.Id)
+		return nil
+	}
+	fmt.Printf("Agent %s updated: %s\n", agent.Id, strings.Join(changed, ", "))
+	return nil
+}
+
+func updateHasFlags(cmd *cobra.Command) bool {
+	for _, name := range []string{"name", "description", "avatar", "voice", "model", "runtime-kind"} {
+		if flagChanged(cmd, name) {
+			return true
+		}
+	}
+	return anyFlagChanged(cmd, managedAgentFlags) || anyFlagChanged(cmd, customAgentFlags)
+}
+
+func anyFlagChanged(cmd *cobra.Command, names []string) bool {
+	for _, name := range names {
+		if flagChanged(cmd, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// agentFields holds the desired values of an agent's fields. Nil fields are
+// left as they are.
+type agentFields struct {
+	Name                   *string
+	Description            *string
+	AvatarID               *string
+	VoiceProfileID         *string
+	Model                  *string
+	RuntimeKind            *string
+	Instruction            *string
+	Tools                  *[]any
+	CustomAgentURL         *string
+	CustomAgentBearerToken *string
+	CustomAgentProtocol    *string
+}
+
+// buildUpdateAgentBody compares the flags with the current agent and returns
+// a body holding only the fields that differ, along with their names
+func buildUpdateAgentBody(cmd *cobra.Command, current api.AgentResponse) (api.UpdateAgentJSONRequestBody, []string, error) {
+	var fields agentFields
+
+	runtimeKind := current.RuntimeKind
+	if flagChanged(cmd, "runtime-kind") {
+		parsed, err := parseRuntimeKind(stringFlag(cmd, "runtime-kind"))
+		if err != nil {
+			return api.UpdateAgentJSONRequestBody{}, nil, err
+		}
+		runtimeKind = string(parsed)
+		fields.RuntimeKind = &runtimeKind
+	}
+	switch {
+	case runtimeKind == managedAgentRuntimeKind && anyFlagChanged(cmd, customAgentFlags):
+		return api.UpdateAgentJSONRequestBody{}, nil, fmt.Errorf("custom agent flags do not apply to managed agents. Use --runtime-kind %s to switch the agent to a custom agent", customAgentRuntimeKind)
+	case runtimeKind == customAgentRuntimeKind && anyFlagChanged(cmd, managedAgentFlags):
+		return api.UpdateAgentJSONRequestBody{}, nil, fmt.Errorf("instruction and tools do not apply to custom agents. Use --runtime-kind %s to switch the agent to a managed agent", managedAgentRuntimeKind)
+	}
+
+	for _, field := range []struct {
+		flag   string
+		target **string
+	}{
+		{"name", &fields.Name},
+		{"description", &fields.Description},
+		{"avatar", &fields.AvatarID},
+		{"voice", &fields.VoiceProfileID},
+		{"model", &fields.Model},
+		{"custom-agent-url", &fields.CustomAgentURL},
+		{"custom-agent-protocol", &fields.CustomAgentProtocol},
+	} {
+		if flagChanged(cmd, field.flag) {
+			value := strings.TrimSpace(stringFlag(cmd, field.flag))
+			*field.target = &value
+		}
+	}
+
+	if flagChanged(cmd, "instruction") || flagChanged(cmd, "instruction-file") {
+		instruction, err := resolveInstruction(cmd)
+		if err != nil {
+			return api.UpdateAgentJSONRequestBody{}, nil, err
+		}
+		fields.Instruction = &instruction
+	}
+	if flagChanged(cmd, "tools") || flagChanged(cmd, "tools-file") {
+		tools, err := resolveTools(cmd)
+		if err != nil {
+			return api.UpdateAgentJSONRequestBody{}, nil, err
+		}
+		fields.Tools = &tools
+	}
+	if flagChanged(cmd, "custom-agent-bearer-token") || flagChanged(cmd, "custom-agent-bearer-token-file") {
+		bearerToken, err := resolveCustomAgentBearerToken(cmd, nil, false)
+		if err != nil {
+			return api.UpdateAgentJSONRequestBody{}, nil, err
+		}
+		fields.CustomAgentBearerToken = &bearerToken
+	}
+
+	return diffAgent(current, fields)
+}
+
+// diffAgent validates the desired fields and returns an update body holding
+// the ones that differ from current, along with their names
+func diffAgent(current api.AgentResponse, desired agentFields) (api.UpdateAgentJSONRequestBody, []string, error) {
+	var body api.UpdateAgentJSONRequestBody
+	var changed []string
+
+	runtimeKind := current.RuntimeKind
+	if desired.RuntimeKind != nil {
+		parsed, err := parseRuntimeKind(*desired.RuntimeKind)
+		if err != nil {
+			return body, nil, err
+		}
+		if string(parsed) != current.RuntimeKind {
+			kind := api.UpdateAgentInputRuntimeKind(parsed)
+			body.RuntimeKind = &kind
+			changed = append(changed, "runtime kind")
+		}
+		runtimeKind = string(parsed)
+	}
+
+	for _, field := range []struct {
+		label   string
+		value   *string
+		current string
+		target  **string
+	}{
+		{"name", desired.Name, current.Name, &body.Name},
+		{"avatar", desired.AvatarID, current.AvatarId, &body.AvatarId},
+		{"voice profile", desired.VoiceProfileID, current.VoiceProfileId, &body.VoiceProfileId},
+		{"model", desired.Model, current.Model, &body.Model},
+	} {
+		if field.value == nil {
+			continue
+		}
+		value := strings.TrimSpace(*field.value)
+		if value == "" {
+			return body, nil, fmt.Errorf("%s cannot be empty", field.label)
+		}
+		if value != field.current {
+			*field.target = &value
+			changed = append(changed, field.label)
+		}
+	}
+
+	if desired.Description != nil {
+		description := strings.TrimSpace(*desired.Description)
+		if description != optionalString(current.Description) {
+			body.Description = &description
+			changed = append(changed, "description")
+		}
+	}
+
+	var fields []string
+	var err error
+	switch runtimeKind {
+	case managedAgentRuntimeKind:
+		fields, err = diffManagedAgent(current, desired, &body)
+	case customAgentRuntimeKind:
+		fields, err = diffCustomAgent(current, desired, &body)
+	default:
+		err = fmt.Errorf("unsupported runtime kind %q", runtimeKind)
+	}
+	if err != nil {
+		return body, nil, err
+	}
+	return body, append(changed, fields...), nil
+}
+
+func diffManagedAgent(current api.AgentResponse, desired agentFields, body *api.UpdateAgentJSONRequestBody) ([]string, error) {
+	var changed []string
+
+	switching := current.RuntimeKind != managedAgentRuntimeKind
+	if desired.Instruction != nil {
+		instruction := *desired.Instruction
+		if strings.TrimSpace(instruction) == "" {
+			return nil, fmt.Errorf("instruction cannot be empty")
+		}
+		if switching || instruction != optionalString(current.Instruction) {
+			body.Instruction = &instruction
+			changed = append(changed, "instruction")
+		}
+	} else if switching && strings.TrimSpace(optionalString(current.Instruction)) == "" {
+		return nil, fmt.Errorf("instruction is required for a managed agent")
+	}
+
+	if desired.Tools != nil {
+		tools := *desired.Tools
+		if switching || !sameTools(tools, current.Tools) {
+			body.Tools = &tools
+			changed = append(changed, "tools")
+		}
+	}
+	return changed, nil
+}
+
+func diffCustomAgent(current api.AgentResponse, desired agentFields, body *api.UpdateAgentJSONRequestBody) ([]string, error) {
+	var changed []string
+
+	switching := current.RuntimeKind != customAgentRuntimeKind
+	if desired.CustomAgentURL != nil {
+		customAgentURL := strings.TrimSpace(*desired.CustomAgentURL)
+		if err := validateCustomAgentURL(customAgentURL); err != nil {
+			return nil, err
+		}
+		if switching || customAgentURL != optionalString(current.CustomAgentUrl) {
+			body.CustomAgentUrl = &customAgentURL
+			changed = append(changed, "custom agent URL")
+		}
+	} else if switching && optionalString(current.CustomAgentUrl) == "" {
+		return nil, fmt.Errorf("custom agent URL is required for a custom agent")
+	}
+
+	// The current token is never returned, so a given token is always sent
+	if desired.CustomAgentBearerToken != nil {
+		bearerToken := *desired.CustomAgentBearerToken
+		body.CustomAgentBearerToken = &bearerToken
+		changed = append(changed, "custom agent bearer token")
+	}
+
+	protocol := optionalString(current.CustomAgentProtocol)
+	if desired.CustomAgentProtocol != nil {
+		protocol = *desired.CustomAgentProtocol
+	}
+	if switching && strings.TrimSpace(protocol) == "" {
+		protocol = customAgentProtocolVercelAISDK
+	}
+	if desired.CustomAgentProtocol != nil || switching {
+		parsed, err := parseCustomAgentProtocol(protocol)
+		if err != nil {
+			return nil, err
+		}
+		if switching || string(parsed) != optionalString(current.CustomAgentProtocol) {
+			customProtocol := api.UpdateAgentInputCustomAgentProtocol(parsed)
+			body.CustomAgentProtocol = &customProtocol
+			changed = append(changed, "custom agent protocol")
+		}
+	}
+	return changed, nil
+}
+
+// sameTools compares tools as JSON, so that numbers decoded from the API and
+// from a file compare equal
+func sameTools(tools []any, current *[]any) bool {
+	currentTools := []any{}
+	if current != nil {
+		currentTools = *current
+	}
+	a, errA := json.Marshal(tools)
+	b, errB := json.Marshal(currentTools)
+	if errA != nil || errB != nil {
+		return false
+	}
+	var left, right any
+	if json.Unmarshal(a, &left) != nil || json.Unmarshal(b, &right) != nil {
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func newEditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit [agent-id]",
+		Short: "Edit the instruction of an agent",
+		Long: `Open the instruction of a managed agent in your editor and save the result.
+
+The editor is taken from $VISUAL or $EDITOR and defaults to vi. The agent is
+only updated when the instruction was changed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runEdit,
+	}
+
+	cmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runEdit(cmd *cobra.Command, args []string) error {
+	agentID := args[0]
+	if !stdinIsTTY() {
+		return fmt.Errorf("agent edit needs an interactive terminal. Use 'mirako agent update %s --instruction-file <path>' instead", agentID)
+	}
+
+	c, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	current, err := c.GetAgent(cmd.Context(), agentID)
+	if err != nil {
+		return formatAPIError(err, "failed to get agent")
+	}
+	if current == nil {
+		return fmt.Errorf("unexpected response from server")
+	}
+	if current.Data.RuntimeKind != managedAgentRuntimeKind {
+		return fmt.Errorf("agent %s is a custom agent and has no instruction. Use 'mirako agent update' to change its endpoint", agentID)
+	}
+
+	original := optionalString(current.Data.Instruction)
+	instruction, err := editText(original, "mirako-agent-*.md")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(instruction) == "" {
+		return fmt.Errorf("instruction is empty, agent %s was not changed", agentID)
+	}
+
+	var body api.UpdateAgentJSONRequestBody
+	var changed []string
+	if instruction != original {
+		body.Instruction = &instruction
+		changed = append(changed, "instruction")
+	}
+	return sendAgentUpdate(cmd, c, current.Data, body, changed)
+}
+
+// editText lets the user edit text in a temporary file named after pattern
+func editText(text, pattern string) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := file.Name()
+	defer os.Remove(path)
+
+	_, err = file.WriteString(text)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := runEditor(path); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited file: %w", err)
+	}
+	return string(data), nil
+}
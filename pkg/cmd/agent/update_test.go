@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newUpdateTestServer serves agent for GET requests and records the bodies of
+// PATCH requests
+func newUpdateTestServer(t *testing.T, agentID, agentJSON string) *[]map[string]any {
+	t.Helper()
+	var patches []map[string]any
+	server := newAgentTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/agents/"+agentID {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, fmt.Sprintf(`{"data":%s}`, agentJSON))
+		case http.MethodPatch:
+			if !assertRequest(t, r, http.MethodPatch, "/v1/agents/"+agentID) {
+				http.Error(w, "unexpected request", http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(r.Body)
+			var body map[string]any
+			if err := json.Unmarshal(data, &body); err != nil {
+				t.Errorf("invalid PATCH body %q: %v", data, err)
+			}
+			patches = append(patches, body)
+			writeJSON(w, http.StatusOK, fmt.Sprintf(`{"data":%s}`, agentJSON))
+		default:
+			http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+		}
+	})
+	configureAgentTest(t, server.URL)
+	return &patches
+}
+
+func TestUpdateSendsOnlyChangedFields(t *testing.T) {
+	forceNonInteractive(t)
+	patches := newUpdateTestServer(t, "agent-1", testAgentJSON)
+
+	instructionFile := filepath.Join(t.TempDir(), "prompt.md")
+	if err := os.WriteFile(instructionFile, []byte("Be brief"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newUpdateCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{
+		"name":             "Agent One",
+		"description":      "",
+		"instruction-file": instructionFile,
+		"tools":            `[{"name":"search","type":"function"}]`,
+	})
+	output, err := captureStdout(t, func() error { return runUpdate(cmd, []string{"agent-1"}) })
+	if err != nil {
+		t.Fatalf("runUpdate() returned error: %v", err)
+	}
+
+	want := []map[string]any{{"description": "", "instruction": "Be brief"}}
+	if !reflect.DeepEqual(*patches, want) {
+		t.Fatalf("PATCH bodies = %v, want %v", *patches, want)
+	}
+	if !strings.Contains(output, "Agent agent-1 updated: description, instruction") {
+		t.Fatalf("unexpected output %q", output)
+	}
+}
+
+func TestUpdateWithoutChangesSkipsRequest(t *testing.T) {
+	forceNonInteractive(t)
+	patches := newUpdateTestServer(t, "agent-1", testAgentJSON)
+
+	cmd := newUpdateCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"name": "Agent One", "instruction": "Be helpful"})
+	output, err := captureStdout(t, func() error { return runUpdate(cmd, []string{"agent-1"}) })
+	if err != nil {
+		t.Fatalf("runUpdate() returned error: %v", err)
+	}
+	if len(*patches) != 0 {
+		t.Fatalf("expected no PATCH request, got %v", *patches)
+	}
+	if !strings.Contains(output, "No changes to agent agent-1") {
+		t.Fatalf("unexpected output %q", output)
+	}
+}
+
+func TestUpdateRotatesCustomAgentBearerToken(t *testing.T) {
+	forceNonInteractive(t)
+	patches := newUpdateTestServer(t, "custom-agent-1", testCustomAgentWithSecretJSON)
+
+	tokenFile := filepath.Join(t.TempDir(), "token.txt")
+	if err := os.WriteFile(tokenFile, []byte("rotated-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newUpdateCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"custom-agent-bearer-token-file": tokenFile, "json": "true"})
+	output, err := captureStdout(t, func() error { return runUpdate(cmd, []string{"custom-agent-1"}) })
+	if err != nil {
+		t.Fatalf("runUpdate() returned error: %v", err)
+	}
+
+	want := []map[string]any{{"custom_agent_bearer_token": "rotated-token"}}
+	if !reflect.DeepEqual(*patches, want) {
+		t.Fatalf("PATCH bodies = %v, want %v", *patches, want)
+	}
+	assertNoSecret(t, output)
+	if strings.Contains(output, "rotated-token") {
+		t.Fatalf("output leaked the new bearer token: %q", output)
+	}
+}
+
+func TestUpdateValidation(t *testing.T) {
+	forceNonInteractive(t)
+
+	tests := []struct {
+		name      string
+		agentID   string
+		agentJSON string
+		flags     map[string]string
+		wantErr   string
+	}{
+		{
+			name:      "custom flags on managed agent",
+			agentID:   "agent-1",
+			agentJSON: testAgentJSON,
+			flags:     map[string]string{"custom-agent-url": "https://agent.example.test"},
+			wantErr:   "custom agent flags do not apply to managed agents",
+		},
+		{
+			name:      "instruction on custom agent",
+			agentID:   "custom-agent-1",
+			agentJSON: testCustomAgentJSON,
+			flags:     map[string]string{"instruction": "Be helpful"},
+			wantErr:   "instruction and tools do not apply to custom agents",
+		},
+		{
+			name:      "switch to managed without instruction",
+			agentID:   "custom-agent-1",
+			agentJSON: testCustomAgentJSON,
+			flags:     map[string]string{"runtime-kind": "managed_agent"},
+			wantErr:   "instruction is required for a managed agent",
+		},
+		{
+			name:      "empty name",
+			agentID:   "agent-1",
+			agentJSON: testAgentJSON,
+			flags:     map[string]string{"name": " "},
+			wantErr:   "name cannot be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patches := newUpdateTestServer(t, tt.agentID, tt.agentJSON)
+			cmd := newUpdateCmd()
+			cmd.SetContext(context.Background())
+			setFlags(t, cmd, tt.flags)
+			err := runUpdate(cmd, []string{tt.agentID})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("runUpdate() error = %v, want %q", err, tt.wantErr)
+			}
+			if len(*patches) != 0 {
+				t.Fatalf("expected no PATCH request, got %v", *patches)
+			}
+		})
+	}
+
+	cmd := newUpdateCmd()
+	if err := runUpdate(cmd, []string{"agent-1"}); err == nil || !strings.Contains(err.Error(), "nothing to update") {
+		t.Fatalf("runUpdate() without flags error = %v", err)
+	}
+}
+
+func TestEditUpdatesInstruction(t *testing.T) {
+	oldTTY, oldEditor := stdinIsTTY, runEditor
+	t.Cleanup(func() { stdinIsTTY, runEditor = oldTTY, oldEditor })
+	stdinIsTTY = func() bool { return true }
+
+	patches := newUpdateTestServer(t, "agent-1", testAgentJSON)
+	var edited string
+	runEditor = func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		edited = string(data)
+		return os.WriteFile(path, []byte("Be helpful and brief\n"), 0600)
+	}
+
+	cmd := newEditCmd()
+	cmd.SetContext(context.Background())
+	if _, err := captureStdout(t, func() error { return runEdit(cmd, []string{"agent-1"}) }); err != nil {
+		t.Fatalf("runEdit() returned error: %v", err)
+	}
+	if edited != "Be helpful" {
+		t.Fatalf("editor was opened with %q, want the current instruction", edited)
+	}
+	want := []map[string]any{{"instruction": "Be helpful and brief\n"}}
+	if !reflect.DeepEqual(*patches, want) {
+		t.Fatalf("PATCH bodies = %v, want %v", *patches, want)
+	}
+
+	// Saving the file unchanged does not update the agent
+	*patches = nil
+	runEditor = func(string) error { return nil }
+	output, err := captureStdout(t, func() error { return runEdit(cmd, []string{"agent-1"}) })
+	if err != nil {
+		t.Fatalf("runEdit() returned error: %v", err)
+	}
+	if len(*patches) != 0 || !strings.Contains(output, "No changes") {
+		t.Fatalf("expected no update, got %v and %q", *patches, output)
+	}
+}
@@ -0,0 +1,297 @@
+package agent
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/agentstream"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
+	"github.com/mirako-ai/mirako-go/api"
+	"github.com/spf13/cobra"
+)
+
+// Defaults of the conformance suite, also used by `agent create --verify`
+const (
+	defaultVerifyMaxFirstEvent = 10 * time.Second
+	defaultVerifyTimeout       = 2 * time.Minute
+)
+
+func newVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that a custom agent endpoint follows the streaming protocol",
+		Long: `Run a suite of conversations against a custom agent endpoint and report
+whether it follows the streaming protocol, before an agent points at it:
+
+  simple reply       a greeting is streamed with valid framing and headers
+  tool call          tool calls carry object inputs and results
+  long stream        a long reply arrives as a stream of text parts
+  client disconnect  the endpoint still answers after a reply is dropped
+  auth rejection     requests without the bearer token get 401 or 403
+
+Every reply must start streaming within --max-first-event. The tool call check
+is skipped when the agent makes no tool call for --tool-prompt, and the auth
+check when no bearer token is given.
+
+The command fails when a check fails. --junit writes the report as JUnit XML
+for CI.`,
+		Example: `  mirako agent verify --url http://localhost:3000/api/chat --custom-agent-bearer-token-file token.txt
+  mirako agent verify --url https://agent.example.com/api/chat --junit report.xml`,
+		Args: cobra.NoArgs,
+		RunE: runVerify,
+	}
+
+	cmd.Flags().String("url", "", "Custom agent endpoint URL")
+	cmd.Flags().String("custom-agent-bearer-token", "", "Bearer token sent to the custom agent endpoint")
+	cmd.Flags().String("custom-agent-bearer-token-file", "", "Path to a file containing the custom agent bearer token")
+	cmd.Flags().String("custom-agent-protocol", customAgentProtocolVercelAISDK, "Custom agent streaming protocol")
+	cmd.Flags().String("tool-prompt", agentstream.DefaultToolPrompt, "Message that makes the agent call a tool")
+	cmd.Flags().Duration("max-first-event", defaultVerifyMaxFirstEvent, "Longest a reply may take to start streaming (0 disables the check)")
+	cmd.Flags().Duration("timeout", defaultVerifyTimeout, "Maximum time to wait for each reply (0 waits indefinitely)")
+	cmd.Flags().String("junit", "", "Also write the report as JUnit XML to this file (- for stdout)")
+	cmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	_ = cmd.MarkFlagRequired("url")
+
+	return cmd
+}
+
+// verifyCheck is a check of `agent verify` in structured output
+type verifyCheck struct {
+	agentstream.CheckResult
+	DurationMs int64 `json:"duration_ms"`
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	endpoint := strings.TrimSpace(stringFlag(cmd, "url"))
+	if err := validateCustomAgentURL(endpoint); err != nil {
+		return err
+	}
+	protocol, err := parseCustomAgentProtocol(stringFlag(cmd, "custom-agent-protocol"))
+	if err != nil {
+		return err
+	}
+	bearerToken, err := resolveCustomAgentBearerToken(cmd, nil, false)
+	if err != nil {
+		return err
+	}
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	junitPath := strings.TrimSpace(stringFlag(cmd, "junit"))
+	if media.IsStdio(junitPath) && !format.IsTable() {
+		return fmt.Errorf("--junit - writes the report to stdout and can't be combined with --json")
+	}
+
+	out := util.ProgressWriter(format)
+	if media.IsStdio(junitPath) {
+		out = os.Stderr
+	}
+	start := time.Now()
+	results := runVerifySuite(cmd, endpoint, string(protocol), bearerToken, out)
+
+	if junitPath != "" {
+		data, err := junitReport(endpoint, string(protocol), start, results)
+		if err != nil {
+			return err
+		}
+		if err := media.WriteFile(junitPath, data); err != nil {
+			return err
+		}
+	}
+	if !format.IsTable() {
+		checks := make([]verifyCheck, 0, len(results))
+		for _, result := range results {
+			checks = append(checks, verifyCheck{CheckResult: result, DurationMs: result.Duration.Milliseconds()})
+		}
+		if err := util.PrintOutput(format, checks); err != nil {
+			return err
+		}
+	}
+	return verifyError(results)
+}
+
+// runVerifySuite runs the conformance suite against endpoint, writing each
+// result to out as it finishes. Commands without the suite's flags use the
+// defaults.
+func runVerifySuite(cmd *cobra.Command, endpoint, protocol, bearerToken string, out io.Writer) []agentstream.CheckResult {
+	options := agentstream.SuiteOptions{
+		MaxFirstEvent: defaultVerifyMaxFirstEvent,
+		Timeout:       defaultVerifyTimeout,
+		ToolPrompt:    agentstream.DefaultToolPrompt,
+	}
+	if cmd.Flags().Lookup("tool-prompt") != nil {
+		options.ToolPrompt = stringFlag(cmd, "tool-prompt")
+		options.MaxFirstEvent, _ = cmd.Flags().GetDuration("max-first-event")
+		options.Timeout, _ = cmd.Flags().GetDuration("timeout")
+	}
+
+	fmt.Fprintf(out, "Verifying %s (%s)\n", endpoint, protocol)
+	client := &agentstream.Client{URL: endpoint, BearerToken: bearerToken}
+	results := agentstream.RunSuite(cmd.Context(), client, options, func(result agentstream.CheckResult) {
+		printCheckResult(out, result)
+	})
+	fmt.Fprintln(out, verifySummary(results))
+	return results
+}
+
+func printCheckResult(out io.Writer, result agentstream.CheckResult) {
+	duration := result.Duration.Round(time.Millisecond)
+	switch result.Status {
+	case agentstream.CheckPassed:
+		ui.StatusReady.Fprint(out, "PASS")
+		fmt.Fprintf(out, "  %s (%s)\n", result.Name, duration)
+	case agentstream.CheckSkipped:
+		ui.StatusBuilding.Fprint(out, "SKIP")
+		fmt.Fprintf(out, "  %s: %s\n", result.Name, result.Reason)
+	default:
+		ui.StatusError.Fprint(out, "FAIL")
+		fmt.Fprintf(out, "  %s (%s)\n", result.Name, duration)
+		for _, failure := range result.Failures {
+			fmt.Fprintf(out, "      - %s\n", failure)
+		}
+	}
+}
+
+func countChecks(results []agentstream.CheckResult, status agentstream.CheckStatus) int {
+	count := 0
+	for _, result := range results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+func verifySummary(results []agentstream.CheckResult) string {
+	return fmt.Sprintf("%d passed, %d failed, %d skipped",
+		countChecks(results, agentstream.CheckPassed),
+		countChecks(results, agentstream.CheckFailed),
+		countChecks(results, agentstream.CheckSkipped))
+}
+
+func verifyError(results []agentstream.CheckResult) error {
+	if failed := countChecks(results, agentstream.CheckFailed); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	return nil
+}
+
+// verifyCreateBody runs the conformance suite against the endpoint of a
+// custom agent before `agent create --verify` creates it
+func verifyCreateBody(cmd *cobra.Command, body api.CreateAgentJSONRequestBody) error {
+	if body.RuntimeKind == nil || *body.RuntimeKind != api.CreateAgentInputRuntimeKindCustomAgent {
+		return fmt.Errorf("--verify only applies to custom agents")
+	}
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	protocol := customAgentProtocolVercelAISDK
+	if body.CustomAgentProtocol != nil {
+		protocol = string(*body.CustomAgentProtocol)
+	}
+	out := util.ProgressWriter(format)
+	results := runVerifySuite(cmd, optionalString(body.CustomAgentUrl), protocol, optionalString(body.CustomAgentBearerToken), out)
+	fmt.Fprintln(out)
+	if err := verifyError(results); err != nil {
+		return fmt.Errorf("the agent was not created: %w. Run 'mirako agent verify' for the full set of options", err)
+	}
+	return nil
+}
+
+// JUnit XML report, in the schema CI systems read
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func junitReport(endpoint, protocol string, start time.Time, results []agentstream.CheckResult) ([]byte, error) {
+	var total time.Duration
+	suite := junitTestSuite{
+		Name:      "custom agent protocol",
+		Tests:     len(results),
+		Failures:  countChecks(results, agentstream.CheckFailed),
+		Skipped:   countChecks(results, agentstream.CheckSkipped),
+		Timestamp: start.UTC().Format(time.RFC3339),
+		Properties: []junitProperty{
+			{Name: "url", Value: endpoint},
+			{Name: "protocol", Value: protocol},
+		},
+	}
+	for _, result := range results {
+		total += result.Duration
+		testCase := junitTestCase{Name: result.Name, Classname: "mirako.agent.verify", Time: junitSeconds(result.Duration)}
+		switch result.Status {
+		case agentstream.CheckFailed:
+			testCase.Failure = &junitFailure{Message: result.Failures[0], Text: strings.Join(result.Failures, "\n")}
+		case agentstream.CheckSkipped:
+			testCase.Skipped = &junitSkipped{Message: result.Reason}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Time = junitSeconds(total)
+
+	report := junitTestSuites{
+		Name:     "mirako agent verify",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
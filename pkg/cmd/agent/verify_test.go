@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyWritesJUnitReport(t *testing.T) {
+	// Replies are whole and unframed, and any bearer token is accepted
+	url, _ := newDevTestServer(t, "Hello there\n")
+
+	junitPath := filepath.Join(t.TempDir(), "report.xml")
+	cmd := newVerifyCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"url": url, "custom-agent-bearer-token": "dev-token", "junit": junitPath, "timeout": "5s"})
+	output, err := captureStdout(t, func() error { return runVerify(cmd, nil) })
+	if err == nil || err.Error() != "4 of 5 checks failed" {
+		t.Fatalf("runVerify() error = %v, want 4 failed checks", err)
+	}
+	assertContainsInOrder(t, output,
+		"Verifying "+url+" (vercel_ai_sdk)",
+		"FAIL  simple reply",
+		`      - line 1: "Hello there" is not a data stream part`,
+		"FAIL  tool call",
+		"FAIL  long stream",
+		"FAIL  client disconnect",
+		"PASS  auth rejection",
+		"1 passed, 4 failed, 0 skipped",
+	)
+
+	data, err := os.ReadFile(junitPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("invalid JUnit report %s: %v", data, err)
+	}
+	if report.Tests != 5 || report.Failures != 4 || len(report.Suites) != 1 || len(report.Suites[0].Cases) != 5 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	simpleReply := report.Suites[0].Cases[0]
+	if simpleReply.Name != "simple reply" || simpleReply.Failure == nil || !strings.Contains(simpleReply.Failure.Text, "stream ended without a finish message") {
+		t.Fatalf("unexpected test case %+v", simpleReply)
+	}
+	if strings.Contains(string(data), "dev-token") {
+		t.Fatalf("JUnit report leaked the bearer token: %s", data)
+	}
+}
+
+func TestCreateVerifyStopsFailingEndpoint(t *testing.T) {
+	endpoint, _ := newDevTestServer(t, "Hello there\n")
+	var created bool
+	server := newAgentTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		created = true
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	})
+	configureAgentTest(t, server.URL)
+
+	cmd := newCreateCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{
+		"name":                      "Custom Agent",
+		"avatar":                    "avatar-1",
+		"voice":                     "voice-1",
+		"runtime-kind":              "custom_agent",
+		"custom-agent-url":          endpoint,
+		"custom-agent-bearer-token": "dev-token",
+		"verify":                    "true",
+	})
+	_, err := captureStdout(t, func() error { return runCreate(cmd, nil) })
+	if err == nil || !strings.Contains(err.Error(), "the agent was not created: 4 of 5 checks failed") {
+		t.Fatalf("runCreate() error = %v, want a verification failure", err)
+	}
+	if created {
+		t.Fatal("agent was created despite failing verification")
+	}
+
+	cmd = newCreateCmd()
+	cmd.SetContext(context.Background())
+	setFlags(t, cmd, map[string]string{"name": "Agent", "avatar": "avatar-1", "voice": "voice-1", "instruction": "Be helpful", "verify": "true"})
+	if err := runCreate(cmd, nil); err == nil || !strings.Contains(err.Error(), "--verify only applies to custom agents") {
+		t.Fatalf("runCreate() error = %v, want --verify to be rejected", err)
+	}
+}
@@ -1,11 +1,17 @@
 package auth
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/mirako-ai/mirako-cli/internal/client"
 	"github.com/mirako-ai/mirako-cli/internal/config"
+	"github.com/mirako-ai/mirako-cli/internal/credentials"
+	"github.com/mirako-ai/mirako-cli/internal/errors"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -13,12 +19,19 @@ func NewAuthCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "auth",
 		Short: "Manage authentication",
-		Long:  `Login, logout, and check authentication status`,
+		Long: `Login, logout, and check authentication status.
+
+Credentials can be kept in named contexts, e.g. one for production and one
+for staging. Select a context for a single command with --context, or change
+the current context with 'mirako auth switch'.`,
 	}
 
 	cmd.AddCommand(newLoginCmd())
 	cmd.AddCommand(newLogoutCmd())
 	cmd.AddCommand(newStatusCmd())
+	cmd.AddCommand(newSwitchCmd())
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newMigrateCmd())
 
 	return cmd
 }
@@ -27,17 +40,25 @@ func newLoginCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "login",
 		Short: "Authenticate with Mirako API",
-		Long:  `Authenticate with your API token to access Mirako services`,
-		RunE:  runLogin,
+		Long: `Authenticate with your API token to access Mirako services.
+
+The token is verified against the API before it is saved, and rejected
+tokens are not saved. With --context, the token is saved to that context,
+which is created if it does not exist yet. Use --api-url to set the API URL
+of the context.`,
+		Example: `  mirako auth login
+  mirako auth login --context staging --api-url https://staging.mirako.co`,
+		RunE: runLogin,
 	}
 
 	cmd.Flags().String("token", "", "API token (optional, can be provided interactively)")
+	cmd.Flags().Bool("skip-verify", false, "Save the token without verifying it against the API")
 
 	return cmd
 }
 
 func runLogin(cmd *cobra.Command, args []string) error {
-	cfg, err := util.GetConfig(cmd)
+	cfg, err := util.GetLoginConfig(cmd)
 	if err != nil {
 		return err
 	}
@@ -55,6 +76,19 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if skipVerify, _ := cmd.Flags().GetBool("skip-verify"); !skipVerify {
+		spinner := ui.NewSpinner(os.Stdout, "Verifying token...")
+		spinner.Start()
+		err := verifyToken(cmd.Context(), cfg, token)
+		spinner.Stop()
+		if err != nil {
+			if apiErr, ok := errors.IsAPIError(err); ok && apiErr.IsAuthenticationError() {
+				return fmt.Errorf("the API at %s rejected the token, it was not saved: %w", cfg.APIURL, apiErr)
+			}
+			return fmt.Errorf("failed to verify token, it was not saved (use --skip-verify to save it anyway): %w", err)
+		}
+	}
+
 	// Save token to config
 	cfg.APIToken = token
 	if err := cfg.Save(); err != nil {
@@ -62,6 +96,9 @@ func runLogin(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Println("✅ Successfully authenticated!")
+	if name := cfg.ContextName(); name != currentContextName(cfg) {
+		fmt.Printf("   Saved to context '%s'. Run 'mirako auth switch %s' to use it by default.\n", name, name)
+	}
 	return nil
 }
 
@@ -69,7 +106,7 @@ func newLogoutCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "logout",
 		Short: "Remove authentication",
-		Long:  `Remove stored API token and logout`,
+		Long:  `Remove the stored API token of the current context and logout`,
 		RunE:  runLogout,
 	}
 }
@@ -90,12 +127,29 @@ func runLogout(cmd *cobra.Command, args []string) error {
 }
 
 func newStatusCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Check authentication status",
-		Long:  `Check if you're currently authenticated`,
-		RunE:  runStatus,
+		Long: `Check if you're currently authenticated, whether the API accepts the token,
+and where the token comes from: the --api-token flag, the MIRAKO_API_TOKEN
+environment variable, the config file or a credential store.`,
+		RunE: runStatus,
 	}
+
+	cmd.Flags().Bool("skip-verify", false, "Do not verify the token against the API")
+
+	return cmd
+}
+
+// authStatus is the structured output of `auth status`
+type authStatus struct {
+	Authenticated bool   `json:"authenticated"`
+	TokenValid    *bool  `json:"token_valid"` // null when not verified
+	TokenSource   string `json:"token_source,omitempty"`
+	VerifyError   string `json:"verify_error,omitempty"`
+	Context       string `json:"context"`
+	APIURL        string `json:"api_url"`
+	ConfigPath    string `json:"config_path"`
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
@@ -104,15 +158,276 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	status := authStatus{
+		Authenticated: cfg.IsAuthenticated(),
+		TokenSource:   cfg.TokenSource(),
+		Context:       cfg.ContextName(),
+		APIURL:        cfg.APIURL,
+		ConfigPath:    config.ConfigPath,
+	}
+	if skipVerify, _ := cmd.Flags().GetBool("skip-verify"); cfg.IsAuthenticated() && !skipVerify {
+		var spinner *ui.Spinner
+		if format.IsTable() {
+			spinner = ui.NewSpinner(os.Stdout, "Verifying token...")
+			spinner.Start()
+		}
+		err := verifyToken(cmd.Context(), cfg, cfg.APIToken)
+		if spinner != nil {
+			spinner.Stop()
+		}
+
+		apiErr, isAPIErr := errors.IsAPIError(err)
+		switch {
+		case err == nil:
+			valid := true
+			status.TokenValid = &valid
+		case isAPIErr && apiErr.IsAuthenticationError():
+			valid := false
+			status.TokenValid = &valid
+		default:
+			// The check itself failed, e.g. the API is unreachable
+			status.VerifyError = err.Error()
+		}
+	}
+
+	if !format.IsTable() {
+		return util.PrintOutput(format, status)
+	}
+
 	if cfg.IsAuthenticated() {
-		fmt.Println("✅ Authenticated")
+		switch {
+		case status.TokenValid == nil && status.VerifyError != "":
+			fmt.Println("⚠️  Authenticated, but the token could not be verified")
+		case status.TokenValid != nil && !*status.TokenValid:
+			fmt.Println("❌ Token rejected by the API")
+		default:
+			fmt.Println("✅ Authenticated")
+		}
+		fmt.Printf("   Context: %s\n", cfg.ContextName())
 		fmt.Printf("   API URL: %s\n", cfg.APIURL)
+		fmt.Printf("   Token: %s\n", describeToken(cfg, status.TokenValid))
 		fmt.Printf("   Config Path: %s\n", config.ConfigPath)
+		if status.VerifyError != "" {
+			fmt.Printf("   Error: %s\n", status.VerifyError)
+		}
+		if status.TokenValid != nil && !*status.TokenValid {
+			fmt.Println("   Run 'mirako auth login' to authenticate")
+		}
 	} else {
 		fmt.Println("❌ Not authenticated")
+		fmt.Printf("   Context: %s\n", cfg.ContextName())
 		fmt.Println("   Run 'mirako auth login' to authenticate")
 	}
 
 	return nil
 }
 
+// verifyToken checks token against the API configured in cfg
+func verifyToken(ctx context.Context, cfg *config.Config, token string) error {
+	check := *cfg
+	check.APIToken = token
+	c, err := client.New(&check)
+	if err != nil {
+		return err
+	}
+	return c.VerifyToken(ctx)
+}
+
+// describeToken reports the validity and source of the token, e.g.
+// "valid (from the keyring credential store)"
+func describeToken(cfg *config.Config, valid *bool) string {
+	validity := "not verified"
+	if valid != nil && *valid {
+		validity = "valid"
+	} else if valid != nil {
+		validity = "invalid"
+	}
+
+	var source string
+	switch cfg.TokenSource() {
+	case config.TokenSourceFlag:
+		source = "the --api-token flag"
+	case config.TokenSourceEnv:
+		source = "the MIRAKO_API_TOKEN environment variable"
+	case config.TokenSourceConfig:
+		source = "the config file"
+		if cfg.ContextName() != config.DefaultContextName {
+			source = fmt.Sprintf("context '%s' in the config file", cfg.ContextName())
+		}
+	default:
+		source = fmt.Sprintf("the %s credential store", cfg.TokenSource())
+	}
+	return fmt.Sprintf("%s (from %s)", validity, source)
+}
+
+func newSwitchCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "switch [context]",
+		Short: "Change the current context",
+		Long: `Change the context used by default. Without an argument, the context is
+selected interactively. Use 'default' for the settings at the top level of the
+config file.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runSwitch,
+	}
+}
+
+func runSwitch(cmd *cobra.Command, args []string) error {
+	// Load without selecting a context, so a current_context that no longer
+	// exists can still be switched away from
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	} else {
+		prompt := &survey.Select{
+			Message: "Select a context:",
+			Options: cfg.ContextNames(),
+			Default: currentContextName(cfg),
+		}
+		if err := survey.AskOne(prompt, &name); err != nil {
+			return fmt.Errorf("failed to select context: %w", err)
+		}
+	}
+
+	if !cfg.HasContext(name) {
+		return fmt.Errorf("context %q not found. Run 'mirako auth list' to see available contexts", name)
+	}
+
+	cfg.CurrentContext = ""
+	if name != config.DefaultContextName {
+		cfg.CurrentContext = name
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("✅ Switched to context '%s'\n", name)
+	return nil
+}
+
+func newListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List auth contexts",
+		Long:  `List the configured auth contexts. Tokens are never printed.`,
+		RunE:  runList,
+	}
+}
+
+// contextInfo is the structured output of `auth list`
+type contextInfo struct {
+	Name          string `json:"name"`
+	Current       bool   `json:"current"`
+	APIURL        string `json:"api_url"`
+	Authenticated bool   `json:"authenticated"`
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	cfg, err := util.GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	var contexts []contextInfo
+	for _, name := range cfg.ContextNames() {
+		settings, _ := cfg.ContextSettings(name)
+		contexts = append(contexts, contextInfo{
+			Name:          name,
+			Current:       name == cfg.ContextName(),
+			APIURL:        settings.APIURL,
+			Authenticated: cfg.HasToken(name),
+		})
+	}
+
+	if !format.IsTable() {
+		return util.PrintOutput(format, contexts)
+	}
+
+	table := ui.NewContextTable(os.Stdout)
+	for _, c := range contexts {
+		current := ""
+		if c.Current {
+			current = "*"
+		}
+		authenticated := "no"
+		if c.Authenticated {
+			authenticated = "yes"
+		}
+		table.AddRow([]interface{}{current, c.Name, c.APIURL, authenticated})
+	}
+	return table.Flush()
+}
+
+// currentContextName returns the context configured as current in the config file
+func currentContextName(cfg *config.Config) string {
+	if cfg.CurrentContext == "" {
+		return config.DefaultContextName
+	}
+	return cfg.CurrentContext
+}
+
+func newMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Move stored API tokens to another credential store",
+		Long: `Move the API tokens of all contexts out of the config file into a credential
+store, or between stores, and select that store for future logins.
+
+Stores:
+  keyring    the OS keyring (Secret Service via secret-tool on Linux, Keychain on macOS)
+  file       a passphrase-encrypted file next to the config file; the passphrase
+             is read from MIRAKO_CREDENTIALS_PASSPHRASE or prompted for
+  helper     an external command, like a git credential helper (see --helper)
+  plaintext  the config file itself`,
+		Example: `  mirako auth migrate --store keyring
+  mirako auth migrate --store file
+  mirako auth migrate --store helper --helper "pass-mirako"`,
+		Args: cobra.NoArgs,
+		RunE: runMigrate,
+	}
+
+	cmd.Flags().String("store", "", "Credential store: "+credentials.SupportedBackends)
+	cmd.Flags().String("helper", "", "Credential helper command, for --store helper")
+	cmd.MarkFlagRequired("store")
+
+	return cmd
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	backend, _ := cmd.Flags().GetString("store")
+	helper, _ := cmd.Flags().GetString("helper")
+	if backend == credentials.BackendHelper && helper == "" {
+		helper = cfg.CredentialHelper
+	}
+	if backend != credentials.BackendHelper {
+		helper = ""
+	}
+
+	moved, err := cfg.MigrateTokens(backend, helper)
+	if err != nil {
+		return fmt.Errorf("failed to migrate tokens: %w", err)
+	}
+
+	fmt.Printf("✅ Moved %d token(s) to the %s credential store\n", moved, backend)
+	return nil
+}
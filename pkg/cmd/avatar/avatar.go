@@ -1,10 +1,8 @@
 package avatar
 
 import (
-	"bufio"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,6 +10,7 @@ import (
 
 	"github.com/mirako-ai/mirako-cli/internal/client"
 	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/internal/media"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
 	"github.com/mirako-ai/mirako-cli/pkg/ui"
 	promptui "github.com/mirako-ai/mirako-cli/pkg/ui/prompt"
@@ -19,8 +18,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
-
 func NewAvatarCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "avatar",
@@ -67,16 +64,17 @@ func runList(cmd *cobra.Command, args []string) error {
 	resp, err := client.ListAvatars(ctx)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to list avatars: %w", err)
 	}
 
-	useJSON, _ := cmd.Flags().GetBool("json")
-	if useJSON {
-		data, _ := json.MarshalIndent(resp, "", "  ")
-		fmt.Println(string(data))
-		return nil
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return util.PrintOutput(format, resp)
 	}
 
 	if resp.Data == nil || len(*resp.Data) == 0 {
@@ -125,11 +123,19 @@ func runView(cmd *cobra.Command, args []string) error {
 	resp, err := client.GetAvatar(ctx, avatarID)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to get avatar: %w", err)
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return util.PrintOutput(format, resp)
+	}
+
 	printAvatarDetails(resp.Data)
 	return nil
 }
@@ -188,9 +194,11 @@ func newGenerateCmd() *cobra.Command {
 
 	cmd.Flags().StringP("prompt", "p", "", "Prompt for avatar generation (max 1000 characters)")
 	cmd.Flags().Int64P("seed", "s", 0, "Seed for reproducible generation (optional)")
-	cmd.Flags().StringP("output", "o", "", "Output file path for the generated avatar (e.g., ./output/avatar.jpg)")
+	cmd.Flags().StringP("output", "o", "", "Output file path for the generated avatar (e.g., ./output/avatar.jpg, - for stdout)")
 	cmd.Flags().BoolP("no-save", "n", false, "Skip saving the image to disk")
 	cmd.Flags().IntP("poll-interval", "i", 2, "Polling interval in seconds for checking status")
+	cmd.Flags().Duration("timeout", 0, "Maximum time to wait for the task to finish, e.g. 10m (0 waits indefinitely)")
+	util.AddDetachFlags(cmd)
 
 	return cmd
 }
@@ -213,9 +221,20 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("prompt is too long (max 1000 characters, got %d)", len(prompt))
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
 	outputPath, _ := cmd.Flags().GetString("output")
+	out, err := util.MediaProgressWriter(format, outputPath)
+	if err != nil {
+		return err
+	}
 	noSave, _ := cmd.Flags().GetBool("no-save")
 	pollInterval, _ := cmd.Flags().GetInt("poll-interval")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	wait, _ := cmd.Flags().GetBool("wait")
 
 	seed, _ := cmd.Flags().GetInt64("seed")
 	var seedPtr *int64
@@ -223,17 +242,19 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		seedPtr = &seed
 	}
 
-	client, err := client.New(cfg)
+	c, err := client.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
 	// Start generation
-	fmt.Printf("🚀 Starting avatar generation...\n")
-	resp, err := client.GenerateAvatar(ctx, prompt, seedPtr)
+	if wait {
+		fmt.Fprintf(out, "🚀 Starting avatar generation...\n")
+	}
+	resp, err := c.GenerateAvatar(ctx, prompt, seedPtr)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to generate avatar: %w", err)
 	}
@@ -243,213 +264,165 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	}
 
 	taskID := resp.Data.TaskId
-	fmt.Printf("✅ Avatar generation started!\n")
-	fmt.Printf("   Task ID: %s\n", taskID)
+	util.RecordJob(cfg, client.TaskKindAvatarGenerate, taskID, map[string]string{"prompt": prompt}, outputPath)
+	if !wait {
+		return util.PrintSubmittedTask(cmd, client.TaskKindAvatarGenerate, taskID, string(resp.Data.Status))
+	}
+
+	fmt.Fprintf(out, "✅ Avatar generation started!\n")
+	fmt.Fprintf(out, "   Task ID: %s\n", taskID)
 
 	// Poll for status until complete
-	fmt.Printf("⏳ Waiting for generation to complete...\n")
-
-	// Use separate tickers for polling and spinner animation
-	pollTicker := time.NewTicker(time.Duration(pollInterval) * time.Second)
-	spinnerTicker := time.NewTicker(100 * time.Millisecond) // Smooth spinner animation
-	defer pollTicker.Stop()
-	defer spinnerTicker.Stop()
-
-	spinnerIndex := 0
-	currentStatus := "PROCESSING" // Initial status
-	clearLine := "\r\033[K"       // ANSI escape codes to clear the line
-
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Print(clearLine) // Clear the spinner line
-			return fmt.Errorf("operation cancelled: %w", ctx.Err())
-		case <-pollTicker.C:
-			statusResp, err := client.GetAvatarStatus(ctx, taskID)
-			if err != nil {
-				fmt.Print(clearLine) // Clear the spinner line
-				if apiErr, ok := errors.IsAPIError(err); ok {
-					return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
-				}
-				return fmt.Errorf("failed to check status: %w", err)
-			}
+	fmt.Fprintf(out, "⏳ Waiting for generation to complete...\n")
+
+	spinner := ui.NewSpinner(out, "Status: PROCESSING")
+	spinner.Start()
+	result, err := c.WaitForTask(ctx, client.TaskKindAvatarGenerate, taskID, client.WaitOptions{
+		Interval: time.Duration(pollInterval) * time.Second,
+		Timeout:  timeout,
+		OnStatus: func(status string) {
+			spinner.Update("Status: " + status)
+			util.RecordJobStatus(taskID, status)
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
+		}
+		return err
+	}
 
-			if statusResp.Data == nil {
-				fmt.Print(clearLine) // Clear the spinner line
-				return fmt.Errorf("unexpected response from server")
-			}
+	fmt.Fprintf(out, "✅ Generation completed!\n")
 
-			currentStatus = string(statusResp.Data.Status)
-
-			if statusResp.Data.Status == api.GenerateAvatarTaskOutputStatusCOMPLETED {
-				fmt.Print(clearLine) // Clear the spinner line
-				fmt.Printf("✅ Generation completed!\n")
-
-				if statusResp.Data.Image != nil {
-					if noSave {
-						fmt.Printf("📸 Image generated (%d bytes) - skipping save due to --no-save flag\n", len(*statusResp.Data.Image))
-						return nil
-					}
-
-					// Save the image
-					imageData := *statusResp.Data.Image
-
-					// Determine output path
-					if outputPath == "" {
-						now := time.Now()
-						timestamp := fmt.Sprintf("%s_%03d", now.Format("20060102_150405"), now.Nanosecond()/1000000)
-						defaultFilename := fmt.Sprintf("avatar_%s.jpg", timestamp)
-						outputPath = filepath.Join(cfg.DefaultSavePath, defaultFilename)
-					}
-
-					// Ensure .jpg extension
-					if !strings.HasSuffix(strings.ToLower(outputPath), ".jpg") && !strings.HasSuffix(strings.ToLower(outputPath), ".jpeg") {
-						outputPath += ".jpg"
-					}
-
-					// Decode base64 image
-					// Remove data URL prefix if present
-					if strings.HasPrefix(imageData, "data:image") {
-						commaIndex := strings.Index(imageData, ",")
-						if commaIndex != -1 {
-							imageData = imageData[commaIndex+1:]
-						}
-					}
-
-					decodedImage, err := base64.StdEncoding.DecodeString(imageData)
-					if err != nil {
-						return fmt.Errorf("failed to decode image data: %w", err)
-					}
-
-					// Create directory if it doesn't exist
-					dir := filepath.Dir(outputPath)
-					if err := os.MkdirAll(dir, 0755); err != nil {
-						return fmt.Errorf("failed to create directory: %w", err)
-					}
-
-					// Save the file
-					if err := os.WriteFile(outputPath, decodedImage, 0644); err != nil {
-						return fmt.Errorf("failed to save image: %w", err)
-					}
-
-					fmt.Printf("💾 Image saved to: %s\n", outputPath)
-				}
-
-				return nil
-			} else if statusResp.Data.Status == api.GenerateAvatarTaskOutputStatusFAILED || statusResp.Data.Status == api.GenerateAvatarTaskOutputStatusCANCELED || statusResp.Data.Status == api.GenerateAvatarTaskOutputStatusTIMEDOUT {
-				fmt.Print(clearLine) // Clear the spinner line
-				return fmt.Errorf("avatar generation failed with status: %s", statusResp.Data.Status)
+	taskOutput := util.TaskOutput{Kind: client.TaskKindAvatarGenerate, TaskID: taskID, Status: result.Status}
+	if result.Image != nil {
+		if noSave {
+			fmt.Fprintf(out, "📸 Image generated (%d bytes) - skipping save due to --no-save flag\n", len(*result.Image))
+		} else {
+			savedPath, err := SaveAvatarImage(out, *result.Image, outputPath, cfg.DefaultSavePath)
+			if err != nil {
+				return err
 			}
-			// Update status but don't draw here - spinner ticker handles animation
-		case <-spinnerTicker.C:
-			// Update spinner animation smoothly
-			frame := spinnerFrames[spinnerIndex%len(spinnerFrames)]
-			fmt.Printf("\r\033[K%s Status: %s", frame, currentStatus)
-			spinnerIndex++
+			taskOutput.Output = savedPath
 		}
 	}
+
+	if !format.IsTable() {
+		return util.PrintOutput(format, taskOutput)
+	}
+	return nil
+}
+
+// SaveAvatarImage saves a generated avatar image to disk, or to stdout when
+// outputPath is "-", and returns the path it was written to
+func SaveAvatarImage(out io.Writer, imageData string, outputPath string, defaultSavePath string) (string, error) {
+	// Determine output path
+	if outputPath == "" {
+		now := time.Now()
+		timestamp := fmt.Sprintf("%s_%03d", now.Format("20060102_150405"), now.Nanosecond()/1000000)
+		defaultFilename := fmt.Sprintf("avatar_%s.jpg", timestamp)
+		outputPath = filepath.Join(defaultSavePath, defaultFilename)
+	}
+
+	// Ensure .jpg extension
+	if !media.IsStdio(outputPath) && !strings.HasSuffix(strings.ToLower(outputPath), ".jpg") && !strings.HasSuffix(strings.ToLower(outputPath), ".jpeg") {
+		outputPath += ".jpg"
+	}
+
+	// Decode base64 image, which may be a data URL
+	decodedImage, err := media.DecodeBase64(imageData)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image data: %w", err)
+	}
+
+	// Save the file
+	if err := media.WriteFile(outputPath, decodedImage); err != nil {
+		return "", fmt.Errorf("failed to save image: %w", err)
+	}
+
+	fmt.Fprintf(out, "💾 Image saved to: %s\n", util.OutputName(outputPath))
+	return outputPath, nil
 }
 
 func newStatusCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "status [task-id]",
 		Short: "Check avatar generation status",
-		Long:  `Check the status of an avatar generation task`,
-		Args:  cobra.ExactArgs(1),
-		RunE:  runStatus,
+		Long: `Check the status of an avatar generation task.
+
+When the task has completed, the generated image is saved to --output, or to the
+path requested when the task was submitted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runStatus,
 	}
+
+	util.AddSaveFlags(cmd)
+
+	return cmd
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 
-	cfg, err := util.GetConfig(cmd)
+	cfg, err := util.GetJobConfig(cmd, util.LookupJob(args[0]))
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	outputPath, _ := cmd.Flags().GetString("output")
+	out, err := util.MediaProgressWriter(format, outputPath)
+	if err != nil {
+		return err
+	}
+
 	taskID := args[0]
 
-	client, err := client.New(cfg)
+	c, err := client.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	resp, err := client.GetAvatarStatus(ctx, taskID)
+	result, err := c.GetTaskStatus(ctx, client.TaskKindAvatarGenerate, taskID)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to get status: %w", err)
 	}
+	util.RecordJobStatus(taskID, result.Status)
 
-	if resp.Data == nil {
-		return fmt.Errorf("unexpected response from server")
-	}
+	fmt.Fprintf(out, "Task ID: %s\n", result.TaskID)
+	fmt.Fprintf(out, "Status: %s\n", result.Status)
 
-	fmt.Printf("Task ID: %s\n", resp.Data.TaskId)
-
-	if resp.Data.Image != nil {
-		fmt.Printf("✅ Avatar generated successfully!\n")
-		fmt.Printf("   Image: %d bytes\n", len(*resp.Data.Image))
-
-		// Ask user if they want to save the image
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Print("\nWould you like to save the generated image? (Y/n): ")
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
-
-		if response == "" || response == "y" || response == "yes" {
-			// Generate default filename
-			defaultFilename := fmt.Sprintf("avatar_%s.jpg", taskID)
-			defaultPath := filepath.Join(cfg.DefaultSavePath, defaultFilename)
-
-			// Ask for save location
-			fmt.Printf("Enter save path [%s]: ", defaultPath)
-			savePath, _ := reader.ReadString('\n')
-			savePath = strings.TrimSpace(savePath)
-
-			if savePath == "" {
-				savePath = defaultPath
-			}
+	if err := result.Err(); err != nil {
+		return err
+	}
 
-			// Ensure the path ends with .jpg
-			if !strings.HasSuffix(strings.ToLower(savePath), ".jpg") && !strings.HasSuffix(strings.ToLower(savePath), ".jpeg") {
-				savePath += ".jpg"
-			}
+	taskOutput := util.TaskOutput{Kind: client.TaskKindAvatarGenerate, TaskID: result.TaskID, Status: result.Status}
+	if result.IsCompleted() && result.Image != nil {
+		fmt.Fprintf(out, "✅ Avatar generated successfully!\n")
+		fmt.Fprintf(out, "   Image: %d bytes\n", len(*result.Image))
 
-			// Decode base64 image
-			imageData := *resp.Data.Image
-			// Remove data URL prefix if present
-			if strings.HasPrefix(imageData, "data:image") {
-				commaIndex := strings.Index(imageData, ",")
-				if commaIndex != -1 {
-					imageData = imageData[commaIndex+1:]
-				}
-			}
-
-			decodedImage, err := base64.StdEncoding.DecodeString(imageData)
+		defaultPath := filepath.Join(cfg.DefaultSavePath, fmt.Sprintf("avatar_%s.jpg", taskID))
+		if savePath, ok := util.ResultSavePath(cmd, taskID, defaultPath); ok {
+			savedPath, err := SaveAvatarImage(out, *result.Image, savePath, cfg.DefaultSavePath)
 			if err != nil {
-				return fmt.Errorf("failed to decode image data: %w", err)
-			}
-
-			// Create directory if it doesn't exist
-			dir := filepath.Dir(savePath)
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				return fmt.Errorf("failed to create directory: %w", err)
-			}
-
-			// Save the file
-			if err := os.WriteFile(savePath, decodedImage, 0644); err != nil {
-				return fmt.Errorf("failed to save image: %w", err)
+				return err
 			}
-
-			fmt.Printf("✅ Image saved to: %s\n", savePath)
+			taskOutput.Output = savedPath
 		} else {
-			fmt.Println("Image not saved.")
+			fmt.Fprintln(out, "Image not saved.")
 		}
 	}
 
+	if !format.IsTable() {
+		return util.PrintOutput(format, taskOutput)
+	}
 	return nil
 }
 
@@ -462,8 +435,11 @@ func newBuildCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringP("name", "n", "", "Name for the new avatar")
-	cmd.Flags().StringP("image", "i", "", "Path to the base image file")
+	cmd.Flags().StringP("image", "i", "", "Path or URL of the base image file (- for standard input)")
 	cmd.Flags().IntP("poll-interval", "p", 10, "Polling interval in seconds for checking status")
+	cmd.Flags().Duration("timeout", 0, "Maximum time to wait for the build to finish, e.g. 30m (0 waits indefinitely)")
+	util.AddDetachFlags(cmd)
+	util.AddNormalizeFlag(cmd)
 
 	return cmd
 }
@@ -486,28 +462,36 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("image path is required. Use --image flag")
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	out := util.ProgressWriter(format)
+
 	pollInterval, _ := cmd.Flags().GetInt("poll-interval")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	wait, _ := cmd.Flags().GetBool("wait")
 
 	// Read and encode the image file
-	imageData, err := os.ReadFile(imagePath)
+	imageInput, err := util.ReadMedia(cmd, out, imagePath, media.ImagePolicy)
 	if err != nil {
 		return fmt.Errorf("failed to read image file: %w", err)
 	}
+	encodedImage := imageInput.Base64()
 
-	// Encode as base64
-	encodedImage := base64.StdEncoding.EncodeToString(imageData)
-
-	client, err := client.New(cfg)
+	c, err := client.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
 	// Start build
-	fmt.Printf("🚀 Starting avatar build...\n")
-	resp, err := client.BuildAvatar(ctx, name, encodedImage)
+	if wait {
+		fmt.Fprintf(out, "🚀 Starting avatar build...\n")
+	}
+	resp, err := c.BuildAvatar(ctx, name, encodedImage)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to build avatar: %w", err)
 	}
@@ -517,66 +501,47 @@ func runBuild(cmd *cobra.Command, args []string) error {
 	}
 
 	avatarID := resp.Data.AvatarId
-	fmt.Printf("✅ Avatar build started!\n")
-	fmt.Printf("   Avatar ID: %s\n", avatarID)
+	util.RecordJob(cfg, client.TaskKindAvatarBuild, avatarID, map[string]string{"name": name, "image": imagePath}, "")
+	if !wait {
+		return util.PrintSubmittedTask(cmd, client.TaskKindAvatarBuild, avatarID, "")
+	}
+
+	fmt.Fprintf(out, "✅ Avatar build started!\n")
+	fmt.Fprintf(out, "   Avatar ID: %s\n", avatarID)
 
 	// Provide helpful guidance instead of prompting
-	fmt.Printf("\n⏳ Avatar build in progress...\n")
-	fmt.Printf("\n💡 Check the avatar build status anytime with:\n")
-	fmt.Printf("   mirako avatar list\n")
-	fmt.Printf("\n  Or view details for this avatar with:\n")
-	fmt.Printf("   mirako avatar view %s\n", avatarID)
-	fmt.Printf("\n✅ You can safely quit this program now (Ctrl+C).\n")
-
-	// Use separate tickers for polling and spinner animation
-	pollTicker := time.NewTicker(time.Duration(pollInterval) * time.Second)
-	spinnerTicker := time.NewTicker(100 * time.Millisecond) // Smooth spinner animation
-	defer pollTicker.Stop()
-	defer spinnerTicker.Stop()
-
-	spinnerIndex := 0
-	currentStatus := "PENDING" // Initial status for avatar build
-	clearLine := "\r\033[K"    // ANSI escape codes to clear the line
-
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Print(clearLine) // Clear the spinner line
-			return fmt.Errorf("operation cancelled: %w", ctx.Err())
-		case <-pollTicker.C:
-			avatarResp, err := client.GetAvatar(ctx, avatarID)
-			if err != nil {
-				fmt.Print(clearLine) // Clear the spinner line
-				if apiErr, ok := errors.IsAPIError(err); ok {
-					return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
-				}
-				return fmt.Errorf("failed to check avatar status: %w", err)
-			}
+	fmt.Fprintf(out, "\n⏳ Avatar build in progress...\n")
+	fmt.Fprintf(out, "\n💡 Check the avatar build status anytime with:\n")
+	fmt.Fprintf(out, "   mirako avatar list\n")
+	fmt.Fprintf(out, "\n  Or view details for this avatar with:\n")
+	fmt.Fprintf(out, "   mirako avatar view %s\n", avatarID)
+	fmt.Fprintf(out, "\n✅ You can safely quit this program now (Ctrl+C).\n")
+
+	spinner := ui.NewSpinner(out, "Status: PENDING")
+	spinner.Start()
+	result, err := c.WaitForTask(ctx, client.TaskKindAvatarBuild, avatarID, client.WaitOptions{
+		Interval: time.Duration(pollInterval) * time.Second,
+		Timeout:  timeout,
+		OnStatus: func(status string) {
+			spinner.Update("Status: " + status)
+			util.RecordJobStatus(avatarID, status)
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
+		}
+		return err
+	}
 
-			if avatarResp == nil {
-				fmt.Print(clearLine) // Clear the spinner line
-				return fmt.Errorf("unexpected response from server")
-			}
+	fmt.Fprintf(out, "✅ Avatar build completed!\n")
+	fmt.Fprintf(out, "   Avatar ID: %s\n", avatarID)
+	fmt.Fprintf(out, "\n💡 Tip: You can view all your avatars with:\n")
+	fmt.Fprintf(out, "   mirako avatar list\n")
 
-			currentStatus = string(avatarResp.Data.Status)
-
-			if avatarResp.Data.Status == api.READY {
-				fmt.Print(clearLine) // Clear the spinner line
-				fmt.Printf("✅ Avatar build completed!\n")
-				fmt.Printf("   Avatar ID: %s\n", avatarID)
-				fmt.Printf("\n💡 Tip: You can view all your avatars with:\n")
-				fmt.Printf("   mirako avatar list\n")
-				return nil
-			} else if avatarResp.Data.Status == api.ERROR {
-				fmt.Print(clearLine) // Clear the spinner line
-				return fmt.Errorf("avatar build failed with status: %s", avatarResp.Data.Status)
-			}
-			// Continue polling for other statuses (PENDING, BUILDING)
-		case <-spinnerTicker.C:
-			// Update spinner animation smoothly
-			frame := spinnerFrames[spinnerIndex%len(spinnerFrames)]
-			fmt.Printf("\r\033[K%s Status: %s", frame, currentStatus)
-			spinnerIndex++
-		}
+	if !format.IsTable() {
+		return util.PrintOutput(format, util.TaskOutput{Kind: client.TaskKindAvatarBuild, TaskID: avatarID, Status: result.Status})
 	}
+	return nil
 }
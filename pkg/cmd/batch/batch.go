@@ -0,0 +1,220 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/spf13/cobra"
+)
+
+// defaultConcurrency is used when neither the flag nor the manifest sets one
+const defaultConcurrency = 4
+
+func NewBatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Run many generation jobs from a manifest",
+		Long:  `Run text-to-speech, image, avatar and video generation jobs in bulk from a manifest file`,
+	}
+
+	cmd.AddCommand(newRunCmd())
+
+	return cmd
+}
+
+func newRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run [manifest]",
+		Short: "Run all jobs in a manifest",
+		Long: fmt.Sprintf(`Run all jobs in a YAML or JSONL manifest.
+
+Each job sets a type (%s) and the same parameters as the flags of the
+equivalent command, e.g. text and voice for tts or audio, image and
+positive-prompt for video-motion. Relative paths are resolved against the
+manifest's directory.
+
+Jobs run independently and in parallel, except that a job reading another
+job's output (as audio, image or labeled-image) waits for that job to finish,
+and fails without running if it fails.
+
+Jobs whose output file already exists are skipped, so an interrupted batch can
+simply be run again. A JSON report with the outcome of every job is written
+next to the manifest unless --report is given.
+
+Example manifest:
+
+  concurrency: 4
+  jobs:
+    - type: tts
+      name: intro
+      text: Welcome to the show
+      voice: <voice-profile-id>
+      output: out/intro.wav
+    - type: image
+      prompt: A lighthouse at dusk
+      aspect-ratio: "16:9"
+      output: out/lighthouse.jpg
+    - type: video-talking
+      audio: out/intro.wav
+      image: avatar.jpg
+      output: out/intro.mp4`, GetSupportedJobTypesString()),
+		Args: cobra.ExactArgs(1),
+		RunE: runRun,
+	}
+
+	cmd.Flags().IntP("concurrency", "c", defaultConcurrency, "Maximum number of jobs running at the same time (overrides the manifest)")
+	cmd.Flags().IntP("poll-interval", "p", 2, "Polling interval in seconds for checking task status")
+	cmd.Flags().Duration("timeout", 0, "Maximum time to wait for each job, e.g. 30m (0 waits indefinitely)")
+	cmd.Flags().StringP("report", "r", "", "Path of the JSON results report (default: <manifest>.report.json)")
+	cmd.Flags().Bool("force", false, "Run jobs even if their output file already exists")
+
+	return cmd
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	cfg, err := util.GetConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	manifestPath := args[0]
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	concurrency := manifest.Concurrency
+	if cmd.Flags().Changed("concurrency") || concurrency <= 0 {
+		concurrency, _ = cmd.Flags().GetInt("concurrency")
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+
+	reportPath, _ := cmd.Flags().GetString("report")
+	if reportPath == "" {
+		reportPath = strings.TrimSuffix(manifestPath, filepath.Ext(manifestPath)) + ".report.json"
+	}
+
+	pollInterval, _ := cmd.Flags().GetInt("poll-interval")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	force, _ := cmd.Flags().GetBool("force")
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	out := util.ProgressWriter(format)
+
+	c, err := client.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	total := len(manifest.Jobs)
+	fmt.Fprintf(out, "📦 Running %d jobs (concurrency %d)...\n", total, concurrency)
+
+	done := 0
+	runner := &Runner{
+		Client:          c,
+		Config:          cfg,
+		Concurrency:     concurrency,
+		PollInterval:    time.Duration(pollInterval) * time.Second,
+		Timeout:         timeout,
+		DefaultSavePath: cfg.DefaultSavePath,
+		MaxRetries:      cfg.MaxRetries,
+		Force:           force,
+		OnResult: func(result Result) {
+			done++
+			printResult(out, done, total, result)
+		},
+	}
+
+	start := time.Now()
+	results := runner.Run(ctx, manifest.Jobs)
+
+	report := newReport(results)
+	if err := writeReport(reportPath, report); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n✅ %d completed, ⏭️  %d skipped, ❌ %d failed in %s\n", report.Completed, report.Skipped, report.Failed, time.Since(start).Round(time.Second))
+	fmt.Fprintf(out, "📄 Report saved to: %s\n", reportPath)
+
+	if !format.IsTable() {
+		if err := util.PrintOutput(format, report); err != nil {
+			return err
+		}
+	}
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d jobs failed", report.Failed, total)
+	}
+	return nil
+}
+
+func printResult(out io.Writer, done, total int, result Result) {
+	progress := fmt.Sprintf("[%d/%d]", done, total)
+	switch result.Status {
+	case ResultCompleted:
+		fmt.Fprintf(out, "%s ✅ %s (%s) → %s (%.1fs)\n", progress, result.Name, result.Type, result.Output, result.Duration)
+	case ResultSkipped:
+		fmt.Fprintf(out, "%s ⏭️  %s (%s) → %s already exists\n", progress, result.Name, result.Type, result.Output)
+	default:
+		fmt.Fprintf(out, "%s ❌ %s (%s): %s\n", progress, result.Name, result.Type, result.Error)
+	}
+}
+
+func summarize(results []Result) (completed, skipped, failed int) {
+	for _, result := range results {
+		switch result.Status {
+		case ResultCompleted:
+			completed++
+		case ResultSkipped:
+			skipped++
+		default:
+			failed++
+		}
+	}
+	return completed, skipped, failed
+}
+
+// report summarizes a batch run; it is written to the report file and printed
+// in structured output formats
+type report struct {
+	Total     int      `json:"total"`
+	Completed int      `json:"completed"`
+	Skipped   int      `json:"skipped"`
+	Failed    int      `json:"failed"`
+	Results   []Result `json:"results"`
+}
+
+func newReport(results []Result) report {
+	completed, skipped, failed := summarize(results)
+	return report{len(results), completed, skipped, failed, results}
+}
+
+func writeReport(path string, report report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+	return nil
+}
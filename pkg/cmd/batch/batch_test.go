@@ -0,0 +1,260 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/config"
+)
+
+func writeManifest(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	return path
+}
+
+func TestLoadManifestYAML(t *testing.T) {
+	path := writeManifest(t, "manifest.yaml", `
+concurrency: 2
+jobs:
+  - type: tts
+    name: intro
+    text: Hello
+    voice: voice-1
+    output: out/intro.wav
+  - type: image
+    prompt: A lighthouse
+    image: [a.jpg, b.jpg]
+    labeled-image: c.jpg:style
+  - type: video-motion
+    audio: https://example.com/intro.wav
+    image: avatar.jpg
+    positive-prompt: waving
+`)
+
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if manifest.Concurrency != 2 || len(manifest.Jobs) != 3 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+
+	dir := filepath.Dir(path)
+	if got := manifest.Jobs[0].Output; got != filepath.Join(dir, "out/intro.wav") {
+		t.Fatalf("expected output relative to manifest, got %s", got)
+	}
+	if got := manifest.Jobs[1].Image; len(got) != 2 || got[1] != filepath.Join(dir, "b.jpg") {
+		t.Fatalf("unexpected images: %v", got)
+	}
+	if got := manifest.Jobs[1].LabeledImage[0]; got != filepath.Join(dir, "c.jpg")+":style" {
+		t.Fatalf("unexpected labeled image: %s", got)
+	}
+	if got := manifest.Jobs[2].Image; len(got) != 1 || got[0] != filepath.Join(dir, "avatar.jpg") {
+		t.Fatalf("expected single image to be accepted as a string, got %v", got)
+	}
+	if got := manifest.Jobs[2].Audio; got != "https://example.com/intro.wav" {
+		t.Fatalf("expected URLs to be kept, got %s", got)
+	}
+}
+
+func TestLoadManifestYAMLList(t *testing.T) {
+	path := writeManifest(t, "manifest.yml", `
+- type: avatar
+  prompt: A friendly assistant
+`)
+
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(manifest.Jobs) != 1 || manifest.Jobs[0].Type != JobTypeAvatar {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+}
+
+func TestLoadManifestJSONL(t *testing.T) {
+	path := writeManifest(t, "manifest.jsonl", `{"type":"tts","text":"Hello","voice":"voice-1"}
+
+{"type":"video-talking","audio":"a.wav","image":"face.jpg"}
+`)
+
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(manifest.Jobs) != 2 || manifest.Jobs[1].Type != JobTypeVideoTalking {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+}
+
+func TestLoadManifestReportsAllInvalidJobs(t *testing.T) {
+	path := writeManifest(t, "manifest.yaml", `
+- type: tts
+  text: Hello
+- type: podcast
+- type: video-talking
+  audio: a.wav
+`)
+
+	_, err := LoadManifest(path)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"job 1 (tts-1): voice is required", "job 2", "unknown type", "job 3 (video-talking-3): exactly one image is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error to contain %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestRunnerRunsJobsAndSkipsExistingOutputs(t *testing.T) {
+	originalConfigPath := config.ConfigPath
+	config.ConfigPath = t.TempDir()
+	t.Cleanup(func() { config.ConfigPath = originalConfigPath })
+
+	var ttsCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/speech/tts":
+			atomic.AddInt32(&ttsCalls, 1)
+			fmt.Fprint(w, `{"data":{"id":"tts-1","b64_audio_str":"UklGRg=="}}`)
+		case "/v1/avatar/async_generate":
+			fmt.Fprint(w, `{"data":{"task_id":"task-1","status":"IN_QUEUE"}}`)
+		case "/v1/avatar/async_generate/task-1/status":
+			fmt.Fprint(w, `{"data":{"task_id":"task-1","status":"FAILED"}}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := client.New(&config.Config{APIToken: "test-token", APIURL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.wav")
+	if err := os.WriteFile(existing, []byte("done"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := []Job{
+		{Type: JobTypeTTS, Text: "Hello", Voice: "voice-1", Output: filepath.Join(dir, "new.wav")},
+		{Type: JobTypeTTS, Text: "Again", Voice: "voice-1", Output: existing},
+		{Type: JobTypeAvatar, Prompt: "A face", Output: filepath.Join(dir, "avatar.jpg")},
+	}
+
+	var reported int32
+	runner := &Runner{
+		Client:       c,
+		Concurrency:  2,
+		PollInterval: time.Millisecond,
+		OnResult:     func(Result) { atomic.AddInt32(&reported, 1) },
+	}
+	results := runner.Run(context.Background(), jobs)
+
+	if results[0].Status != ResultCompleted {
+		t.Fatalf("expected first job to complete, got %+v", results[0])
+	}
+	if data, err := os.ReadFile(jobs[0].Output); err != nil || string(data) != "RIFF" {
+		t.Fatalf("expected decoded audio output, got %q (%v)", data, err)
+	}
+	if results[1].Status != ResultSkipped {
+		t.Fatalf("expected existing output to be skipped, got %+v", results[1])
+	}
+	if results[2].Status != ResultFailed || results[2].TaskID != "task-1" || !strings.Contains(results[2].Error, "FAILED") {
+		t.Fatalf("expected failed avatar job, got %+v", results[2])
+	}
+	if ttsCalls != 1 {
+		t.Fatalf("expected 1 tts call, got %d", ttsCalls)
+	}
+	if reported != 3 {
+		t.Fatalf("expected 3 reported results, got %d", reported)
+	}
+}
+
+func TestLoadManifestRejectsOutOfRangeImageSeed(t *testing.T) {
+	path := writeManifest(t, "manifest.yaml", `
+- type: image
+  prompt: A lighthouse
+  seed: 4294967296
+- type: avatar
+  prompt: A face
+  seed: 4294967296
+`)
+
+	_, err := LoadManifest(path)
+	if err == nil || !strings.Contains(err.Error(), "job 1 (image-1): seed must be between -2147483648 and 2147483647") || strings.Contains(err.Error(), "job 2") {
+		t.Fatalf("expected only the image seed to be rejected, got: %v", err)
+	}
+}
+
+func TestRunnerOrdersJobsByTheirInputs(t *testing.T) {
+	originalConfigPath := config.ConfigPath
+	config.ConfigPath = t.TempDir()
+	t.Cleanup(func() { config.ConfigPath = originalConfigPath })
+
+	var imageCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/avatar/async_generate":
+			// Slow enough that a job not waiting for it would run first
+			time.Sleep(50 * time.Millisecond)
+			fmt.Fprint(w, `{"data":{"task_id":"task-1","status":"IN_QUEUE"}}`)
+		case "/v1/avatar/async_generate/task-1/status":
+			fmt.Fprint(w, `{"data":{"task_id":"task-1","status":"FAILED"}}`)
+		default:
+			atomic.AddInt32(&imageCalls, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := client.New(&config.Config{APIToken: "test-token", APIURL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	dir := t.TempDir()
+	jobs := []Job{
+		{Type: JobTypeImage, Name: "scene", Prompt: "A scene", Image: stringList{filepath.Join(dir, "face.jpg")}, Output: filepath.Join(dir, "scene.jpg")},
+		{Type: JobTypeAvatar, Name: "face", Prompt: "A face", Output: filepath.Join(dir, "face.jpg")},
+		{Type: JobTypeImage, Name: "a", Prompt: "A", Image: stringList{filepath.Join(dir, "b.jpg")}, Output: filepath.Join(dir, "a.jpg")},
+		{Type: JobTypeImage, Name: "b", Prompt: "B", LabeledImage: stringList{filepath.Join(dir, "a.jpg") + ":ref"}, Output: filepath.Join(dir, "b.jpg")},
+	}
+
+	runner := &Runner{Client: c, Concurrency: 4, PollInterval: time.Millisecond}
+	results := runner.Run(context.Background(), jobs)
+
+	if results[1].Status != ResultFailed {
+		t.Fatalf("expected the avatar job to fail, got %+v", results[1])
+	}
+	if results[0].Status != ResultFailed || results[0].Error != "job 2 (face), whose output it reads, failed" {
+		t.Fatalf("expected the image job to fail on its input, got %+v", results[0])
+	}
+	for _, result := range results[2:] {
+		if result.Status != ResultFailed || !strings.Contains(result.Error, "dependency cycle") {
+			t.Fatalf("expected a dependency cycle, got %+v", result)
+		}
+	}
+	if imageCalls != 0 {
+		t.Fatalf("expected no image requests, got %d", imageCalls)
+	}
+}
@@ -0,0 +1,324 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"gopkg.in/yaml.v3"
+)
+
+// JobType identifies the kind of work a manifest job performs
+type JobType string
+
+const (
+	JobTypeTTS          JobType = "tts"
+	JobTypeImage        JobType = "image"
+	JobTypeAvatar       JobType = "avatar"
+	JobTypeVideoTalking JobType = "video-talking"
+	JobTypeVideoMotion  JobType = "video-motion"
+)
+
+func (t JobType) IsValid() bool {
+	switch t {
+	case JobTypeTTS, JobTypeImage, JobTypeAvatar, JobTypeVideoTalking, JobTypeVideoMotion:
+		return true
+	default:
+		return false
+	}
+}
+
+// extension returns the file extension used for default output names
+func (t JobType) extension() string {
+	switch t {
+	case JobTypeTTS:
+		return ".wav"
+	case JobTypeImage, JobTypeAvatar:
+		return ".jpg"
+	default:
+		return ".mp4"
+	}
+}
+
+func GetSupportedJobTypesString() string {
+	types := []JobType{JobTypeTTS, JobTypeImage, JobTypeAvatar, JobTypeVideoTalking, JobTypeVideoMotion}
+	strs := make([]string, len(types))
+	for i, t := range types {
+		strs[i] = string(t)
+	}
+	return strings.Join(strs, ", ")
+}
+
+// stringList accepts either a single string or a list of strings
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = stringList{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Job is a single manifest entry. Field names follow the flags of the
+// equivalent CLI command.
+type Job struct {
+	Type   JobType `yaml:"type" json:"type"`
+	Name   string  `yaml:"name" json:"name"`
+	Output string  `yaml:"output" json:"output"`
+
+	// tts
+	Text             string   `yaml:"text" json:"text"`
+	Voice            string   `yaml:"voice" json:"voice"`
+	Chinese          string   `yaml:"chinese" json:"chinese"`
+	Temperature      *float32 `yaml:"temperature" json:"temperature"`
+	FragmentInterval *float32 `yaml:"fragment-interval" json:"fragment-interval"`
+
+	// image and avatar
+	Prompt       string     `yaml:"prompt" json:"prompt"`
+	AspectRatio  string     `yaml:"aspect-ratio" json:"aspect-ratio"`
+	Seed         int64      `yaml:"seed" json:"seed"`
+	Image        stringList `yaml:"image" json:"image"`
+	LabeledImage stringList `yaml:"labeled-image" json:"labeled-image"`
+
+	// video-talking and video-motion
+	Audio          string `yaml:"audio" json:"audio"`
+	PositivePrompt string `yaml:"positive-prompt" json:"positive-prompt"`
+	NegativePrompt string `yaml:"negative-prompt" json:"negative-prompt"`
+}
+
+// Manifest is a list of jobs to run, with optional run settings
+type Manifest struct {
+	Concurrency int   `yaml:"concurrency" json:"concurrency"`
+	Jobs        []Job `yaml:"jobs" json:"jobs"`
+}
+
+// LoadManifest reads a YAML or JSONL manifest. Files ending in .jsonl or
+// .ndjson hold one job per line; anything else is parsed as YAML, either as a
+// list of jobs or as a mapping with a "jobs" key.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest *Manifest
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		manifest, err = parseJSONLManifest(data)
+	default:
+		manifest, err = parseYAMLManifest(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifest.Jobs) == 0 {
+		return nil, fmt.Errorf("manifest %s contains no jobs", path)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	manifest.resolvePaths(filepath.Dir(path))
+	return manifest, nil
+}
+
+// resolvePaths makes relative file paths in the manifest relative to baseDir,
+// so a manifest behaves the same regardless of the working directory. URLs
+// are left as they are.
+func (m *Manifest) resolvePaths(baseDir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) || media.IsURL(path) {
+			return path
+		}
+		return filepath.Join(baseDir, path)
+	}
+
+	for i := range m.Jobs {
+		job := &m.Jobs[i]
+		job.Output = resolve(job.Output)
+		job.Audio = resolve(job.Audio)
+		for k := range job.Image {
+			job.Image[k] = resolve(job.Image[k])
+		}
+		for k, labeled := range job.LabeledImage {
+			if lastColon := strings.LastIndex(labeled, ":"); lastColon != -1 {
+				job.LabeledImage[k] = resolve(labeled[:lastColon]) + labeled[lastColon:]
+			}
+		}
+	}
+}
+
+func parseYAMLManifest(data []byte) (*Manifest, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	manifest := &Manifest{}
+	if len(node.Content) == 0 {
+		return manifest, nil
+	}
+
+	root := node.Content[0]
+	var err error
+	if root.Kind == yaml.SequenceNode {
+		err = root.Decode(&manifest.Jobs)
+	} else {
+		err = root.Decode(manifest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func parseJSONLManifest(data []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var job Job
+		if err := json.Unmarshal([]byte(line), &job); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest line %d: %w", lineNum, err)
+		}
+		manifest.Jobs = append(manifest.Jobs, job)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Validate checks every job and reports all problems at once, so a manifest
+// can be fixed before any credits are spent
+func (m *Manifest) Validate() error {
+	var problems []string
+	for i, job := range m.Jobs {
+		if err := job.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("  job %d (%s): %v", i+1, job.label(i), err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid manifest:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// Validate checks that the job has the parameters its type requires
+func (j Job) Validate() error {
+	if j.Type == "" {
+		return fmt.Errorf("type is required (%s)", GetSupportedJobTypesString())
+	}
+	if !j.Type.IsValid() {
+		return fmt.Errorf("unknown type %q. Supported types: %s", j.Type, GetSupportedJobTypesString())
+	}
+
+	switch j.Type {
+	case JobTypeTTS:
+		if j.Text == "" {
+			return fmt.Errorf("text is required")
+		}
+		if j.Voice == "" {
+			return fmt.Errorf("voice is required")
+		}
+		if j.Chinese != "" && j.Chinese != "mandarin" && j.Chinese != "yue" {
+			return fmt.Errorf("invalid chinese language variant. Use 'mandarin' or 'yue'")
+		}
+	case JobTypeImage:
+		if j.Prompt == "" {
+			return fmt.Errorf("prompt is required")
+		}
+		if j.Seed < math.MinInt32 || j.Seed > math.MaxInt32 {
+			return fmt.Errorf("seed must be between %d and %d for image jobs", math.MinInt32, math.MaxInt32)
+		}
+	case JobTypeAvatar:
+		if j.Prompt == "" {
+			return fmt.Errorf("prompt is required")
+		}
+		if len(j.Prompt) > 1000 {
+			return fmt.Errorf("prompt is too long (max 1000 characters, got %d)", len(j.Prompt))
+		}
+	case JobTypeVideoTalking, JobTypeVideoMotion:
+		if j.Audio == "" {
+			return fmt.Errorf("audio is required")
+		}
+		if len(j.Image) != 1 {
+			return fmt.Errorf("exactly one image is required")
+		}
+		if j.Type == JobTypeVideoMotion {
+			if j.PositivePrompt == "" {
+				return fmt.Errorf("positive-prompt is required")
+			}
+			if len(j.PositivePrompt) > 512 || len(j.NegativePrompt) > 512 {
+				return fmt.Errorf("prompts must be 512 characters or less")
+			}
+		}
+	}
+	return nil
+}
+
+// inputs returns the files the job reads
+func (j Job) inputs() []string {
+	inputs := []string{}
+	if j.Audio != "" {
+		inputs = append(inputs, j.Audio)
+	}
+	inputs = append(inputs, j.Image...)
+	for _, labeled := range j.LabeledImage {
+		if lastColon := strings.LastIndex(labeled, ":"); lastColon != -1 {
+			inputs = append(inputs, labeled[:lastColon])
+		}
+	}
+	return inputs
+}
+
+// label returns a human readable name for the job at index i
+func (j Job) label(i int) string {
+	if j.Name != "" {
+		return j.Name
+	}
+	return fmt.Sprintf("%s-%d", j.Type, i+1)
+}
+
+// outputPath returns the file the job writes, defaulting to a name derived
+// from the job so that re-running the manifest finds existing outputs
+func (j Job) outputPath(i int, defaultSavePath string) string {
+	if j.Output != "" {
+		return j.Output
+	}
+	return filepath.Join(defaultSavePath, j.label(i)+j.Type.extension())
+}
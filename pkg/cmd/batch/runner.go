@@ -0,0 +1,412 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/config"
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/mirako-ai/mirako-cli/internal/parallel"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/image"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-go/api"
+)
+
+// Result states reported for each job
+const (
+	ResultCompleted = "completed"
+	ResultSkipped   = "skipped"
+	ResultFailed    = "failed"
+)
+
+// Result describes the outcome of a single manifest job
+type Result struct {
+	Index    int     `json:"index"`
+	Name     string  `json:"name"`
+	Type     JobType `json:"type"`
+	Status   string  `json:"status"`
+	Output   string  `json:"output"`
+	TaskID   string  `json:"task_id,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+}
+
+// Runner executes manifest jobs with bounded concurrency
+type Runner struct {
+	Client          *client.Client
+	Concurrency     int
+	PollInterval    time.Duration
+	Timeout         time.Duration
+	DefaultSavePath string
+	// Config is recorded with every submitted task in the job ledger
+	Config *config.Config
+	// MaxRetries is how often an interrupted video download is resumed
+	MaxRetries int
+	// Force re-runs jobs whose output file already exists
+	Force bool
+	// OnResult is called once for every finished job; calls are serialized
+	OnResult func(Result)
+}
+
+// Run executes all jobs and returns their results in manifest order. A job
+// that reads another job's output runs after that job, and fails without
+// running when that job fails.
+func (r *Runner) Run(ctx context.Context, jobs []Job) []Result {
+	deps := dependencies(jobs, r.DefaultSavePath)
+	cyclic := onCycle(deps)
+	results := make([]Result, len(jobs))
+	var mu sync.Mutex
+
+	for _, stage := range stages(deps, cyclic) {
+		parallel.ForEach(ctx, len(stage), r.Concurrency, func(ctx context.Context, k int) error {
+			i := stage[k]
+			var blocked error
+			if cyclic[i] {
+				blocked = fmt.Errorf("its inputs depend on its own output (dependency cycle)")
+			}
+			for _, dep := range deps[i] {
+				if blocked == nil && results[dep].Status == ResultFailed {
+					blocked = fmt.Errorf("job %d (%s), whose output it reads, failed", dep+1, results[dep].Name)
+				}
+			}
+
+			result := r.runJob(ctx, i, jobs[i], blocked)
+
+			mu.Lock()
+			results[i] = result
+			if r.OnResult != nil {
+				r.OnResult(result)
+			}
+			mu.Unlock()
+			return nil
+		})
+	}
+	return results
+}
+
+// dependencies returns, for every job, the jobs whose output it reads
+func dependencies(jobs []Job, defaultSavePath string) [][]int {
+	producers := make(map[string]int, len(jobs))
+	for i, job := range jobs {
+		producers[filepath.Clean(job.outputPath(i, defaultSavePath))] = i
+	}
+
+	deps := make([][]int, len(jobs))
+	for i, job := range jobs {
+		for _, input := range job.inputs() {
+			if media.IsURL(input) {
+				continue
+			}
+			if producer, ok := producers[filepath.Clean(input)]; ok && !slices.Contains(deps[i], producer) {
+				deps[i] = append(deps[i], producer)
+			}
+		}
+	}
+	return deps
+}
+
+// onCycle reports for every job whether it depends on itself, directly or
+// through other jobs
+func onCycle(deps [][]int) []bool {
+	cyclic := make([]bool, len(deps))
+	for start := range deps {
+		seen := make([]bool, len(deps))
+		stack := append([]int(nil), deps[start]...)
+		for len(stack) > 0 && !cyclic[start] {
+			next := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if next == start {
+				cyclic[start] = true
+			} else if !seen[next] {
+				seen[next] = true
+				stack = append(stack, deps[next]...)
+			}
+		}
+	}
+	return cyclic
+}
+
+// stages groups the jobs so that every job comes after the jobs whose output
+// it reads. Jobs on a dependency cycle can't run and go in the first stage.
+func stages(deps [][]int, cyclic []bool) [][]int {
+	level := make([]int, len(deps))
+	for i := range level {
+		level[i] = -1
+	}
+	var levelOf func(i int) int
+	levelOf = func(i int) int {
+		if level[i] < 0 {
+			level[i] = 0
+			if !cyclic[i] {
+				for _, dep := range deps[i] {
+					level[i] = max(level[i], levelOf(dep)+1)
+				}
+			}
+		}
+		return level[i]
+	}
+
+	var stages [][]int
+	for i := range deps {
+		l := levelOf(i)
+		for len(stages) <= l {
+			stages = append(stages, nil)
+		}
+		stages[l] = append(stages[l], i)
+	}
+	return stages
+}
+
+// runJob runs a job unless its output already exists. blocked, when set, is
+// why the job can't run.
+func (r *Runner) runJob(ctx context.Context, index int, job Job, blocked error) Result {
+	result := Result{
+		Index:  index + 1,
+		Name:   job.label(index),
+		Type:   job.Type,
+		Output: job.outputPath(index, r.DefaultSavePath),
+	}
+
+	if !r.Force {
+		if _, err := os.Stat(result.Output); err == nil {
+			result.Status = ResultSkipped
+			return result
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		blocked = err
+	}
+	if blocked != nil {
+		result.Status = ResultFailed
+		result.Error = blocked.Error()
+		return result
+	}
+
+	start := time.Now()
+	taskID, err := r.execute(ctx, job, result.Output)
+	result.TaskID = taskID
+	result.Duration = time.Since(start).Seconds()
+	if err != nil {
+		result.Status = ResultFailed
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			result.Error = apiErr.GetUserFriendlyMessage()
+		} else {
+			result.Error = err.Error()
+		}
+		return result
+	}
+
+	result.Status = ResultCompleted
+	return result
+}
+
+// execute submits a job, waits for it and writes its output file
+func (r *Runner) execute(ctx context.Context, job Job, outputPath string) (string, error) {
+	switch job.Type {
+	case JobTypeTTS:
+		return "", r.runTTS(ctx, job, outputPath)
+	case JobTypeImage:
+		return r.runImage(ctx, job, outputPath)
+	case JobTypeAvatar:
+		return r.runAvatar(ctx, job, outputPath)
+	case JobTypeVideoTalking, JobTypeVideoMotion:
+		return r.runVideo(ctx, job, outputPath)
+	default:
+		return "", fmt.Errorf("unknown job type: %s", job.Type)
+	}
+}
+
+func (r *Runner) runTTS(ctx context.Context, job Job, outputPath string) error {
+	var chineseLanguage *api.TTSApiRequestBodyChineseLanguage
+	switch job.Chinese {
+	case "mandarin":
+		chinese := api.Mandarin
+		chineseLanguage = &chinese
+	case "yue":
+		chinese := api.Yue
+		chineseLanguage = &chinese
+	}
+
+	var opts *api.TTSParams
+	if job.Temperature != nil || job.FragmentInterval != nil {
+		opts = &api.TTSParams{
+			Temperature:      job.Temperature,
+			FragmentInterval: job.FragmentInterval,
+		}
+	}
+
+	resp, err := r.Client.TextToSpeech(ctx, job.Text, job.Voice, "b64_audio_str", chineseLanguage, opts)
+	if err != nil {
+		return err
+	}
+	if resp.Data == nil || resp.Data.B64AudioStr == nil {
+		return fmt.Errorf("no audio data received from server")
+	}
+	return writeBase64File(outputPath, *resp.Data.B64AudioStr)
+}
+
+func (r *Runner) runImage(ctx context.Context, job Job, outputPath string) (string, error) {
+	inputImages, err := image.ParseInputImages(ctx, io.Discard, job.Image, job.LabeledImage, true)
+	if err != nil {
+		return "", err
+	}
+
+	aspectRatio := job.AspectRatio
+	if aspectRatio == "" {
+		aspectRatio = "16:9"
+	}
+
+	var seed *int32
+	if job.Seed != 0 {
+		// Validate keeps image seeds within int32
+		s := int32(job.Seed)
+		seed = &s
+	}
+
+	resp, err := r.Client.GenerateImage(ctx, job.Prompt, api.AsyncGenerateImageApiRequestBodyAspectRatio(aspectRatio), seed, inputImages)
+	if err != nil {
+		return "", err
+	}
+	if resp.Data == nil {
+		return "", fmt.Errorf("unexpected response from server")
+	}
+
+	taskID := resp.Data.TaskId
+	util.RecordJob(r.Config, client.TaskKindImageGenerate, taskID, map[string]string{"prompt": job.Prompt, "aspect_ratio": aspectRatio}, outputPath)
+	result, err := r.wait(ctx, client.TaskKindImageGenerate, taskID)
+	if err != nil {
+		return taskID, err
+	}
+	if result.Image == nil {
+		return taskID, fmt.Errorf("no image data received from server")
+	}
+	return taskID, writeBase64File(outputPath, *result.Image)
+}
+
+func (r *Runner) runAvatar(ctx context.Context, job Job, outputPath string) (string, error) {
+	var seed *int64
+	if job.Seed != 0 {
+		seed = &job.Seed
+	}
+
+	resp, err := r.Client.GenerateAvatar(ctx, job.Prompt, seed)
+	if err != nil {
+		return "", err
+	}
+	if resp.Data == nil {
+		return "", fmt.Errorf("unexpected response from server")
+	}
+
+	taskID := resp.Data.TaskId
+	util.RecordJob(r.Config, client.TaskKindAvatarGenerate, taskID, map[string]string{"prompt": job.Prompt}, outputPath)
+	result, err := r.wait(ctx, client.TaskKindAvatarGenerate, taskID)
+	if err != nil {
+		return taskID, err
+	}
+	if result.Image == nil {
+		return taskID, fmt.Errorf("no image data received from server")
+	}
+	return taskID, writeBase64File(outputPath, *result.Image)
+}
+
+func (r *Runner) runVideo(ctx context.Context, job Job, outputPath string) (string, error) {
+	audioInput, err := readMedia(ctx, job.Audio, media.VideoAudioPolicy)
+	if err != nil {
+		return "", fmt.Errorf("failed to read audio file: %w", err)
+	}
+	imageInput, err := readMedia(ctx, job.Image[0], media.ImagePolicy)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image file: %w", err)
+	}
+	audioBase64 := audioInput.Base64()
+	imageBase64 := imageInput.Base64()
+
+	var kind client.TaskKind
+	var taskID string
+	inputs := map[string]string{"audio": job.Audio, "image": job.Image[0]}
+	if job.Type == JobTypeVideoMotion {
+		kind = client.TaskKindAvatarMotion
+		inputs["positive_prompt"] = job.PositivePrompt
+		resp, err := r.Client.GenerateAvatarMotion(ctx, audioBase64, imageBase64, job.PositivePrompt, job.NegativePrompt)
+		if err != nil {
+			return "", err
+		}
+		if resp.Data == nil {
+			return "", fmt.Errorf("unexpected response from server")
+		}
+		taskID = resp.Data.TaskId
+	} else {
+		kind = client.TaskKindTalkingAvatar
+		resp, err := r.Client.GenerateTalkingAvatar(ctx, audioBase64, imageBase64)
+		if err != nil {
+			return "", err
+		}
+		if resp.Data == nil {
+			return "", fmt.Errorf("unexpected response from server")
+		}
+		taskID = resp.Data.TaskId
+	}
+
+	util.RecordJob(r.Config, kind, taskID, inputs, outputPath)
+	result, err := r.wait(ctx, kind, taskID)
+	if err != nil {
+		return taskID, err
+	}
+	if result.FileURL == nil {
+		return taskID, fmt.Errorf("no video URL received from server")
+	}
+	if _, err := client.DownloadFile(ctx, *result.FileURL, outputPath, client.DownloadOptions{MaxRetries: r.MaxRetries}); err != nil {
+		return taskID, fmt.Errorf("failed to download video: %w", err)
+	}
+	return taskID, nil
+}
+
+func (r *Runner) wait(ctx context.Context, kind client.TaskKind, taskID string) (*client.TaskResult, error) {
+	return r.Client.WaitForTask(ctx, kind, taskID, client.WaitOptions{
+		Interval: r.PollInterval,
+		Timeout:  r.Timeout,
+		OnStatus: func(status string) { util.RecordJobStatus(taskID, status) },
+	})
+}
+
+// readMedia reads an input of a job and converts it to fit the upload limits
+func readMedia(ctx context.Context, source string, policy media.Policy) (*media.Input, error) {
+	in, err := media.Read(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := media.Preflight(in, policy, true); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// writeBase64File decodes base64 data, optionally prefixed as a data URL, into
+// path. Outputs are written under a temporary name first so that an interrupted
+// run never leaves a partial file that would be skipped on the next run, the
+// same scheme client.DownloadFile uses for videos.
+func writeBase64File(path, data string) error {
+	decoded, err := media.DecodeBase64(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode output data: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmpPath := path + ".part"
+	if err := os.WriteFile(tmpPath, decoded, 0644); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save output: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
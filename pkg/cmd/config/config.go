@@ -112,6 +112,7 @@ func runList(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Println("Configuration:")
+	fmt.Printf("  context: %s\n", cfg.ContextName())
 	fmt.Printf("  api-url: %s\n", cfg.APIURL)
 	fmt.Printf("  api-token: %s\n", formatToken(cfg.APIToken))
 	fmt.Printf("  default-voice: %s\n", cfg.DefaultVoice)
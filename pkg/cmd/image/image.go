@@ -1,23 +1,22 @@
 package image
 
 import (
-	"bufio"
-	"encoding/base64"
+	"context"
 	"fmt"
-	"os"
+	"io"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/mirako-ai/mirako-cli/internal/client"
 	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/internal/media"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
 	"github.com/mirako-ai/mirako-go/api"
 	"github.com/spf13/cobra"
 )
 
-var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
-
 func NewImageCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "image",
@@ -42,12 +41,15 @@ func newGenerateCmd() *cobra.Command {
 	cmd.Flags().StringP("prompt", "p", "", "Prompt for image generation")
 	cmd.Flags().StringP("aspect-ratio", "a", "16:9", "Aspect ratio for the image (1:1, 16:9, 2:3, 3:2, 3:4, 4:3, 9:16)")
 	cmd.Flags().Int32P("seed", "s", 0, "Seed for reproducible generation (optional)")
-	cmd.Flags().StringP("output", "o", "", "Output file path for the generated image (e.g., ./output/image.jpg)")
+	cmd.Flags().StringP("output", "o", "", "Output file path for the generated image (e.g., ./output/image.jpg, - for stdout)")
 	cmd.Flags().BoolP("no-save", "n", false, "Skip saving the image to disk")
 	cmd.Flags().IntP("poll-interval", "i", 2, "Polling interval in seconds for checking status")
+	cmd.Flags().Duration("timeout", 0, "Maximum time to wait for the task to finish, e.g. 10m (0 waits indefinitely)")
 	cmd.Flags().Bool("sync", false, "Use synchronous generation (instant results)")
-	cmd.Flags().StringArrayP("image", "", []string{}, "Input image path (can be specified multiple times)")
-	cmd.Flags().StringArrayP("labeled-image", "", []string{}, "Labeled input image in format path:label (can be specified multiple times)")
+	util.AddDetachFlags(cmd)
+	util.AddNormalizeFlag(cmd)
+	cmd.Flags().StringArrayP("image", "", []string{}, "Input image path or URL, - for standard input (can be specified multiple times)")
+	cmd.Flags().StringArrayP("labeled-image", "", []string{}, "Labeled input image in format path:label, the path may be a URL or - (can be specified multiple times)")
 
 	return cmd
 }
@@ -65,11 +67,18 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("prompt is required. Use --prompt flag")
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
 	aspectRatioStr, _ := cmd.Flags().GetString("aspect-ratio")
 	outputPath, _ := cmd.Flags().GetString("output")
 	noSave, _ := cmd.Flags().GetBool("no-save")
 	pollInterval, _ := cmd.Flags().GetInt("poll-interval")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
 	syncMode, _ := cmd.Flags().GetBool("sync")
+	wait, _ := cmd.Flags().GetBool("wait")
 	images, _ := cmd.Flags().GetStringArray("image")
 	labeledImages, _ := cmd.Flags().GetStringArray("labeled-image")
 
@@ -79,13 +88,23 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		seedPtr = &seed
 	}
 
+	if syncMode && !wait {
+		return fmt.Errorf("--wait=false cannot be used with --sync")
+	}
+
+	out, err := util.MediaProgressWriter(format, outputPath)
+	if err != nil {
+		return err
+	}
+
 	// Parse input images
-	inputImages, err := parseInputImages(images, labeledImages)
+	noNormalize, _ := cmd.Flags().GetBool("no-normalize")
+	inputImages, err := ParseInputImages(ctx, out, images, labeledImages, !noNormalize)
 	if err != nil {
 		return fmt.Errorf("failed to parse input images: %w", err)
 	}
 
-	client, err := client.New(cfg)
+	c, err := client.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
@@ -93,12 +112,12 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	// Use synchronous mode if requested
 	if syncMode {
 		aspectRatio := api.GenerateImageApiRequestBodyAspectRatio(aspectRatioStr)
-		fmt.Printf("🚀 Generating image synchronously...\n")
+		fmt.Fprintf(out, "🚀 Generating image synchronously...\n")
 
-		resp, err := client.GenerateImageSync(ctx, prompt, aspectRatio, seedPtr, inputImages)
+		resp, err := c.GenerateImageSync(ctx, prompt, aspectRatio, seedPtr, inputImages)
 		if err != nil {
 			if apiErr, ok := errors.IsAPIError(err); ok {
-				return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+				return apiErr.UserError()
 			}
 			return fmt.Errorf("failed to generate image: %w", err)
 		}
@@ -107,23 +126,20 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("unexpected response from server")
 		}
 
-		fmt.Printf("✅ Generation completed!\n")
-
-		if noSave {
-			fmt.Printf("📸 Image generated (%d bytes) - skipping save due to --no-save flag\n", len(*resp.Data.Image))
-			return nil
-		}
+		fmt.Fprintf(out, "✅ Generation completed!\n")
 
-		return saveImageFromBase64(*resp.Data.Image, outputPath, cfg.DefaultSavePath)
+		return finishGenerate(out, format, util.TaskOutput{Kind: client.TaskKindImageGenerate}, *resp.Data.Image, outputPath, cfg.DefaultSavePath, noSave)
 	}
 
 	// Async mode (default)
 	aspectRatio := api.AsyncGenerateImageApiRequestBodyAspectRatio(aspectRatioStr)
-	fmt.Printf("🚀 Starting image generation...\n")
-	resp, err := client.GenerateImage(ctx, prompt, aspectRatio, seedPtr, inputImages)
+	if wait {
+		fmt.Fprintf(out, "🚀 Starting image generation...\n")
+	}
+	resp, err := c.GenerateImage(ctx, prompt, aspectRatio, seedPtr, inputImages)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to generate image: %w", err)
 	}
@@ -133,203 +149,165 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	}
 
 	taskID := resp.Data.TaskId
-	fmt.Printf("✅ Image generation started!\n")
-	fmt.Printf("   Task ID: %s\n", taskID)
-
-	// Poll for status until complete
-	fmt.Printf("⏳ Waiting for generation to complete...\n")
-
-	// Use separate tickers for polling and spinner animation
-	pollTicker := time.NewTicker(time.Duration(pollInterval) * time.Second)
-	spinnerTicker := time.NewTicker(100 * time.Millisecond) // Smooth spinner animation
-	defer pollTicker.Stop()
-	defer spinnerTicker.Stop()
-
-	spinnerIndex := 0
-	currentStatus := "PROCESSING" // Initial status
-	clearLine := "\r\033[K"       // ANSI escape codes to clear the line
-
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Print(clearLine) // Clear the spinner line
-			return fmt.Errorf("operation cancelled: %w", ctx.Err())
-		case <-pollTicker.C:
-			statusResp, err := client.GetImageStatus(ctx, taskID)
-			if err != nil {
-				fmt.Print(clearLine) // Clear the spinner line
-				if apiErr, ok := errors.IsAPIError(err); ok {
-					return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
-				}
-				return fmt.Errorf("failed to check status: %w", err)
-			}
-
-			if statusResp.Data == nil {
-				fmt.Print(clearLine) // Clear the spinner line
-				return fmt.Errorf("unexpected response from server")
-			}
+	util.RecordJob(cfg, client.TaskKindImageGenerate, taskID, map[string]string{"prompt": prompt, "aspect_ratio": aspectRatioStr}, outputPath)
+	if !wait {
+		return util.PrintSubmittedTask(cmd, client.TaskKindImageGenerate, taskID, string(resp.Data.Status))
+	}
 
-			currentStatus = string(statusResp.Data.Status)
+	fmt.Fprintf(out, "✅ Image generation started!\n")
+	fmt.Fprintf(out, "   Task ID: %s\n", taskID)
 
-			if statusResp.Data.Status == api.GenerateTaskOutputStatusCOMPLETED {
-				fmt.Print(clearLine) // Clear the spinner line
-				fmt.Printf("✅ Generation completed!\n")
+	// Poll for status until complete
+	fmt.Fprintf(out, "⏳ Waiting for generation to complete...\n")
+
+	spinner := ui.NewSpinner(out, "Status: PROCESSING")
+	spinner.Start()
+	result, err := c.WaitForTask(ctx, client.TaskKindImageGenerate, taskID, client.WaitOptions{
+		Interval: time.Duration(pollInterval) * time.Second,
+		Timeout:  timeout,
+		OnStatus: func(status string) {
+			spinner.Update("Status: " + status)
+			util.RecordJobStatus(taskID, status)
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
+		}
+		return err
+	}
 
-				if statusResp.Data.Image != nil {
-					if noSave {
-						fmt.Printf("📸 Image generated (%d bytes) - skipping save due to --no-save flag\n", len(*statusResp.Data.Image))
-						return nil
-					}
+	fmt.Fprintf(out, "✅ Generation completed!\n")
 
-					return saveImageFromBase64(*statusResp.Data.Image, outputPath, cfg.DefaultSavePath)
-				}
+	taskOutput := util.TaskOutput{Kind: client.TaskKindImageGenerate, TaskID: taskID, Status: result.Status}
+	if result.Image == nil {
+		if !format.IsTable() {
+			return util.PrintOutput(format, taskOutput)
+		}
+		return nil
+	}
+	return finishGenerate(out, format, taskOutput, *result.Image, outputPath, cfg.DefaultSavePath, noSave)
+}
 
-				return nil
-			} else if statusResp.Data.Status == api.GenerateTaskOutputStatusFAILED ||
-				statusResp.Data.Status == api.GenerateTaskOutputStatusCANCELED ||
-				statusResp.Data.Status == api.GenerateTaskOutputStatusTIMEDOUT {
-				fmt.Print(clearLine) // Clear the spinner line
-				return fmt.Errorf("image generation failed with status: %s", statusResp.Data.Status)
-			}
-			// Update status but don't draw here - spinner ticker handles animation
-		case <-spinnerTicker.C:
-			// Update spinner animation smoothly
-			frame := spinnerFrames[spinnerIndex%len(spinnerFrames)]
-			fmt.Printf("\r\033[K%s Status: %s", frame, currentStatus)
-			spinnerIndex++
+// finishGenerate saves a generated image unless --no-save was given and prints
+// the result in structured output formats
+func finishGenerate(out io.Writer, format ui.OutputFormat, taskOutput util.TaskOutput, imageData, outputPath, defaultSavePath string, noSave bool) error {
+	if noSave {
+		fmt.Fprintf(out, "📸 Image generated (%d bytes) - skipping save due to --no-save flag\n", len(imageData))
+	} else {
+		savedPath, err := SaveImageFromBase64(out, imageData, outputPath, defaultSavePath)
+		if err != nil {
+			return err
 		}
+		taskOutput.Output = savedPath
 	}
+
+	if !format.IsTable() {
+		return util.PrintOutput(format, taskOutput)
+	}
+	return nil
 }
 
 func newStatusCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "status [task-id]",
 		Short: "Check image generation status",
-		Long:  `Check the status of an image generation task`,
-		Args:  cobra.ExactArgs(1),
-		RunE:  runStatus,
+		Long: `Check the status of an image generation task.
+
+When the task has completed, the generated image is saved to --output, or to the
+path requested when the task was submitted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runStatus,
 	}
+
+	util.AddSaveFlags(cmd)
+
+	return cmd
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 
-	cfg, err := util.GetConfig(cmd)
+	cfg, err := util.GetJobConfig(cmd, util.LookupJob(args[0]))
+	if err != nil {
+		return err
+	}
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	outputPath, _ := cmd.Flags().GetString("output")
+	out, err := util.MediaProgressWriter(format, outputPath)
 	if err != nil {
 		return err
 	}
 
 	taskID := args[0]
 
-	client, err := client.New(cfg)
+	c, err := client.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	resp, err := client.GetImageStatus(ctx, taskID)
+	result, err := c.GetTaskStatus(ctx, client.TaskKindImageGenerate, taskID)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to get status: %w", err)
 	}
+	util.RecordJobStatus(taskID, result.Status)
 
-	if resp.Data == nil {
-		return fmt.Errorf("unexpected response from server")
-	}
-
-	fmt.Printf("Task ID: %s\n", resp.Data.TaskId)
-
-	if resp.Data.Image != nil {
-		fmt.Printf("✅ Image generated successfully!\n")
-		fmt.Printf("   Image: %d bytes\n", len(*resp.Data.Image))
-
-		// Ask user if they want to save the image
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Print("\nWould you like to save the generated image? (Y/n): ")
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
+	fmt.Fprintf(out, "Task ID: %s\n", result.TaskID)
+	fmt.Fprintf(out, "Status: %s\n", result.Status)
 
-		if response == "" || response == "y" || response == "yes" {
-			// Generate default filename
-			defaultFilename := fmt.Sprintf("image_%s.jpg", taskID)
-			defaultPath := filepath.Join(cfg.DefaultSavePath, defaultFilename)
-
-			// Ask for save location
-			fmt.Printf("Enter save path [%s]: ", defaultPath)
-			savePath, _ := reader.ReadString('\n')
-			savePath = strings.TrimSpace(savePath)
-
-			if savePath == "" {
-				savePath = defaultPath
-			}
-
-			// Ensure the path ends with .jpg
-			if !strings.HasSuffix(strings.ToLower(savePath), ".jpg") && !strings.HasSuffix(strings.ToLower(savePath), ".jpeg") {
-				savePath += ".jpg"
-			}
+	if err := result.Err(); err != nil {
+		return err
+	}
 
-			// Decode base64 image
-			imageData := *resp.Data.Image
-			// Remove data URL prefix if present
-			if strings.HasPrefix(imageData, "data:image") {
-				commaIndex := strings.Index(imageData, ",")
-				if commaIndex != -1 {
-					imageData = imageData[commaIndex+1:]
-				}
-			}
+	taskOutput := util.TaskOutput{Kind: client.TaskKindImageGenerate, TaskID: result.TaskID, Status: result.Status}
+	if result.IsCompleted() && result.Image != nil {
+		fmt.Fprintf(out, "✅ Image generated successfully!\n")
+		fmt.Fprintf(out, "   Image: %d bytes\n", len(*result.Image))
 
-			decodedImage, err := base64.StdEncoding.DecodeString(imageData)
+		defaultPath := filepath.Join(cfg.DefaultSavePath, fmt.Sprintf("image_%s.jpg", taskID))
+		if savePath, ok := util.ResultSavePath(cmd, taskID, defaultPath); ok {
+			savedPath, err := SaveImageFromBase64(out, *result.Image, savePath, cfg.DefaultSavePath)
 			if err != nil {
-				return fmt.Errorf("failed to decode image data: %w", err)
-			}
-
-			// Create directory if it doesn't exist
-			dir := filepath.Dir(savePath)
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				return fmt.Errorf("failed to create directory: %w", err)
+				return err
 			}
-
-			// Save the file
-			if err := os.WriteFile(savePath, decodedImage, 0644); err != nil {
-				return fmt.Errorf("failed to save image: %w", err)
-			}
-
-			fmt.Printf("✅ Image saved to: %s\n", savePath)
+			taskOutput.Output = savedPath
 		} else {
-			fmt.Println("Image not saved.")
+			fmt.Fprintln(out, "Image not saved.")
 		}
 	}
 
+	if !format.IsTable() {
+		return util.PrintOutput(format, taskOutput)
+	}
 	return nil
 }
 
-// encodeImageToDataURL reads an image file and converts it to a data URL base64 format
-func encodeImageToDataURL(imagePath string) (string, error) {
-	// Read the image file
-	imageData, err := os.ReadFile(imagePath)
+// encodeImageToDataURL reads an image from a path, URL or standard input,
+// checks it before upload and converts it to a data URL
+func encodeImageToDataURL(ctx context.Context, out io.Writer, imagePath string, normalize bool) (string, error) {
+	in, err := media.Read(ctx, imagePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read image file %s: %w", imagePath, err)
 	}
-
-	// Detect content type
-	contentType := "image/jpeg"
-	ext := strings.ToLower(filepath.Ext(imagePath))
-	if ext == ".png" {
-		contentType = "image/png"
-	} else if ext == ".jpg" || ext == ".jpeg" {
-		contentType = "image/jpeg"
+	report, err := media.Preflight(in, media.ImagePolicy, normalize)
+	if err != nil {
+		return "", errors.NewValidationError(err)
 	}
-
-	// Encode to base64
-	encoded := base64.StdEncoding.EncodeToString(imageData)
-
-	// Return data URL format
-	return fmt.Sprintf("data:%s;base64,%s", contentType, encoded), nil
+	util.PrintMediaReport(out, report)
+	return in.DataURL(), nil
 }
 
-// parseInputImages parses --image and --labeled-image flags and returns a slice of LabeledImage
-func parseInputImages(images []string, labeledImages []string) (*[]api.LabeledImage, error) {
+// ParseInputImages parses --image and --labeled-image flags and returns a slice
+// of LabeledImage. Conversions made to fit the upload limits are reported on out.
+func ParseInputImages(ctx context.Context, out io.Writer, images []string, labeledImages []string, normalize bool) (*[]api.LabeledImage, error) {
 	if len(images) == 0 && len(labeledImages) == 0 {
 		return nil, nil
 	}
@@ -338,7 +316,7 @@ func parseInputImages(images []string, labeledImages []string) (*[]api.LabeledIm
 
 	// Process unlabeled images
 	for _, imagePath := range images {
-		dataURL, err := encodeImageToDataURL(imagePath)
+		dataURL, err := encodeImageToDataURL(ctx, out, imagePath, normalize)
 		if err != nil {
 			return nil, err
 		}
@@ -363,7 +341,7 @@ func parseInputImages(images []string, labeledImages []string) (*[]api.LabeledIm
 			return nil, fmt.Errorf("label cannot be empty for labeled image: %s", labeledImage)
 		}
 
-		dataURL, err := encodeImageToDataURL(imagePath)
+		dataURL, err := encodeImageToDataURL(ctx, out, imagePath, normalize)
 		if err != nil {
 			return nil, err
 		}
@@ -382,8 +360,9 @@ func parseInputImages(images []string, labeledImages []string) (*[]api.LabeledIm
 	return &result, nil
 }
 
-// saveImageFromBase64 saves a base64 encoded image to disk
-func saveImageFromBase64(imageData string, outputPath string, defaultSavePath string) error {
+// SaveImageFromBase64 saves a base64 encoded image to disk, or to stdout when
+// outputPath is "-", and returns the path it was written to
+func SaveImageFromBase64(out io.Writer, imageData string, outputPath string, defaultSavePath string) (string, error) {
 	// Determine output path
 	if outputPath == "" {
 		now := time.Now()
@@ -393,35 +372,21 @@ func saveImageFromBase64(imageData string, outputPath string, defaultSavePath st
 	}
 
 	// Ensure .jpg extension
-	if !strings.HasSuffix(strings.ToLower(outputPath), ".jpg") && !strings.HasSuffix(strings.ToLower(outputPath), ".jpeg") {
+	if !media.IsStdio(outputPath) && !strings.HasSuffix(strings.ToLower(outputPath), ".jpg") && !strings.HasSuffix(strings.ToLower(outputPath), ".jpeg") {
 		outputPath += ".jpg"
 	}
 
-	// Remove data URL prefix if present
-	if strings.HasPrefix(imageData, "data:image") {
-		commaIndex := strings.Index(imageData, ",")
-		if commaIndex != -1 {
-			imageData = imageData[commaIndex+1:]
-		}
-	}
-
-	// Decode base64 image
-	decodedImage, err := base64.StdEncoding.DecodeString(imageData)
+	// Decode base64 image, which may be a data URL
+	decodedImage, err := media.DecodeBase64(imageData)
 	if err != nil {
-		return fmt.Errorf("failed to decode image data: %w", err)
-	}
-
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+		return "", fmt.Errorf("failed to decode image data: %w", err)
 	}
 
 	// Save the file
-	if err := os.WriteFile(outputPath, decodedImage, 0644); err != nil {
-		return fmt.Errorf("failed to save image: %w", err)
+	if err := media.WriteFile(outputPath, decodedImage); err != nil {
+		return "", fmt.Errorf("failed to save image: %w", err)
 	}
 
-	fmt.Printf("💾 Image saved to: %s\n", outputPath)
-	return nil
+	fmt.Fprintf(out, "💾 Image saved to: %s\n", util.OutputName(outputPath))
+	return outputPath, nil
 }
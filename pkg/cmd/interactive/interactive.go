@@ -58,16 +58,17 @@ func runList(cmd *cobra.Command, args []string) error {
 	resp, err := client.ListSessions(context.Background())
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to list sessions: %w", err)
 	}
 
-	useJSON, _ := cmd.Flags().GetBool("json")
-	if useJSON {
-		data, _ := json.MarshalIndent(resp, "", "  ")
-		fmt.Println(string(data))
-		return nil
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return util.PrintOutput(format, resp)
 	}
 
 	if resp == nil || resp.Data == nil || len(*resp.Data) == 0 {
@@ -273,7 +274,7 @@ func runStart(cmd *cobra.Command, args []string) error {
 	resp, err := client.StartSession(context.Background(), body)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to start session: %w", err)
 	}
@@ -329,7 +330,7 @@ func runStop(cmd *cobra.Command, args []string) error {
 	resp, err := client.StopSessions(context.Background(), args)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to stop sessions: %w", err)
 	}
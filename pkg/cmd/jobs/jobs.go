@@ -0,0 +1,287 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/config"
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/internal/jobs"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/avatar"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/image"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/video"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+func NewJobsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "Manage locally recorded jobs",
+		Long: `List, inspect and resume asynchronous tasks submitted from this machine.
+
+Every avatar, image, video and voice clone task is recorded in a local job
+ledger, so an interrupted wait can be resumed later without losing the result.`,
+	}
+
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newShowCmd())
+	cmd.AddCommand(newResumeCmd())
+
+	return cmd
+}
+
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List recorded jobs",
+		Long:  `List jobs recorded in the local job ledger, most recent first`,
+		RunE:  runList,
+	}
+
+	cmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	cmd.Flags().IntP("limit", "n", 20, "Maximum number of jobs to show (0 shows all)")
+
+	return cmd
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	recorded, err := jobs.DefaultLedger().List()
+	if err != nil {
+		return err
+	}
+
+	limit, _ := cmd.Flags().GetInt("limit")
+	if limit > 0 && len(recorded) > limit {
+		recorded = recorded[:limit]
+	}
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		if recorded == nil {
+			recorded = []jobs.Job{}
+		}
+		return util.PrintOutput(format, recorded)
+	}
+
+	if len(recorded) == 0 {
+		fmt.Println("No jobs found")
+		return nil
+	}
+
+	t := ui.NewJobTable(os.Stdout)
+	for _, job := range recorded {
+		t.AddRow([]interface{}{
+			job.TaskID,
+			job.Kind,
+			valueOrDash(job.Context),
+			valueOrDash(job.Status),
+			valueOrDash(job.OutputPath),
+			ui.FormatTimestamp(job.SubmittedAt),
+		})
+	}
+	t.Flush()
+	return nil
+}
+
+func newShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [task-id]",
+		Short: "Show details of a recorded job",
+		Long:  `Show the details of a recorded job. A unique prefix of the task ID is accepted.`,
+		Args:  cobra.ExactArgs(1),
+		RunE:  runShow,
+	}
+
+	cmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	job, err := jobs.DefaultLedger().Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return util.PrintOutput(format, job)
+	}
+
+	fmt.Printf("Job Details:\n")
+	fmt.Printf("  Task ID: %s\n", job.TaskID)
+	fmt.Printf("  Kind: %s\n", job.Kind)
+	fmt.Printf("  Context: %s\n", valueOrDash(job.Context))
+	fmt.Printf("  API URL: %s\n", valueOrDash(job.APIURL))
+	fmt.Printf("  Status: %s\n", valueOrDash(job.Status))
+	fmt.Printf("  Output: %s\n", valueOrDash(job.OutputPath))
+	fmt.Printf("  Submitted: %s\n", ui.FormatTimestamp(job.SubmittedAt))
+	fmt.Printf("  Updated: %s\n", ui.FormatTimestamp(job.UpdatedAt))
+
+	if len(job.Inputs) > 0 {
+		keys := make([]string, 0, len(job.Inputs))
+		for key := range job.Inputs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Printf("  Inputs:\n")
+		for _, key := range keys {
+			fmt.Printf("    %s: %s\n", key, job.Inputs[key])
+		}
+	}
+	return nil
+}
+
+func newResumeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resume [task-id]",
+		Short: "Resume waiting for a recorded job",
+		Long: `Re-attach to a recorded job, wait for it to finish and save the result to the
+output path that was originally requested.
+
+The task is looked up with the auth context and API URL it was submitted with,
+unless --context is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runResume,
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Override the output file path recorded for the job (- for stdout)")
+	cmd.Flags().IntP("poll-interval", "p", 2, "Polling interval in seconds for checking status")
+	cmd.Flags().Duration("timeout", 0, "Maximum time to wait for the task to finish, e.g. 30m (0 waits indefinitely)")
+
+	return cmd
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	job, err := jobs.DefaultLedger().Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	cfg, err := util.GetJobConfig(cmd, job)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	outputPath := job.OutputPath
+	if cmd.Flags().Changed("output") {
+		outputPath, _ = cmd.Flags().GetString("output")
+	}
+	out, err := util.MediaProgressWriter(format, outputPath)
+	if err != nil {
+		return err
+	}
+	pollInterval, _ := cmd.Flags().GetInt("poll-interval")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	c, err := client.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	fmt.Fprintf(out, "⏳ Resuming %s...\n", job.Kind.Description())
+	fmt.Fprintf(out, "   Task ID: %s\n", job.TaskID)
+
+	status := job.Status
+	if status == "" {
+		status = "PROCESSING"
+	}
+	spinner := ui.NewSpinner(out, "Status: "+status)
+	spinner.Start()
+	result, err := c.WaitForTask(ctx, job.Kind, job.TaskID, client.WaitOptions{
+		Interval: time.Duration(pollInterval) * time.Second,
+		Timeout:  timeout,
+		OnStatus: func(status string) {
+			spinner.Update("Status: " + status)
+			util.RecordJobStatus(job.TaskID, status)
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
+		}
+		return err
+	}
+
+	fmt.Fprintf(out, "✅ Task completed!\n")
+	savedPath, captionsPath, err := saveJobResult(ctx, out, cfg, c, job, result, outputPath)
+	if err != nil {
+		return err
+	}
+
+	if !format.IsTable() {
+		taskOutput := util.TaskOutput{Kind: job.Kind, TaskID: job.TaskID, Status: result.Status, Output: savedPath, Captions: captionsPath}
+		if result.FileURL != nil {
+			taskOutput.FileURL = *result.FileURL
+		}
+		if result.OutputDuration != nil {
+			taskOutput.Duration = *result.OutputDuration
+		}
+		if result.ProfileID != nil {
+			taskOutput.ProfileID = *result.ProfileID
+		}
+		return util.PrintOutput(format, taskOutput)
+	}
+	return nil
+}
+
+// saveJobResult saves the result of a completed job the same way the
+// originating command would have and returns the saved path, if any, and the
+// path of the captions saved with a video
+func saveJobResult(ctx context.Context, out io.Writer, cfg *config.Config, c *client.Client, job *jobs.Job, result *client.TaskResult, outputPath string) (string, string, error) {
+	switch job.Kind {
+	case client.TaskKindAvatarGenerate:
+		if result.Image == nil {
+			return "", "", nil
+		}
+		savedPath, err := avatar.SaveAvatarImage(out, *result.Image, outputPath, cfg.DefaultSavePath)
+		return savedPath, "", err
+	case client.TaskKindImageGenerate:
+		if result.Image == nil {
+			return "", "", nil
+		}
+		savedPath, err := image.SaveImageFromBase64(out, *result.Image, outputPath, cfg.DefaultSavePath)
+		return savedPath, "", err
+	case client.TaskKindTalkingAvatar, client.TaskKindAvatarMotion:
+		return video.SaveVideoJobResult(ctx, out, cfg, c, job, result, outputPath)
+	case client.TaskKindAvatarBuild:
+		fmt.Fprintf(out, "   Avatar ID: %s\n", job.TaskID)
+		return "", "", nil
+	case client.TaskKindVoiceClone:
+		if result.ProfileID != nil {
+			fmt.Fprintf(out, "   Profile ID: %s\n", *result.ProfileID)
+		}
+		return "", "", nil
+	default:
+		return "", "", fmt.Errorf("unsupported job kind: %s", job.Kind)
+	}
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -2,23 +2,29 @@ package root
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/mirako-ai/mirako-cli/internal/config"
+	apierrors "github.com/mirako-ai/mirako-cli/internal/errors"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/agent"
 	"github.com/mirako-ai/mirako-cli/internal/updater"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/auth"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/avatar"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/batch"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/completion"
 	configcmd "github.com/mirako-ai/mirako-cli/pkg/cmd/config"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/image"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/interactive"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/jobs"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/speech"
 	updatecmd "github.com/mirako-ai/mirako-cli/pkg/cmd/update"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/video"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/voice"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -44,13 +50,53 @@ It allows you to:
 - Clone and manage voice profiles
 
 For more information, visit: https://mirako.ai`,
-	SilenceUsage: true,
-	Version:      getVersionString(),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Version:       getVersionString(),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Reject an invalid --output-format before any work is done
+		if _, err := util.GetOutputFormat(cmd); err != nil {
+			return apierrors.NewValidationError(err)
+		}
+		return nil
+	},
 }
 
+// Execute runs the CLI and prints any error to stderr. Use errors.ExitCode on
+// the returned error to get the process exit code.
 func Execute() error {
 	rootCmd.Version = versionStringForArgs(os.Args[1:])
-	return rootCmd.Execute()
+	cmd, err := rootCmd.ExecuteC()
+	if err != nil {
+		printError(cmd, err)
+	}
+	return err
+}
+
+// printError writes err to stderr, as a JSON object when a structured output
+// format is selected so scripts can inspect the status code and API detail
+func printError(cmd *cobra.Command, err error) {
+	if format, formatErr := util.GetOutputFormat(cmd); formatErr == nil && !format.IsTable() {
+		data, marshalErr := json.MarshalIndent(apierrors.NewErrorOutput(err), "", "  ")
+		if marshalErr == nil {
+			fmt.Fprintln(os.Stderr, string(data))
+			return
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+}
+
+// markUsageErrors makes argument validation errors of every command exit with
+// the validation exit code
+func markUsageErrors(cmd *cobra.Command) {
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			return apierrors.NewValidationError(args(cmd, a))
+		}
+	}
+	for _, child := range cmd.Commands() {
+		markUsageErrors(child)
+	}
 }
 
 func getVersionString() string {
@@ -91,6 +137,8 @@ func init() {
 	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug mode")
 	rootCmd.PersistentFlags().String("api-token", "", "API token for authentication")
 	rootCmd.PersistentFlags().String("api-url", "", "API URL (default https://mirako.co)")
+	rootCmd.PersistentFlags().String(util.ContextFlag, "", "Auth context to use (default current_context from the config file)")
+	rootCmd.PersistentFlags().String(util.OutputFormatFlag, ui.FormatTable, "Output format: "+ui.SupportedOutputFormats)
 
 	// Set custom version template to show only the version string
 	rootCmd.SetVersionTemplate("{{.Version}}\n")
@@ -99,14 +147,21 @@ func init() {
 	rootCmd.AddCommand(agent.NewAgentCmd())
 	rootCmd.AddCommand(auth.NewAuthCmd())
 	rootCmd.AddCommand(avatar.NewAvatarCmd())
+	rootCmd.AddCommand(batch.NewBatchCmd())
 	rootCmd.AddCommand(completion.NewCompletionCmd())
 	rootCmd.AddCommand(configcmd.NewConfigCmd())
 	rootCmd.AddCommand(image.NewImageCmd())
 	rootCmd.AddCommand(interactive.NewInteractiveCmd())
+	rootCmd.AddCommand(jobs.NewJobsCmd())
 	rootCmd.AddCommand(speech.NewSpeechCmd())
 	rootCmd.AddCommand(updatecmd.NewUpdateCmd(func() string { return Version }))
 	rootCmd.AddCommand(video.NewVideoCmd())
 	rootCmd.AddCommand(voice.NewVoiceCmd())
+
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return apierrors.NewValidationError(err)
+	})
+	markUsageErrors(rootCmd)
 }
 
 func initConfig() {
@@ -0,0 +1,296 @@
+package speech
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/mirako-ai/mirako-cli/internal/tts"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
+	"github.com/mirako-ai/mirako-go/api"
+	"github.com/spf13/cobra"
+)
+
+// scriptTimings is the timing map written next to a rendered script and the
+// structured output of `speech script render`
+type scriptTimings struct {
+	Audio    string             `json:"audio"`
+	Timings  string             `json:"timings,omitempty"`
+	Duration float64            `json:"duration"`
+	Lines    []scriptLineTiming `json:"lines"`
+}
+
+// scriptLineTiming gives the offsets of a script line in seconds
+type scriptLineTiming struct {
+	Line    int     `json:"line"`
+	Speaker string  `json:"speaker"`
+	Voice   string  `json:"voice"`
+	Text    string  `json:"text"`
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+}
+
+func newScriptCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "script",
+		Short: "Render multi-speaker scripts",
+		Long:  `Render dialogue scripts with several speakers into a single audio track`,
+	}
+
+	cmd.AddCommand(newScriptRenderCmd())
+
+	return cmd
+}
+
+func newScriptRenderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render [script.yaml]",
+		Short: "Render a script to one WAV file",
+		Long: `Render a YAML script to a single WAV file and a JSON timing map.
+
+Speakers map to voice profiles, given by ID or by the name of one of your
+custom or premade voice profiles. Lines are spoken in order with the script
+pause between them; a line with only a pause replaces it. Line text may use
+the same markup as speech tts.
+
+The timing map lists the start and end of every line in seconds, e.g. to
+create captions. It is written next to the audio unless --timings is given.
+
+Example script:
+
+  speakers:
+    host: <voice-profile-id>
+    guest:
+      voice: <premade voice name>
+      chinese: yue
+      temperature: 0.7
+  pause: 400ms
+  lines:
+    - speaker: host
+      text: Welcome to the show.
+    - speaker: guest
+      text: Thanks for having me.
+      pause: 1s
+    - speaker: host
+      text: Let's get started.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runScriptRender,
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Output file path for the audio, - for stdout (default: the script path with a .wav extension)")
+	cmd.Flags().String("timings", "", "Output file path for the timing map (default: <output>.timings.json)")
+	cmd.Flags().Duration("gap", 100*time.Millisecond, "Pause between the chunks of a long line")
+	cmd.Flags().Int("max-chunk", 1000, fmt.Sprintf("Maximum characters per request (up to %d)", tts.MaxChunkRunes))
+	cmd.Flags().Int("concurrency", 4, "Number of requests to send at once")
+
+	return cmd
+}
+
+func runScriptRender(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	cfg, err := util.GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	scriptPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	timingsPath, _ := cmd.Flags().GetString("timings")
+	gap, _ := cmd.Flags().GetDuration("gap")
+	maxChunk, _ := cmd.Flags().GetInt("max-chunk")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+
+	if maxChunk < 1 || maxChunk > tts.MaxChunkRunes {
+		return fmt.Errorf("--max-chunk must be between 1 and %d", tts.MaxChunkRunes)
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if gap < 0 {
+		return fmt.Errorf("--gap cannot be negative")
+	}
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := util.MediaProgressWriter(format, outputPath)
+	if err != nil {
+		return err
+	}
+
+	script, err := tts.LoadScript(scriptPath)
+	if err != nil {
+		return errors.NewValidationError(err)
+	}
+
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(scriptPath, filepath.Ext(scriptPath)) + ".wav"
+	}
+	if !media.IsStdio(outputPath) && !strings.HasSuffix(strings.ToLower(outputPath), ".wav") {
+		outputPath += ".wav"
+	}
+	// Audio written to stdout gets timings only when --timings is given
+	if timingsPath == "" && !media.IsStdio(outputPath) {
+		timingsPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".timings.json"
+	}
+
+	client, err := client.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	voices, err := resolveScriptVoices(ctx, client, script)
+	if err != nil {
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
+		}
+		return err
+	}
+
+	fmt.Fprintf(out, "🎬 Rendering %s...\n", filepath.Base(scriptPath))
+	spinner := ui.NewSpinner(out, "Generating audio...")
+	spinner.Start()
+
+	result, err := tts.Render(ctx, client, script, voices, tts.RenderOptions{
+		MaxRunes:    maxChunk,
+		Gap:         gap,
+		Concurrency: concurrency,
+		OnProgress: func(done, total int) {
+			spinner.Update(fmt.Sprintf("Generating audio %d/%d chunks...", done, total))
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("operation cancelled: %w", ctx.Err())
+		}
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
+		}
+		return fmt.Errorf("failed to render script: %w", err)
+	}
+
+	if err := saveWAV(outputPath, result.Audio); err != nil {
+		return fmt.Errorf("failed to save audio: %w", err)
+	}
+
+	timings := scriptTimings{Audio: filepath.Base(outputPath), Duration: seconds(result.Audio.Duration())}
+	for _, line := range result.Lines {
+		timings.Lines = append(timings.Lines, scriptLineTiming{
+			Line:    line.Line,
+			Speaker: line.Speaker,
+			Voice:   line.Voice,
+			Text:    line.Text,
+			Start:   seconds(line.Start),
+			End:     seconds(line.End),
+		})
+	}
+	if timingsPath != "" {
+		if err := writeJSONFile(timingsPath, timings); err != nil {
+			return fmt.Errorf("failed to save timings: %w", err)
+		}
+	}
+
+	if format.IsTable() {
+		printScriptTimings(out, timings.Lines)
+	}
+	fmt.Fprintf(out, "✅ Audio saved to: %s\n", util.OutputName(outputPath))
+	if timingsPath != "" {
+		fmt.Fprintf(out, "🕒 Timings saved to: %s\n", timingsPath)
+	}
+	fmt.Fprintf(out, "📊 Duration: %.2f seconds\n", timings.Duration)
+
+	if !format.IsTable() {
+		timings.Audio = outputPath
+		timings.Timings = timingsPath
+		return util.PrintOutput(format, timings)
+	}
+	return nil
+}
+
+// resolveScriptVoices maps every speaker to a voice profile ID. A speaker voice
+// is used as is when it is the ID of a custom or premade profile, and otherwise
+// looked up by profile name.
+func resolveScriptVoices(ctx context.Context, c *client.Client, script *tts.Script) (map[string]string, error) {
+	premadeResp, err := c.ListPremadeProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	customResp, err := c.ListVoiceProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []api.PresignedVoiceProfile
+	if customResp != nil && customResp.Data != nil {
+		profiles = append(profiles, *customResp.Data...)
+	}
+	if premadeResp != nil && premadeResp.Data != nil {
+		profiles = append(profiles, *premadeResp.Data...)
+	}
+
+	voices := map[string]string{}
+	for _, name := range script.SpeakerNames() {
+		voice := strings.TrimSpace(script.Speakers[name].Voice)
+		var matches []string
+		for _, profile := range profiles {
+			if profile.Id == voice {
+				matches = []string{profile.Id}
+				break
+			}
+			if profile.Name != nil && strings.EqualFold(strings.TrimSpace(*profile.Name), voice) {
+				matches = append(matches, profile.Id)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, errors.NewValidationError(fmt.Errorf("speaker %q: no custom or premade voice profile with the ID or name %q. Use 'mirako voice list' or 'mirako voice premade' to see available voices", name, voice))
+		case 1:
+			voices[name] = matches[0]
+		default:
+			return nil, errors.NewValidationError(fmt.Errorf("speaker %q: %d voice profiles are named %q, use the profile ID instead", name, len(matches), voice))
+		}
+	}
+	return voices, nil
+}
+
+func printScriptTimings(out io.Writer, lines []scriptLineTiming) {
+	t := ui.NewScriptTimingTable(out)
+	for _, line := range lines {
+		text := line.Text
+		if runes := []rune(text); len(runes) > 60 {
+			text = string(runes[:57]) + "..."
+		}
+		t.AddRow([]interface{}{line.Line, line.Speaker, fmt.Sprintf("%.2fs", line.Start), fmt.Sprintf("%.2fs", line.End), text})
+	}
+	t.Flush()
+	fmt.Fprintln(out)
+}
+
+// seconds rounds d to milliseconds
+func seconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*1000) / 1000
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
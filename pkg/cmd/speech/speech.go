@@ -1,21 +1,50 @@
 package speech
 
 import (
-	"encoding/base64"
+	stderrors "errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/mirako-ai/mirako-cli/internal/audio"
 	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/config"
 	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/mirako-ai/mirako-cli/internal/stt"
+	"github.com/mirako-ai/mirako-cli/internal/tts"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
 	"github.com/mirako-ai/mirako-go/api"
 	"github.com/spf13/cobra"
 )
 
-var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+// sttOutput is the structured output of `speech stt`
+type sttOutput struct {
+	Text     string                  `json:"text"`
+	Output   string                  `json:"output,omitempty"`
+	Segments []stt.TranscriptSegment `json:"segments,omitempty"`
+}
+
+// Modes of splitting long audio for `speech stt`
+const (
+	splitSilence = "silence"
+	splitFixed   = "fixed"
+)
+
+// sttCheckpointDir holds the progress of split transcriptions, below the
+// config directory
+const sttCheckpointDir = "stt"
+
+// ttsOutput is the structured output of `speech tts`
+type ttsOutput struct {
+	Output   string   `json:"output"`
+	Duration *float64 `json:"duration,omitempty"`
+	Chunks   int      `json:"chunks"`
+}
 
 func NewSpeechCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -26,6 +55,7 @@ func NewSpeechCmd() *cobra.Command {
 
 	cmd.AddCommand(newSTTCmd())
 	cmd.AddCommand(newTTSCmd())
+	cmd.AddCommand(newScriptCmd())
 
 	return cmd
 }
@@ -34,12 +64,36 @@ func newSTTCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "stt",
 		Short: "Speech to text",
-		Long:  `Convert audio to text using speech recognition`,
-		RunE:  runSTT,
+		Long: `Convert audio to text using speech recognition.
+
+With --format srt, vtt or json the transcript is written with timestamps. When
+the API returns no timestamps, WAV audio is split at pauses and transcribed
+segment by segment; for other formats the whole recording becomes a single
+segment. The format defaults to the extension of --output.
+
+WAV recordings longer than --split-over are split locally, at pauses or into
+fixed windows (--split), and the parts are transcribed concurrently. When some
+parts fail, the finished ones are kept and running the same command again
+only transcribes the rest.
+
+WAV audio is converted to 16 kHz mono before it is sent, unless
+--no-normalize is given.`,
+		Example: `  mirako speech stt --audio meeting.wav
+  mirako speech stt --audio https://example.com/talk.wav --output talk.srt
+  mirako speech stt --audio talk.wav --format vtt > talk.vtt
+  mirako speech stt --audio all-hands.wav --split fixed --window 1m --concurrency 8`,
+		RunE: runSTT,
 	}
 
-	cmd.Flags().StringP("audio", "a", "", "Path to the audio file to convert to text")
-	cmd.Flags().StringP("output", "o", "", "Output file path for the text (optional, prints to stdout if not provided)")
+	cmd.Flags().StringP("audio", "a", "", "Path or URL of the audio file to convert to text (- for standard input)")
+	cmd.Flags().StringP("output", "o", "", "Output file path for the text (optional, prints to stdout if not provided or -)")
+	cmd.Flags().String("format", "", fmt.Sprintf("Transcript format (%s, default text)", strings.Join(stt.Formats, ", ")))
+	cmd.Flags().Duration("split-over", stt.DefaultSplitOver, "Split WAV audio longer than this into parts before sending")
+	cmd.Flags().String("split", splitSilence, "How to split long audio: silence (at pauses) or fixed (fixed windows)")
+	cmd.Flags().Duration("window", stt.DefaultSplitOptions.Window, "Maximum length of each part of split audio")
+	cmd.Flags().Int("concurrency", 4, "Number of parts to transcribe at once")
+	cmd.Flags().Bool("restart", false, "Discard the progress of an earlier failed run and transcribe everything again")
+	util.AddNormalizeFlag(cmd)
 
 	return cmd
 }
@@ -58,105 +112,157 @@ func runSTT(cmd *cobra.Command, args []string) error {
 	}
 
 	outputPath, _ := cmd.Flags().GetString("output")
+	transcriptFormat, _ := cmd.Flags().GetString("format")
+	if transcriptFormat == "" {
+		transcriptFormat = stt.FormatForPath(outputPath)
+	}
+	if !slices.Contains(stt.Formats, transcriptFormat) {
+		return fmt.Errorf("invalid format %q. Supported formats: %s", transcriptFormat, strings.Join(stt.Formats, ", "))
+	}
+
+	splitOver, _ := cmd.Flags().GetDuration("split-over")
+	splitMode, _ := cmd.Flags().GetString("split")
+	window, _ := cmd.Flags().GetDuration("window")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	restart, _ := cmd.Flags().GetBool("restart")
+
+	splitOptions := stt.DefaultSplitOptions
+	switch splitMode {
+	case splitSilence:
+	case splitFixed:
+		splitOptions.MinSilence = 0
+	default:
+		return fmt.Errorf("invalid split mode %q. Use '%s' or '%s'", splitMode, splitSilence, splitFixed)
+	}
+	if window < time.Second {
+		return fmt.Errorf("--window must be at least 1s")
+	}
+	splitOptions.Window = window
+	if splitOver <= 0 {
+		return fmt.Errorf("--split-over must be positive")
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
 
-	// Read and encode the audio file
-	audioData, err := os.ReadFile(audioPath)
+	checkpointDir := filepath.Join(config.DefaultUserConfigDirPath(), sttCheckpointDir)
+	if config.ConfigPath != "" {
+		checkpointDir = filepath.Join(config.ConfigPath, sttCheckpointDir)
+	}
+
+	format, err := util.GetOutputFormat(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to read audio file: %w", err)
+		return err
+	}
+	out, err := util.MediaProgressWriter(format, outputPath)
+	if err != nil {
+		return err
 	}
 
-	// Encode as base64
-	encodedAudio := base64.StdEncoding.EncodeToString(audioData)
+	audioInput, err := util.ReadMedia(cmd, out, audioPath, media.SpeechPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to read audio file: %w", err)
+	}
 
 	client, err := client.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	// Start STT processing with spinner
-	fmt.Printf("🎤 Converting speech to text...\n")
-
-	// Show loading spinner
-	spinnerTicker := time.NewTicker(100 * time.Millisecond)
-	defer spinnerTicker.Stop()
-
-	spinnerIndex := 0
-	clearLine := "\r\033[K"
-
-	// Create a channel to receive the result
-	resultChan := make(chan *api.STTApiResponseBody, 1)
-	errorChan := make(chan error, 1)
-
-	// Start the API call in a goroutine
-	go func() {
-		resp, err := client.SpeechToText(ctx, encodedAudio)
-		if err != nil {
-			errorChan <- err
-			return
-		}
-		resultChan <- resp
-	}()
-
-	// Wait for result with spinner
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Print(clearLine)
+	fmt.Fprintf(out, "🎤 Converting speech to text...\n")
+	spinner := ui.NewSpinner(out, "Processing...")
+	spinner.Start()
+	result, err := stt.Transcribe(ctx, client, audioInput, stt.Options{
+		Split:         splitOptions,
+		SplitOver:     splitOver,
+		Timestamps:    transcriptFormat != stt.FormatText,
+		Concurrency:   concurrency,
+		CheckpointDir: checkpointDir,
+		Restart:       restart,
+		OnProgress: func(done, total int) {
+			spinner.Update(fmt.Sprintf("Transcribing part %d/%d...", done, total))
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		if ctx.Err() != nil {
 			return fmt.Errorf("operation cancelled: %w", ctx.Err())
-		case err := <-errorChan:
-			fmt.Print(clearLine)
-			if apiErr, ok := errors.IsAPIError(err); ok {
-				return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
-			}
+		}
+		var windowErr *stt.WindowError
+		if stderrors.As(err, &windowErr) {
 			return fmt.Errorf("failed to convert speech to text: %w", err)
-		case resp := <-resultChan:
-			fmt.Print(clearLine)
-			if resp.Data == nil {
-				return fmt.Errorf("unexpected response from server")
-			}
-
-			text := resp.Data.Text
-
-			if outputPath != "" {
-				// Save to file
-				dir := filepath.Dir(outputPath)
-				if err := os.MkdirAll(dir, 0755); err != nil {
-					return fmt.Errorf("failed to create directory: %w", err)
-				}
-
-				if err := os.WriteFile(outputPath, []byte(text), 0644); err != nil {
-					return fmt.Errorf("failed to save text: %w", err)
-				}
+		}
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
+		}
+		return fmt.Errorf("failed to convert speech to text: %w", err)
+	}
 
-				fmt.Printf("✅ Text saved to: %s\n", outputPath)
-			} else {
-				// Print to stdout
-				fmt.Printf("📝 Transcribed text:\n%s\n", text)
-			}
+	if media.IsStdio(outputPath) {
+		if err := stt.Write(os.Stdout, transcriptFormat, result); err != nil {
+			return err
+		}
+	} else if outputPath != "" {
+		if err := stt.WriteFile(outputPath, transcriptFormat, result); err != nil {
+			return fmt.Errorf("failed to save text: %w", err)
+		}
+		fmt.Fprintf(out, "✅ Text saved to: %s\n", outputPath)
+	} else if format.IsTable() {
+		if transcriptFormat == stt.FormatText {
+			fmt.Fprintf(out, "📝 Transcribed text:\n%s\n", result.Text)
+		} else if err := stt.Write(os.Stdout, transcriptFormat, result); err != nil {
+			return err
+		}
+	}
+	if result.Resumed > 0 {
+		fmt.Fprintf(out, "ℹ️  Resumed an earlier run, %d parts were already transcribed\n", result.Resumed)
+	}
 
-			return nil
-		case <-spinnerTicker.C:
-			frame := spinnerFrames[spinnerIndex%len(spinnerFrames)]
-			fmt.Printf("\r\033[K%s Processing...", frame)
-			spinnerIndex++
+	if !format.IsTable() {
+		output := sttOutput{Text: result.Text, Output: outputPath}
+		if transcriptFormat != stt.FormatText {
+			output.Segments = result.Transcript().Segments
 		}
+		return util.PrintOutput(format, output)
 	}
+	return nil
 }
 
 func newTTSCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "tts",
 		Short: "Text to speech",
-		Long:  `Convert text to speech audio using a voice profile`,
-		RunE:  runTTS,
+		Long: `Convert text to speech audio using a voice profile.
+
+The text is read from --text, --file (- for standard input), or from standard
+input when it is piped or redirected from a file. Long text is split at
+sentence boundaries into chunks that are synthesized in parallel and joined
+into a single WAV file.
+
+The text may contain a small SSML-like markup:
+
+  <break time="800ms"/>                pause for the given time (500ms by default)
+  <voice id="PROFILE_ID">...</voice>   speak with another voice profile
+  <voice temperature="0.6">...</voice> change the temperature
+  <lang chinese="yue">...</lang>       switch between Mandarin and Cantonese`,
+		Example: `  mirako speech tts -v VOICE_ID -t "Hello there"
+  mirako speech tts -v VOICE_ID --file chapter1.txt -o chapter1.wav
+  cat script.txt | mirako speech tts -v VOICE_ID --pause 300ms
+  mirako speech tts -v VOICE_ID -t "Hi" -o - | mirako video generate -m talking_avatar --audio - --image face.jpg`,
+		RunE: runTTS,
 	}
 
 	cmd.Flags().StringP("text", "t", "", "Text to convert to speech")
+	cmd.Flags().StringP("file", "F", "", "Read the text from a file or URL (use - for standard input)")
 	cmd.Flags().StringP("voice", "v", "", "Voice profile ID to use")
-	cmd.Flags().StringP("output", "o", "", "Output file path for the audio file (e.g., ./output/audio.wav)")
+	cmd.Flags().StringP("output", "o", "", "Output file path for the audio file (e.g., ./output/audio.wav, - for stdout)")
 	cmd.Flags().StringP("chinese", "c", "", "Chinese language variant (mandarin or yue)")
 	cmd.Flags().Float32P("temperature", "T", 1.0, "Temperature for TTS generation (0.0-1.0)")
 	cmd.Flags().Float32P("fragment-interval", "f", 0.1, "Fragment interval between sentences (0.0-1.0)")
+	cmd.Flags().Int("max-chunk", 1000, fmt.Sprintf("Maximum characters per request (up to %d)", tts.MaxChunkRunes))
+	cmd.Flags().Duration("pause", 0, "Pause between chunks (default: the fragment interval)")
+	cmd.Flags().Duration("paragraph-pause", 500*time.Millisecond, "Pause at blank lines between paragraphs")
+	cmd.Flags().Int("concurrency", 4, "Number of chunks to synthesize at once")
 
 	return cmd
 }
@@ -169,9 +275,9 @@ func runTTS(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	text, _ := cmd.Flags().GetString("text")
-	if text == "" {
-		return fmt.Errorf("text is required. Use --text flag")
+	text, err := readTTSText(cmd)
+	if err != nil {
+		return err
 	}
 
 	voiceProfileID, _ := cmd.Flags().GetString("voice")
@@ -183,21 +289,42 @@ func runTTS(cmd *cobra.Command, args []string) error {
 	chinese, _ := cmd.Flags().GetString("chinese")
 	temperature, _ := cmd.Flags().GetFloat32("temperature")
 	fragmentInterval, _ := cmd.Flags().GetFloat32("fragment-interval")
+	maxChunk, _ := cmd.Flags().GetInt("max-chunk")
+	pause, _ := cmd.Flags().GetDuration("pause")
+	paragraphPause, _ := cmd.Flags().GetDuration("paragraph-pause")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
 
-	// Prepare TTS parameters
-	var chineseLanguage *api.TTSApiRequestBodyChineseLanguage
-	if chinese != "" {
-		if chinese == "mandarin" {
-			chinese := api.Mandarin
-			chineseLanguage = &chinese
-		} else if chinese == "yue" {
-			chinese := api.Yue
-			chineseLanguage = &chinese
-		} else {
-			return fmt.Errorf("invalid chinese language variant. Use 'mandarin' or 'yue'")
-		}
+	if chinese != "" && chinese != tts.ChineseMandarin && chinese != tts.ChineseYue {
+		return fmt.Errorf("invalid chinese language variant. Use 'mandarin' or 'yue'")
+	}
+	if maxChunk < 1 || maxChunk > tts.MaxChunkRunes {
+		return fmt.Errorf("--max-chunk must be between 1 and %d", tts.MaxChunkRunes)
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if pause < 0 || paragraphPause < 0 {
+		return fmt.Errorf("pauses cannot be negative")
+	}
+	if !cmd.Flags().Changed("pause") {
+		pause = time.Duration(float64(fragmentInterval) * float64(time.Second))
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	out, err := util.MediaProgressWriter(format, outputPath)
+	if err != nil {
+		return err
+	}
+
+	segments, err := tts.ParseMarkup(text)
+	if err != nil {
+		return errors.NewValidationError(fmt.Errorf("invalid markup: %w", err))
+	}
+	chunks := tts.Split(segments, tts.ChunkOptions{MaxRunes: maxChunk, ParagraphPause: paragraphPause})
+
 	var opts *api.TTSParams
 	if temperature != 1.0 || fragmentInterval != 0.1 {
 		opts = &api.TTSParams{
@@ -211,91 +338,126 @@ func runTTS(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	// Start TTS processing with spinner
-	fmt.Printf("🗣️  Converting text to speech...\n")
+	textChunks := 0
+	for _, chunk := range chunks {
+		if chunk.Text != "" {
+			textChunks++
+		}
+	}
 
-	// Show loading spinner
-	spinnerTicker := time.NewTicker(100 * time.Millisecond)
-	defer spinnerTicker.Stop()
+	fmt.Fprintf(out, "🗣️  Converting text to speech...\n")
+	spinner := ui.NewSpinner(out, "Generating audio...")
+	if textChunks > 1 {
+		spinner.Update(fmt.Sprintf("Generating audio 0/%d chunks...", textChunks))
+	}
+	spinner.Start()
+
+	result, err := tts.Synthesize(ctx, client, chunks, tts.Options{
+		Voice:       voiceProfileID,
+		Chinese:     chinese,
+		Params:      opts,
+		Gap:         pause,
+		Concurrency: concurrency,
+		OnProgress: func(done, total int) {
+			if total > 1 {
+				spinner.Update(fmt.Sprintf("Generating audio %d/%d chunks...", done, total))
+			}
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("operation cancelled: %w", ctx.Err())
+		}
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
+		}
+		return fmt.Errorf("failed to convert text to speech: %w", err)
+	}
 
-	spinnerIndex := 0
-	clearLine := "\r\033[K"
+	// Determine output path
+	if outputPath == "" {
+		defaultFilename := fmt.Sprintf("speech_%s.wav", time.Now().Format("20060102_150405"))
+		outputPath = filepath.Join(cfg.DefaultSavePath, defaultFilename)
+	}
 
-	// Create a channel to receive the result
-	resultChan := make(chan *api.TTSApiResponseBody, 1)
-	errorChan := make(chan error, 1)
+	// Ensure .wav extension
+	if !media.IsStdio(outputPath) && !strings.HasSuffix(strings.ToLower(outputPath), ".wav") {
+		outputPath += ".wav"
+	}
 
-	// Start the API call in a goroutine
-	go func() {
-		resp, err := client.TextToSpeech(ctx, text, voiceProfileID, "b64_audio_str", chineseLanguage, opts)
-		if err != nil {
-			errorChan <- err
-			return
-		}
-		resultChan <- resp
-	}()
-
-	// Wait for result with spinner
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Print(clearLine)
-			return fmt.Errorf("operation cancelled: %w", ctx.Err())
-		case err := <-errorChan:
-			fmt.Print(clearLine)
-			if apiErr, ok := errors.IsAPIError(err); ok {
-				return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
-			}
-			return fmt.Errorf("failed to convert text to speech: %w", err)
-		case resp := <-resultChan:
-			fmt.Print(clearLine)
-			if resp.Data == nil {
-				return fmt.Errorf("unexpected response from server")
-			}
+	if err := saveWAV(outputPath, result.Audio); err != nil {
+		return fmt.Errorf("failed to save audio: %w", err)
+	}
 
-			audioData := resp.Data.B64AudioStr
-			if audioData == nil {
-				return fmt.Errorf("no audio data received from server")
-			}
+	duration := result.Audio.Duration().Seconds()
+	fmt.Fprintf(out, "✅ Audio saved to: %s\n", util.OutputName(outputPath))
+	fmt.Fprintf(out, "📊 Duration: %.2f seconds\n", duration)
+	if textChunks > 1 {
+		fmt.Fprintf(out, "🧩 Chunks: %d\n", textChunks)
+	}
 
-			// Determine output path
-			if outputPath == "" {
-				defaultFilename := fmt.Sprintf("speech_%s.wav", time.Now().Format("20060102_150405"))
-				outputPath = filepath.Join(cfg.DefaultSavePath, defaultFilename)
-			}
+	if !format.IsTable() {
+		return util.PrintOutput(format, ttsOutput{Output: outputPath, Duration: &duration, Chunks: textChunks})
+	}
+	return nil
+}
 
-			// Ensure .wav extension
-			if !strings.HasSuffix(strings.ToLower(outputPath), ".wav") {
-				outputPath += ".wav"
-			}
+// maxTextBytes bounds the text read from a file, a URL or standard input
+const maxTextBytes = 10 << 20
 
-			// Decode base64 audio
-			decodedAudio, err := base64.StdEncoding.DecodeString(*audioData)
-			if err != nil {
-				return fmt.Errorf("failed to decode audio data: %w", err)
-			}
+// readTTSText returns the text from --text or --file, or from standard input
+// when it is a pipe or a file
+func readTTSText(cmd *cobra.Command) (string, error) {
+	text, _ := cmd.Flags().GetString("text")
+	file, _ := cmd.Flags().GetString("file")
+	if text != "" && file != "" {
+		return "", fmt.Errorf("--text and --file cannot be used together")
+	}
 
-			// Create directory if it doesn't exist
-			dir := filepath.Dir(outputPath)
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				return fmt.Errorf("failed to create directory: %w", err)
-			}
+	switch {
+	case text != "":
+		return text, nil
+	case file == "" && stdinHasInput():
+		file = media.Stdio
+	case file == "":
+		return "", fmt.Errorf("text is required. Use --text or --file, or pipe the text to standard input")
+	}
 
-			// Save the audio file
-			if err := os.WriteFile(outputPath, decodedAudio, 0644); err != nil {
-				return fmt.Errorf("failed to save audio: %w", err)
-			}
+	in, err := media.ReadWithin(cmd.Context(), file, maxTextBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to read text: %w", err)
+	}
+	if strings.TrimSpace(string(in.Data)) == "" {
+		return "", fmt.Errorf("no text to convert to speech")
+	}
+	return string(in.Data), nil
+}
 
-			fmt.Printf("✅ Audio saved to: %s\n", outputPath)
-			if resp.Data.OutputDuration != nil {
-				fmt.Printf("📊 Duration: %.2f seconds\n", *resp.Data.OutputDuration)
-			}
+// stdinHasInput reports whether standard input is a pipe or a regular file.
+// Terminals and other devices are not read, so a command run without input
+// fails right away instead of waiting for it.
+func stdinHasInput() bool {
+	f, ok := media.Stdin.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular()
+}
 
-			return nil
-		case <-spinnerTicker.C:
-			frame := spinnerFrames[spinnerIndex%len(spinnerFrames)]
-			fmt.Printf("\r\033[K%s Generating audio...", frame)
-			spinnerIndex++
-		}
+// saveWAV writes pcm as a WAV file to path, or to stdout when path is "-"
+func saveWAV(path string, pcm *audio.PCM) error {
+	w, err := media.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pcm.EncodeWAV(w); err != nil {
+		w.Close()
+		return err
 	}
+	return w.Close()
 }
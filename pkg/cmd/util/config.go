@@ -1,21 +1,66 @@
 package util
 
 import (
+	"fmt"
+
 	"github.com/mirako-ai/mirako-cli/internal/config"
+	"github.com/mirako-ai/mirako-cli/internal/jobs"
 	"github.com/spf13/cobra"
 )
 
-// GetConfig loads configuration and applies flag overrides
+// ContextFlag is the name of the root persistent flag selecting the auth context
+const ContextFlag = "context"
+
+// GetConfig loads configuration, selects the auth context and applies flag overrides
 func GetConfig(cmd *cobra.Command) (*config.Config, error) {
+	contextName, _ := cmd.Flags().GetString(ContextFlag)
+	return loadConfig(cmd, contextName, false)
+}
+
+// GetJobConfig is GetConfig for commands that look up a recorded job: unless
+// --context is given, the job's task is looked up with the auth context and
+// API URL it was submitted with. job may be nil for tasks not in the ledger.
+func GetJobConfig(cmd *cobra.Command, job *jobs.Job) (*config.Config, error) {
+	if job == nil || job.Context == "" || cmd.Flags().Changed(ContextFlag) {
+		return GetConfig(cmd)
+	}
+
+	cfg, err := loadConfig(cmd, job.Context, false)
+	if err != nil {
+		return nil, fmt.Errorf("task %s was submitted with context %q: %w", job.TaskID, job.Context, err)
+	}
+	if job.APIURL != "" && !cmd.Flags().Changed("api-url") {
+		cfg.APIURL = job.APIURL
+	}
+	return cfg, nil
+}
+
+// GetLoginConfig is GetConfig for `auth login`: a context named with
+// --context that does not exist yet is created
+func GetLoginConfig(cmd *cobra.Command) (*config.Config, error) {
+	contextName, _ := cmd.Flags().GetString(ContextFlag)
+	return loadConfig(cmd, contextName, true)
+}
+
+func loadConfig(cmd *cobra.Command, contextName string, createContext bool) (*config.Config, error) {
 	cfg, err := config.Load()
 	if err != nil {
 		return nil, err
 	}
 
+	if createContext && contextName != "" && !cfg.HasContext(contextName) {
+		err = cfg.AddContext(contextName)
+	} else {
+		err = cfg.UseContext(contextName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	// Apply flag overrides (similar to root.go)
 	if cmd.Flags().Changed("api-token") {
 		apiToken, _ := cmd.Flags().GetString("api-token")
-		cfg.APIToken = apiToken
+		cfg.OverrideAPIToken(apiToken)
 	}
 
 	if cmd.Flags().Changed("api-url") {
@@ -23,6 +68,12 @@ func GetConfig(cmd *cobra.Command) (*config.Config, error) {
 		cfg.APIURL = apiURL
 	}
 
+	// Only consult the credential store when no token was given on the command line
+	if !cmd.Flags().Changed("api-token") {
+		if err := cfg.LoadToken(); err != nil {
+			return nil, err
+		}
+	}
+
 	return cfg, nil
 }
-
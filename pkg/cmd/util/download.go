@@ -0,0 +1,22 @@
+package util
+
+import (
+	"context"
+	"io"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/config"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
+)
+
+// DownloadFile downloads url to path with a progress bar on out. Interrupted
+// downloads are resumed up to the configured number of retries.
+func DownloadFile(ctx context.Context, out io.Writer, cfg *config.Config, url, path, label string) (*client.DownloadResult, error) {
+	progress := ui.NewProgressBar(out, label)
+	defer progress.Finish()
+
+	return client.DownloadFile(ctx, url, path, client.DownloadOptions{
+		MaxRetries: cfg.MaxRetries,
+		Progress:   progress.Update,
+	})
+}
@@ -0,0 +1,166 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/config"
+	"github.com/mirako-ai/mirako-cli/internal/jobs"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// maxJobInputLength limits how much of each input is kept in the job ledger
+const maxJobInputLength = 80
+
+// RecordJob adds a submitted task to the local job ledger so it can be resumed
+// later with `mirako jobs resume`, along with the auth context and API URL of
+// cfg it was submitted with. Failures only print a warning because the task
+// has already been accepted by the server.
+func RecordJob(cfg *config.Config, kind client.TaskKind, taskID string, inputs map[string]string, outputPath string) {
+	addJob(newJob(cfg, kind, taskID, inputs, outputPath))
+}
+
+// RecordVideoJob records a video task like RecordJob, along with the audio to
+// transcribe into captions when the video is saved by a later command
+func RecordVideoJob(cfg *config.Config, kind client.TaskKind, taskID string, inputs map[string]string, outputPath, captionsAudio string) {
+	job := newJob(cfg, kind, taskID, inputs, outputPath)
+	job.CaptionsAudio = captionsAudio
+	addJob(job)
+}
+
+func newJob(cfg *config.Config, kind client.TaskKind, taskID string, inputs map[string]string, outputPath string) jobs.Job {
+	summary := make(map[string]string, len(inputs))
+	for key, value := range inputs {
+		if value == "" {
+			continue
+		}
+		if runes := []rune(value); len(runes) > maxJobInputLength {
+			value = string(runes[:maxJobInputLength-3]) + "..."
+		}
+		summary[key] = value
+	}
+
+	// Output written to stdout can't be saved again by a later status command
+	if media.IsStdio(outputPath) {
+		outputPath = ""
+	}
+
+	job := jobs.Job{
+		Kind:        kind,
+		TaskID:      taskID,
+		Inputs:      summary,
+		OutputPath:  absolutePath(outputPath),
+		SubmittedAt: time.Now(),
+	}
+	if cfg != nil {
+		job.Context = cfg.ContextName()
+		job.APIURL = cfg.APIURL
+	}
+	return job
+}
+
+// absolutePath makes a local path absolute, so resuming from another
+// directory uses the same file. URLs and empty paths are returned as they are.
+func absolutePath(path string) string {
+	if path == "" || media.IsURL(path) {
+		return path
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		return absPath
+	}
+	return path
+}
+
+func addJob(job jobs.Job) {
+	if err := jobs.DefaultLedger().Add(job); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to record job in local ledger: %v\n", err)
+	}
+}
+
+// LookupJob returns the recorded job with exactly the given task ID, or nil
+func LookupJob(taskID string) *jobs.Job {
+	job, err := jobs.DefaultLedger().Get(taskID)
+	if err != nil || job.TaskID != taskID {
+		return nil
+	}
+	return job
+}
+
+// RecordJobStatus updates the last known status of a job in the local ledger
+func RecordJobStatus(taskID, status string) {
+	err := jobs.DefaultLedger().UpdateStatus(taskID, status)
+	if err != nil && !errors.Is(err, jobs.ErrJobNotFound) {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to update job in local ledger: %v\n", err)
+	}
+}
+
+// AddDetachFlags registers the flags used to submit a task without waiting for it
+func AddDetachFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("wait", true, "Wait for the task to finish (use --wait=false to only submit it and print the task ID)")
+	cmd.Flags().BoolP("json", "j", false, "Print the result as JSON (same as --output-format=json)")
+}
+
+// PrintSubmittedTask prints the ID of a task submitted with --wait=false. Only
+// the ID is printed for the table format so it can be captured by scripts.
+func PrintSubmittedTask(cmd *cobra.Command, kind client.TaskKind, taskID, status string) error {
+	format, err := GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if format.IsTable() {
+		fmt.Println(taskID)
+		return nil
+	}
+	return PrintOutput(format, TaskOutput{Kind: kind, TaskID: taskID, Status: status})
+}
+
+// AddSaveFlags registers the flags used by status commands to save a finished result
+func AddSaveFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "", "Output file path for the result, - for stdout (defaults to the path requested at submission)")
+	cmd.Flags().BoolP("no-save", "n", false, "Skip saving the result to disk")
+}
+
+// ResultSavePath decides where a status command saves a finished result. An
+// explicit --output wins, then the output path recorded in the job ledger.
+// Otherwise the user is asked when running in a terminal, and defaultPath is
+// used without prompting when running non-interactively or with a structured
+// output format.
+func ResultSavePath(cmd *cobra.Command, taskID, defaultPath string) (string, bool) {
+	if noSave, _ := cmd.Flags().GetBool("no-save"); noSave {
+		return "", false
+	}
+	if cmd.Flags().Changed("output") {
+		outputPath, _ := cmd.Flags().GetString("output")
+		return outputPath, true
+	}
+	if job, err := jobs.DefaultLedger().Get(taskID); err == nil && job.TaskID == taskID && job.OutputPath != "" {
+		return job.OutputPath, true
+	}
+	if format, err := GetOutputFormat(cmd); err != nil || !format.IsTable() || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return defaultPath, true
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("\nWould you like to save the result? (Y/n): ")
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	if response != "" && response != "y" && response != "yes" {
+		return "", false
+	}
+
+	fmt.Printf("Enter save path [%s]: ", defaultPath)
+	savePath, _ := reader.ReadString('\n')
+	savePath = strings.TrimSpace(savePath)
+	if savePath == "" {
+		savePath = defaultPath
+	}
+	return savePath, true
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// AddNormalizeFlag registers the flag that turns off converting media inputs
+// to fit the upload limits
+func AddNormalizeFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-normalize", false, "Fail on media inputs outside the upload limits instead of converting them")
+}
+
+// ReadMedia reads a media input from a path, URL or standard input and checks
+// it against policy before it is uploaded, converting it unless --no-normalize
+// is given. Conversions are reported on out. Inputs that would be rejected are
+// validation errors.
+func ReadMedia(cmd *cobra.Command, out io.Writer, source string, policy media.Policy) (*media.Input, error) {
+	noNormalize, _ := cmd.Flags().GetBool("no-normalize")
+	var in *media.Input
+	var err error
+	if noNormalize && policy.MaxBytes > 0 {
+		// Larger inputs would be rejected, so there is no need to read them whole
+		in, err = media.ReadWithin(cmd.Context(), source, policy.MaxBytes)
+	} else {
+		in, err = media.Read(cmd.Context(), source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	report, err := media.Preflight(in, policy, !noNormalize)
+	if err != nil {
+		return nil, errors.NewValidationError(err)
+	}
+	PrintMediaReport(out, report)
+	return in, nil
+}
+
+// PrintMediaReport prints what the preflight checks changed in an input
+func PrintMediaReport(out io.Writer, report *media.Report) {
+	if !report.Changed() {
+		return
+	}
+	fmt.Fprintf(out, "🔧 %s: %s (%s → %s)\n", report.Input, strings.Join(report.Changes, ", "), ui.FormatBytes(int64(report.OriginalSize)), ui.FormatBytes(int64(report.Size)))
+}
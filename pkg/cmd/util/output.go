@@ -0,0 +1,80 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// OutputFormatFlag is the name of the root persistent flag selecting the output format
+const OutputFormatFlag = "output-format"
+
+// GetOutputFormat returns the output format selected with --output-format.
+// A command's own --json flag is kept as a shorthand for --output-format=json.
+func GetOutputFormat(cmd *cobra.Command) (ui.OutputFormat, error) {
+	if flag := cmd.Flags().Lookup("json"); flag != nil && flag.Value.Type() == "bool" {
+		if useJSON, _ := cmd.Flags().GetBool("json"); useJSON {
+			return ui.OutputFormat{Name: ui.FormatJSON}, nil
+		}
+	}
+
+	value, err := cmd.Flags().GetString(OutputFormatFlag)
+	if err != nil {
+		// The flag is only registered on the root command
+		return ui.OutputFormat{Name: ui.FormatTable}, nil
+	}
+	return ui.ParseOutputFormat(value)
+}
+
+// PrintOutput writes value to stdout in the selected structured format
+func PrintOutput(format ui.OutputFormat, value any) error {
+	return ui.PrintOutput(os.Stdout, format, value)
+}
+
+// ProgressWriter returns where progress messages go: stdout for the table
+// format, and stderr for structured formats so stdout stays parseable.
+func ProgressWriter(format ui.OutputFormat) io.Writer {
+	if format.IsTable() {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+// MediaProgressWriter is ProgressWriter for commands that save a media file
+// to outputPath. Writing the file to stdout with "-" moves progress to stderr,
+// and can't be combined with a structured output format.
+func MediaProgressWriter(format ui.OutputFormat, outputPath string) (io.Writer, error) {
+	if !media.IsStdio(outputPath) {
+		return ProgressWriter(format), nil
+	}
+	if !format.IsTable() {
+		return nil, errors.NewValidationError(fmt.Errorf("--output - writes the file to stdout and can't be combined with --%s %s", OutputFormatFlag, format.Name))
+	}
+	return os.Stderr, nil
+}
+
+// OutputName returns how outputPath is shown in messages
+func OutputName(outputPath string) string {
+	if media.IsStdio(outputPath) {
+		return "stdout"
+	}
+	return outputPath
+}
+
+// TaskOutput is the structured result printed by generate commands
+type TaskOutput struct {
+	Kind      client.TaskKind `json:"kind"`
+	TaskID    string          `json:"task_id"`
+	Status    string          `json:"status,omitempty"`
+	Output    string          `json:"output,omitempty"`
+	FileURL   string          `json:"file_url,omitempty"`
+	Duration  float64         `json:"duration,omitempty"`
+	ProfileID string          `json:"profile_id,omitempty"`
+	Captions  string          `json:"captions,omitempty"`
+}
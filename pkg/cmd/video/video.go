@@ -1,25 +1,25 @@
 package video
 
 import (
-	"bufio"
-	"encoding/base64"
+	"context"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/config"
 	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/internal/jobs"
+	"github.com/mirako-ai/mirako-cli/internal/media"
+	"github.com/mirako-ai/mirako-cli/internal/stt"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
-	"github.com/mirako-ai/mirako-go/api"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
 	"github.com/spf13/cobra"
 )
 
-var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
-
 type VideoModel string
 
 const (
@@ -40,6 +40,14 @@ func (m VideoModel) IsValid() bool {
 	}
 }
 
+// TaskKind returns the kind of task created by generating with this model
+func (m VideoModel) TaskKind() client.TaskKind {
+	if m == VideoModelMotion {
+		return client.TaskKindAvatarMotion
+	}
+	return client.TaskKindTalkingAvatar
+}
+
 func GetSupportedModels() []VideoModel {
 	return []VideoModel{VideoModelTalkingAvatar, VideoModelMotion}
 }
@@ -70,18 +78,35 @@ func newGenerateCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "generate",
 		Short: "Generate a video",
-		Long:  `Generate AI videos using various models`,
-		RunE:  runGenerate,
+		Long: `Generate AI videos using various models.
+
+When the video is saved, the input audio is transcribed and written as
+captions in a .srt file next to the .mp4, unless --no-captions is given. With
+--wait=false, this happens when 'video status' or 'jobs resume' saves it.
+
+--audio and --image take a path, an http(s) URL, or - for standard input.
+With --output - the video is written to stdout.
+
+Before upload, images larger than 4096 pixels per side or 10 MB are scaled
+down, and audio over 50 MB is mixed to mono and resampled. Use --no-normalize
+to fail instead.`,
+		Example: `  mirako video generate -m talking_avatar --audio speech.wav --image face.jpg
+  mirako speech tts -v VOICE_ID -t "Hi" -o - | mirako video generate -m talking_avatar --audio - --image https://example.com/face.jpg -o - > hi.mp4`,
+		RunE: runGenerate,
 	}
 
 	cmd.Flags().StringP("model", "m", "", fmt.Sprintf("Model type for video generation (%s)", GetSupportedModelsString()))
-	cmd.Flags().StringP("audio", "a", "", "Path to the audio file for speech")
-	cmd.Flags().StringP("image", "i", "", "Path to the image file for avatar face")
+	cmd.Flags().StringP("audio", "a", "", "Path or URL of the audio file for speech (- for standard input)")
+	cmd.Flags().StringP("image", "i", "", "Path or URL of the image file for avatar face (- for standard input)")
 	cmd.Flags().StringP("positive-prompt", "", "", "Positive prompt to guide avatar motion generation (motion model only)")
 	cmd.Flags().StringP("negative-prompt", "", "", "Negative prompt to guide avatar motion generation (motion model only)")
-	cmd.Flags().StringP("output", "o", "", "Output file path for the generated video (e.g., ./output/video.mp4)")
+	cmd.Flags().StringP("output", "o", "", "Output file path for the generated video (e.g., ./output/video.mp4, - for stdout)")
 	cmd.Flags().BoolP("no-save", "n", false, "Skip saving the video to disk")
+	cmd.Flags().Bool("no-captions", false, "Skip writing captions (.srt) transcribed from the audio next to the video")
 	cmd.Flags().IntP("poll-interval", "p", 2, "Polling interval in seconds for checking status")
+	cmd.Flags().Duration("timeout", 0, "Maximum time to wait for the task to finish, e.g. 30m (0 waits indefinitely)")
+	util.AddDetachFlags(cmd)
+	util.AddNormalizeFlag(cmd)
 
 	return cmd
 }
@@ -126,35 +151,41 @@ func runGenerateTalkingAvatar(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("image path is required. Use --image flag")
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
 	outputPath, _ := cmd.Flags().GetString("output")
 	noSave, _ := cmd.Flags().GetBool("no-save")
+	noCaptions, _ := cmd.Flags().GetBool("no-captions")
 	pollInterval, _ := cmd.Flags().GetInt("poll-interval")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	wait, _ := cmd.Flags().GetBool("wait")
 
-	// Read and encode the audio file
-	audioData, err := os.ReadFile(audioPath)
+	out, err := util.MediaProgressWriter(format, outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to read audio file: %w", err)
+		return err
 	}
-	audioBase64 := base64.StdEncoding.EncodeToString(audioData)
 
-	// Read and encode the image file
-	imageData, err := os.ReadFile(imagePath)
+	audioInput, imageInput, err := readInputs(cmd, out, audioPath, imagePath)
 	if err != nil {
-		return fmt.Errorf("failed to read image file: %w", err)
+		return err
 	}
-	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
 
-	client, err := client.New(cfg)
+	c, err := client.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
 	// Start generation
-	fmt.Printf("🚀 Starting talking avatar video generation...\n")
-	resp, err := client.GenerateTalkingAvatar(ctx, audioBase64, imageBase64)
+	if wait {
+		fmt.Fprintf(out, "🚀 Starting talking avatar video generation...\n")
+	}
+	resp, err := c.GenerateTalkingAvatar(ctx, audioInput.Base64(), imageInput.Base64())
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to generate talking avatar video: %w", err)
 	}
@@ -164,122 +195,44 @@ func runGenerateTalkingAvatar(cmd *cobra.Command, args []string) error {
 	}
 
 	taskID := resp.Data.TaskId
-	fmt.Printf("✅ Talking avatar video generation started!\n")
-	fmt.Printf("   Task ID: %s\n", taskID)
+	withCaptions := !noSave && !noCaptions && !media.IsStdio(outputPath)
+	util.RecordVideoJob(cfg, client.TaskKindTalkingAvatar, taskID, map[string]string{"audio": audioPath, "image": imagePath}, outputPath, captionsAudio(withCaptions, audioPath))
+	if !wait {
+		warnUnrecordedCaptions(withCaptions, audioPath)
+		return util.PrintSubmittedTask(cmd, client.TaskKindTalkingAvatar, taskID, string(resp.Data.Status))
+	}
+
+	var captions *captionsJob
+	if withCaptions {
+		captions = startCaptions(ctx, c, audioInput)
+	}
+
+	fmt.Fprintf(out, "✅ Talking avatar video generation started!\n")
+	fmt.Fprintf(out, "   Task ID: %s\n", taskID)
 
 	// Poll for status until complete
-	fmt.Printf("⏳ Waiting for generation to complete...\n")
-
-	// Use separate tickers for polling and spinner animation
-	pollTicker := time.NewTicker(time.Duration(pollInterval) * time.Second)
-	spinnerTicker := time.NewTicker(100 * time.Millisecond) // Smooth spinner animation
-	defer pollTicker.Stop()
-	defer spinnerTicker.Stop()
-
-	spinnerIndex := 0
-	currentStatus := "PROCESSING" // Initial status
-	clearLine := "\r\033[K"       // ANSI escape codes to clear the line
-
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Print(clearLine) // Clear the spinner line
-			return fmt.Errorf("operation cancelled: %w", ctx.Err())
-		case <-pollTicker.C:
-			statusResp, err := client.GetTalkingAvatarStatus(ctx, taskID)
-			if err != nil {
-				fmt.Print(clearLine) // Clear the spinner line
-				if apiErr, ok := errors.IsAPIError(err); ok {
-					return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
-				}
-				return fmt.Errorf("failed to check status: %w", err)
-			}
-
-			if statusResp.Data == nil {
-				fmt.Print(clearLine) // Clear the spinner line
-				return fmt.Errorf("unexpected response from server")
-			}
-
-			currentStatus = string(statusResp.Data.Status)
-
-			if statusResp.Data.Status == api.GenerateTalkingAvatarTaskOutputStatusCOMPLETED {
-				fmt.Print(clearLine) // Clear the spinner line
-				fmt.Printf("✅ Generation completed!\n")
-
-				if statusResp.Data.FileUrl != nil {
-					if noSave {
-						fmt.Printf("🎥 Video generated - URL: %s\n", *statusResp.Data.FileUrl)
-						return nil
-					}
-
-					// Download the video file from URL
-					videoURL := *statusResp.Data.FileUrl
-					fmt.Printf("🎥 Downloading video...\n")
-
-					// Determine output path
-					if outputPath == "" {
-						now := time.Now()
-						timestamp := fmt.Sprintf("%s_%03d", now.Format("20060102_150405"), now.Nanosecond()/1000000)
-						defaultFilename := fmt.Sprintf("video_%s.mp4", timestamp)
-						outputPath = filepath.Join(cfg.DefaultSavePath, defaultFilename)
-					}
-
-					// Ensure .mp4 extension
-					if !strings.HasSuffix(strings.ToLower(outputPath), ".mp4") {
-						outputPath += ".mp4"
-					}
-
-					// Create directory if it doesn't exist
-					dir := filepath.Dir(outputPath)
-					if err := os.MkdirAll(dir, 0755); err != nil {
-						return fmt.Errorf("failed to create directory: %w", err)
-					}
-
-					// Download the video
-					resp, err := http.Get(videoURL)
-					if err != nil {
-						return fmt.Errorf("failed to download video: %w", err)
-					}
-					defer resp.Body.Close()
-
-					if resp.StatusCode != http.StatusOK {
-						return fmt.Errorf("failed to download video: HTTP %d", resp.StatusCode)
-					}
-
-					// Create the output file
-					outFile, err := os.Create(outputPath)
-					if err != nil {
-						return fmt.Errorf("failed to create output file: %w", err)
-					}
-					defer outFile.Close()
-
-					// Copy the response body to the file
-					bytesWritten, err := io.Copy(outFile, resp.Body)
-					if err != nil {
-						return fmt.Errorf("failed to save video: %w", err)
-					}
-
-					fmt.Printf("✅ Video saved successfully!\n")
-					fmt.Printf("   File: %s\n", outputPath)
-					fmt.Printf("   Size: %d bytes\n", bytesWritten)
-					if statusResp.Data.OutputDuration != nil {
-						fmt.Printf("   Duration: %.2f seconds\n", *statusResp.Data.OutputDuration)
-					}
-				}
-
-				return nil
-			} else if statusResp.Data.Status == api.GenerateTalkingAvatarTaskOutputStatusFAILED || statusResp.Data.Status == api.GenerateTalkingAvatarTaskOutputStatusCANCELED || statusResp.Data.Status == api.GenerateTalkingAvatarTaskOutputStatusTIMEDOUT {
-				fmt.Print(clearLine) // Clear the spinner line
-				return fmt.Errorf("talking avatar video generation failed with status: %s", statusResp.Data.Status)
-			}
-			// Update status but don't draw here - spinner ticker handles animation
-		case <-spinnerTicker.C:
-			// Update spinner animation smoothly
-			frame := spinnerFrames[spinnerIndex%len(spinnerFrames)]
-			fmt.Printf("\r\033[K%s Status: %s", frame, currentStatus)
-			spinnerIndex++
+	fmt.Fprintf(out, "⏳ Waiting for generation to complete...\n")
+
+	spinner := ui.NewSpinner(out, "Status: PROCESSING")
+	spinner.Start()
+	result, err := c.WaitForTask(ctx, client.TaskKindTalkingAvatar, taskID, client.WaitOptions{
+		Interval: time.Duration(pollInterval) * time.Second,
+		Timeout:  timeout,
+		OnStatus: func(status string) {
+			spinner.Update("Status: " + status)
+			util.RecordJobStatus(taskID, status)
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
 		}
+		return err
 	}
+
+	fmt.Fprintf(out, "✅ Generation completed!\n")
+	return finishVideoTask(ctx, out, format, cfg, VideoModelTalkingAvatar.TaskKind(), result, outputPath, noSave, captions)
 }
 
 func runGenerateAvatarMotion(cmd *cobra.Command, args []string) error {
@@ -315,32 +268,40 @@ func runGenerateAvatarMotion(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("negative prompt must be 512 characters or less")
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
 	outputPath, _ := cmd.Flags().GetString("output")
 	noSave, _ := cmd.Flags().GetBool("no-save")
+	noCaptions, _ := cmd.Flags().GetBool("no-captions")
 	pollInterval, _ := cmd.Flags().GetInt("poll-interval")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	wait, _ := cmd.Flags().GetBool("wait")
 
-	audioData, err := os.ReadFile(audioPath)
+	out, err := util.MediaProgressWriter(format, outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to read audio file: %w", err)
+		return err
 	}
-	audioBase64 := base64.StdEncoding.EncodeToString(audioData)
 
-	imageData, err := os.ReadFile(imagePath)
+	audioInput, imageInput, err := readInputs(cmd, out, audioPath, imagePath)
 	if err != nil {
-		return fmt.Errorf("failed to read image file: %w", err)
+		return err
 	}
-	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
 
-	client, err := client.New(cfg)
+	c, err := client.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	fmt.Printf("🚀 Starting avatar motion video generation...\n")
-	resp, err := client.GenerateAvatarMotion(ctx, audioBase64, imageBase64, positivePrompt, negativePrompt)
+	if wait {
+		fmt.Fprintf(out, "🚀 Starting avatar motion video generation...\n")
+	}
+	resp, err := c.GenerateAvatarMotion(ctx, audioInput.Base64(), imageInput.Base64(), positivePrompt, negativePrompt)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to generate avatar motion video: %w", err)
 	}
@@ -350,222 +311,340 @@ func runGenerateAvatarMotion(cmd *cobra.Command, args []string) error {
 	}
 
 	taskID := resp.Data.TaskId
-	fmt.Printf("✅ Avatar motion video generation started!\n")
-	fmt.Printf("   Task ID: %s\n", taskID)
-
-	fmt.Printf("⏳ Waiting for generation to complete...\n")
-
-	pollTicker := time.NewTicker(time.Duration(pollInterval) * time.Second)
-	spinnerTicker := time.NewTicker(100 * time.Millisecond)
-	defer pollTicker.Stop()
-	defer spinnerTicker.Stop()
-
-	spinnerIndex := 0
-	currentStatus := "PROCESSING"
-	clearLine := "\r\033[K"
-
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Print(clearLine)
-			return fmt.Errorf("operation cancelled: %w", ctx.Err())
-		case <-pollTicker.C:
-			statusResp, err := client.GetAvatarMotionStatus(ctx, taskID)
-			if err != nil {
-				fmt.Print(clearLine)
-				if apiErr, ok := errors.IsAPIError(err); ok {
-					return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
-				}
-				return fmt.Errorf("failed to check status: %w", err)
-			}
-
-			if statusResp.Data == nil {
-				fmt.Print(clearLine)
-				return fmt.Errorf("unexpected response from server")
-			}
-
-			currentStatus = string(statusResp.Data.Status)
-
-			if statusResp.Data.Status == api.GenerateAvatarMotionTaskOutputStatusCOMPLETED {
-				fmt.Print(clearLine)
-				fmt.Printf("✅ Generation completed!\n")
-
-				if statusResp.Data.FileUrl != nil {
-					if noSave {
-						fmt.Printf("🎥 Video generated - URL: %s\n", *statusResp.Data.FileUrl)
-						return nil
-					}
-
-					videoURL := *statusResp.Data.FileUrl
-					fmt.Printf("🎥 Downloading video...\n")
-
-					if outputPath == "" {
-						now := time.Now()
-						timestamp := fmt.Sprintf("%s_%03d", now.Format("20060102_150405"), now.Nanosecond()/1000000)
-						defaultFilename := fmt.Sprintf("video_%s.mp4", timestamp)
-						outputPath = filepath.Join(cfg.DefaultSavePath, defaultFilename)
-					}
-
-					if !strings.HasSuffix(strings.ToLower(outputPath), ".mp4") {
-						outputPath += ".mp4"
-					}
-
-					dir := filepath.Dir(outputPath)
-					if err := os.MkdirAll(dir, 0755); err != nil {
-						return fmt.Errorf("failed to create directory: %w", err)
-					}
-
-					resp, err := http.Get(videoURL)
-					if err != nil {
-						return fmt.Errorf("failed to download video: %w", err)
-					}
-					defer resp.Body.Close()
-
-					if resp.StatusCode != http.StatusOK {
-						return fmt.Errorf("failed to download video: HTTP %d", resp.StatusCode)
-					}
-
-					outFile, err := os.Create(outputPath)
-					if err != nil {
-						return fmt.Errorf("failed to create output file: %w", err)
-					}
-					defer outFile.Close()
-
-					bytesWritten, err := io.Copy(outFile, resp.Body)
-					if err != nil {
-						return fmt.Errorf("failed to save video: %w", err)
-					}
-
-					fmt.Printf("✅ Video saved successfully!\n")
-					fmt.Printf("   File: %s\n", outputPath)
-					fmt.Printf("   Size: %d bytes\n", bytesWritten)
-				}
-
-				return nil
-			} else if statusResp.Data.Status == api.GenerateAvatarMotionTaskOutputStatusFAILED || statusResp.Data.Status == api.GenerateAvatarMotionTaskOutputStatusCANCELED || statusResp.Data.Status == api.GenerateAvatarMotionTaskOutputStatusTIMEDOUT {
-				fmt.Print(clearLine)
-				return fmt.Errorf("avatar motion video generation failed with status: %s", statusResp.Data.Status)
-			}
-		case <-spinnerTicker.C:
-			frame := spinnerFrames[spinnerIndex%len(spinnerFrames)]
-			fmt.Printf("\r\033[K%s Status: %s", frame, currentStatus)
-			spinnerIndex++
+	withCaptions := !noSave && !noCaptions && !media.IsStdio(outputPath)
+	util.RecordVideoJob(cfg, client.TaskKindAvatarMotion, taskID, map[string]string{"audio": audioPath, "image": imagePath, "positive_prompt": positivePrompt}, outputPath, captionsAudio(withCaptions, audioPath))
+	if !wait {
+		warnUnrecordedCaptions(withCaptions, audioPath)
+		return util.PrintSubmittedTask(cmd, client.TaskKindAvatarMotion, taskID, string(resp.Data.Status))
+	}
+
+	var captions *captionsJob
+	if withCaptions {
+		captions = startCaptions(ctx, c, audioInput)
+	}
+
+	fmt.Fprintf(out, "✅ Avatar motion video generation started!\n")
+	fmt.Fprintf(out, "   Task ID: %s\n", taskID)
+
+	fmt.Fprintf(out, "⏳ Waiting for generation to complete...\n")
+
+	spinner := ui.NewSpinner(out, "Status: PROCESSING")
+	spinner.Start()
+	result, err := c.WaitForTask(ctx, client.TaskKindAvatarMotion, taskID, client.WaitOptions{
+		Interval: time.Duration(pollInterval) * time.Second,
+		Timeout:  timeout,
+		OnStatus: func(status string) {
+			spinner.Update("Status: " + status)
+			util.RecordJobStatus(taskID, status)
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
 		}
+		return err
 	}
+
+	fmt.Fprintf(out, "✅ Generation completed!\n")
+	return finishVideoTask(ctx, out, format, cfg, VideoModelMotion.TaskKind(), result, outputPath, noSave, captions)
+}
+
+// readInputs reads the audio and image of a video from paths, URLs or
+// standard input and checks them before upload
+func readInputs(cmd *cobra.Command, out io.Writer, audioPath, imagePath string) (*media.Input, *media.Input, error) {
+	audio, err := util.ReadMedia(cmd, out, audioPath, media.VideoAudioPolicy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read audio file: %w", err)
+	}
+	image, err := util.ReadMedia(cmd, out, imagePath, media.ImagePolicy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read image file: %w", err)
+	}
+	return audio, image, nil
+}
+
+// finishVideoTask saves the video of a completed task, and its captions when
+// captions is not nil, and prints the result in structured output formats
+func finishVideoTask(ctx context.Context, out io.Writer, format ui.OutputFormat, cfg *config.Config, kind client.TaskKind, result *client.TaskResult, outputPath string, noSave bool, captions *captionsJob) error {
+	savedPath, err := SaveVideoResult(ctx, out, cfg, result, outputPath, noSave)
+	if err != nil {
+		return err
+	}
+	var captionsPath string
+	if captions != nil && savedPath != "" {
+		captionsPath = captions.save(out, savedPath)
+	}
+	if format.IsTable() {
+		return nil
+	}
+	output := videoTaskOutput(kind, result, savedPath)
+	output.Captions = captionsPath
+	return util.PrintOutput(format, output)
+}
+
+// captionsJob transcribes the audio of a video while the video is generated
+type captionsJob struct {
+	done   chan struct{}
+	result *stt.Result
+	err    error
+}
+
+func startCaptions(ctx context.Context, c *client.Client, audio *media.Input) *captionsJob {
+	job := &captionsJob{done: make(chan struct{})}
+	go func() {
+		defer close(job.done)
+		job.result, job.err = stt.Transcribe(ctx, c, audio, stt.Options{Split: stt.DefaultSplitOptions, Timestamps: true, Concurrency: 4})
+	}()
+	return job
+}
+
+// captionsAudio returns the audio to record for captions of a video saved by
+// a later command, which may run in another directory: the absolute path of a
+// local file, or a URL. It returns "" when the video gets no captions, and
+// for standard input, which the later command can't read again.
+func captionsAudio(withCaptions bool, audioPath string) string {
+	if !withCaptions || media.IsStdio(audioPath) {
+		return ""
+	}
+	if media.IsURL(audioPath) {
+		return audioPath
+	}
+	if absPath, err := filepath.Abs(audioPath); err == nil {
+		return absPath
+	}
+	return audioPath
+}
+
+// warnUnrecordedCaptions tells that a video submitted with --wait=false gets
+// no captions when its audio was read from standard input
+func warnUnrecordedCaptions(withCaptions bool, audioPath string) {
+	if withCaptions && media.IsStdio(audioPath) {
+		fmt.Fprintf(os.Stderr, "⚠️  No captions will be written when the video is saved later: audio read from standard input can't be read again\n")
+	}
+}
+
+// startRecordedCaptions transcribes the audio recorded for job, for a video
+// saved by `video status` or `jobs resume` rather than the command that
+// submitted it. It returns nil when the job has no captions or the video
+// isn't saved to a file.
+func startRecordedCaptions(ctx context.Context, c *client.Client, job *jobs.Job, outputPath string) *captionsJob {
+	if job == nil || job.CaptionsAudio == "" || media.IsStdio(outputPath) {
+		return nil
+	}
+	audio, err := media.Read(ctx, job.CaptionsAudio)
+	if err != nil {
+		failed := &captionsJob{done: make(chan struct{}), err: fmt.Errorf("failed to read audio file: %w", err)}
+		close(failed.done)
+		return failed
+	}
+	return startCaptions(ctx, c, audio)
+}
+
+// SaveVideoJobResult saves the video of a completed job recorded in the
+// ledger, with captions if they were requested when it was submitted. It
+// returns the paths of the video and the captions, which are empty when
+// nothing was saved.
+func SaveVideoJobResult(ctx context.Context, out io.Writer, cfg *config.Config, c *client.Client, job *jobs.Job, result *client.TaskResult, outputPath string) (string, string, error) {
+	var captions *captionsJob
+	if result.FileURL != nil {
+		captions = startRecordedCaptions(ctx, c, job, outputPath)
+	}
+	savedPath, err := SaveVideoResult(ctx, out, cfg, result, outputPath, false)
+	if err != nil || captions == nil || savedPath == "" {
+		return savedPath, "", err
+	}
+	return savedPath, captions.save(out, savedPath), nil
+}
+
+// save waits for the transcription and writes it as SubRip captions next to
+// videoPath. Failures are reported as warnings since the video itself is
+// already saved; the path is empty then.
+func (j *captionsJob) save(out io.Writer, videoPath string) string {
+	<-j.done
+	err := j.err
+	if err == nil {
+		path := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + ".srt"
+		if err = stt.WriteFile(path, stt.FormatSRT, j.result); err == nil {
+			fmt.Fprintf(out, "   Captions: %s\n", path)
+			return path
+		}
+	}
+	if apiErr, ok := errors.IsAPIError(err); ok {
+		err = apiErr.UserError()
+	}
+	fmt.Fprintf(out, "⚠️  Failed to create captions: %v\n", err)
+	return ""
+}
+
+// videoTaskOutput builds the structured result of a video task
+func videoTaskOutput(kind client.TaskKind, result *client.TaskResult, savedPath string) util.TaskOutput {
+	taskOutput := util.TaskOutput{Kind: kind, TaskID: result.TaskID, Status: result.Status, Output: savedPath}
+	if result.FileURL != nil {
+		taskOutput.FileURL = *result.FileURL
+	}
+	if result.OutputDuration != nil {
+		taskOutput.Duration = *result.OutputDuration
+	}
+	return taskOutput
+}
+
+// SaveVideoResult downloads the video of a completed task to outputPath and
+// returns the path it was written to, or "" if nothing was saved
+func SaveVideoResult(ctx context.Context, out io.Writer, cfg *config.Config, result *client.TaskResult, outputPath string, noSave bool) (string, error) {
+	if result.FileURL == nil {
+		return "", nil
+	}
+	if noSave {
+		fmt.Fprintf(out, "🎥 Video generated - URL: %s\n", *result.FileURL)
+		return "", nil
+	}
+
+	// Determine output path
+	if outputPath == "" {
+		now := time.Now()
+		timestamp := fmt.Sprintf("%s_%03d", now.Format("20060102_150405"), now.Nanosecond()/1000000)
+		defaultFilename := fmt.Sprintf("video_%s.mp4", timestamp)
+		outputPath = filepath.Join(cfg.DefaultSavePath, defaultFilename)
+	}
+
+	if media.IsStdio(outputPath) {
+		return outputPath, streamVideo(ctx, out, cfg, *result.FileURL)
+	}
+
+	// Ensure .mp4 extension
+	if !strings.HasSuffix(strings.ToLower(outputPath), ".mp4") {
+		outputPath += ".mp4"
+	}
+
+	download, err := util.DownloadFile(ctx, out, cfg, *result.FileURL, outputPath, "🎥 Downloading video")
+	if err != nil {
+		return "", fmt.Errorf("failed to download video: %w", err)
+	}
+
+	fmt.Fprintf(out, "✅ Video saved successfully!\n")
+	fmt.Fprintf(out, "   File: %s\n", download.Path)
+	fmt.Fprintf(out, "   Size: %s (%d bytes)\n", ui.FormatBytes(download.Size), download.Size)
+	if download.Verified {
+		fmt.Fprintf(out, "   SHA-256: %s (matches the server)\n", download.SHA256)
+	} else {
+		fmt.Fprintf(out, "   SHA-256: %s\n", download.SHA256)
+	}
+	if result.OutputDuration != nil {
+		fmt.Fprintf(out, "   Duration: %.2f seconds\n", *result.OutputDuration)
+	}
+	return download.Path, nil
+}
+
+// streamVideo writes the video at url to stdout. It is downloaded to a
+// temporary file first, so that an interrupted download can be resumed
+// without sending the same bytes twice.
+func streamVideo(ctx context.Context, out io.Writer, cfg *config.Config, url string) error {
+	dir, err := os.MkdirTemp("", "mirako-video-*")
+	if err != nil {
+		return fmt.Errorf("failed to download video: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	download, err := util.DownloadFile(ctx, out, cfg, url, filepath.Join(dir, "video.mp4"), "🎥 Downloading video")
+	if err != nil {
+		return fmt.Errorf("failed to download video: %w", err)
+	}
+	if err := media.CopyFile(media.Stdio, download.Path); err != nil {
+		return fmt.Errorf("failed to write video: %w", err)
+	}
+	fmt.Fprintf(out, "✅ Video written to stdout (%s)\n", ui.FormatBytes(download.Size))
+	return nil
 }
 
 func newStatusCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "status [task-id]",
-		Short: "Check talking avatar generation status",
-		Long:  `Check the status of a talking avatar generation task`,
-		Args:  cobra.ExactArgs(1),
-		RunE:  runStatus,
+		Short: "Check video generation status",
+		Long: `Check the status of a video generation task.
+
+When the task has completed, the video is downloaded to --output, or to the
+path requested when the task was submitted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runStatus,
 	}
+
+	cmd.Flags().StringP("model", "m", "", fmt.Sprintf("Model the task was submitted with (%s). Defaults to the recorded job, or %s", GetSupportedModelsString(), VideoModelTalkingAvatar))
+	util.AddSaveFlags(cmd)
+
+	return cmd
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 
-	cfg, err := util.GetConfig(cmd)
+	taskID := args[0]
+	job := util.LookupJob(taskID)
+
+	cfg, err := util.GetJobConfig(cmd, job)
 	if err != nil {
 		return err
 	}
 
-	taskID := args[0]
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	outputPath, _ := cmd.Flags().GetString("output")
+	out, err := util.MediaProgressWriter(format, outputPath)
+	if err != nil {
+		return err
+	}
 
-	client, err := client.New(cfg)
+	kind := client.TaskKindTalkingAvatar
+	if modelStr, _ := cmd.Flags().GetString("model"); modelStr != "" {
+		model := VideoModel(modelStr)
+		if !model.IsValid() {
+			return fmt.Errorf("unknown model type: %s. Supported models: %s", modelStr, GetSupportedModelsString())
+		}
+		kind = model.TaskKind()
+	} else if job != nil && job.Kind == client.TaskKindAvatarMotion {
+		kind = job.Kind
+	}
+
+	c, err := client.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	resp, err := client.GetTalkingAvatarStatus(ctx, taskID)
+	result, err := c.GetTaskStatus(ctx, kind, taskID)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to get status: %w", err)
 	}
+	util.RecordJobStatus(taskID, result.Status)
 
-	if resp.Data == nil {
-		return fmt.Errorf("unexpected response from server")
-	}
+	fmt.Fprintf(out, "Task ID: %s\n", result.TaskID)
+	fmt.Fprintf(out, "Status: %s\n", result.Status)
 
-	fmt.Printf("Task ID: %s\n", resp.Data.TaskId)
-
-	if resp.Data.Status == api.GenerateTalkingAvatarTaskOutputStatusCOMPLETED {
-		if resp.Data.FileUrl != nil {
-			videoURL := *resp.Data.FileUrl
-			fmt.Printf("✅ Talking avatar video generated successfully!\n")
-			fmt.Printf("   Video URL: %s\n", videoURL)
-			if resp.Data.OutputDuration != nil {
-				fmt.Printf("   Duration: %.2f seconds\n", *resp.Data.OutputDuration)
-			}
-
-			// Ask user if they want to download the video
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Print("\nWould you like to download the generated video? (Y/n): ")
-			response, _ := reader.ReadString('\n')
-			response = strings.TrimSpace(strings.ToLower(response))
-
-			if response == "" || response == "y" || response == "yes" {
-				// Generate default filename
-				defaultFilename := fmt.Sprintf("video_%s.mp4", taskID)
-				defaultPath := filepath.Join(cfg.DefaultSavePath, defaultFilename)
-
-				// Ask for save location
-				fmt.Printf("Enter save path [%s]: ", defaultPath)
-				savePath, _ := reader.ReadString('\n')
-				savePath = strings.TrimSpace(savePath)
-
-				if savePath == "" {
-					savePath = defaultPath
-				}
-
-				// Ensure .mp4 extension
-				if !strings.HasSuffix(strings.ToLower(savePath), ".mp4") {
-					savePath += ".mp4"
-				}
-
-				// Create directory if it doesn't exist
-				dir := filepath.Dir(savePath)
-				if err := os.MkdirAll(dir, 0755); err != nil {
-					return fmt.Errorf("failed to create directory: %w", err)
-				}
-
-				// Download the video
-				fmt.Printf("🎥 Downloading video...\n")
-				httpResp, err := http.Get(videoURL)
-				if err != nil {
-					return fmt.Errorf("failed to download video: %w", err)
-				}
-				defer httpResp.Body.Close()
-
-				if httpResp.StatusCode != http.StatusOK {
-					return fmt.Errorf("failed to download video: HTTP %d", httpResp.StatusCode)
-				}
-
-				// Create the output file
-				outFile, err := os.Create(savePath)
-				if err != nil {
-					return fmt.Errorf("failed to create output file: %w", err)
-				}
-				defer outFile.Close()
-
-				// Copy the response body to the file
-				bytesWritten, err := io.Copy(outFile, httpResp.Body)
-				if err != nil {
-					return fmt.Errorf("failed to save video: %w", err)
-				}
-
-				fmt.Printf("✅ Video saved successfully!\n")
-				fmt.Printf("   File: %s\n", savePath)
-				fmt.Printf("   Size: %d bytes\n", bytesWritten)
-			} else {
-				fmt.Println("Video not downloaded.")
-			}
+	if err := result.Err(); err != nil {
+		return err
+	}
+	if !result.IsCompleted() || result.FileURL == nil {
+		if !format.IsTable() {
+			return util.PrintOutput(format, videoTaskOutput(kind, result, ""))
 		}
+		return nil
 	}
 
-	return nil
+	fmt.Fprintf(out, "✅ Video generated successfully!\n")
+	fmt.Fprintf(out, "   Video URL: %s\n", *result.FileURL)
+
+	defaultPath := filepath.Join(cfg.DefaultSavePath, fmt.Sprintf("video_%s.mp4", taskID))
+	savePath, ok := util.ResultSavePath(cmd, taskID, defaultPath)
+	if !ok {
+		fmt.Fprintln(out, "Video not downloaded.")
+		if !format.IsTable() {
+			return util.PrintOutput(format, videoTaskOutput(kind, result, ""))
+		}
+		return nil
+	}
+	return finishVideoTask(ctx, out, format, cfg, kind, result, savePath, false, startRecordedCaptions(ctx, c, job, savePath))
 }
@@ -0,0 +1,317 @@
+package voice
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/dataset"
+	"github.com/mirako-ai/mirako-cli/internal/errors"
+	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
+	"github.com/mirako-ai/mirako-cli/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+func newDatasetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dataset",
+		Short: "Prepare voice clone datasets",
+		Long:  `Inspect and prepare the audio samples and annotation file used by voice clone`,
+	}
+
+	cmd.AddCommand(newDatasetCheckCmd())
+	cmd.AddCommand(newDatasetAnnotateCmd())
+
+	return cmd
+}
+
+func newDatasetCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check voice clone samples before uploading them",
+		Long: fmt.Sprintf(`Check the audio samples and annotation file of a voice clone dataset.
+
+Every .wav and .mp3 file in the audio directory is decoded to report its
+duration, sample rate and channels. For WAV files the peak level, the share of
+clipped samples and the share of silence are measured as well; MP3 files are
+not decoded, so a warning notes that their levels were not checked.
+
+Errors make voice clone fail or leave a sample unusable:
+- fewer than %d audio files
+- files that cannot be decoded or contain no audio
+- files missing from the annotation file, or listed but not found
+- empty transcriptions
+
+Warnings point out samples outside the recommended ranges:
+- clips shorter than 3s or longer than 15s
+- a total duration below 1 minute or above 30 minutes
+- sample rates below 16 kHz
+- clipping in more than 0.1%% of the samples, or more than 30%% silence
+- duplicate transcriptions
+
+The command exits with status 2 when errors are found.
+
+Example usage:
+   mirako voice dataset check --audio-dir ./samples/ --annotations ./annotation.list
+   mirako voice dataset check --audio-dir ./samples/ --json`, client.MinVoiceCloneSamples),
+		Args: cobra.NoArgs,
+		RunE: runDatasetCheck,
+	}
+
+	cmd.Flags().StringP("audio-dir", "a", "", "Directory containing .wav or .mp3 audio sample files")
+	cmd.Flags().StringP("annotations", "t", "", "Path to annotation file (default: annotation.list in the audio directory, if present)")
+	cmd.Flags().BoolP("json", "j", false, "Print the report as JSON (same as --output-format=json)")
+	cmd.MarkFlagRequired("audio-dir")
+
+	return cmd
+}
+
+func runDatasetCheck(cmd *cobra.Command, args []string) error {
+	audioDir, _ := cmd.Flags().GetString("audio-dir")
+	annotations, _ := cmd.Flags().GetString("annotations")
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(audioDir); err != nil || !info.IsDir() {
+		return errors.NewValidationError(fmt.Errorf("audio directory does not exist: %s", audioDir))
+	}
+	if annotations == "" {
+		if path := filepath.Join(audioDir, dataset.DefaultAnnotationFileName); fileExists(path) {
+			annotations = path
+		}
+	} else if !fileExists(annotations) {
+		return errors.NewValidationError(fmt.Errorf("annotations file does not exist: %s", annotations))
+	}
+
+	report, err := dataset.Check(audioDir, annotations)
+	if err != nil {
+		return errors.NewValidationError(err)
+	}
+
+	if format.IsTable() {
+		printDatasetReport(cmd.OutOrStdout(), report, annotations)
+	} else if err := util.PrintOutput(format, report); err != nil {
+		return err
+	}
+
+	if report.Errors > 0 {
+		return errors.NewValidationError(fmt.Errorf("dataset check found %d errors", report.Errors))
+	}
+	return nil
+}
+
+func printDatasetReport(out io.Writer, report *dataset.Report, annotations string) {
+	if len(report.Samples) > 0 {
+		t := ui.NewDatasetSampleTable(out)
+		for _, sample := range report.Samples {
+			rate, channels := "", ""
+			if sample.SampleRate > 0 {
+				rate = fmt.Sprintf("%d Hz", sample.SampleRate)
+				channels = fmt.Sprintf("%d", sample.Channels)
+			}
+			t.AddRow([]interface{}{
+				sample.File,
+				fmt.Sprintf("%.1fs", sample.Duration),
+				rate,
+				channels,
+				formatLevel(sample.Peak, 1, "%.2f"),
+				formatLevel(sample.ClippingRatio, 100, "%.2f%%"),
+				formatLevel(sample.SilenceRatio, 100, "%.0f%%"),
+			})
+		}
+		t.Flush()
+		fmt.Fprintln(out)
+	}
+
+	total := time.Duration(report.TotalDuration * float64(time.Second)).Round(100 * time.Millisecond)
+	fmt.Fprintf(out, "%d samples, %s in total\n", len(report.Samples), total)
+	if annotations == "" {
+		fmt.Fprintf(out, "No annotation file given, transcriptions were not checked\n")
+	}
+
+	if len(report.Issues) > 0 {
+		fmt.Fprintln(out)
+	}
+	for _, issue := range report.Issues {
+		icon := "⚠️ "
+		if issue.Severity == dataset.SeverityError {
+			icon = "❌"
+		}
+		location := issue.File
+		if issue.Line > 0 {
+			location = fmt.Sprintf("%s (line %d)", issue.File, issue.Line)
+		}
+		if location == "" {
+			fmt.Fprintf(out, "%s %s\n", icon, issue.Message)
+		} else {
+			fmt.Fprintf(out, "%s %s: %s\n", icon, location, issue.Message)
+		}
+	}
+
+	if report.Errors == 0 {
+		fmt.Fprintf(out, "\n✅ No errors found (%d warnings)\n", report.Warnings)
+	} else {
+		fmt.Fprintf(out, "\n%d errors, %d warnings\n", report.Errors, report.Warnings)
+	}
+}
+
+// annotateOutput is the structured output of `voice dataset annotate`
+type annotateOutput struct {
+	Output         string                  `json:"output"`
+	Transcriptions []dataset.Transcription `json:"transcriptions"`
+	Review         int                     `json:"review"`
+	Failed         int                     `json:"failed"`
+}
+
+func newDatasetAnnotateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "annotate",
+		Short: "Draft the annotation file of a voice clone dataset with speech to text",
+		Long: `Transcribe every .wav and .mp3 file in the audio directory and write a draft
+annotation file in the "filename|transcription" format expected by voice clone.
+
+Transcriptions that are empty, failed, have a low confidence or an implausible
+speech rate for the length of the clip are listed for review. Failed files are
+written with an empty transcription, to be filled in by hand. Always proofread
+the draft before cloning: transcription errors are learned by the voice.
+
+Example usage:
+   mirako voice dataset annotate --audio-dir ./samples/
+   mirako voice dataset annotate --audio-dir ./samples/ --output ./annotation.list --concurrency 8`,
+		Args: cobra.NoArgs,
+		RunE: runDatasetAnnotate,
+	}
+
+	cmd.Flags().StringP("audio-dir", "a", "", "Directory containing .wav or .mp3 audio sample files")
+	cmd.Flags().StringP("output", "o", "", "Path of the annotation file to write (default: annotation.list in the audio directory)")
+	cmd.Flags().IntP("concurrency", "c", 4, "Number of files transcribed at once")
+	cmd.Flags().BoolP("force", "f", false, "Overwrite an existing annotation file")
+	cmd.Flags().BoolP("json", "j", false, "Print the result as JSON (same as --output-format=json)")
+	cmd.MarkFlagRequired("audio-dir")
+
+	return cmd
+}
+
+func runDatasetAnnotate(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	audioDir, _ := cmd.Flags().GetString("audio-dir")
+	outputPath, _ := cmd.Flags().GetString("output")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	force, _ := cmd.Flags().GetBool("force")
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	out := util.ProgressWriter(format)
+
+	if info, err := os.Stat(audioDir); err != nil || !info.IsDir() {
+		return errors.NewValidationError(fmt.Errorf("audio directory does not exist: %s", audioDir))
+	}
+	if concurrency < 1 {
+		return errors.NewValidationError(fmt.Errorf("concurrency must be at least 1"))
+	}
+	if outputPath == "" {
+		outputPath = filepath.Join(audioDir, dataset.DefaultAnnotationFileName)
+	}
+	if fileExists(outputPath) && !force {
+		return errors.NewValidationError(fmt.Errorf("annotation file already exists: %s (use --force to overwrite it)", outputPath))
+	}
+
+	cfg, err := util.GetConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	c, err := client.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	files, err := client.ScanAudioFiles(audioDir)
+	if err != nil {
+		return fmt.Errorf("failed to scan audio files: %w", err)
+	}
+	total := len(files)
+
+	spinner := ui.NewSpinner(out, fmt.Sprintf("Transcribing 0/%d files...", total))
+	spinner.Start()
+	done := 0
+	transcriptions, err := dataset.Annotate(ctx, c, audioDir, dataset.AnnotateOptions{
+		Concurrency: concurrency,
+		OnResult: func(dataset.Transcription) {
+			done++
+			spinner.Update(fmt.Sprintf("Transcribing %d/%d files...", done, total))
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("operation cancelled: %w", ctx.Err())
+	}
+
+	if err := dataset.WriteAnnotations(outputPath, transcriptions); err != nil {
+		return err
+	}
+
+	result := annotateOutput{Output: outputPath, Transcriptions: transcriptions}
+	for _, t := range transcriptions {
+		if t.Error != "" {
+			result.Failed++
+		}
+		if len(t.Review) > 0 {
+			result.Review++
+		}
+	}
+
+	if format.IsTable() {
+		fmt.Fprintf(out, "✅ Draft annotation file saved to: %s\n", outputPath)
+		if result.Review > 0 {
+			fmt.Fprintf(out, "\n⚠️  %d of %d transcriptions need review:\n", result.Review, total)
+			for _, t := range transcriptions {
+				if len(t.Review) == 0 {
+					continue
+				}
+				reason := strings.Join(t.Review, ", ")
+				if t.Error != "" {
+					reason += ": " + t.Error
+				}
+				fmt.Fprintf(out, "   %s: %s\n", t.File, reason)
+				if t.Text != "" {
+					fmt.Fprintf(out, "      %q\n", t.Text)
+				}
+			}
+		}
+		fmt.Fprintf(out, "\nProofread the file, then run: mirako voice dataset check --audio-dir %s --annotations %s\n", audioDir, outputPath)
+	} else if err := util.PrintOutput(format, result); err != nil {
+		return err
+	}
+
+	if result.Failed > 0 {
+		return fmt.Errorf("%d of %d files could not be transcribed", result.Failed, total)
+	}
+	return nil
+}
+
+// formatLevel formats a measured level scaled by scale, or "-" when it was
+// not measured
+func formatLevel(value *float64, scale float64, format string) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf(format, *value*scale)
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
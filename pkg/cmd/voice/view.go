@@ -2,9 +2,9 @@ package voice
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/mirako-ai/mirako-cli/internal/client"
+	"github.com/mirako-ai/mirako-cli/internal/errors"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
 	"github.com/spf13/cobra"
 )
@@ -14,34 +14,41 @@ var viewCmd = &cobra.Command{
 	Short: "Get a specific voice profile by ID",
 	Long:  `Get detailed information about a specific voice profile by its unique ID.`,
 	Args:  cobra.ExactArgs(1),
-	Run:   runView,
+	RunE:  runView,
 }
 
-func runView(cmd *cobra.Command, args []string) {
+func runView(cmd *cobra.Command, args []string) error {
 	profileID := args[0]
 	
 	cfg, err := util.GetConfig(cmd)
 	if err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 	
 	client, err := client.New(cfg)
 	if err != nil {
-		fmt.Printf("Error creating client: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to create client: %w", err)
 	}
 
 	resp, err := client.GetVoiceProfile(cmd.Context(), profileID)
 	if err != nil {
-		fmt.Printf("Error getting voice profile: %v\n", err)
-		os.Exit(1)
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
+		}
+		return fmt.Errorf("failed to get voice profile: %w", err)
 	}
 
 	profile := resp.Data
 	if profile.Id == "" {
-		fmt.Println("Voice profile not found")
-		os.Exit(1)
+		return fmt.Errorf("voice profile not found")
+	}
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return util.PrintOutput(format, resp)
 	}
 
 	fmt.Printf("Voice Profile Details:\n")
@@ -57,8 +64,9 @@ func runView(cmd *cobra.Command, args []string) {
 	if profile.SampleClip != nil {
 		fmt.Printf("  Sample: %s\n", *profile.SampleClip)
 	}
+	return nil
 }
 
 func init() {
 	// This will be registered in cmd.go
-}
\ No newline at end of file
+}
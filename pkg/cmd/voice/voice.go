@@ -10,7 +10,6 @@ import (
 	"github.com/mirako-ai/mirako-cli/internal/errors"
 	"github.com/mirako-ai/mirako-cli/pkg/cmd/util"
 	"github.com/mirako-ai/mirako-cli/pkg/ui"
-	"github.com/mirako-ai/mirako-go/api"
 	"github.com/spf13/cobra"
 )
 
@@ -49,6 +48,7 @@ func NewVoiceCmd() *cobra.Command {
 	cmd.AddCommand(newListProfilesCmd())
 	cmd.AddCommand(newListCmd())
 	cmd.AddCommand(newCloneVoiceCmd())
+	cmd.AddCommand(newDatasetCmd())
 	cmd.AddCommand(viewCmd)
 	cmd.AddCommand(deleteCmd)
 
@@ -80,11 +80,19 @@ func runListProfiles(cmd *cobra.Command, args []string) error {
 	resp, err := client.ListPremadeProfiles(ctx)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to list voice profiles: %w", err)
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return util.PrintOutput(format, resp)
+	}
+
 	if resp == nil || resp.Data == nil || len(*resp.Data) == 0 {
 		fmt.Println("No voice profiles found")
 		return nil
@@ -137,11 +145,19 @@ func runListCustomProfiles(cmd *cobra.Command, args []string) error {
 	resp, err := client.ListVoiceProfiles(ctx)
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to list voice profiles: %w", err)
 	}
 
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	if !format.IsTable() {
+		return util.PrintOutput(format, resp)
+	}
+
 	if resp == nil || resp.Data == nil || len(*resp.Data) == 0 {
 		fmt.Println("No custom voice profiles found")
 		return nil
@@ -173,13 +189,13 @@ func newCloneVoiceCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "clone",
 		Short: "Clone a voice from audio samples",
-		Long: `Clone a voice by providing audio samples and annotations.
+		Long: fmt.Sprintf(`Clone a voice by providing audio samples and annotations.
 
 This command creates a new custom voice profile by training on provided audio samples.
 The process is asynchronous and may take significant time to complete.
 
 Required files:
-- Audio samples: At least 6 .wav or .mp3 files in the specified directory
+- Audio samples: At least %d .wav or .mp3 files in the specified directory
 - Annotations: text file with training annotations
 
 Example usage:
@@ -191,7 +207,7 @@ The command will:
 1. Scan the audio directory for .wav or .mp3 files
 2. Upload files and start training
 3. Poll status until completion
-4. Display the new voice profile ID`,
+4. Display the new voice profile ID`, client.MinVoiceCloneSamples),
 		RunE: runCloneVoice,
 	}
 
@@ -199,8 +215,11 @@ The command will:
 	cmd.Flags().StringP("audio-dir", "a", "", "Directory containing .wav or .mp3 audio sample files")
 	cmd.Flags().StringP("annotations", "t", "", "Path to annotation file")
 	cmd.Flags().IntP("poll-interval", "p", 10, "Polling interval in seconds for checking status")
+	cmd.Flags().Duration("timeout", 0, "Maximum time to wait for training to finish, e.g. 2h (0 waits indefinitely)")
 	cmd.Flags().BoolP("clean-data", "c", false, "Enable de-noise processing (default: false)")
 	cmd.Flags().StringP("description", "d", "", "Optional description for the voice profile (max 512 characters)")
+	cmd.Flags().Duration("upload-timeout", 0, "Maximum time for uploading the samples, e.g. 2h (default: upload_timeout from the config, 1h)")
+	util.AddDetachFlags(cmd)
 
 	cmd.MarkFlagRequired("name")
 	cmd.MarkFlagRequired("audio-dir")
@@ -221,8 +240,25 @@ func runCloneVoice(cmd *cobra.Command, args []string) error {
 	audioDir, _ := cmd.Flags().GetString("audio-dir")
 	annotations, _ := cmd.Flags().GetString("annotations")
 	pollInterval, _ := cmd.Flags().GetInt("poll-interval")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
 	cleanData, _ := cmd.Flags().GetBool("clean-data")
 	description, _ := cmd.Flags().GetString("description")
+	wait, _ := cmd.Flags().GetBool("wait")
+	if cmd.Flags().Changed("upload-timeout") {
+		cfg.UploadTimeout, _ = cmd.Flags().GetDuration("upload-timeout")
+	}
+
+	format, err := util.GetOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	out := util.ProgressWriter(format)
+	// With --wait=false stdout only carries the task ID, so the messages
+	// before it go to stderr
+	progressOut := out
+	if !wait {
+		progressOut = os.Stderr
+	}
 
 	// Validate name length
 	if len(name) < 3 || len(name) > 64 {
@@ -248,11 +284,11 @@ func runCloneVoice(cmd *cobra.Command, args []string) error {
 	}
 
 	// Validate annotation file and audio files consistency
-	fmt.Printf("🔍 Validating annotation file and audio samples...\n")
+	fmt.Fprintf(progressOut, "🔍 Validating annotation file and audio samples...\n")
 	if err := c.ValidateVoiceCloneInput(audioDir, annotations); err != nil {
 		return fmt.Errorf("validation failed: %w", err)
 	}
-	fmt.Printf("✅ Validation passed!\n")
+	fmt.Fprintf(progressOut, "✅ Validation passed!\n")
 
 	// Scan audio files and show count
 	audioFiles, err := client.ScanAudioFiles(audioDir)
@@ -260,25 +296,29 @@ func runCloneVoice(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to scan audio files: %w", err)
 	}
 
-	if len(audioFiles) < 6 {
-		return fmt.Errorf("at least 6 audio files (.wav or .mp3) are required for voice cloning. Found: %d", len(audioFiles))
+	if len(audioFiles) < client.MinVoiceCloneSamples {
+		return fmt.Errorf("at least %d audio files (.wav or .mp3) are required for voice cloning. Found: %d", client.MinVoiceCloneSamples, len(audioFiles))
 	}
 
 	// Start voice cloning
-	fmt.Printf("🎤 Starting voice cloning...\n")
-	fmt.Printf("   Name: %s\n", name)
-	fmt.Printf("   Audio directory: %s\n", audioDir)
-	fmt.Printf("   Annotations file: %s\n", annotations)
-	fmt.Printf("   Found %d audio files\n", len(audioFiles))
-	fmt.Printf("   Clean data: %t\n", cleanData)
+	fmt.Fprintf(progressOut, "🎤 Starting voice cloning...\n")
+	fmt.Fprintf(progressOut, "   Name: %s\n", name)
+	fmt.Fprintf(progressOut, "   Audio directory: %s\n", audioDir)
+	fmt.Fprintf(progressOut, "   Annotations file: %s\n", annotations)
+	fmt.Fprintf(progressOut, "   Found %d audio files\n", len(audioFiles))
+	fmt.Fprintf(progressOut, "   Clean data: %t\n", cleanData)
 	if description != "" {
-		fmt.Printf("   Description: %s\n", description)
+		fmt.Fprintf(progressOut, "   Description: %s\n", description)
 	}
 
-	resp, err := c.CloneVoice(ctx, name, audioDir, annotations, cleanData, description)
+	progress := ui.NewProgressBar(progressOut, "Uploading samples")
+	resp, err := c.CloneVoice(ctx, name, audioDir, annotations, cleanData, description, client.CloneVoiceOptions{
+		Progress: progress.Update,
+	})
+	progress.Finish()
 	if err != nil {
 		if apiErr, ok := errors.IsAPIError(err); ok {
-			return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
+			return apiErr.UserError()
 		}
 		return fmt.Errorf("failed to start voice cloning: %w", err)
 	}
@@ -288,65 +328,47 @@ func runCloneVoice(cmd *cobra.Command, args []string) error {
 	}
 
 	taskID := resp.Data.TaskId
-	fmt.Printf("✅ Voice cloning started!\n")
-	fmt.Printf("   Task ID: %s\n", taskID)
+	util.RecordJob(cfg, client.TaskKindVoiceClone, taskID, map[string]string{"name": name, "audio_dir": audioDir}, "")
+	if !wait {
+		return util.PrintSubmittedTask(cmd, client.TaskKindVoiceClone, taskID, string(resp.Data.Status))
+	}
+
+	fmt.Fprintf(out, "✅ Voice cloning started!\n")
+	fmt.Fprintf(out, "   Task ID: %s\n", taskID)
 
 	// Poll for status until complete
-	fmt.Printf("⏳ Waiting for training to complete...\n")
-
-	// Use separate tickers for polling and spinner animation
-	pollTicker := time.NewTicker(time.Duration(pollInterval) * time.Second)
-	spinnerTicker := time.NewTicker(100 * time.Millisecond)
-	defer pollTicker.Stop()
-	defer spinnerTicker.Stop()
-
-	spinnerFrames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
-	spinnerIndex := 0
-	currentStatus := "IN_QUEUE"
-	clearLine := "\r\033[K"
-
-	for {
-		select {
-		case <-ctx.Done():
-			fmt.Print(clearLine)
-			return fmt.Errorf("operation cancelled: %w", ctx.Err())
-		case <-pollTicker.C:
-			statusResp, err := c.GetVoiceCloneStatus(ctx, taskID)
-			if err != nil {
-				fmt.Print(clearLine)
-				if apiErr, ok := errors.IsAPIError(err); ok {
-					return fmt.Errorf("%s", apiErr.GetUserFriendlyMessage())
-				}
-				return fmt.Errorf("failed to check status: %w", err)
-			}
-
-			if statusResp == nil || statusResp.Data == nil {
-				fmt.Print(clearLine)
-				return fmt.Errorf("unexpected response from server")
-			}
-
-			currentStatus = string(statusResp.Data.Status)
-
-			if statusResp.Data.Status == api.FinetuningTaskOutputStatusCOMPLETED {
-				fmt.Print(clearLine)
-				fmt.Printf("✅ Voice cloning completed!\n")
-				fmt.Printf("   Profile ID: %s\n", *statusResp.Data.ProfileId)
-				fmt.Printf("   Task completed successfully\n")
-				return nil
-			} else if statusResp.Data.Status == api.FinetuningTaskOutputStatusFAILED ||
-				statusResp.Data.Status == api.FinetuningTaskOutputStatusCANCELED ||
-				statusResp.Data.Status == api.FinetuningTaskOutputStatusTIMEDOUT {
-				fmt.Print(clearLine)
-				if statusResp.Data.Error != nil && *statusResp.Data.Error != "" {
-					return fmt.Errorf("voice cloning failed: %s", *statusResp.Data.Error)
-				}
-				return fmt.Errorf("voice cloning failed with status: %s", statusResp.Data.Status)
-			}
-			// Continue polling for other statuses
-		case <-spinnerTicker.C:
-			frame := spinnerFrames[spinnerIndex%len(spinnerFrames)]
-			fmt.Printf("\r\033[K%s Status: %s", frame, currentStatus)
-			spinnerIndex++
+	fmt.Fprintf(out, "⏳ Waiting for training to complete...\n")
+
+	spinner := ui.NewSpinner(out, "Status: IN_QUEUE")
+	spinner.Start()
+	result, err := c.WaitForTask(ctx, client.TaskKindVoiceClone, taskID, client.WaitOptions{
+		Interval: time.Duration(pollInterval) * time.Second,
+		Timeout:  timeout,
+		OnStatus: func(status string) {
+			spinner.Update("Status: " + status)
+			util.RecordJobStatus(taskID, status)
+		},
+	})
+	spinner.Stop()
+	if err != nil {
+		if apiErr, ok := errors.IsAPIError(err); ok {
+			return apiErr.UserError()
 		}
+		return err
 	}
+
+	fmt.Fprintf(out, "✅ Voice cloning completed!\n")
+	if result.ProfileID != nil {
+		fmt.Fprintf(out, "   Profile ID: %s\n", *result.ProfileID)
+	}
+	fmt.Fprintf(out, "   Task completed successfully\n")
+
+	if !format.IsTable() {
+		taskOutput := util.TaskOutput{Kind: client.TaskKindVoiceClone, TaskID: taskID, Status: result.Status}
+		if result.ProfileID != nil {
+			taskOutput.ProfileID = *result.ProfileID
+		}
+		return util.PrintOutput(format, taskOutput)
+	}
+	return nil
 }
@@ -0,0 +1,416 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output format names accepted by --output-format
+const (
+	FormatTable    = "table"
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+	FormatCSV      = "csv"
+	FormatTemplate = "template"
+	FormatJQ       = "jq"
+)
+
+// SupportedOutputFormats describes the accepted --output-format values
+const SupportedOutputFormats = "table, json, yaml, csv, template=<go-template>, jq=<selector>"
+
+// OutputFormat selects how command results are printed
+type OutputFormat struct {
+	Name string
+	// Expr holds the template text or selector for the template and jq formats
+	Expr string
+}
+
+// ParseOutputFormat parses an --output-format value such as "json",
+// "template={{.data.id}}" or "jq=.data[].id"
+func ParseOutputFormat(value string) (OutputFormat, error) {
+	name, expr, hasExpr := strings.Cut(value, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	switch name {
+	case "", FormatTable:
+		return OutputFormat{Name: FormatTable}, nil
+	case FormatJSON, FormatYAML, FormatCSV:
+		if hasExpr {
+			return OutputFormat{}, fmt.Errorf("output format %q does not take an expression", name)
+		}
+		return OutputFormat{Name: name}, nil
+	case FormatTemplate, "go-template":
+		if expr == "" {
+			return OutputFormat{}, fmt.Errorf("template output requires a template, e.g. template='{{.data.id}}'")
+		}
+		if _, err := template.New("output").Parse(expr); err != nil {
+			return OutputFormat{}, fmt.Errorf("invalid output template: %w", err)
+		}
+		return OutputFormat{Name: FormatTemplate, Expr: expr}, nil
+	case FormatJQ:
+		if expr == "" {
+			return OutputFormat{}, fmt.Errorf("jq output requires a selector, e.g. jq=.data[].id")
+		}
+		if _, err := parseSelector(expr); err != nil {
+			return OutputFormat{}, err
+		}
+		return OutputFormat{Name: FormatJQ, Expr: expr}, nil
+	default:
+		return OutputFormat{}, fmt.Errorf("unknown output format %q. Supported formats: %s", name, SupportedOutputFormats)
+	}
+}
+
+// IsTable reports whether the human readable output should be used
+func (f OutputFormat) IsTable() bool {
+	return f.Name == "" || f.Name == FormatTable
+}
+
+// PrintOutput writes value to w in a machine-readable format. The value is
+// first converted to its JSON form, so field names match the JSON output in
+// every format. The table format is rendered by each command itself.
+func PrintOutput(w io.Writer, format OutputFormat, value any) error {
+	if format.Name == FormatJSON {
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	normalized, err := normalizeValue(value)
+	if err != nil {
+		return err
+	}
+
+	switch format.Name {
+	case FormatYAML:
+		data, err := yaml.Marshal(normalized)
+		if err != nil {
+			return fmt.Errorf("failed to format YAML output: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatCSV:
+		return writeCSV(w, normalized)
+	case FormatTemplate:
+		return writeTemplate(w, format.Expr, normalized)
+	case FormatJQ:
+		return writeSelection(w, format.Expr, normalized)
+	default:
+		return fmt.Errorf("output format %q is not supported here", format.Name)
+	}
+}
+
+// normalizeValue converts value into plain maps, slices and scalars
+func normalizeValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format output: %w", err)
+	}
+
+	var normalized any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&normalized); err != nil {
+		return nil, fmt.Errorf("failed to format output: %w", err)
+	}
+	return convertNumbers(normalized), nil
+}
+
+// convertNumbers replaces json.Number values with int64 or float64, keeping
+// large integers such as seeds exact
+func convertNumbers(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			v[key] = convertNumbers(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = convertNumbers(child)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	}
+	return value
+}
+
+func writeTemplate(w io.Writer, text string, value any) error {
+	tmpl, err := template.New("output").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid output template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, value); err != nil {
+		return fmt.Errorf("failed to render output template: %w", err)
+	}
+	if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// writeCSV renders a list of objects as rows. API response envelopes of the
+// form {"data": ...} are unwrapped first, and a single object becomes one row.
+func writeCSV(w io.Writer, value any) error {
+	if envelope, ok := value.(map[string]any); ok && len(envelope) == 1 {
+		if data, ok := envelope["data"]; ok {
+			value = data
+		}
+	}
+
+	var rows []any
+	switch v := value.(type) {
+	case []any:
+		rows = v
+	case nil:
+		rows = nil
+	default:
+		rows = []any{v}
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Collect the union of object keys as columns
+	var columns []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		if obj, ok := row.(map[string]any); ok {
+			for key := range obj {
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, key)
+				}
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	writer := csv.NewWriter(w)
+	if len(columns) == 0 {
+		// A list of scalars
+		if err := writer.Write([]string{"value"}); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := writer.Write([]string{formatScalar(row)}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		obj, _ := row.(map[string]any)
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = formatScalar(obj[column])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatScalar renders a value as plain text, with nested values as compact JSON
+func formatScalar(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func writeSelection(w io.Writer, expr string, value any) error {
+	steps, err := parseSelector(expr)
+	if err != nil {
+		return err
+	}
+
+	results := []any{value}
+	for _, step := range steps {
+		var next []any
+		for _, current := range results {
+			selected, err := step.apply(current)
+			if err != nil {
+				return err
+			}
+			next = append(next, selected...)
+		}
+		results = next
+	}
+
+	for _, result := range results {
+		switch v := result.(type) {
+		case map[string]any, []any:
+			data, err := json.MarshalIndent(v, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to format output: %w", err)
+			}
+			fmt.Fprintln(w, string(data))
+		case nil:
+			fmt.Fprintln(w, "null")
+		default:
+			fmt.Fprintln(w, formatScalar(v))
+		}
+	}
+	return nil
+}
+
+// selectorStep is one segment of a jq-like selector: a field, an index or an iteration
+type selectorStep struct {
+	field   string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+func (s selectorStep) apply(value any) ([]any, error) {
+	switch {
+	case s.iterate:
+		switch v := value.(type) {
+		case []any:
+			return v, nil
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			values := make([]any, len(keys))
+			for i, key := range keys {
+				values[i] = v[key]
+			}
+			return values, nil
+		case nil:
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("cannot iterate over %s", describeValue(value))
+		}
+	case s.isIndex:
+		switch v := value.(type) {
+		case []any:
+			index := s.index
+			if index < 0 {
+				index += len(v)
+			}
+			if index < 0 || index >= len(v) {
+				return []any{nil}, nil
+			}
+			return []any{v[index]}, nil
+		case nil:
+			return []any{nil}, nil
+		default:
+			return nil, fmt.Errorf("cannot index %s with a number", describeValue(value))
+		}
+	default:
+		switch v := value.(type) {
+		case map[string]any:
+			return []any{v[s.field]}, nil
+		case nil:
+			return []any{nil}, nil
+		default:
+			return nil, fmt.Errorf("cannot select field %q from %s", s.field, describeValue(value))
+		}
+	}
+}
+
+func describeValue(value any) string {
+	switch value.(type) {
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	case string:
+		return "a string"
+	case int64, float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return "a value"
+	}
+}
+
+// parseSelector parses selectors such as ".", ".data[].id" and ".data[0].name"
+func parseSelector(expr string) ([]selectorStep, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, ".") {
+		return nil, fmt.Errorf("invalid selector %q: must start with '.'", expr)
+	}
+
+	var steps []selectorStep
+	rest := expr
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if field := rest[:end]; field != "" {
+				steps = append(steps, selectorStep{field: field})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid selector %q: missing ']'", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			switch {
+			case inner == "":
+				steps = append(steps, selectorStep{iterate: true})
+			case strings.HasPrefix(inner, `"`):
+				field, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid selector %q: %w", expr, err)
+				}
+				steps = append(steps, selectorStep{field: field})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid selector %q: %q is not an index", expr, inner)
+				}
+				steps = append(steps, selectorStep{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid selector %q", expr)
+		}
+	}
+	return steps, nil
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type testItem struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Count  int               `json:"count"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type testEnvelope struct {
+	Data []testItem `json:"data"`
+}
+
+var testValue = testEnvelope{Data: []testItem{
+	{ID: "a1", Name: "First", Count: 3, Labels: map[string]string{"env": "prod"}},
+	{ID: "b2", Name: "Second, with comma", Count: 10},
+}}
+
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    OutputFormat
+		wantErr string
+	}{
+		{value: "", want: OutputFormat{Name: FormatTable}},
+		{value: "JSON", want: OutputFormat{Name: FormatJSON}},
+		{value: "yaml", want: OutputFormat{Name: FormatYAML}},
+		{value: "template={{.data}}", want: OutputFormat{Name: FormatTemplate, Expr: "{{.data}}"}},
+		{value: "go-template={{.id}}", want: OutputFormat{Name: FormatTemplate, Expr: "{{.id}}"}},
+		{value: "jq=.data[].id", want: OutputFormat{Name: FormatJQ, Expr: ".data[].id"}},
+		{value: "xml", wantErr: "unknown output format"},
+		{value: "csv=.id", wantErr: "does not take an expression"},
+		{value: "template=", wantErr: "requires a template"},
+		{value: "template={{.id", wantErr: "invalid output template"},
+		{value: "jq=data", wantErr: "must start with '.'"},
+		{value: "jq=.data[0", wantErr: "missing ']'"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseOutputFormat(tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseOutputFormat(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseOutputFormat(%q) returned error: %v", tt.value, err)
+		}
+		if got != tt.want {
+			t.Fatalf("ParseOutputFormat(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func printTestOutput(t *testing.T, value string) string {
+	t.Helper()
+	format, err := ParseOutputFormat(value)
+	if err != nil {
+		t.Fatalf("ParseOutputFormat(%q) returned error: %v", value, err)
+	}
+	var buf bytes.Buffer
+	if err := PrintOutput(&buf, format, testValue); err != nil {
+		t.Fatalf("PrintOutput(%q) returned error: %v", value, err)
+	}
+	return buf.String()
+}
+
+func TestPrintOutputYAML(t *testing.T) {
+	got := printTestOutput(t, "yaml")
+	for _, want := range []string{"data:\n", "- count: 3\n", "id: a1\n", "env: prod\n"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("yaml output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestPrintOutputCSVUnwrapsDataEnvelope(t *testing.T) {
+	got := printTestOutput(t, "csv")
+	want := "count,id,labels,name\n" +
+		"3,a1,\"{\"\"env\"\":\"\"prod\"\"}\",First\n" +
+		"10,b2,,\"Second, with comma\"\n"
+	if got != want {
+		t.Fatalf("csv output = %q, want %q", got, want)
+	}
+}
+
+func TestPrintOutputTemplate(t *testing.T) {
+	got := printTestOutput(t, `template={{range .data}}{{.id}}={{.count}} {{end}}`)
+	if got != "a1=3 b2=10 \n" {
+		t.Fatalf("template output = %q", got)
+	}
+}
+
+func TestPrintOutputSelector(t *testing.T) {
+	tests := map[string]string{
+		"jq=.data[].id":         "a1\nb2\n",
+		"jq=.data[-1].count":    "10\n",
+		"jq=.data[0].labels":    "{\n  \"env\": \"prod\"\n}\n",
+		`jq=.data[0]["name"]`:   "First\n",
+		"jq=.data[5].id":        "null\n",
+		"jq=.data[].labels.env": "prod\nnull\n",
+	}
+	for value, want := range tests {
+		if got := printTestOutput(t, value); got != want {
+			t.Fatalf("%s output = %q, want %q", value, got, want)
+		}
+	}
+
+	format, _ := ParseOutputFormat("jq=.data.id")
+	if err := PrintOutput(&bytes.Buffer{}, format, testValue); err == nil || !strings.Contains(err.Error(), "from an array") {
+		t.Fatalf("expected error selecting a field from an array, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const progressBarWidth = 24
+
+// ProgressBar renders a single-line byte progress bar
+type ProgressBar struct {
+	output  io.Writer
+	label   string
+	mu      sync.Mutex
+	written int64
+	total   int64
+	drawn   time.Time
+}
+
+// NewProgressBar creates a progress bar that writes to output with the given label
+func NewProgressBar(output io.Writer, label string) *ProgressBar {
+	return &ProgressBar{output: output, label: label, total: -1}
+}
+
+// Update records the bytes written so far and the total size, or -1 when
+// unknown. The bar is redrawn at most every 100ms.
+func (p *ProgressBar) Update(written, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.written, p.total = written, total
+
+	if now := time.Now(); now.Sub(p.drawn) >= 100*time.Millisecond || (total > 0 && written >= total) {
+		p.drawn = now
+		fmt.Fprint(p.output, clearLine+p.render())
+	}
+}
+
+// Finish clears the progress line
+func (p *ProgressBar) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.drawn.IsZero() {
+		fmt.Fprint(p.output, clearLine)
+	}
+}
+
+func (p *ProgressBar) render() string {
+	if p.total <= 0 {
+		return fmt.Sprintf("%s %s", p.label, FormatBytes(p.written))
+	}
+
+	fraction := float64(p.written) / float64(p.total)
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * progressBarWidth)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+	return fmt.Sprintf("%s %s %3.0f%% %s / %s", p.label, bar, fraction*100, FormatBytes(p.written), FormatBytes(p.total))
+}
+
+// FormatBytes formats a byte count for display, e.g. "12.3 MB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
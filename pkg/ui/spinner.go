@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// SpinnerFrames are the animation frames used by Spinner
+var SpinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const clearLine = "\r\033[K" // ANSI escape codes to clear the line
+
+// Spinner renders an animated single-line status indicator
+type Spinner struct {
+	output io.Writer
+	mu     sync.Mutex
+	text   string
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewSpinner creates a spinner that writes to output with the given initial text
+func NewSpinner(output io.Writer, text string) *Spinner {
+	return &Spinner{
+		output: output,
+		text:   text,
+	}
+}
+
+// Start begins animating the spinner in the background
+func (s *Spinner) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go s.run(s.stop, s.done)
+}
+
+func (s *Spinner) run(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(100 * time.Millisecond) // Smooth spinner animation
+	defer ticker.Stop()
+
+	index := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			frame := SpinnerFrames[index%len(SpinnerFrames)]
+			fmt.Fprintf(s.output, "%s%s %s", clearLine, frame, s.text)
+			s.mu.Unlock()
+			index++
+		}
+	}
+}
+
+// Update replaces the text shown next to the spinner
+func (s *Spinner) Update(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.text = text
+}
+
+// Stop halts the animation and clears the spinner line
+func (s *Spinner) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+	fmt.Fprint(s.output, clearLine)
+}
@@ -179,3 +179,31 @@ func NewVoiceProfileTable(output io.Writer) *TableWriter {
 	t.SetHeader([]string{"ID", "NAME", "DESCRIPTION", "LANGUAGES"})
 	return t
 }
+
+// NewContextTable creates a table for displaying auth contexts
+func NewContextTable(output io.Writer) *TableWriter {
+	t := NewTableWriter(output)
+	t.SetHeader([]string{"CURRENT", "NAME", "API URL", "AUTHENTICATED"})
+	return t
+}
+
+// NewJobTable creates a table for displaying locally recorded jobs
+func NewJobTable(output io.Writer) *TableWriter {
+	t := NewTableWriter(output)
+	t.SetHeader([]string{"TASK ID", "KIND", "CONTEXT", "STATUS", "OUTPUT", "SUBMITTED"})
+	return t
+}
+
+// NewDatasetSampleTable creates a table for displaying voice clone dataset samples
+func NewDatasetSampleTable(output io.Writer) *TableWriter {
+	t := NewTableWriter(output)
+	t.SetHeader([]string{"FILE", "DURATION", "SAMPLE RATE", "CHANNELS", "PEAK", "CLIPPING", "SILENCE"})
+	return t
+}
+
+// NewScriptTimingTable creates a table for displaying the line timings of a rendered script
+func NewScriptTimingTable(output io.Writer) *TableWriter {
+	t := NewTableWriter(output)
+	t.SetHeader([]string{"LINE", "SPEAKER", "START", "END", "TEXT"})
+	return t
+}